@@ -1,5 +1,6 @@
 #!/bin/bash
 echo "YQ_VERSION=v4.6.3" >> $GITHUB_ENV
 echo "HELM_VERSION=v3.5.3" >> $GITHUB_ENV
+echo "PROMETHEUS_VERSION=2.27.1" >> $GITHUB_ENV
 echo "GO_VERSION=1.15" >> $GITHUB_ENV
 echo "GOROOT=/usr/local/go1.15" >> $GITHUB_ENV
\ No newline at end of file
@@ -347,4 +347,106 @@ Cassandra image (Management API image and version tag).
 */}}
 {{- define "k8ssandra.cassandraImage" -}}
 {{- default (get .Values.cassandra.versionImageMap .Values.cassandra.version) .Values.cassandra.image }}
-{{- end }}
\ No newline at end of file
+{{- end }}
+{{/*
+Rules of the cleaner in the namespaces it cleans
+*/}}
+{{- define "k8ssandra.cleanerRules" }}
+  - apiGroups:
+      - cassandra.datastax.com
+    resources:
+      - cassandradatacenters
+    verbs:
+      - get
+      - list
+      - delete
+  # the leftovers of the release are swept whatever their type, the roles
+  # are pre-delete hooks which only exist while the cleaner runs and a Role
+  # only grants the namespaced resources of its namespace
+  - apiGroups:
+      - "*"
+    resources:
+      - "*"
+    verbs:
+      - list
+      - delete
+{{- end }}
+{{/*
+Rules of the cleaner in every namespace, limited to the namespaced resources the
+release creates. The other types are only swept from the namespace of the
+release, through its Role.
+*/}}
+{{- define "k8ssandra.cleanerClusterRules" }}
+  - apiGroups:
+      - cassandra.datastax.com
+    resources:
+      - cassandradatacenters
+    verbs:
+      - get
+      - list
+      - delete
+  - apiGroups:
+      - ""
+    resources:
+      - configmaps
+      - persistentvolumeclaims
+      - secrets
+      - services
+    verbs:
+      - list
+      - delete
+  - apiGroups:
+      - apps
+    resources:
+      - deployments
+      - statefulsets
+    verbs:
+      - list
+      - delete
+  - apiGroups:
+      - batch
+    resources:
+      - jobs
+    verbs:
+      - list
+      - delete
+  - apiGroups:
+      - networking.k8s.io
+    resources:
+      - ingresses
+    verbs:
+      - list
+      - delete
+  - apiGroups:
+      - traefik.containo.us
+    resources:
+      - ingressroutes
+      - ingressroutetcps
+    verbs:
+      - list
+      - delete
+  - apiGroups:
+      - monitoring.coreos.com
+    resources:
+      - podmonitors
+      - prometheusrules
+      - servicemonitors
+    verbs:
+      - list
+      - delete
+  - apiGroups:
+      - reaper.cassandra-reaper.io
+    resources:
+      - reapers
+    verbs:
+      - list
+      - delete
+  - apiGroups:
+      - cassandra.k8ssandra.io
+    resources:
+      - cassandrabackups
+      - cassandrarestores
+    verbs:
+      - list
+      - delete
+{{- end }}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/k8ssandra/k8ssandra/pkg/crds"
+)
+
+func crdsCommand(args []string) {
+	dispatch("crds", map[string]func(args []string){
+		"delete": crdsDelete,
+	}, args)
+}
+
+// crdsDelete deletes the CRDs of the chart and its subcharts after a full uninstall, which helm never does, see
+// crds.DeleteCRDs
+func crdsDelete(args []string) {
+	fs := newFlagSet("crds delete")
+	version := fs.String("version", "", "Version of the k8ssandra chart whose CRDs to delete, downloaded from the Helm repository")
+	chartDir := fs.String("chart", "", "Directory of the k8ssandra chart whose CRDs to delete, instead of -version")
+	force := fs.Bool("force", false, "Delete the CRDs even though custom resources of them exist, deleting those custom resources")
+	_ = fs.Parse(args)
+	if (*version == "") == (*chartDir == "") {
+		log.Fatalf("Set one of -version or -chart")
+	}
+
+	dir := *chartDir
+	if *version != "" {
+		var err error
+		if dir, err = crds.FetchChart(*version); err != nil {
+			log.Fatalf("Failed to fetch the chart version %s: %v", *version, err)
+		}
+	}
+	chartCRDs, err := crds.ChartCRDs(dir)
+	if err != nil {
+		log.Fatalf("Failed to read the CRDs of the chart: %v", err)
+	}
+	if len(chartCRDs) == 0 {
+		log.Fatalf("No CRD found in chart %s", dir)
+	}
+
+	deleted, blocking, err := crds.DeleteCRDs(context.Background(), newClient(), chartCRDs, *force)
+	if len(blocking) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "CRD\tKIND\tNAMESPACE\tNAME")
+		for _, resource := range blocking {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", resource.CRD, resource.Kind, resource.Namespace, resource.Name)
+		}
+		_ = w.Flush()
+	}
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if len(blocking) > 0 && !*force {
+		log.Fatalf("Refusing to delete the CRDs, %d custom resources exist: delete them first, or use -force to delete them with the CRDs", len(blocking))
+	}
+	log.Printf("Deleted %d CRDs", len(deleted))
+}
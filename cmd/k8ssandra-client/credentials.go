@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/k8ssandra/k8ssandra/pkg/cqlsh"
+	"github.com/k8ssandra/k8ssandra/pkg/rotation"
+)
+
+const (
+	defaultRotationTimeout = 30 * time.Minute
+)
+
+func credentialsCommand(args []string) {
+	dispatch("credentials", map[string]func(args []string){
+		"rotate": credentialsRotate,
+	}, args)
+}
+
+func credentialsRotate(args []string) {
+	fs := newFlagSet("credentials rotate")
+	namespace, releaseName := releaseFlags(fs)
+	userName := fs.String("user", "", "User whose password is rotated: superuser, reaper, medusa or stargate")
+	secretName := fs.String("secret", "", "Secret holding the user's credentials, if the release does not use the secret generated by the chart")
+	timeout := fs.Duration("timeout", defaultRotationTimeout, "Timeout of the restart of each component using the credentials")
+	_ = fs.Parse(args)
+	requireRelease(*namespace, *releaseName)
+
+	user, err := rotation.ParseUser(*userName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	config := ctrl.GetConfigOrDie()
+	c := newClientForConfig(config)
+
+	session, err := cqlsh.LookupSession(ctx, c, config, *namespace, *releaseName)
+	if err != nil {
+		log.Fatalf("Failed to connect to Cassandra: %v", err)
+	}
+
+	rotator := &rotation.Rotator{
+		Client:      c,
+		Namespace:   *namespace,
+		ReleaseName: *releaseName,
+		Session:     rotation.CqlshSessionFactory(session),
+		Timeout:     *timeout,
+	}
+	if err := rotator.Rotate(ctx, user, *secretName); err != nil {
+		log.Fatalf("Failed to rotate the credentials of %s: %v", user, err)
+	}
+	log.Printf("Rotated the credentials of %s", user)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	"github.com/k8ssandra/k8ssandra/pkg/cqlsh"
+	"github.com/k8ssandra/k8ssandra/pkg/multidc"
+	"github.com/k8ssandra/k8ssandra/pkg/scale"
+)
+
+const (
+	defaultJoinTimeout = 30 * time.Minute
+)
+
+func datacenterCommand(args []string) {
+	dispatch("datacenter", map[string]func(args []string){
+		"seeds": datacenterSeeds,
+		"add":   datacenterAdd,
+	}, args)
+}
+
+// datacenterSeeds publishes the seeds of the datacenters of a release to a ConfigMap, usually in the Kubernetes
+// cluster of a new datacenter whose release sets cassandra.additionalSeedsConfigMap
+func datacenterSeeds(args []string) {
+	fs := newFlagSet("datacenter seeds")
+	namespace, releaseName := releaseFlags(fs)
+	kubeContext := fs.String("context", "", "Kubeconfig context of the Kubernetes cluster of the release, the current context if empty")
+	targetContext := fs.String("targetContext", "", "Kubeconfig context of the Kubernetes cluster the ConfigMap is written to, the current context if empty")
+	targetNamespace := fs.String("targetNamespace", "", "Namespace the ConfigMap is written to, the namespace of the release if empty")
+	configMap := fs.String("configMap", "", "Name of the ConfigMap, <cluster name>-seeds if empty")
+	_ = fs.Parse(args)
+	requireRelease(*namespace, *releaseName)
+
+	ctx := context.Background()
+	seeds, err := multidc.Seeds(ctx, newClientForConfig(contextConfig(*kubeContext)), *namespace, *releaseName)
+	if err != nil {
+		log.Fatalf("Failed to get the seeds of release %s: %v", *releaseName, err)
+	}
+
+	key := types.NamespacedName{Namespace: *targetNamespace, Name: *configMap}
+	if key.Namespace == "" {
+		key.Namespace = *namespace
+	}
+	if key.Name == "" {
+		key.Name = multidc.SeedsConfigMapName(seeds.ClusterName)
+	}
+	if err := multidc.PublishSeeds(ctx, newClientForConfig(contextConfig(*targetContext)), key, seeds); err != nil {
+		log.Fatalf("Failed to publish the seeds: %v", err)
+	}
+	log.Printf("Published seeds %v of datacenters %v to ConfigMap %s", seeds.Addresses, seeds.Datacenters, key)
+}
+
+// datacenterAdd completes the addition of a datacenter to a cluster once its release is installed
+func datacenterAdd(args []string) {
+	fs := newFlagSet("datacenter add")
+	namespace, releaseName := releaseFlags(fs)
+	kubeContext := fs.String("context", "", "Kubeconfig context of the Kubernetes cluster of the new datacenter, the current context if empty")
+	dc := fs.String("dc", "", "Name of the new datacenter")
+	sourceDc := fs.String("sourceDc", "", "Name of the existing datacenter to stream the data from")
+	keyspaces := fs.String("keyspaces", "", "Comma separated list of keyspaces to replicate to the new datacenter, on top of the system keyspaces")
+	rf := fs.Int("rf", 0, "Replication factor of the keyspaces in the new datacenter, the size of the datacenter up to 3 if unset")
+	timeout := fs.Duration("timeout", defaultJoinTimeout, "How long to wait for the new datacenter to be ready")
+	_ = fs.Parse(args)
+	requireRelease(*namespace, *releaseName)
+
+	if *dc == "" || *sourceDc == "" {
+		log.Fatalf("Both -dc and -sourceDc are required")
+	}
+
+	ctx := context.Background()
+	restConfig := contextConfig(*kubeContext)
+	c := newClientForConfig(restConfig)
+
+	session, err := cqlsh.LookupSession(ctx, c, restConfig, *namespace, *releaseName)
+	if err != nil {
+		log.Fatalf("Failed to connect to Cassandra: %v", err)
+	}
+
+	joiner := &multidc.Joiner{
+		Client:            c,
+		Session:           session,
+		Nodetool:          scale.CqlshNodetool(session),
+		Datacenter:        types.NamespacedName{Namespace: *namespace, Name: *dc},
+		SourceDatacenter:  *sourceDc,
+		Keyspaces:         splitList(*keyspaces),
+		ReplicationFactor: *rf,
+		Timeout:           *timeout,
+	}
+	if err := joiner.Join(ctx); err != nil {
+		log.Fatalf("Failed to add datacenter %s: %v", *dc, err)
+	}
+	log.Printf("Datacenter %s joined the cluster", *dc)
+}
+
+// contextConfig returns the configuration of the given kubeconfig context
+func contextConfig(kubeContext string) *rest.Config {
+	restConfig, err := config.GetConfigWithContext(kubeContext)
+	if err != nil {
+		log.Fatalf("Failed to load the Kubernetes configuration of context %q: %v", kubeContext, err)
+	}
+	return restConfig
+}
@@ -1,9 +1,22 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	reaperapi "github.com/k8ssandra/reaper-operator/api/v1alpha1"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd/api"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k8ssandra/k8ssandra/pkg/cleaner"
 	"github.com/k8ssandra/k8ssandra/pkg/crds"
@@ -11,9 +24,35 @@ import (
 
 var (
 	podNameSpaceEnvVar = "POD_NAMESPACE"
+
+	// commands are the k8ssandra-client subcommands. Invocations which do not start with one of them run the
+	// flag based helm hook modes (-clean, -checkupgrade, -upgradecrds, -checkvalues).
+	commands = map[string]func(args []string){
+		"credentials":       credentialsCommand,
+		"crds":              crdsCommand,
+		"datacenter":        datacenterCommand,
+		"node":              nodeCommand,
+		"orphans":           orphansCommand,
+		"repair":            repairCommand,
+		"replication":       replicationCommand,
+		"restart":           restartCommand,
+		"scale":             scaleCommand,
+		"smoketest":         smoketestCommand,
+		"support-bundle":    supportBundleCommand,
+		"upgrade-cassandra": upgradeCassandraCommand,
+		"values":            valuesCommand,
+		"vault":             vaultCommand,
+	}
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, found := commands[os.Args[1]]; found {
+			command(os.Args[2:])
+			return
+		}
+	}
+
 	namespace := os.Getenv(podNameSpaceEnvVar)
 	if namespace == "" {
 		log.Fatalf("Failed to parse pod's namespace from env variable %s", podNameSpaceEnvVar)
@@ -23,12 +62,40 @@ func main() {
 	var releaseName string
 	flag.StringVar(&releaseName, "release", "", "Defines the releaseName to be cleaned")
 	cleanResources := flag.Bool("clean", false, "Clean resources with finalizers")
+	reportOnly := flag.Bool("report", false, "With -clean, only report the resources of the release which would be deleted")
+	cleanNamespaces := flag.String("namespaces", "", "With -clean, comma separated list of the namespaces to clean besides the namespace of the release")
+	cleanAllNamespaces := flag.Bool("allNamespaces", false, "With -clean, clean every namespace")
+	cleanClusterScoped := flag.Bool("clusterScoped", false, "With -clean, also remove the cluster-scoped leftovers of the release")
 
 	var targetVersion string
 	flag.StringVar(&targetVersion, "targetVersion", "", "Defines the targetVersion to be upgraded to")
 	upgradeCRDs := flag.Bool("upgradecrds", false, "Upgrade CRDs to target version")
+	checkValues := flag.Bool("checkvalues", false, "Report the values of the deployed release which the target version changes")
+	checkUpgrade := flag.Bool("checkupgrade", false, "Refuse the upgrade if the release cannot be upgraded to the target version")
+	cassandraVersion := flag.String("cassandraVersion", "", "With -checkupgrade, refuse the upgrade if datacenters run a Cassandra version above this one")
 	flag.Parse()
 
+	// the upgrade is checked before the CRDs are changed
+	if *checkUpgrade {
+		if releaseName == "" || targetVersion == "" {
+			log.Fatal("No releaseName or targetVersion set")
+			return
+		}
+		problems, err := crds.CheckUpgrade(context.Background(), newClient(), namespace, releaseName, targetVersion, *cassandraVersion)
+		if err != nil {
+			log.Fatalf("Failed to check the upgrade of release %s: %v", releaseName, err)
+			return
+		}
+		for _, problem := range problems {
+			log.Printf("Upgrade check failed: %s", problem)
+		}
+		if len(problems) > 0 {
+			log.Fatalf("Refusing to upgrade release %s to version %s, %d checks failed", releaseName, targetVersion, len(problems))
+			return
+		}
+		log.Printf("Release %s can be upgraded to version %s", releaseName, targetVersion)
+	}
+
 	// Add flags for parsing stuff
 	if *cleanResources {
 		log.Printf("Cleaning resources for uninstall")
@@ -43,6 +110,10 @@ func main() {
 			log.Fatalf("Failed to create new cleaner: %v", err)
 			return
 		}
+		ca.ReportOnly = *reportOnly
+		ca.Namespaces = splitList(*cleanNamespaces)
+		ca.AllNamespaces = *cleanAllNamespaces
+		ca.ClusterScoped = *cleanClusterScoped
 
 		err = ca.RemoveResources(releaseName)
 		if err != nil {
@@ -70,4 +141,74 @@ func main() {
 			return
 		}
 	}
+
+	if *checkValues {
+		if releaseName == "" || targetVersion == "" {
+			log.Fatal("No releaseName or targetVersion set")
+			return
+		}
+		checkDeployedValues(newClient(), namespace, releaseName, targetVersion)
+	}
+}
+
+// dispatch runs the subcommand named by the first argument
+func dispatch(name string, subcommands map[string]func(args []string), args []string) {
+	if len(args) > 0 {
+		if subcommand, found := subcommands[args[0]]; found {
+			subcommand(args[1:])
+			return
+		}
+	}
+
+	names := make([]string, 0, len(subcommands))
+	for subcommand := range subcommands {
+		names = append(names, subcommand)
+	}
+	sort.Strings(names)
+	log.Fatalf("Usage: k8ssandra-client %s <%s> [flags]", name, strings.Join(names, "|"))
+}
+
+// newFlagSet returns the flag set of a subcommand
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: k8ssandra-client %s [flags]\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// releaseFlags registers the flags identifying the target release on a subcommand's flag set
+func releaseFlags(fs *flag.FlagSet) (namespace, releaseName *string) {
+	namespace = fs.String("namespace", os.Getenv(podNameSpaceEnvVar), "Namespace of the release, defaults to $"+podNameSpaceEnvVar)
+	releaseName = fs.String("release", "", "Name of the k8ssandra release")
+	return namespace, releaseName
+}
+
+// requireRelease fails if the release flags were not set
+func requireRelease(namespace, releaseName string) {
+	if namespace == "" {
+		log.Fatalf("No namespace set, use -namespace or $%s", podNameSpaceEnvVar)
+	}
+	if releaseName == "" {
+		log.Fatalf("No release set")
+	}
+}
+
+// newClient returns a controller-runtime client which knows all the resource types used by k8ssandra-client
+func newClient() client.Client {
+	return newClientForConfig(ctrl.GetConfigOrDie())
+}
+
+func newClientForConfig(config *rest.Config) client.Client {
+	_ = api.AddToScheme(scheme.Scheme)
+	_ = cassdcapi.AddToScheme(scheme.Scheme)
+	_ = reaperapi.AddToScheme(scheme.Scheme)
+	_ = apiextv1.AddToScheme(scheme.Scheme)
+
+	c, err := client.New(config, client.Options{Scheme: scheme.Scheme})
+	if err != nil {
+		log.Fatalf("Failed to create Kubernetes client: %v", err)
+	}
+	return c
 }
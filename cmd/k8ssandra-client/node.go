@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8ssandra/k8ssandra/pkg/cqlsh"
+	"github.com/k8ssandra/k8ssandra/pkg/mgmtapi"
+)
+
+func nodeCommand(args []string) {
+	dispatch("node", map[string]func(args []string){
+		"status":   nodeStatus,
+		"drain":    nodeDrain,
+		"flush":    nodeFlush,
+		"cleanup":  nodeCleanup,
+		"compact":  nodeCompact,
+		"snapshot": nodeSnapshot,
+	}, args)
+}
+
+// nodeFlags are the flags shared by all the node subcommands, which select the pods to run an operation on
+type nodeFlags struct {
+	namespace   *string
+	releaseName *string
+	dc          *string
+	pod         *string
+	concurrency *int
+	direct      *bool
+	timeout     *time.Duration
+}
+
+func addNodeFlags(fs *flag.FlagSet, concurrency int, timeout time.Duration) *nodeFlags {
+	f := &nodeFlags{}
+	f.namespace, f.releaseName = releaseFlags(fs)
+	f.dc = fs.String("dc", "", "Name of the datacenter whose pods to run the operation on, the datacenter of the release if empty")
+	f.pod = fs.String("pod", "", "Name of the pod to run the operation on, all the pods of the datacenter if empty")
+	f.concurrency = fs.Int("concurrency", concurrency, "Number of pods to run the operation on at a time")
+	f.direct = fs.Bool("direct", false, "Call the Management API at the IP of the pods instead of through the API server, from inside the Kubernetes cluster")
+	f.timeout = fs.Duration("timeout", timeout, "Timeout of the operation on each pod, none if 0")
+	return f
+}
+
+// keyspaceFlags select the tables of an operation
+func keyspaceFlags(fs *flag.FlagSet) (keyspace, tables *string) {
+	keyspace = fs.String("keyspace", "", "Keyspace to run the operation on, all the keyspaces if empty")
+	tables = fs.String("tables", "", "Comma separated list of tables of the keyspace, all the tables if empty")
+	return keyspace, tables
+}
+
+// connect returns the Management API client and the pods selected by the flags, which must be part of the release
+func (f *nodeFlags) connect(ctx context.Context) (*mgmtapi.Client, []*corev1.Pod) {
+	requireRelease(*f.namespace, *f.releaseName)
+	if *f.dc != "" && *f.pod != "" {
+		log.Fatalf("Both -dc and -pod are set, use one of them")
+	}
+
+	config := ctrl.GetConfigOrDie()
+	c := newClientForConfig(config)
+
+	var mgmtClient *mgmtapi.Client
+	if *f.direct {
+		mgmtClient = mgmtapi.NewPodIPClient()
+	} else {
+		var err error
+		if mgmtClient, err = mgmtapi.NewProxyClient(config); err != nil {
+			log.Fatalf("Failed to create the Management API client: %v", err)
+		}
+	}
+
+	if *f.pod != "" {
+		pod := &corev1.Pod{}
+		key := types.NamespacedName{Namespace: *f.namespace, Name: *f.pod}
+		if err := c.Get(ctx, key, pod); err != nil {
+			log.Fatalf("Failed to get pod %s: %v", key, err)
+		}
+		dc, found := pod.Labels[cassdcapi.DatacenterLabel]
+		if !found {
+			log.Fatalf("Pod %s is not a Cassandra pod", key)
+		}
+		f.checkDatacenter(ctx, c, dc)
+		return mgmtClient, []*corev1.Pod{pod}
+	}
+
+	dc := *f.dc
+	if dc == "" {
+		cassdc, err := cqlsh.LookupDatacenter(ctx, c, *f.namespace, *f.releaseName)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		dc = cassdc.Name
+	} else {
+		f.checkDatacenter(ctx, c, dc)
+	}
+	pods, err := mgmtapi.DatacenterPods(ctx, c, types.NamespacedName{Namespace: *f.namespace, Name: dc})
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	return mgmtClient, pods
+}
+
+// operationContext bounds the operation on a pod with -timeout
+func (f *nodeFlags) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if *f.timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, *f.timeout)
+}
+
+// checkDatacenter fails unless the CassandraDatacenter is part of the release
+func (f *nodeFlags) checkDatacenter(ctx context.Context, c client.Client, dc string) {
+	key := types.NamespacedName{Namespace: *f.namespace, Name: dc}
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	if err := c.Get(ctx, key, cassdc); err != nil {
+		log.Fatalf("Failed to get CassandraDatacenter %s: %v", key, err)
+	}
+	if cassdc.Labels[instanceLabel] != *f.releaseName {
+		log.Fatalf("CassandraDatacenter %s is not part of release %s", key, *f.releaseName)
+	}
+}
+
+// runNodeOperation runs the operation on the selected pods, logging the outcome on each pod, and fails if it failed on
+// any of them
+func runNodeOperation(name string, f *nodeFlags, operation func(ctx context.Context, c *mgmtapi.Client, pod *corev1.Pod) error) {
+	ctx := context.Background()
+	mgmtClient, pods := f.connect(ctx)
+
+	start := time.Now()
+	results := mgmtapi.Run(ctx, pods, *f.concurrency, func(ctx context.Context, pod *corev1.Pod) error {
+		log.Printf("Running %s on pod %s", name, pod.Name)
+		podStart := time.Now()
+		ctx, cancel := f.operationContext(ctx)
+		defer cancel()
+		if err := operation(ctx, mgmtClient, pod); err != nil {
+			return err
+		}
+		log.Printf("Ran %s on pod %s in %s", name, pod.Name, time.Since(podStart).Round(time.Second))
+		return nil
+	})
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			log.Printf("%s failed on pod %s: %v", name, result.Pod, result.Err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		log.Fatalf("%s failed on %d of %d pods", name, failed, len(pods))
+	}
+	log.Printf("Ran %s on %d pods in %s", name, len(pods), time.Since(start).Round(time.Second))
+}
+
+func nodeStatus(args []string) {
+	fs := newFlagSet("node status")
+	f := addNodeFlags(fs, 10, time.Minute)
+	_ = fs.Parse(args)
+
+	ctx := context.Background()
+	mgmtClient, pods := f.connect(ctx)
+
+	var mu sync.Mutex
+	statuses := make(map[string]*mgmtapi.NodeStatus)
+	results := mgmtapi.Run(ctx, pods, *f.concurrency, func(ctx context.Context, pod *corev1.Pod) error {
+		ctx, cancel := f.operationContext(ctx)
+		defer cancel()
+		status, err := mgmtClient.Status(ctx, pod)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		statuses[pod.Name] = status
+		return nil
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "POD\tADDRESS\tREADY\tVERSION\tSTATUS\tALIVE")
+	for i, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(w, "%s\t%s\t%s\t\t\t\n", result.Pod, pods[i].Status.PodIP, "unknown")
+			continue
+		}
+		status := statuses[result.Pod]
+		gossip, alive := "", ""
+		if self := status.Self(); self != nil {
+			gossip = self.Status
+			alive = fmt.Sprintf("%d/%d", status.Alive(), len(status.Endpoints))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\t%s\n", status.Pod, status.Address, status.Ready, status.Version, gossip, alive)
+	}
+	_ = w.Flush()
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			log.Printf("Failed to get the status of pod %s: %v", result.Pod, result.Err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		log.Fatalf("Failed to get the status of %d of %d pods", failed, len(pods))
+	}
+}
+
+// nodeDrain drains the node of -pod, or with -all every node of a datacenter after a confirmation: drained nodes stop
+// serving requests until they are restarted
+func nodeDrain(args []string) {
+	fs := newFlagSet("node drain")
+	f := addNodeFlags(fs, 1, 0)
+	all := fs.Bool("all", false, "Drain every node of the datacenter instead of the one of -pod, after a confirmation")
+	yes := fs.Bool("yes", false, "Drain every node of the datacenter with -all without asking for a confirmation")
+	_ = fs.Parse(args)
+
+	if *all == (*f.pod != "") {
+		log.Fatalf("Set one of -pod or -all")
+	}
+	if *all && !*yes {
+		dc := "of release " + *f.releaseName
+		if *f.dc != "" {
+			dc = *f.dc
+		}
+		if !confirm(bufio.NewReader(os.Stdin), fmt.Sprintf("Drain every node of datacenter %s? They stop serving requests until they are restarted.", dc)) {
+			log.Fatalf("Aborted")
+		}
+	}
+
+	runNodeOperation("drain", f, func(ctx context.Context, c *mgmtapi.Client, pod *corev1.Pod) error {
+		return c.Drain(ctx, pod)
+	})
+}
+
+func nodeFlush(args []string) {
+	fs := newFlagSet("node flush")
+	f := addNodeFlags(fs, 1, 0)
+	keyspace, tables := keyspaceFlags(fs)
+	_ = fs.Parse(args)
+
+	request := mgmtapi.KeyspaceRequest{Keyspace: *keyspace, Tables: splitList(*tables)}
+	runNodeOperation("flush", f, func(ctx context.Context, c *mgmtapi.Client, pod *corev1.Pod) error {
+		return c.Flush(ctx, pod, request)
+	})
+}
+
+func nodeCleanup(args []string) {
+	fs := newFlagSet("node cleanup")
+	f := addNodeFlags(fs, 1, 0)
+	keyspace, tables := keyspaceFlags(fs)
+	_ = fs.Parse(args)
+
+	request := mgmtapi.KeyspaceRequest{Keyspace: *keyspace, Tables: splitList(*tables)}
+	runNodeOperation("cleanup", f, func(ctx context.Context, c *mgmtapi.Client, pod *corev1.Pod) error {
+		return c.Cleanup(ctx, pod, request)
+	})
+}
+
+func nodeCompact(args []string) {
+	fs := newFlagSet("node compact")
+	f := addNodeFlags(fs, 1, 0)
+	keyspace, tables := keyspaceFlags(fs)
+	_ = fs.Parse(args)
+
+	request := mgmtapi.KeyspaceRequest{Keyspace: *keyspace, Tables: splitList(*tables)}
+	runNodeOperation("compact", f, func(ctx context.Context, c *mgmtapi.Client, pod *corev1.Pod) error {
+		return c.Compact(ctx, pod, request)
+	})
+}
+
+func nodeSnapshot(args []string) {
+	fs := newFlagSet("node snapshot")
+	f := addNodeFlags(fs, 1, 0)
+	name := fs.String("name", "", "Name of the snapshot, k8ssandra-client-<timestamp> if empty")
+	keyspaces := fs.String("keyspaces", "", "Comma separated list of keyspaces to snapshot, all the keyspaces if empty")
+	_ = fs.Parse(args)
+
+	if *name == "" {
+		*name = fmt.Sprintf("k8ssandra-client-%s", time.Now().UTC().Format("20060102150405"))
+	}
+	runNodeOperation("snapshot "+*name, f, func(ctx context.Context, c *mgmtapi.Client, pod *corev1.Pod) error {
+		return c.Snapshot(ctx, pod, *name, splitList(*keyspaces))
+	})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	"github.com/k8ssandra/k8ssandra/pkg/cleaner"
+)
+
+// orphansCommand reports the resources of the namespace left by releases which no longer exist, and optionally deletes
+// them, see cleaner.FindOrphans
+func orphansCommand(args []string) {
+	fs := newFlagSet("orphans")
+	namespace := fs.String("namespace", os.Getenv(podNameSpaceEnvVar), "Namespace to look for orphans in, defaults to $"+podNameSpaceEnvVar)
+	deleteOrphans := fs.Bool("delete", false, "Delete the orphans, asking for a confirmation for each of them")
+	yes := fs.Bool("yes", false, "Delete the orphans without asking for a confirmation")
+	_ = fs.Parse(args)
+	if *namespace == "" {
+		log.Fatalf("No namespace set, use -namespace or $%s", podNameSpaceEnvVar)
+	}
+
+	ctx := context.Background()
+	c := newClient()
+	orphans, err := cleaner.FindOrphans(ctx, c, *namespace)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if len(orphans) == 0 {
+		log.Printf("No orphaned resources in namespace %s", *namespace)
+		return
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tSIZE\tAGE\tREASON")
+	for _, orphan := range orphans {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", orphan.Kind, orphan.Name, orphan.Size, duration.HumanDuration(now.Sub(orphan.Created)), orphan.Reason)
+	}
+	_ = w.Flush()
+
+	if !*deleteOrphans && !*yes {
+		return
+	}
+	stdin := bufio.NewReader(os.Stdin)
+	deleted := 0
+	for _, orphan := range orphans {
+		if !*yes && !confirm(stdin, fmt.Sprintf("Delete %s %s?", orphan.Kind, orphan.Name)) {
+			continue
+		}
+		if err := cleaner.DeleteOrphan(ctx, c, orphan); err != nil {
+			log.Fatalf("%v", err)
+		}
+		log.Printf("Deleted %s %s", orphan.Kind, orphan.Name)
+		deleted++
+	}
+	log.Printf("Deleted %d of %d orphaned resources in namespace %s", deleted, len(orphans), *namespace)
+}
+
+// confirm asks a yes or no question on the terminal, no being the default
+func confirm(stdin *bufio.Reader, question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := stdin.ReadString('\n')
+	if err != nil && answer == "" {
+		log.Fatalf("Failed to read the answer: %v", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	reapergo "github.com/k8ssandra/reaper-client-go/reaper"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/k8ssandra/k8ssandra/pkg/reaper"
+)
+
+func repairCommand(args []string) {
+	dispatch("repair", map[string]func(args []string){
+		"run":      repairRun,
+		"list":     repairList,
+		"pause":    repairPause,
+		"resume":   repairResume,
+		"abort":    repairAbort,
+		"schedule": repairSchedule,
+	}, args)
+}
+
+func repairSchedule(args []string) {
+	dispatch("repair schedule", map[string]func(args []string){
+		"create":    repairScheduleCreate,
+		"list":      repairScheduleList,
+		"delete":    repairScheduleDelete,
+		"reconcile": repairScheduleReconcile,
+	}, args)
+}
+
+// reaperFlags are the flags shared by all the repair subcommands
+type reaperFlags struct {
+	namespace   *string
+	releaseName *string
+	reaperURL   *string
+}
+
+func addReaperFlags(fs *flag.FlagSet) *reaperFlags {
+	f := &reaperFlags{}
+	f.namespace, f.releaseName = releaseFlags(fs)
+	f.reaperURL = fs.String("reaperUrl", "", "Overrides the URL of Reaper's REST API, for use outside of the Kubernetes cluster")
+	return f
+}
+
+// connect logs in to the release's Reaper
+func (f *reaperFlags) connect(ctx context.Context) (*reaper.Client, *reaper.Connection) {
+	requireRelease(*f.namespace, *f.releaseName)
+
+	c, conn, err := f.tryConnect(ctx)
+	if err != nil {
+		log.Fatalf("Failed to connect to Reaper: %v", err)
+	}
+	return c, conn
+}
+
+func (f *reaperFlags) tryConnect(ctx context.Context) (*reaper.Client, *reaper.Connection, error) {
+	conn, err := reaper.LookupConnection(ctx, newClient(), *f.namespace, *f.releaseName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if *f.reaperURL != "" {
+		if conn.URL, err = url.Parse(*f.reaperURL); err != nil {
+			return nil, nil, fmt.Errorf("invalid Reaper URL %s: %w", *f.reaperURL, err)
+		}
+	}
+
+	c, err := conn.Connect(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to log in to Reaper at %s: %w", conn.URL, err)
+	}
+	return c, conn, nil
+}
+
+func repairRun(args []string) {
+	fs := newFlagSet("repair run")
+	rf := addReaperFlags(fs)
+	keyspace := fs.String("keyspace", "", "Keyspace to repair")
+	tables := fs.String("tables", "", "Comma separated list of tables to repair, all tables if empty")
+	datacenters := fs.String("datacenters", "", "Comma separated list of datacenters to repair, all datacenters if empty")
+	intensity := fs.Float64("intensity", 0, "Repair intensity in (0.0, 1.0], Reaper's default if unset")
+	segmentsPerNode := fs.Int("segmentsPerNode", 0, "Number of segments per node, Reaper's default if unset")
+	parallelism := fs.String("parallelism", "", "One of SEQUENTIAL, PARALLEL or DATACENTER_AWARE, Reaper's default if unset")
+	owner := fs.String("owner", reaper.DefaultOwner, "Owner of the repair run")
+	_ = fs.Parse(args)
+
+	if *keyspace == "" {
+		log.Fatalf("No keyspace set")
+	}
+
+	ctx := context.Background()
+	c, conn := rf.connect(ctx)
+
+	if err := c.EnsureCluster(ctx, conn.ClusterName, conn.SeedHost, conn.JmxCredentials); err != nil {
+		log.Fatalf("Failed to register cluster %s in Reaper: %v", conn.ClusterName, err)
+	}
+
+	options := &reapergo.RepairRunCreateOptions{
+		Tables:              splitList(*tables),
+		Datacenters:         splitList(*datacenters),
+		Intensity:           *intensity,
+		SegmentCountPerNode: *segmentsPerNode,
+		RepairParallelism:   reapergo.RepairParallelism(*parallelism),
+		Cause:               "k8ssandra-client repair run",
+	}
+
+	runId, err := c.CreateRepairRun(ctx, conn.ClusterName, *keyspace, *owner, options)
+	if err != nil {
+		log.Fatalf("Failed to create repair run: %v", err)
+	}
+	if err = c.StartRepairRun(ctx, runId); err != nil {
+		log.Fatalf("Failed to start repair run %s: %v", runId, err)
+	}
+
+	log.Printf("Started repair run %s on %s/%s", runId, conn.ClusterName, *keyspace)
+	fmt.Println(runId)
+}
+
+func repairList(args []string) {
+	fs := newFlagSet("repair list")
+	rf := addReaperFlags(fs)
+	keyspace := fs.String("keyspace", "", "Only list the repair runs of this keyspace")
+	states := fs.String("states", "", "Comma separated list of states to filter on, e.g. RUNNING,PAUSED")
+	_ = fs.Parse(args)
+
+	ctx := context.Background()
+	c, conn := rf.connect(ctx)
+
+	searchOptions := &reapergo.RepairRunSearchOptions{
+		Cluster:  conn.ClusterName,
+		Keyspace: *keyspace,
+	}
+	for _, state := range splitList(*states) {
+		searchOptions.States = append(searchOptions.States, reapergo.RepairRunState(strings.ToUpper(state)))
+	}
+
+	runs, err := c.RepairRuns(ctx, searchOptions)
+	if err != nil {
+		log.Fatalf("Failed to list repair runs: %v", err)
+	}
+
+	sorted := make([]*reapergo.RepairRun, 0, len(runs))
+	for _, run := range runs {
+		sorted = append(sorted, run)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Id.String() < sorted[j].Id.String()
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tKEYSPACE\tSTATE\tPROGRESS\tINTENSITY\tOWNER")
+	for _, run := range sorted {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\t%.2f\t%s\n", run.Id, run.Keyspace, run.State, run.SegmentsRepaired, run.TotalSegments, run.Intensity, run.Owner)
+	}
+	_ = w.Flush()
+}
+
+func repairPause(args []string) {
+	repairRunAction("pause", args, func(ctx context.Context, c *reaper.Client, runId uuid.UUID) error {
+		return c.PauseRepairRun(ctx, runId)
+	})
+}
+
+func repairResume(args []string) {
+	repairRunAction("resume", args, func(ctx context.Context, c *reaper.Client, runId uuid.UUID) error {
+		return c.ResumeRepairRun(ctx, runId)
+	})
+}
+
+func repairAbort(args []string) {
+	repairRunAction("abort", args, func(ctx context.Context, c *reaper.Client, runId uuid.UUID) error {
+		return c.AbortRepairRun(ctx, runId)
+	})
+}
+
+// repairRunAction runs an action against the repair run given by the -id flag
+func repairRunAction(action string, args []string, do func(ctx context.Context, c *reaper.Client, runId uuid.UUID) error) {
+	fs := newFlagSet("repair " + action)
+	rf := addReaperFlags(fs)
+	id := fs.String("id", "", "Id of the repair run")
+	_ = fs.Parse(args)
+
+	runId := parseId(*id)
+
+	ctx := context.Background()
+	c, _ := rf.connect(ctx)
+
+	if err := do(ctx, c, runId); err != nil {
+		log.Fatalf("Failed to %s repair run %s: %v", action, runId, err)
+	}
+	log.Printf("Repair run %s: %s done", runId, action)
+}
+
+func repairScheduleCreate(args []string) {
+	fs := newFlagSet("repair schedule create")
+	rf := addReaperFlags(fs)
+	keyspace := fs.String("keyspace", "", "Keyspace to repair")
+	tables := fs.String("tables", "", "Comma separated list of tables to repair, all tables if empty")
+	datacenters := fs.String("datacenters", "", "Comma separated list of datacenters to repair, all datacenters if empty")
+	intensity := fs.Float64("intensity", 0, "Repair intensity in (0.0, 1.0], Reaper's default if unset")
+	segmentsPerNode := fs.Int("segmentsPerNode", 0, "Number of segments per node, Reaper's default if unset")
+	parallelism := fs.String("parallelism", "", "One of SEQUENTIAL, PARALLEL or DATACENTER_AWARE, Reaper's default if unset")
+	daysBetween := fs.Int("daysBetween", 7, "Number of days between two repairs")
+	triggerTime := fs.String("triggerTime", "", "Time of the first repair, formatted as yyyy-MM-ddTHH:mm:ss, next midnight if unset")
+	owner := fs.String("owner", reaper.DefaultOwner, "Owner of the repair schedule")
+	_ = fs.Parse(args)
+
+	if *keyspace == "" {
+		log.Fatalf("No keyspace set")
+	}
+
+	ctx := context.Background()
+	c, conn := rf.connect(ctx)
+
+	if err := c.EnsureCluster(ctx, conn.ClusterName, conn.SeedHost, conn.JmxCredentials); err != nil {
+		log.Fatalf("Failed to register cluster %s in Reaper: %v", conn.ClusterName, err)
+	}
+
+	options := &reaper.RepairScheduleCreateOptions{
+		Tables:              splitList(*tables),
+		Datacenters:         splitList(*datacenters),
+		Intensity:           *intensity,
+		SegmentCountPerNode: *segmentsPerNode,
+		RepairParallelism:   *parallelism,
+		DaysBetween:         *daysBetween,
+		TriggerTime:         *triggerTime,
+	}
+
+	schedule, err := c.CreateRepairSchedule(ctx, conn.ClusterName, *keyspace, *owner, options)
+	if err != nil {
+		log.Fatalf("Failed to create repair schedule: %v", err)
+	}
+
+	log.Printf("Created repair schedule %s on %s/%s, next activation %s", schedule.Id, conn.ClusterName, *keyspace, schedule.NextActivation)
+	fmt.Println(schedule.Id)
+}
+
+func repairScheduleList(args []string) {
+	fs := newFlagSet("repair schedule list")
+	rf := addReaperFlags(fs)
+	_ = fs.Parse(args)
+
+	ctx := context.Background()
+	c, conn := rf.connect(ctx)
+
+	schedules, err := c.ListRepairSchedules(ctx, conn.ClusterName)
+	if err != nil {
+		log.Fatalf("Failed to list repair schedules: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tKEYSPACE\tSTATE\tDAYS BETWEEN\tNEXT ACTIVATION\tOWNER")
+	for _, schedule := range schedules {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", schedule.Id, schedule.KeyspaceName, schedule.State, schedule.DaysBetween, schedule.NextActivation, schedule.Owner)
+	}
+	_ = w.Flush()
+}
+
+func repairScheduleDelete(args []string) {
+	fs := newFlagSet("repair schedule delete")
+	rf := addReaperFlags(fs)
+	id := fs.String("id", "", "Id of the repair schedule")
+	owner := fs.String("owner", reaper.DefaultOwner, "Owner of the repair schedule")
+	_ = fs.Parse(args)
+
+	scheduleId := parseId(*id)
+
+	ctx := context.Background()
+	c, _ := rf.connect(ctx)
+
+	if err := c.DeleteRepairSchedule(ctx, scheduleId, *owner); err != nil {
+		log.Fatalf("Failed to delete repair schedule: %v", err)
+	}
+	log.Printf("Deleted repair schedule %s", scheduleId)
+}
+
+// repairScheduleReconcile is run by the chart's post-install and post-upgrade hook to make the release's repair
+// schedules match the reaper.repairSchedules.schedules value
+func repairScheduleReconcile(args []string) {
+	fs := newFlagSet("repair schedule reconcile")
+	rf := addReaperFlags(fs)
+	schedules := fs.String("schedules", "[]", "JSON list of the desired repair schedules")
+	timeout := fs.Duration("timeout", 15*time.Minute, "How long to wait for Reaper to be up")
+	_ = fs.Parse(args)
+
+	specs := make([]reaper.ScheduleSpec, 0)
+	if err := json.Unmarshal([]byte(*schedules), &specs); err != nil {
+		log.Fatalf("Failed to parse repair schedules: %v", err)
+	}
+
+	ctx := context.Background()
+	requireRelease(*rf.namespace, *rf.releaseName)
+
+	var c *reaper.Client
+	var conn *reaper.Connection
+	log.Printf("Waiting for Reaper of release %s to be up", *rf.releaseName)
+	err := wait.PollImmediate(10*time.Second, *timeout, func() (bool, error) {
+		var err error
+		if c, conn, err = rf.tryConnect(ctx); err != nil {
+			log.Printf("Reaper is not reachable yet: %v", err)
+			return false, nil
+		}
+		up, err := c.IsReaperUp(ctx)
+		return up && err == nil, nil
+	})
+	if err != nil {
+		log.Fatalf("Reaper of release %s was not up after %s", *rf.releaseName, *timeout)
+	}
+
+	if err = c.EnsureCluster(ctx, conn.ClusterName, conn.SeedHost, conn.JmxCredentials); err != nil {
+		log.Fatalf("Failed to register cluster %s in Reaper: %v", conn.ClusterName, err)
+	}
+
+	result, err := c.ReconcileSchedules(ctx, conn.ClusterName, reaper.ScheduleOwner(*rf.releaseName), specs)
+	if err != nil {
+		log.Fatalf("Failed to reconcile repair schedules: %v", err)
+	}
+
+	log.Printf("Reconciled repair schedules of cluster %s: created %v, updated %v, deleted %v, unchanged %v",
+		conn.ClusterName, result.Created, result.Updated, result.Deleted, result.Unchanged)
+}
+
+// startRepairRuns starts a repair run of each keyspace, limited to the given datacenters
+func startRepairRuns(ctx context.Context, rf *reaperFlags, keyspaces, datacenters []string, cause string) {
+	c, conn := rf.connect(ctx)
+	if err := c.EnsureCluster(ctx, conn.ClusterName, conn.SeedHost, conn.JmxCredentials); err != nil {
+		log.Fatalf("Failed to register cluster %s in Reaper: %v", conn.ClusterName, err)
+	}
+
+	for _, keyspace := range keyspaces {
+		options := &reapergo.RepairRunCreateOptions{
+			Datacenters: datacenters,
+			Cause:       cause,
+		}
+		runId, err := c.CreateRepairRun(ctx, conn.ClusterName, keyspace, reaper.DefaultOwner, options)
+		if err != nil {
+			log.Fatalf("Failed to create repair run of keyspace %s: %v", keyspace, err)
+		}
+		if err = c.StartRepairRun(ctx, runId); err != nil {
+			log.Fatalf("Failed to start repair run %s: %v", runId, err)
+		}
+		log.Printf("Started repair run %s on %s/%s", runId, conn.ClusterName, keyspace)
+	}
+}
+
+func parseId(id string) uuid.UUID {
+	if id == "" {
+		log.Fatalf("No id set")
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		log.Fatalf("Invalid id %s: %v", id, err)
+	}
+	return parsed
+}
+
+// splitList splits a comma separated flag value, an empty value gives an empty list
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
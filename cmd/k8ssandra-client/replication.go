@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/k8ssandra/k8ssandra/pkg/cqlsh"
+	"github.com/k8ssandra/k8ssandra/pkg/replication"
+)
+
+func replicationCommand(args []string) {
+	dispatch("replication", map[string]func(args []string){
+		"reconcile": replicationReconcile,
+	}, args)
+}
+
+// replicationReconcile makes the replication of the system keyspaces and of the keyspaces of the release follow its
+// datacenters. It runs once, as the chart's post-install and post-upgrade hook does, or every -interval.
+func replicationReconcile(args []string) {
+	fs := newFlagSet("replication reconcile")
+	rf := addReaperFlags(fs)
+	keyspaces := fs.String("keyspaces", "", "Comma separated list of keyspaces to reconcile, on top of the system keyspaces and the ones of Reaper and Stargate")
+	repair := fs.Bool("repair", false, "Start a Reaper repair of each altered keyspace")
+	allowDecrease := fs.Bool("allowDecrease", false, "Lower the replication factors above their target, which are only raised otherwise")
+	dryRun := fs.Bool("dryRun", false, "Log the statements instead of running them")
+	interval := fs.Duration("interval", 0, "Reconcile every interval instead of once")
+	timeout := fs.Duration("timeout", 15*time.Minute, "How long to wait for the datacenters to be ready")
+	_ = fs.Parse(args)
+	requireRelease(*rf.namespace, *rf.releaseName)
+
+	ctx := context.Background()
+	config := ctrl.GetConfigOrDie()
+	c := newClientForConfig(config)
+
+	reconciler := &replication.Reconciler{
+		Client:        c,
+		Namespace:     *rf.namespace,
+		ReleaseName:   *rf.releaseName,
+		Keyspaces:     append(replication.DefaultKeyspaces(), splitList(*keyspaces)...),
+		DryRun:        *dryRun,
+		AllowDecrease: *allowDecrease,
+	}
+
+	reconcile := func() error {
+		if err := reconciler.WaitForDatacenters(ctx, *timeout); err != nil {
+			return err
+		}
+		// The session is looked up on each run as the pods it runs in come and go
+		session, err := cqlsh.LookupSession(ctx, c, config, *rf.namespace, *rf.releaseName)
+		if err != nil {
+			return err
+		}
+		reconciler.Session = session
+
+		result, err := reconciler.Reconcile(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Reconciled keyspace replication of release %s: altered %v, unchanged %v, missing %v",
+			*rf.releaseName, result.Altered, result.Unchanged, result.Missing)
+
+		if *repair && !*dryRun && len(result.Altered) > 0 {
+			datacenters, err := reconciler.Datacenters(ctx)
+			if err != nil {
+				return err
+			}
+			startRepairRuns(ctx, rf, result.Altered, datacenters, "k8ssandra-client replication reconcile")
+		}
+		return nil
+	}
+
+	if *interval == 0 {
+		if err := reconcile(); err != nil {
+			log.Fatalf("Failed to reconcile keyspace replication: %v", err)
+		}
+		return
+	}
+
+	for {
+		if err := reconcile(); err != nil {
+			log.Printf("Failed to reconcile keyspace replication, retrying in %s: %v", *interval, err)
+		}
+		time.Sleep(*interval)
+	}
+}
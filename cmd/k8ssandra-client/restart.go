@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/k8ssandra/k8ssandra/pkg/cqlsh"
+	"github.com/k8ssandra/k8ssandra/pkg/restart"
+	"github.com/k8ssandra/k8ssandra/pkg/scale"
+)
+
+const (
+	defaultRestartTimeout      = 20 * time.Minute
+	defaultRestartPauseTimeout = 10 * time.Minute
+)
+
+// restartCommand restarts the Cassandra nodes of a datacenter of a release one at a time, see pkg/restart
+func restartCommand(args []string) {
+	fs := newFlagSet("restart")
+	namespace, releaseName := releaseFlags(fs)
+	dc := fs.String("dc", "", "Name of the datacenter to restart")
+	rack := fs.String("rack", "", "Name of the rack to restart, all the racks of the datacenter if empty")
+	drain := fs.Bool("drain", false, "Run nodetool drain on each node before restarting its pod, on top of the drain of the preStop hook of the pod")
+	timeout := fs.Duration("timeout", defaultRestartTimeout, "How long to wait for each restarted node to rejoin the cluster")
+	pauseTimeout := fs.Duration("pauseTimeout", defaultRestartPauseTimeout, "How long to pause when some nodes are not up and normal before aborting the restart")
+	_ = fs.Parse(args)
+	requireRelease(*namespace, *releaseName)
+
+	if *dc == "" {
+		log.Fatalf("No datacenter set")
+	}
+
+	ctx := context.Background()
+	config := ctrl.GetConfigOrDie()
+	c := newClientForConfig(config)
+
+	key := types.NamespacedName{Namespace: *namespace, Name: *dc}
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	if err := c.Get(ctx, key, cassdc); err != nil {
+		log.Fatalf("Failed to get CassandraDatacenter %s: %v", key, err)
+	}
+	if cassdc.Labels[instanceLabel] != *releaseName {
+		log.Fatalf("CassandraDatacenter %s is not part of release %s", key, *releaseName)
+	}
+
+	session, err := cqlsh.DatacenterSession(ctx, c, config, cassdc)
+	if err != nil {
+		log.Fatalf("Failed to connect to Cassandra: %v", err)
+	}
+
+	restarter := &restart.Restarter{
+		Client:       c,
+		Nodetool:     scale.CqlshNodetool(session),
+		Timeout:      *timeout,
+		PauseTimeout: *pauseTimeout,
+	}
+	if err := restarter.Restart(ctx, key, restart.Options{Rack: *rack, Drain: *drain}); err != nil {
+		log.Fatalf("Failed to restart datacenter %s: %v", *dc, err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/k8ssandra/k8ssandra/pkg/cqlsh"
+	"github.com/k8ssandra/k8ssandra/pkg/replication"
+	"github.com/k8ssandra/k8ssandra/pkg/scale"
+)
+
+const (
+	defaultScaleTimeout = 2 * time.Hour
+	instanceLabel       = "app.kubernetes.io/instance"
+
+	cleanupNodetool = "nodetool"
+	cleanupReaper   = "reaper"
+	cleanupNone     = "none"
+)
+
+func scaleCommand(args []string) {
+	fs := newFlagSet("scale")
+	rf := addReaperFlags(fs)
+	dc := fs.String("dc", "", "Name of the datacenter to scale")
+	size := fs.Int("size", 0, "Number of nodes of the datacenter")
+	timeout := fs.Duration("timeout", defaultScaleTimeout, "How long to wait for the nodes to be bootstrapped or decommissioned")
+	cleanup := fs.String("cleanup", cleanupNodetool, "What to run after a scale-up: nodetool runs nodetool cleanup on the previous nodes one at a time, reaper starts a repair of the datacenter's keyspaces, none does nothing")
+	_ = fs.Parse(args)
+	requireRelease(*rf.namespace, *rf.releaseName)
+
+	if *dc == "" {
+		log.Fatalf("No datacenter set")
+	}
+	if *cleanup != cleanupNodetool && *cleanup != cleanupReaper && *cleanup != cleanupNone {
+		log.Fatalf("Invalid cleanup %s, expected one of %s, %s or %s", *cleanup, cleanupNodetool, cleanupReaper, cleanupNone)
+	}
+
+	ctx := context.Background()
+	config := ctrl.GetConfigOrDie()
+	c := newClientForConfig(config)
+
+	key := types.NamespacedName{Namespace: *rf.namespace, Name: *dc}
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	if err := c.Get(ctx, key, cassdc); err != nil {
+		log.Fatalf("Failed to get CassandraDatacenter %s: %v", key, err)
+	}
+	if cassdc.Labels[instanceLabel] != *rf.releaseName {
+		log.Fatalf("CassandraDatacenter %s is not part of release %s", key, *rf.releaseName)
+	}
+
+	session, err := cqlsh.DatacenterSession(ctx, c, config, cassdc)
+	if err != nil {
+		log.Fatalf("Failed to connect to Cassandra: %v", err)
+	}
+
+	scaler := &scale.Scaler{
+		Client:   c,
+		Session:  session,
+		Nodetool: scale.CqlshNodetool(session),
+		Timeout:  *timeout,
+	}
+	result, err := scaler.Scale(ctx, key, int32(*size))
+	if err != nil {
+		log.Fatalf("Failed to scale datacenter %s: %v", *dc, err)
+	}
+	if result.Size != result.PreviousSize {
+		log.Printf("Set the size of datacenter %s to %d in the values of release %s, or the next helm upgrade will scale it back to %d nodes",
+			*dc, result.Size, *rf.releaseName, result.PreviousSize)
+	}
+	if !result.ScaledUp() {
+		return
+	}
+
+	switch *cleanup {
+	case cleanupNodetool:
+		if err := scaler.Cleanup(ctx, result.PreviousPods); err != nil {
+			log.Fatalf("Cleanup failed: %v", err)
+		}
+	case cleanupReaper:
+		keyspaces, err := replication.Read(ctx, session)
+		if err != nil {
+			log.Fatalf("Failed to list keyspaces: %v", err)
+		}
+		names := make([]string, 0)
+		for _, keyspace := range keyspaces {
+			if !strings.HasPrefix(keyspace.Keyspace, "system") && (keyspace.Factors[*dc] > 0 || keyspace.Factors["replication_factor"] > 0) {
+				names = append(names, keyspace.Keyspace)
+			}
+		}
+		startRepairRuns(ctx, rf, names, []string{*dc}, "k8ssandra-client scale")
+	}
+}
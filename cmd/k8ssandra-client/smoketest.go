@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/k8ssandra/k8ssandra/pkg/smoketest"
+	"github.com/k8ssandra/k8ssandra/pkg/stargate"
+)
+
+const (
+	defaultSmoketestTimeout = 2 * time.Minute
+)
+
+func smoketestCommand(args []string) {
+	fs := newFlagSet("smoketest")
+	namespace, releaseName := releaseFlags(fs)
+	timeout := fs.Duration("timeout", defaultSmoketestTimeout, "Timeout of each check")
+	output := fs.String("output", "-", "File the JUnit report is written to, - for stdout")
+	stargateHost := fs.String("stargateHost", "", "Overrides the host of the Stargate APIs, for use outside of the Kubernetes cluster")
+	stargateSecret := fs.String("stargateSecret", "", "Secret holding the Stargate credentials, if stargate.cassandraUser.secret is set in the release")
+	reaperURL := fs.String("reaperUrl", "", "Overrides the URL of Reaper's REST API, for use outside of the Kubernetes cluster")
+	prometheusURL := fs.String("prometheusUrl", "", "Overrides the URL of Prometheus, for use outside of the Kubernetes cluster")
+	_ = fs.Parse(args)
+	requireRelease(*namespace, *releaseName)
+
+	config := ctrl.GetConfigOrDie()
+	release := &smoketest.Release{
+		Client:         newClientForConfig(config),
+		Config:         config,
+		Namespace:      *namespace,
+		Name:           *releaseName,
+		StargateSecret: *stargateSecret,
+		PrometheusURL:  *prometheusURL,
+	}
+	if *stargateHost != "" {
+		endpoints := stargate.EndpointsForHost("http", *stargateHost)
+		release.StargateEndpoints = &endpoints
+	}
+	if *reaperURL != "" {
+		u, err := url.Parse(*reaperURL)
+		if err != nil {
+			log.Fatalf("Invalid Reaper URL %s: %v", *reaperURL, err)
+		}
+		release.ReaperURL = u
+	}
+
+	report := smoketest.Run(context.Background(), "k8ssandra-smoketest", *timeout, release.Checks())
+	for _, result := range report.Results {
+		switch {
+		case result.Skipped:
+			log.Printf("SKIP %s: %v", result.Name, result.Err)
+		case result.Err != nil:
+			log.Printf("FAIL %s: %v", result.Name, result.Err)
+		default:
+			log.Printf("PASS %s (%s)", result.Name, result.Duration.Round(time.Millisecond))
+		}
+	}
+
+	if err := writeReport(report, *output); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+
+	if failures := report.Failures(); failures > 0 {
+		log.Fatalf("%d of %d checks failed", failures, len(report.Results))
+	}
+}
+
+// writeReport writes the JUnit report to the file, or to stdout if file is -
+func writeReport(report *smoketest.Report, file string) error {
+	if file == "-" {
+		return report.WriteJUnit(os.Stdout)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteJUnit(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
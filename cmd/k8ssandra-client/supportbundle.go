@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/k8ssandra/k8ssandra/pkg/mgmtapi"
+	"github.com/k8ssandra/k8ssandra/pkg/support"
+)
+
+// supportBundleCommand writes the support bundle of a release to a timestamped tarball, see pkg/support
+func supportBundleCommand(args []string) {
+	fs := newFlagSet("support-bundle")
+	namespace, releaseName := releaseFlags(fs)
+	output := fs.String("o", ".", "Directory the tarball is written to")
+	tailLines := fs.Int64("tailLines", 10000, "Number of lines of each container log to collect, all the lines if 0")
+	direct := fs.Bool("direct", false, "Call the Management API at the IP of the pods instead of through the API server, from inside the Kubernetes cluster")
+	_ = fs.Parse(args)
+	requireRelease(*namespace, *releaseName)
+
+	ctx := context.Background()
+	config := ctrl.GetConfigOrDie()
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create Kubernetes client: %v", err)
+	}
+
+	collector := &support.Collector{
+		Client: newClientForConfig(config),
+		Logs:   support.ClientsetLogs(clientset),
+	}
+	if *direct {
+		collector.Mgmt = mgmtapi.NewPodIPClient()
+	} else if collector.Mgmt, err = mgmtapi.NewProxyClient(config); err != nil {
+		log.Fatalf("Failed to create the Management API client: %v", err)
+	}
+
+	now := time.Now()
+	path := filepath.Join(*output, support.Name(*releaseName, now)+".tar.gz")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", path, err)
+	}
+	defer f.Close()
+
+	log.Printf("Collecting the support bundle of release %s", *releaseName)
+	opts := support.Options{Namespace: *namespace, ReleaseName: *releaseName, TailLines: *tailLines}
+	if err := collector.Collect(ctx, opts, now, f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		log.Fatalf("Failed to collect the support bundle of release %s: %v", *releaseName, err)
+	}
+	log.Printf("Wrote the support bundle of release %s to %s", *releaseName, path)
+}
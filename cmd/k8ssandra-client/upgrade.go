@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/k8ssandra/k8ssandra/pkg/cqlsh"
+	"github.com/k8ssandra/k8ssandra/pkg/helmutil"
+	"github.com/k8ssandra/k8ssandra/pkg/scale"
+	"github.com/k8ssandra/k8ssandra/pkg/upgrade"
+	"github.com/k8ssandra/k8ssandra/pkg/values"
+)
+
+const (
+	defaultUpgradeTimeout = 2 * time.Hour
+)
+
+// upgradeCassandraCommand upgrades the Cassandra version of a datacenter of a release after checking that it can be
+// done safely, see pkg/upgrade
+func upgradeCassandraCommand(args []string) {
+	fs := newFlagSet("upgrade-cassandra")
+	namespace, releaseName := releaseFlags(fs)
+	dc := fs.String("dc", "", "Name of the datacenter to upgrade, the datacenter of the release if empty")
+	to := fs.String("to", "", "Cassandra version to upgrade to")
+	image := fs.String("image", "", "Image of the Cassandra version, looked up in cassandra.versionImageMap of the release if empty")
+	backup := fs.String("backup", string(upgrade.BackupSnapshot), "How to save the data before the upgrade: snapshot takes a nodetool snapshot on each node, medusa creates a CassandraBackup, none saves nothing")
+	timeout := fs.Duration("timeout", defaultUpgradeTimeout, "How long to wait for the backup and for the new version to be rolled out")
+	dryRun := fs.Bool("dryRun", false, "Only run the pre-upgrade checks and print the plan")
+	_ = fs.Parse(args)
+	requireRelease(*namespace, *releaseName)
+
+	if *to == "" {
+		log.Fatalf("No version set, use -to")
+	}
+	backupMode, err := upgrade.ParseBackup(*backup)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	ctx := context.Background()
+	config := ctrl.GetConfigOrDie()
+	c := newClientForConfig(config)
+
+	var cassdc *cassdcapi.CassandraDatacenter
+	if *dc == "" {
+		if cassdc, err = cqlsh.LookupDatacenter(ctx, c, *namespace, *releaseName); err != nil {
+			log.Fatalf("%v", err)
+		}
+	} else {
+		cassdc = &cassdcapi.CassandraDatacenter{}
+		key := types.NamespacedName{Namespace: *namespace, Name: *dc}
+		if err := c.Get(ctx, key, cassdc); err != nil {
+			log.Fatalf("Failed to get CassandraDatacenter %s: %v", key, err)
+		}
+		if cassdc.Labels[instanceLabel] != *releaseName {
+			log.Fatalf("CassandraDatacenter %s is not part of release %s", key, *releaseName)
+		}
+	}
+
+	release, err := helmutil.DeployedRelease(c, *namespace, *releaseName)
+	if err != nil {
+		log.Fatalf("Failed to get release %s: %v", *releaseName, err)
+	}
+	if release == nil {
+		log.Fatalf("Release %s has no deployed revision", *releaseName)
+	}
+	merged, err := helmutil.ReleaseValues(release)
+	if err != nil {
+		log.Fatalf("Failed to get the values of release %s: %v", *releaseName, err)
+	}
+	releaseValues, err := values.Decode(merged)
+	if err != nil {
+		log.Fatalf("Failed to decode the values of release %s: %v", *releaseName, err)
+	}
+
+	session, err := cqlsh.DatacenterSession(ctx, c, config, cassdc)
+	if err != nil {
+		log.Fatalf("Failed to connect to Cassandra: %v", err)
+	}
+	upgrader := &upgrade.Upgrader{
+		Client:   c,
+		Nodetool: scale.CqlshNodetool(session),
+		Timeout:  *timeout,
+	}
+
+	key := types.NamespacedName{Namespace: cassdc.Namespace, Name: cassdc.Name}
+	plan, err := upgrader.Plan(ctx, key, upgrade.Options{Version: *to, Image: *image, Backup: backupMode, Values: releaseValues})
+	if err != nil {
+		log.Fatalf("Failed to plan the upgrade of datacenter %s: %v", cassdc.Name, err)
+	}
+	log.Printf("Upgrade of datacenter %s from Cassandra %s to %s:", cassdc.Name, plan.FromVersion, plan.ToVersion)
+	for i, step := range plan.Steps() {
+		log.Printf("  %d. %s", i+1, step)
+	}
+	for _, problem := range plan.Problems {
+		log.Printf("Pre-upgrade check failed: %s", problem)
+	}
+	if len(plan.Problems) > 0 {
+		log.Fatalf("Refusing to upgrade datacenter %s, %d pre-upgrade checks failed", cassdc.Name, len(plan.Problems))
+	}
+	if *dryRun {
+		return
+	}
+
+	if err := upgrader.Upgrade(ctx, plan); err != nil {
+		log.Fatalf("Failed to upgrade datacenter %s: %v", cassdc.Name, err)
+	}
+	log.Printf("Set these values in release %s, the pre-upgrade hook refuses the helm upgrades rolling Cassandra %s back:", *releaseName, plan.FromVersion)
+	for _, value := range plan.Values {
+		log.Printf("  %s", value)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/k8ssandra/k8ssandra/pkg/helmutil"
+	"github.com/k8ssandra/k8ssandra/pkg/values"
+)
+
+// listFlag is a repeatable flag
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func valuesCommand(args []string) {
+	dispatch("values", map[string]func(args []string){
+		"migrate":  valuesMigrate,
+		"validate": valuesValidate,
+	}, args)
+}
+
+// valuesValidate checks values files against the schema of the chart and the constraints between values the schema
+// cannot express, before they are passed to helm install or helm upgrade
+func valuesValidate(args []string) {
+	fs := newFlagSet("values validate")
+	files := &listFlag{}
+	fs.Var(files, "f", "Values file, can be repeated, later files take precedence")
+	chart := fs.String("chart", "", "Path of the k8ssandra chart, the files are merged over its values. Without it, the files are checked on their own")
+	_ = fs.Parse(args)
+
+	if len(*files) == 0 {
+		log.Fatalf("No values file set, use -f")
+	}
+
+	merged, err := values.Load(*chart, *files...)
+	if err != nil {
+		log.Fatalf("Failed to load values: %v", err)
+	}
+	errs := values.Validate(merged)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "- %v\n", err)
+	}
+	if len(errs) > 0 {
+		log.Fatalf("Found %d problems in %s", len(errs), strings.Join(*files, ", "))
+	}
+	log.Printf("%s are valid", strings.Join(*files, ", "))
+}
+
+// valuesMigrate upgrades a values file written for a release of the chart to a later release, following the renamed
+// keys, removed options and changed defaults of the releases in between. With -check it only reports the changes.
+func valuesMigrate(args []string) {
+	fs := newFlagSet("values migrate")
+	file := fs.String("f", "", "Values file to migrate")
+	from := fs.String("from", "", "Version of the chart the values file was written for")
+	to := fs.String("to", "", "Version of the chart to migrate the values file to")
+	out := fs.String("o", "", "File to write the migrated values to, defaults to stdout. Comments of the values file are not kept")
+	check := fs.Bool("check", false, "Only report the changes, fail if the values file needs some")
+	_ = fs.Parse(args)
+
+	if *file == "" || *from == "" || *to == "" {
+		log.Fatalf("-f, -from and -to are required")
+	}
+
+	migrated, err := chartutil.ReadValuesFile(*file)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *file, err)
+	}
+	changes, err := values.Migrate(migrated, *from, *to)
+	if err != nil {
+		log.Fatalf("Failed to migrate %s: %v", *file, err)
+	}
+	for _, change := range changes {
+		fmt.Fprintf(os.Stderr, "- %v\n", change)
+	}
+
+	if *check {
+		if len(changes) > 0 {
+			log.Fatalf("%s needs %d changes to be used with version %s", *file, len(changes), *to)
+		}
+		log.Printf("%s needs no change to be used with version %s", *file, *to)
+		return
+	}
+
+	data, err := yaml.Marshal(migrated)
+	if err != nil {
+		log.Fatalf("Failed to marshal the migrated values: %v", err)
+	}
+	if *out == "" {
+		fmt.Print(string(data))
+	} else if err := ioutil.WriteFile(*out, data, 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+	log.Printf("Migrated %s from version %s to %s with %d changes", *file, *from, *to, len(changes))
+}
+
+// checkDeployedValues reports the values the deployed release was installed or upgraded with which the migrations up to
+// targetVersion change. Upgrades with --reuse-values keep them as they are, so they are only reported, the upgrade
+// goes on.
+func checkDeployedValues(c client.Client, namespace, releaseName, targetVersion string) {
+	deployed, err := helmutil.DeployedRelease(c, namespace, releaseName)
+	if err != nil {
+		log.Printf("Failed to get the deployed release %s, its values are not checked: %v", releaseName, err)
+		return
+	}
+	if deployed == nil || deployed.Chart == nil || deployed.Chart.Metadata == nil {
+		log.Printf("Release %s has no deployed revision, its values are not checked", releaseName)
+		return
+	}
+
+	version := deployed.Chart.Metadata.Version
+	changes, err := values.Migrate(deployed.Config, version, targetVersion)
+	if err != nil {
+		log.Printf("Failed to check the values of release %s: %v", releaseName, err)
+		return
+	}
+	for _, change := range changes {
+		log.Printf("Values of release %s: %v", releaseName, change)
+	}
+	if len(changes) > 0 {
+		log.Printf("The values of release %s need %d changes for version %s, run k8ssandra-client values migrate --from %s --to %s on its values files",
+			releaseName, len(changes), targetVersion, version, targetVersion)
+	}
+}
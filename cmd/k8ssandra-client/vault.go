@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/k8ssandra/k8ssandra/pkg/vault"
+)
+
+const (
+	vaultAddressEnvVar = "VAULT_ADDR"
+	vaultTokenEnvVar   = "VAULT_TOKEN"
+)
+
+// mappingFlag is a repeatable flag of name=value pairs
+type mappingFlag map[string]string
+
+func (m mappingFlag) String() string {
+	pairs := make([]string, 0, len(m))
+	for name, value := range m {
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m mappingFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("expected name=value, got %s", value)
+	}
+	m[parts[0]] = parts[1]
+	return nil
+}
+
+func vaultCommand(args []string) {
+	dispatch("vault", map[string]func(args []string){
+		"sync": vaultSync,
+	}, args)
+}
+
+func vaultSync(args []string) {
+	fs := newFlagSet("vault sync")
+	namespace, releaseName := releaseFlags(fs)
+	address := fs.String("address", os.Getenv(vaultAddressEnvVar), "Address of the Vault server, defaults to $"+vaultAddressEnvVar)
+	authMethod := fs.String("authMethod", "kubernetes", "Vault auth method, kubernetes or token. The token is read from $"+vaultTokenEnvVar)
+	authMount := fs.String("authMount", "kubernetes", "Mount path of the Kubernetes auth method")
+	role := fs.String("role", "", "Vault role to log in with, for the Kubernetes auth method")
+	kvMount := fs.String("kvMount", "secret", "Mount path of the KV secrets engine")
+	kvVersion := fs.Int("kvVersion", 2, "Version of the KV secrets engine, 1 or 2")
+	secrets := mappingFlag{}
+	fs.Var(secrets, "secret", "Secret to materialize as secretName=kvPath, can be repeated")
+	_ = fs.Parse(args)
+	requireRelease(*namespace, *releaseName)
+
+	if *address == "" {
+		log.Fatalf("No Vault address set, use -address or $%s", vaultAddressEnvVar)
+	}
+	if len(secrets) == 0 {
+		log.Fatalf("No secret to sync")
+	}
+
+	ctx := context.Background()
+	v := vault.NewClient(*address)
+	switch *authMethod {
+	case "kubernetes":
+		jwt, err := ioutil.ReadFile(vault.ServiceAccountTokenPath)
+		if err != nil {
+			log.Fatalf("Failed to read the service account token: %v", err)
+		}
+		if err := v.LoginKubernetes(ctx, *authMount, *role, strings.TrimSpace(string(jwt))); err != nil {
+			log.Fatal(err)
+		}
+	case "token":
+		token := os.Getenv(vaultTokenEnvVar)
+		if token == "" {
+			log.Fatalf("No Vault token set in $%s", vaultTokenEnvVar)
+		}
+		v.SetToken(token)
+	default:
+		log.Fatalf("Unknown auth method %s, expected kubernetes or token", *authMethod)
+	}
+
+	syncer := &vault.Syncer{
+		Client:      newClient(),
+		Vault:       v,
+		Namespace:   *namespace,
+		ReleaseName: *releaseName,
+		KVMount:     *kvMount,
+		KVVersion:   *kvVersion,
+	}
+	if err := syncer.Sync(ctx, secrets); err != nil {
+		log.Fatalf("Failed to sync secrets from Vault: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	"k8s.io/klog/v2/klogr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	api "github.com/k8ssandra/k8ssandra/pkg/apis/k8ssandra/v1alpha1"
+	"github.com/k8ssandra/k8ssandra/pkg/controllers"
+	"github.com/k8ssandra/k8ssandra/pkg/monitoring"
+)
+
+const (
+	// watchNamespaceEnvVar restricts the operator to a namespace, all namespaces are watched when it is empty
+	watchNamespaceEnvVar = "WATCH_NAMESPACE"
+)
+
+var (
+	scheme   = runtime.NewScheme()
+	setupLog = ctrl.Log.WithName("setup")
+)
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = cassdcapi.AddToScheme(scheme)
+	_ = api.AddToScheme(scheme)
+}
+
+func main() {
+	metricsAddr := flag.String("metrics-addr", ":8080", "The address the metric endpoint binds to")
+	enableLeaderElection := flag.Bool("enable-leader-election", false, "Enable leader election, ensuring there is only one active controller manager")
+	dashboardsDir := flag.String("dashboards-dir", "/dashboards", "The directory of the Grafana dashboards generated for each datacenter")
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	// zapr does not build against the version of logr required by the other dependencies of the module
+	ctrl.SetLogger(klogr.New())
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Scheme:             scheme,
+		MetricsBindAddress: *metricsAddr,
+		LeaderElection:     *enableLeaderElection,
+		LeaderElectionID:   "k8ssandra-operator-leader",
+		Namespace:          os.Getenv(watchNamespaceEnvVar),
+	})
+	if err != nil {
+		setupLog.Error(err, "Unable to create manager")
+		os.Exit(1)
+	}
+
+	if err := (&controllers.K8ssandraClusterReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("K8ssandraCluster"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "Unable to create controller", "controller", "K8ssandraCluster")
+		os.Exit(1)
+	}
+	if err := (&controllers.StargateReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("Stargate"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "Unable to create controller", "controller", "Stargate")
+		os.Exit(1)
+	}
+
+	dashboards, err := monitoring.LoadDashboards(*dashboardsDir)
+	if err != nil {
+		setupLog.Error(err, "Unable to load dashboards", "directory", *dashboardsDir)
+		os.Exit(1)
+	}
+	if len(dashboards) == 0 {
+		setupLog.Info("No dashboards found, no dashboard ConfigMaps will be generated", "directory", *dashboardsDir)
+	}
+	if err := (&controllers.MonitoringConfigurationReconciler{
+		Client:     mgr.GetClient(),
+		Log:        ctrl.Log.WithName("controllers").WithName("MonitoringConfiguration"),
+		Scheme:     mgr.GetScheme(),
+		Dashboards: dashboards,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "Unable to create controller", "controller", "MonitoringConfiguration")
+		os.Exit(1)
+	}
+
+	setupLog.Info("Starting manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "Problem running manager")
+		os.Exit(1)
+	}
+}
@@ -3,6 +3,8 @@ module github.com/k8ssandra/k8ssandra
 go 1.15
 
 require (
+	github.com/Masterminds/semver/v3 v3.1.1
+	github.com/go-logr/logr v0.4.0
 	github.com/go-resty/resty/v2 v2.1.1-0.20191201195748-d7b97669fe48
 	github.com/google/uuid v1.2.0
 	github.com/gruntwork-io/terratest v0.30.15
@@ -17,7 +19,9 @@ require (
 	k8s.io/apiextensions-apiserver v0.20.4
 	k8s.io/apimachinery v0.20.4
 	k8s.io/client-go v12.0.0+incompatible
+	k8s.io/klog/v2 v2.5.0
 	sigs.k8s.io/controller-runtime v0.6.4
+	sigs.k8s.io/yaml v1.2.0
 )
 
 replace (
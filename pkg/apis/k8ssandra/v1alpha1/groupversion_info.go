@@ -0,0 +1,20 @@
+// Package v1alpha1 contains the API of the k8ssandra operator
+// +kubebuilder:object:generate=true
+// +groupName=k8ssandra.io
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group and version of the objects of this package
+	GroupVersion = schema.GroupVersion{Group: "k8ssandra.io", Version: "v1alpha1"}
+
+	// SchemeBuilder registers the types of this package
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the types of this package to a scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
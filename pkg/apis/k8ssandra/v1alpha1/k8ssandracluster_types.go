@@ -0,0 +1,132 @@
+package v1alpha1
+
+import (
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// K8ssandraClusterSpec defines the Cassandra cluster and the components deployed along with it. Stargate settings are
+// set for the whole cluster in Stargate and overridden per datacenter.
+type K8ssandraClusterSpec struct {
+	Cassandra *CassandraClusterTemplate `json:"cassandra,omitempty"`
+
+	Stargate *StargateClusterTemplate `json:"stargate,omitempty"`
+}
+
+// CassandraClusterTemplate holds the settings of the Cassandra cluster. Settings of the cluster apply to each of its
+// datacenters unless the datacenter overrides them.
+type CassandraClusterTemplate struct {
+	// ClusterName is the name of the Cassandra cluster
+	ClusterName string `json:"clusterName"`
+
+	// ServerVersion is the version of Cassandra, e.g. 4.0.0
+	ServerVersion string `json:"serverVersion"`
+
+	// ServerImage overrides the management API image derived from ServerVersion by cass-operator
+	// +optional
+	ServerImage string `json:"serverImage,omitempty"`
+
+	// +optional
+	Auth *AuthConfig `json:"auth,omitempty"`
+
+	// SuperuserSecretName is the name of the Secret holding the superuser credentials. cass-operator generates one
+	// when empty.
+	// +optional
+	SuperuserSecretName string `json:"superuserSecretName,omitempty"`
+
+	// +optional
+	StorageConfig *cassdcapi.StorageConfig `json:"storageConfig,omitempty"`
+
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	Datacenters []CassandraDatacenterTemplate `json:"datacenters"`
+}
+
+// AuthConfig enables Cassandra authentication and authorization
+type AuthConfig struct {
+	// Enabled defaults to true
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// CassandraDatacenterTemplate holds the settings of a datacenter. A CassandraDatacenter with the same name is created
+// for each of them.
+type CassandraDatacenterTemplate struct {
+	Name string `json:"name"`
+
+	Size int32 `json:"size"`
+
+	// +optional
+	Racks []cassdcapi.Rack `json:"racks,omitempty"`
+
+	// +optional
+	ServerVersion string `json:"serverVersion,omitempty"`
+
+	// +optional
+	StorageConfig *cassdcapi.StorageConfig `json:"storageConfig,omitempty"`
+
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// +optional
+	Stargate *StargateClusterTemplate `json:"stargate,omitempty"`
+}
+
+// StargateClusterTemplate enables Stargate and holds its settings
+type StargateClusterTemplate struct {
+	// Enabled deploys Stargate in the datacenters. At the datacenter level it defaults to the value of the cluster
+	// level.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	StargateTemplate `json:",inline"`
+}
+
+// K8ssandraClusterStatus holds the status of the components of each datacenter
+type K8ssandraClusterStatus struct {
+	// Datacenters maps datacenter names to their status
+	// +optional
+	Datacenters map[string]K8ssandraStatus `json:"datacenters,omitempty"`
+}
+
+// K8ssandraStatus is the status of the components of a datacenter
+type K8ssandraStatus struct {
+	// +optional
+	Cassandra *cassdcapi.CassandraDatacenterStatus `json:"cassandra,omitempty"`
+
+	// +optional
+	Stargate *StargateStatus `json:"stargate,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=k8c;k8cs
+
+// K8ssandraCluster is the Schema for the k8ssandraclusters API
+type K8ssandraCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   K8ssandraClusterSpec   `json:"spec,omitempty"`
+	Status K8ssandraClusterStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// K8ssandraClusterList contains a list of K8ssandraCluster
+type K8ssandraClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []K8ssandraCluster `json:"items"`
+}
+
+// AuthEnabled returns true unless authentication is explicitly disabled
+func (in *CassandraClusterTemplate) AuthEnabled() bool {
+	return in.Auth == nil || in.Auth.Enabled == nil || *in.Auth.Enabled
+}
+
+func init() {
+	SchemeBuilder.Register(&K8ssandraCluster{}, &K8ssandraClusterList{})
+}
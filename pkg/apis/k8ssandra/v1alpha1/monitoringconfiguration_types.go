@@ -0,0 +1,231 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MonitoringConfigurationSpec defines the monitoring of the datacenters of a Cassandra cluster. The ServiceMonitors,
+// PrometheusRules and dashboards of the CassandraDatacenters of the namespace belonging to the cluster are generated
+// as the datacenters are created, as well as a ServiceMonitor for each Stargate of these datacenters.
+type MonitoringConfigurationSpec struct {
+	// ClusterName is the name of the Cassandra cluster whose datacenters are monitored
+	ClusterName string `json:"clusterName"`
+
+	// +optional
+	Prometheus *PrometheusConfig `json:"prometheus,omitempty"`
+
+	// +optional
+	Grafana *GrafanaConfig `json:"grafana,omitempty"`
+}
+
+// PrometheusConfig holds the settings of the Prometheus Operator objects
+type PrometheusConfig struct {
+	// Enabled generates the ServiceMonitors and PrometheusRules. Defaults to true.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Labels are set on the ServiceMonitors and PrometheusRules, so that the selectors of the Prometheus pick them
+	// up. The Prometheus of the chart selects the release label.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// ScrapeInterval is the scrape interval and timeout of the ServiceMonitors. Defaults to 15s.
+	// +optional
+	ScrapeInterval string `json:"scrapeInterval,omitempty"`
+
+	// Alerts overrides the settings of the alerting rules of the PrometheusRules
+	// +optional
+	Alerts *AlertsConfig `json:"alerts,omitempty"`
+}
+
+// AlertsConfig holds the settings of the alerting rules, named like the alerts values of the chart. Unset settings
+// take their default value.
+type AlertsConfig struct {
+	// NodeDown fires when the metrics of a Cassandra node cannot be scraped
+	// +optional
+	NodeDown *AlertConfig `json:"nodeDown,omitempty"`
+
+	// PendingCompactions fires when a node has more than threshold pending compactions
+	// +optional
+	PendingCompactions *AlertConfig `json:"pendingCompactions,omitempty"`
+
+	// DroppedMutations fires when a node dropped more than threshold mutations over the last 5 minutes
+	// +optional
+	DroppedMutations *AlertConfig `json:"droppedMutations,omitempty"`
+
+	// ReadLatency fires when the quantile of the coordinator read latency of the datacenter exceeds threshold
+	// milliseconds
+	// +optional
+	ReadLatency *LatencyAlertConfig `json:"readLatency,omitempty"`
+
+	// WriteLatency fires when the quantile of the coordinator write latency of the datacenter exceeds threshold
+	// milliseconds
+	// +optional
+	WriteLatency *LatencyAlertConfig `json:"writeLatency,omitempty"`
+
+	// DiskUsage fires when more than threshold percent of a volume of a node is used
+	// +optional
+	DiskUsage *AlertConfig `json:"diskUsage,omitempty"`
+
+	// HintedHandoffs fires when a node has more than threshold hints in progress
+	// +optional
+	HintedHandoffs *AlertConfig `json:"hintedHandoffs,omitempty"`
+
+	// Repair fires when Reaper did not complete a repair of a keyspace for threshold hours, because its repairs fail
+	// or are not scheduled. Failed segments and runs do not fire an alert until then.
+	// +optional
+	Repair *AlertConfig `json:"repair,omitempty"`
+
+	// BackupAge fires when the last successful backup is older than threshold hours
+	// +optional
+	BackupAge *BackupAlertConfig `json:"backupAge,omitempty"`
+}
+
+// AlertConfig holds the settings of an alerting rule
+type AlertConfig struct {
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Threshold is the value the alert fires above, its unit depends on the alert
+	// +optional
+	Threshold *int64 `json:"threshold,omitempty"`
+
+	// For is how long the condition must hold before the alert fires, e.g. 10m
+	// +optional
+	For string `json:"for,omitempty"`
+
+	// Severity is the value of the severity label of the alert
+	// +optional
+	Severity string `json:"severity,omitempty"`
+}
+
+// LatencyAlertConfig holds the settings of a latency alerting rule
+type LatencyAlertConfig struct {
+	AlertConfig `json:",inline"`
+
+	// Quantile is the latency quantile compared to the threshold, e.g. 0.99
+	// +optional
+	Quantile string `json:"quantile,omitempty"`
+}
+
+// BackupAlertConfig holds the settings of the backup age alerting rule. Neither Medusa nor any other component of
+// k8ssandra exports a backup metric, the alert is disabled by default and its metric must be provided by the user.
+type BackupAlertConfig struct {
+	AlertConfig `json:",inline"`
+
+	// Metric is the name of a gauge holding the Unix time of the last successful backup of the cluster, e.g. pushed
+	// to a Pushgateway by the job running the backups. It must have the namespace label of the cluster.
+	// +optional
+	Metric string `json:"metric,omitempty"`
+}
+
+// GrafanaConfig holds the settings of the Grafana dashboards
+type GrafanaConfig struct {
+	// Enabled generates the dashboard ConfigMaps. Defaults to true.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// DashboardLabels are set on the dashboard ConfigMaps, so that the dashboards sidecar of Grafana loads them.
+	// Defaults to grafana_dashboard: "true", as in the chart.
+	// +optional
+	DashboardLabels map[string]string `json:"dashboardLabels,omitempty"`
+}
+
+// MonitoringConditionType is the type of a condition of a MonitoringConfiguration
+type MonitoringConditionType string
+
+const (
+	// MonitoringReady is true when the monitoring objects of all the datacenters are up to date
+	MonitoringReady MonitoringConditionType = "Ready"
+)
+
+// MonitoringCondition is a condition of a MonitoringConfiguration
+type MonitoringCondition struct {
+	Type   MonitoringConditionType `json:"type"`
+	Status corev1.ConditionStatus  `json:"status"`
+
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// MonitoringConfigurationStatus is the observed state of a MonitoringConfiguration
+type MonitoringConfigurationStatus struct {
+	// Datacenters are the names of the monitored CassandraDatacenters
+	// +optional
+	Datacenters []string `json:"datacenters,omitempty"`
+
+	// +optional
+	Conditions []MonitoringCondition `json:"conditions,omitempty"`
+}
+
+// PrometheusEnabled returns true unless the Prometheus Operator objects are explicitly disabled
+func (in *MonitoringConfigurationSpec) PrometheusEnabled() bool {
+	return in.Prometheus == nil || in.Prometheus.Enabled == nil || *in.Prometheus.Enabled
+}
+
+// GrafanaEnabled returns true unless the dashboards are explicitly disabled
+func (in *MonitoringConfigurationSpec) GrafanaEnabled() bool {
+	return in.Grafana == nil || in.Grafana.Enabled == nil || *in.Grafana.Enabled
+}
+
+// GetConditionStatus returns the status of the condition, or unknown when the MonitoringConfiguration does not have
+// it
+func (in *MonitoringConfigurationStatus) GetConditionStatus(conditionType MonitoringConditionType) corev1.ConditionStatus {
+	for _, condition := range in.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status
+		}
+	}
+	return corev1.ConditionUnknown
+}
+
+// SetCondition adds or replaces the condition of the same type. Its transition time is kept when its status does not
+// change.
+func (in *MonitoringConfigurationStatus) SetCondition(condition MonitoringCondition) {
+	for i := range in.Conditions {
+		if in.Conditions[i].Type != condition.Type {
+			continue
+		}
+		if in.Conditions[i].Status == condition.Status {
+			condition.LastTransitionTime = in.Conditions[i].LastTransitionTime
+		}
+		in.Conditions[i] = condition
+		return
+	}
+	in.Conditions = append(in.Conditions, condition)
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterName`
+// +kubebuilder:printcolumn:name="Datacenters",type=string,JSONPath=`.status.datacenters`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// MonitoringConfiguration is the Schema for the monitoringconfigurations API
+type MonitoringConfiguration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MonitoringConfigurationSpec   `json:"spec,omitempty"`
+	Status MonitoringConfigurationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MonitoringConfigurationList contains a list of MonitoringConfiguration
+type MonitoringConfigurationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MonitoringConfiguration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MonitoringConfiguration{}, &MonitoringConfigurationList{})
+}
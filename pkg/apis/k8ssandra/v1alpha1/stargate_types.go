@@ -0,0 +1,164 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StargateTemplate holds the settings of a Stargate deployment shared by K8ssandraCluster and Stargate
+type StargateTemplate struct {
+	// Replicas is the number of Stargate instances, spread evenly across the racks of the datacenter
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ContainerImage overrides the image derived from the Cassandra version of the datacenter
+	// +optional
+	ContainerImage string `json:"containerImage,omitempty"`
+
+	// HeapSize is the heap of the JVM. The memory request and limit of the pods are set to two and four times its
+	// value unless Resources are set. Defaults to 256Mi.
+	// +optional
+	HeapSize *resource.Quantity `json:"heapSize,omitempty"`
+
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+// StargateSpec defines a Stargate deployment joining a CassandraDatacenter of the same namespace
+type StargateSpec struct {
+	StargateTemplate `json:",inline"`
+
+	DatacenterRef CassandraDatacenterRef `json:"cassandraDatacenter"`
+}
+
+// CassandraDatacenterRef references a CassandraDatacenter by name
+type CassandraDatacenterRef struct {
+	Name string `json:"name"`
+}
+
+// StargateProgress is the progress of the deployment of a Stargate
+type StargateProgress string
+
+const (
+	// StargateProgressPending is the progress of a Stargate waiting for its CassandraDatacenter to be ready
+	StargateProgressPending StargateProgress = "Pending"
+	// StargateProgressDeploying is the progress of a Stargate whose Deployments are rolling out
+	StargateProgressDeploying StargateProgress = "Deploying"
+	// StargateProgressRunning is the progress of a Stargate whose Deployments are rolled out
+	StargateProgressRunning StargateProgress = "Running"
+)
+
+// StargateConditionType is the type of a condition of a Stargate
+type StargateConditionType string
+
+const (
+	// StargateReady is true when all the replicas of the Stargate run the latest pod template and are ready
+	StargateReady StargateConditionType = "Ready"
+)
+
+// StargateCondition is a condition of a Stargate
+type StargateCondition struct {
+	Type   StargateConditionType  `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// StargateStatus is the observed state of a Stargate deployment
+type StargateStatus struct {
+	// +optional
+	Progress StargateProgress `json:"progress,omitempty"`
+
+	// +optional
+	Conditions []StargateCondition `json:"conditions,omitempty"`
+
+	// DeploymentRefs are the names of the Deployments of the Stargate, one per rack of the datacenter
+	// +optional
+	DeploymentRefs []string `json:"deploymentRefs,omitempty"`
+
+	// CassandraVersion is the Cassandra version the Deployments were last rolled out for
+	// +optional
+	CassandraVersion string `json:"cassandraVersion,omitempty"`
+
+	// Replicas is the total number of replicas of the Deployments
+	Replicas int32 `json:"replicas"`
+
+	// ReadyReplicas is the total number of ready replicas of the Deployments
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// UpdatedReplicas is the total number of replicas of the Deployments running their latest pod template
+	UpdatedReplicas int32 `json:"updatedReplicas"`
+
+	// AvailableReplicas is the total number of available replicas of the Deployments
+	AvailableReplicas int32 `json:"availableReplicas"`
+}
+
+// GetConditionStatus returns the status of the condition, or unknown when the Stargate does not have it
+func (in *StargateStatus) GetConditionStatus(conditionType StargateConditionType) corev1.ConditionStatus {
+	for _, condition := range in.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status
+		}
+	}
+	return corev1.ConditionUnknown
+}
+
+// SetCondition adds or replaces the condition of the same type. Its transition time is kept when its status does not
+// change.
+func (in *StargateStatus) SetCondition(condition StargateCondition) {
+	for i := range in.Conditions {
+		if in.Conditions[i].Type != condition.Type {
+			continue
+		}
+		if in.Conditions[i].Status == condition.Status {
+			condition.LastTransitionTime = in.Conditions[i].LastTransitionTime
+		}
+		in.Conditions[i] = condition
+		return
+	}
+	in.Conditions = append(in.Conditions, condition)
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Datacenter",type=string,JSONPath=`.spec.cassandraDatacenter.name`
+// +kubebuilder:printcolumn:name="Progress",type=string,JSONPath=`.status.progress`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+// +kubebuilder:printcolumn:name="Replicas",type=integer,JSONPath=`.status.replicas`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Stargate is the Schema for the stargates API
+type Stargate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   StargateSpec   `json:"spec,omitempty"`
+	Status StargateStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// StargateList contains a list of Stargate
+type StargateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Stargate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Stargate{}, &StargateList{})
+}
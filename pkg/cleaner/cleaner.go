@@ -2,14 +2,18 @@ package cleaner
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/clientcmd/api"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -23,12 +27,27 @@ const (
 	nameLabel         = "app.kubernetes.io/name"
 	nameLabelValue    = "k8ssandra"
 	releaseAnnotation = "meta.helm.sh/release-name"
+	// releaseNamespaceAnnotation tells the resources of a release from those of a release with the same name in another
+	// namespace
+	releaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
 )
 
 // Agent is a cleaner utility for resources which helm pre-delete requires
 type Agent struct {
-	Client    client.Client
+	Client client.Client
+	// Namespace is the namespace of the release
 	Namespace string
+	// Namespaces are cleaned besides the namespace of the release, for the datacenters of a release in the other
+	// namespaces cass-operator watches
+	Namespaces []string
+	// AllNamespaces cleans every namespace instead of Namespaces
+	AllNamespaces bool
+	// ClusterScoped also removes the cluster-scoped leftovers of the release
+	ClusterScoped bool
+	// APIResources returns the namespaced API resources served by the cluster, whose resources Sweep looks through
+	APIResources func() ([]*metav1.APIResourceList, error)
+	// ReportOnly only logs the resources which would be deleted
+	ReportOnly bool
 }
 
 // New returns a new instance of cleaning agent
@@ -36,42 +55,108 @@ func New(namespace string) (*Agent, error) {
 	_ = api.AddToScheme(scheme.Scheme)
 	_ = cassdcapi.AddToScheme(scheme.Scheme)
 
-	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme.Scheme})
+	config := ctrl.GetConfigOrDie()
+	c, err := client.New(config, client.Options{Scheme: scheme.Scheme})
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	dc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Agent{
-		Client:    c,
-		Namespace: namespace,
+		Client:       c,
+		Namespace:    namespace,
+		APIResources: dc.ServerPreferredNamespacedResources,
 	}, nil
 }
 
-// RemoveResources deletes all the resources with finalizers or which we want an operator to trigger a deletion
+// RemoveResources deletes all the resources with finalizers or which we want an operator to trigger a deletion, then
+// sweeps the leftovers of the release. In report-only mode nothing is deleted.
 func (a *Agent) RemoveResources(releaseName string) error {
-	// Remove CassandraDatacenter (cass-operator should delete all the finalizers and associated resources)
-	if err := a.removeCassandraDatacenter(releaseName); err != nil {
-		log.Fatalf("Failed to remove Cassandra cluster(s): %v", err)
+	if !a.ReportOnly {
+		// Remove CassandraDatacenter (cass-operator should delete all the finalizers and associated resources)
+		if err := a.removeCassandraDatacenter(releaseName); err != nil {
+			log.Fatalf("Failed to remove Cassandra cluster(s): %v", err)
+			return err
+		}
+	}
+
+	resources, err := a.Sweep(releaseName)
+	if err != nil {
 		return err
 	}
+	if a.ReportOnly {
+		log.Printf("Found %d resource(s) of release %s in %s\n", len(resources), releaseName, a.describeNamespaces())
+	} else {
+		log.Printf("Swept %d leftover resource(s) of release %s from %s\n", len(resources), releaseName, a.describeNamespaces())
+	}
 	return nil
 }
 
-func (a *Agent) removeCassandraDatacenter(releaseName string) error {
-	log.Printf("Removing CassandraDatacenter(s) managed in release %s from namespace %s\n", releaseName, a.Namespace)
+// namespaces returns the namespaces to clean, a single empty namespace in all-namespaces mode
+func (a *Agent) namespaces() []string {
+	if a.AllNamespaces {
+		return []string{metav1.NamespaceAll}
+	}
+	namespaces := []string{a.Namespace}
+	for _, namespace := range a.Namespaces {
+		if namespace != "" && namespace != a.Namespace {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+func (a *Agent) describeNamespaces() string {
+	if a.AllNamespaces {
+		return "all namespaces"
+	}
+	return "namespace(s) " + strings.Join(a.namespaces(), ",")
+}
+
+// inRelease tells whether an object carrying the labels of the release is part of it: helm annotates the resources it
+// renders in other namespaces with the namespace of the release, which the instance label does not tell
+func (a *Agent) inRelease(obj metav1.Object) bool {
+	if obj.GetNamespace() == a.Namespace {
+		return true
+	}
+	return obj.GetAnnotations()[releaseNamespaceAnnotation] == a.Namespace
+}
+
+// listCassandraDatacenters returns the CassandraDatacenters of the release in the namespaces to clean
+func (a *Agent) listCassandraDatacenters(releaseName string) ([]cassdcapi.CassandraDatacenter, error) {
 	releaseLabels := client.MatchingLabels{
 		managedLabel:  managedLabelValue,
 		instanceLabel: releaseName,
 		nameLabel:     nameLabelValue,
 	}
-	list := &cassdcapi.CassandraDatacenterList{}
-	err := a.Client.List(context.Background(), list, client.InNamespace(a.Namespace), releaseLabels)
+	var cassdcs []cassdcapi.CassandraDatacenter
+	for _, namespace := range a.namespaces() {
+		list := &cassdcapi.CassandraDatacenterList{}
+		if err := a.Client.List(context.Background(), list, client.InNamespace(namespace), releaseLabels); err != nil {
+			return nil, fmt.Errorf("failed to list CassandraDatacenters in namespace %s: %w", namespace, err)
+		}
+		for _, cassdc := range list.Items {
+			if a.inRelease(&cassdc) {
+				cassdcs = append(cassdcs, cassdc)
+			}
+		}
+	}
+	return cassdcs, nil
+}
+
+func (a *Agent) removeCassandraDatacenter(releaseName string) error {
+	log.Printf("Removing CassandraDatacenter(s) managed in release %s from %s\n", releaseName, a.describeNamespaces())
+	list, err := a.listCassandraDatacenters(releaseName)
 	if err != nil {
-		log.Fatalf("Failed to list CassandraDatacenters in namespace: %s", a.Namespace)
+		log.Fatalf("Failed to list CassandraDatacenters: %v", err)
 		return err
 	}
 
-	for _, cassdc := range list.Items {
+	for _, cassdc := range list {
 		if err = a.Client.Delete(context.Background(), &cassdc); err != nil && !apierrors.IsNotFound(err) && !apierrors.IsResourceExpired(err) {
 			log.Fatalf("failed to delete CassandraDatacenter %s: %s",
 				types.NamespacedName{Namespace: cassdc.Namespace, Name: cassdc.Name}, err)
@@ -81,12 +166,11 @@ func (a *Agent) removeCassandraDatacenter(releaseName string) error {
 	// We need to wait until the CassandraDatacenter is terminated; otherwise, cass-operator could get
 	// deleted before it has a chance to clear the CassandraDatacenter's finalizer.
 	return wait.PollImmediate(10*time.Second, 10*time.Minute, func() (bool, error) {
-		list := &cassdcapi.CassandraDatacenterList{}
-		err := a.Client.List(context.Background(), list, client.InNamespace(a.Namespace), releaseLabels)
+		list, err := a.listCassandraDatacenters(releaseName)
 		if err != nil {
-			log.Printf("failed to list CassandraDatacenters: %s\n", err)
+			log.Printf("%s\n", err)
 			return false, err
 		}
-		return len(list.Items) == 0, nil
+		return len(list) == 0, nil
 	})
 }
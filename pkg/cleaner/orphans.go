@@ -0,0 +1,176 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8ssandra/k8ssandra/pkg/helmutil"
+)
+
+const releaseSecretType = "helm.sh/release.v1"
+
+// Orphan is a resource left by a release which no longer exists
+type Orphan struct {
+	Kind string
+	Name string
+	// Reason tells what the resource belonged to
+	Reason  string
+	Size    string
+	Created time.Time
+	Object  runtime.Object
+}
+
+// FindOrphans returns the CassandraDatacenters, PersistentVolumeClaims and Secrets of the namespace whose release,
+// from the release annotations or else the instance label of helm's resources, has no revision in the storage of helm
+// or was uninstalled. A release is looked up in the namespace it is annotated with, which cass-operator watching other
+// namespaces makes different from the namespace of its resources, so the storage of helm is read in every namespace.
+// Nothing is returned when no storage is found at all, helm storing its releases elsewhere than in secrets. The
+// PersistentVolumeClaims of cass-operator are orphaned with their CassandraDatacenter, or when it no longer exists.
+// The orphans are returned by kind and name.
+func FindOrphans(ctx context.Context, c client.Client, namespace string) ([]Orphan, error) {
+	releases, err := helmutil.Releases(c, "")
+	if err != nil {
+		if errors.Is(err, helmutil.ErrNoReleaseStorage) {
+			return nil, fmt.Errorf("%w, refusing to report every resource as orphaned: helm must store its releases in secrets (HELM_DRIVER=secret)", err)
+		}
+		return nil, fmt.Errorf("failed to list the releases: %w", err)
+	}
+	releaseGone := func(obj metav1.Object) (string, bool) {
+		release, found := releaseOf(obj)
+		if !found || releases[release] {
+			return "", false
+		}
+		if release.Namespace != namespace {
+			return fmt.Sprintf("release %s does not exist in namespace %s", release.Name, release.Namespace), true
+		}
+		return fmt.Sprintf("release %s does not exist", release.Name), true
+	}
+
+	var orphans []Orphan
+
+	cassdcs := &cassdcapi.CassandraDatacenterList{}
+	if err := c.List(ctx, cassdcs, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list CassandraDatacenters in namespace %s: %w", namespace, err)
+	}
+	// the reasons the PersistentVolumeClaims of each datacenter are orphaned, empty if the datacenter is not
+	datacenters := make(map[string]string)
+	for i := range cassdcs.Items {
+		cassdc := &cassdcs.Items[i]
+		reason, orphaned := releaseGone(cassdc)
+		if !orphaned {
+			datacenters[cassdc.Name] = ""
+			continue
+		}
+		datacenters[cassdc.Name] = fmt.Sprintf("CassandraDatacenter %s is orphaned", cassdc.Name)
+		orphans = append(orphans, newOrphan("CassandraDatacenter", cassdc, reason, fmt.Sprintf("%d node(s)", cassdc.Spec.Size)))
+	}
+
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := c.List(ctx, pvcs, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list PersistentVolumeClaims in namespace %s: %w", namespace, err)
+	}
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		reason, orphaned := releaseGone(pvc)
+		if dc, found := pvc.Labels[cassdcapi.DatacenterLabel]; !orphaned && found {
+			if dcReason, exists := datacenters[dc]; !exists {
+				reason, orphaned = fmt.Sprintf("CassandraDatacenter %s does not exist", dc), true
+			} else if dcReason != "" {
+				reason, orphaned = dcReason, true
+			}
+		}
+		if orphaned {
+			orphans = append(orphans, newOrphan("PersistentVolumeClaim", pvc, reason, pvcSize(pvc)))
+		}
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := c.List(ctx, secrets, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list Secrets in namespace %s: %w", namespace, err)
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if secret.Type == releaseSecretType {
+			continue
+		}
+		if reason, orphaned := releaseGone(secret); orphaned {
+			size := 0
+			for _, value := range secret.Data {
+				size += len(value)
+			}
+			orphans = append(orphans, newOrphan("Secret", secret, reason, fmt.Sprintf("%d bytes", size)))
+		}
+	}
+
+	kinds := map[string]int{"CassandraDatacenter": 0, "PersistentVolumeClaim": 1, "Secret": 2}
+	sort.SliceStable(orphans, func(i, j int) bool {
+		if orphans[i].Kind != orphans[j].Kind {
+			return kinds[orphans[i].Kind] < kinds[orphans[j].Kind]
+		}
+		return orphans[i].Name < orphans[j].Name
+	})
+	return orphans, nil
+}
+
+// DeleteOrphan deletes an orphan returned by FindOrphans
+func DeleteOrphan(ctx context.Context, c client.Client, orphan Orphan) error {
+	if err := c.Delete(ctx, orphan.Object); err != nil {
+		return fmt.Errorf("failed to delete %s %s: %w", orphan.Kind, orphan.Name, err)
+	}
+	return nil
+}
+
+type orphanObject interface {
+	metav1.Object
+	runtime.Object
+}
+
+func newOrphan(kind string, obj orphanObject, reason, size string) Orphan {
+	return Orphan{
+		Kind:    kind,
+		Name:    obj.GetName(),
+		Reason:  reason,
+		Size:    size,
+		Created: obj.GetCreationTimestamp().Time,
+		Object:  obj,
+	}
+}
+
+// releaseOf returns the release of a resource, from the annotations helm sets on the resources of a release or else
+// the instance label of the resources helm manages, in the namespace of the resource
+func releaseOf(obj metav1.Object) (types.NamespacedName, bool) {
+	release := types.NamespacedName{Namespace: obj.GetNamespace()}
+	if name, found := obj.GetAnnotations()[releaseAnnotation]; found {
+		release.Name = name
+		if namespace, annotated := obj.GetAnnotations()[releaseNamespaceAnnotation]; annotated {
+			release.Namespace = namespace
+		}
+		return release, true
+	}
+	if obj.GetLabels()[managedLabel] == managedLabelValue && obj.GetLabels()[instanceLabel] != "" {
+		release.Name = obj.GetLabels()[instanceLabel]
+		return release, true
+	}
+	return release, false
+}
+
+// pvcSize returns the capacity of a bound claim, or else the storage it requests
+func pvcSize(pvc *corev1.PersistentVolumeClaim) string {
+	if capacity, found := pvc.Status.Capacity[corev1.ResourceStorage]; found {
+		return capacity.String()
+	}
+	if request, found := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; found {
+		return request.String()
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k8ssandra/k8ssandra/pkg/helmutil"
+	"github.com/k8ssandra/k8ssandra/pkg/helmutil/helmtest"
+)
+
+func orphanObjects(t *testing.T) []runtime.Object {
+	releaseMeta := func(name, release string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Namespace:   CleanerTestNamespace,
+			Name:        name,
+			Labels:      map[string]string{managedLabel: managedLabelValue, instanceLabel: release},
+			Annotations: map[string]string{releaseAnnotation: release},
+		}
+	}
+	remoteMeta := func(name, release, releaseNamespace string) metav1.ObjectMeta {
+		meta := releaseMeta(name, release)
+		meta.Annotations[releaseNamespaceAnnotation] = releaseNamespace
+		return meta
+	}
+	storageSecret := func(namespace, name string, version int, status release.Status) *corev1.Secret {
+		return helmtest.ReleaseSecret(t, namespace, &release.Release{Name: name, Version: version, Info: &release.Info{Status: status}})
+	}
+	pvc := func(name, dc string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: CleanerTestNamespace, Name: name, Labels: map[string]string{cassdcapi.DatacenterLabel: dc}},
+			Spec: corev1.PersistentVolumeClaimSpec{Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("5Gi")},
+			}},
+		}
+	}
+
+	boundPVC := pvc("server-data-gone-dc1-default-sts-0", "gone-dc1")
+	boundPVC.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")}
+	hookSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Namespace: CleanerTestNamespace,
+		Name:      "gone-hook-secret",
+		Labels:    map[string]string{managedLabel: managedLabelValue, instanceLabel: "gone"},
+	}}
+	return []runtime.Object{
+		storageSecret(CleanerTestNamespace, "live", 1, release.StatusDeployed),
+		storageSecret(CleanerTestNamespace, "gone", 1, release.StatusSuperseded),
+		storageSecret(CleanerTestNamespace, "gone", 2, release.StatusUninstalled),
+		storageSecret("other", "remote", 1, release.StatusDeployed),
+		&cassdcapi.CassandraDatacenter{ObjectMeta: releaseMeta("live-dc1", "live"), Spec: cassdcapi.CassandraDatacenterSpec{Size: 3}},
+		&cassdcapi.CassandraDatacenter{ObjectMeta: releaseMeta("gone-dc1", "gone"), Spec: cassdcapi.CassandraDatacenterSpec{Size: 3}},
+		&cassdcapi.CassandraDatacenter{ObjectMeta: releaseMeta("deleted-dc1", "deleted"), Spec: cassdcapi.CassandraDatacenterSpec{Size: 1}},
+		&cassdcapi.CassandraDatacenter{ObjectMeta: metav1.ObjectMeta{Namespace: CleanerTestNamespace, Name: "unmanaged-dc1"}},
+		// the datacenter of a release of another namespace, which cass-operator watches this namespace for
+		&cassdcapi.CassandraDatacenter{ObjectMeta: remoteMeta("remote-dc2", "remote", "other"), Spec: cassdcapi.CassandraDatacenterSpec{Size: 3}},
+		pvc("server-data-live-dc1-default-sts-0", "live-dc1"),
+		boundPVC,
+		pvc("server-data-removed-dc1-default-sts-0", "removed-dc1"),
+		pvc("server-data-unmanaged-dc1-default-sts-0", "unmanaged-dc1"),
+		pvc("server-data-remote-dc2-default-sts-0", "remote-dc2"),
+		&corev1.Secret{ObjectMeta: releaseMeta("live-superuser", "live"), Data: map[string][]byte{"password": []byte("secret")}},
+		&corev1.Secret{ObjectMeta: releaseMeta("gone-superuser", "gone"), Data: map[string][]byte{"username": []byte("gone-superuser"), "password": []byte("secret")}},
+		hookSecret,
+		// a release with the same name in another namespace
+		&corev1.Secret{ObjectMeta: remoteMeta("live-elsewhere-superuser", "live", "elsewhere")},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: CleanerTestNamespace, Name: "unmanaged-secret"}},
+	}
+}
+
+func TestFindOrphans(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(cassdcapi.AddToScheme(scheme)).To(Succeed())
+	c := fake.NewFakeClientWithScheme(scheme, orphanObjects(t)...)
+
+	orphans, err := FindOrphans(context.Background(), c, CleanerTestNamespace)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	type row struct{ Kind, Name, Reason, Size string }
+	rows := make([]row, len(orphans))
+	for i, orphan := range orphans {
+		rows[i] = row{orphan.Kind, orphan.Name, orphan.Reason, orphan.Size}
+	}
+	g.Expect(rows).To(Equal([]row{
+		{"CassandraDatacenter", "deleted-dc1", "release deleted does not exist", "1 node(s)"},
+		{"CassandraDatacenter", "gone-dc1", "release gone does not exist", "3 node(s)"},
+		{"PersistentVolumeClaim", "server-data-gone-dc1-default-sts-0", "CassandraDatacenter gone-dc1 is orphaned", "10Gi"},
+		{"PersistentVolumeClaim", "server-data-removed-dc1-default-sts-0", "CassandraDatacenter removed-dc1 does not exist", "5Gi"},
+		{"Secret", "gone-hook-secret", "release gone does not exist", "0 bytes"},
+		{"Secret", "gone-superuser", "release gone does not exist", "20 bytes"},
+		{"Secret", "live-elsewhere-superuser", "release live does not exist in namespace elsewhere", "0 bytes"},
+	}))
+
+	g.Expect(DeleteOrphan(context.Background(), c, orphans[1])).To(Succeed())
+	orphans, err = FindOrphans(context.Background(), c, CleanerTestNamespace)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(orphans[1].Reason).To(Equal("CassandraDatacenter gone-dc1 does not exist"))
+}
+
+func TestFindOrphansWithoutReleaseStorage(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(cassdcapi.AddToScheme(scheme)).To(Succeed())
+	// helm stores its releases in ConfigMaps or SQL with HELM_DRIVER, the resources of every release would look orphaned
+	c := fake.NewFakeClientWithScheme(scheme, &cassdcapi.CassandraDatacenter{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   CleanerTestNamespace,
+		Name:        "live-dc1",
+		Annotations: map[string]string{releaseAnnotation: "live"},
+	}})
+
+	orphans, err := FindOrphans(context.Background(), c, CleanerTestNamespace)
+	g.Expect(err).To(MatchError(ContainSubstring("HELM_DRIVER")))
+	g.Expect(errors.Is(err, helmutil.ErrNoReleaseStorage)).To(BeTrue())
+	g.Expect(orphans).To(BeEmpty())
+}
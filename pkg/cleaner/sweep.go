@@ -0,0 +1,232 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const hookAnnotation = "helm.sh/hook"
+
+// rbacKinds are not swept: the hooks run with them, and helm deletes them after the hooks
+var rbacKinds = map[schema.GroupKind]bool{
+	{Kind: "ServiceAccount"}:                                  true,
+	{Group: "rbac.authorization.k8s.io", Kind: "Role"}:        true,
+	{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}: true,
+}
+
+// Resource is a resource of a release found by Sweep, whose namespace is empty if it is cluster-scoped
+type Resource struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+}
+
+func (r Resource) String() string {
+	kind := r.GroupVersionKind.Kind
+	if r.GroupVersionKind.Group != "" {
+		kind += "." + r.GroupVersionKind.Group
+	}
+	if r.Namespace == "" {
+		return fmt.Sprintf("%s %s", kind, r.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", kind, r.Namespace, r.Name)
+}
+
+// Sweep deletes every namespaced resource of the namespaces to clean carrying the instance label of the release,
+// whatever its type: the ServiceMonitors and dashboard ConfigMaps left when kube-prometheus-stack is managed
+// separately, the jobs of hooks with a before-hook-creation policy, and so on. Outside of the namespace of the release,
+// only the resources annotated with the namespace of the release are part of it. The resources owned by another
+// resource are left to the garbage collector, the resources of the pre-delete hooks, this one included, and the RBAC
+// resources to helm. With ClusterScoped, the cluster-scoped leftovers are deleted as well. In report-only mode the
+// resources are only logged. The resources found are returned sorted by type, namespace and name.
+//
+// Stargate keeps its auth tables in the data_endpoint_auth keyspace of the cluster, which goes with the
+// PersistentVolumeClaims cass-operator deletes along with the CassandraDatacenters.
+func (a *Agent) Sweep(releaseName string) ([]Resource, error) {
+	ctx := context.Background()
+	lists, err := a.APIResources()
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return nil, fmt.Errorf("failed to discover the API resources: %w", err)
+		}
+		// the resources of the other groups are still returned
+		log.Printf("Failed to discover some API resources, their resources are not swept: %v\n", err)
+	}
+	lists = discovery.FilteredBy(discovery.SupportsAllVerbs{Verbs: []string{"list", "delete"}}, lists)
+
+	var found []Resource
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse group version %s: %w", list.GroupVersion, err)
+		}
+		for _, apiResource := range list.APIResources {
+			if !apiResource.Namespaced || strings.Contains(apiResource.Name, "/") || rbacKinds[gv.WithKind(apiResource.Kind).GroupKind()] {
+				continue
+			}
+			gvk := gv.WithKind(apiResource.Kind)
+			objects, err := a.listReleaseObjects(ctx, gvk, releaseName)
+			if err != nil {
+				return nil, err
+			}
+			for i := range objects {
+				resource := Resource{GroupVersionKind: gvk, Namespace: objects[i].GetNamespace(), Name: objects[i].GetName()}
+				if err = a.deleteObject(ctx, resource, &objects[i]); err != nil {
+					return nil, err
+				}
+				found = append(found, resource)
+			}
+		}
+	}
+
+	if a.ClusterScoped {
+		clusterScoped, err := a.sweepClusterScoped(ctx, releaseName)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, clusterScoped...)
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].GroupVersionKind.Kind != found[j].GroupVersionKind.Kind {
+			return found[i].GroupVersionKind.Kind < found[j].GroupVersionKind.Kind
+		}
+		if found[i].Namespace != found[j].Namespace {
+			return found[i].Namespace < found[j].Namespace
+		}
+		return found[i].Name < found[j].Name
+	})
+	return found, nil
+}
+
+// sweepClusterScoped deletes the ClusterRoles and ClusterRoleBindings carrying the instance label of the release, such
+// as those the CRD upgrader hook leaves when it fails. The instance label does not tell the releases with the same name
+// in different namespaces apart, and hooks are not annotated with the namespace of their release: a ClusterRoleBinding
+// is part of the release when it binds service accounts of the namespace of the release only, and a ClusterRole unless
+// a ClusterRoleBinding binds it to service accounts of another namespace. Those of the pre-delete hooks, the
+// cleaner's own included, are left to helm. The CustomResourceDefinitions are kept, deleting them would delete the
+// resources of every release.
+func (a *Agent) sweepClusterScoped(ctx context.Context, releaseName string) ([]Resource, error) {
+	releaseLabels := client.MatchingLabels{instanceLabel: releaseName}
+	bindings := &rbacv1.ClusterRoleBindingList{}
+	if err := a.Client.List(ctx, bindings, releaseLabels); err != nil {
+		return nil, fmt.Errorf("failed to list ClusterRoleBindings: %w", err)
+	}
+	roles := &rbacv1.ClusterRoleList{}
+	if err := a.Client.List(ctx, roles, releaseLabels); err != nil {
+		return nil, fmt.Errorf("failed to list ClusterRoles: %w", err)
+	}
+
+	var found []Resource
+	boundElsewhere := make(map[string]bool)
+	for i := range bindings.Items {
+		binding := &bindings.Items[i]
+		if isPreDeleteHook(binding.Annotations) {
+			continue
+		}
+		if namespace, annotated := binding.Annotations[releaseNamespaceAnnotation]; annotated && namespace != a.Namespace {
+			boundElsewhere[binding.RoleRef.Name] = true
+			continue
+		}
+		if !a.bindsReleaseOnly(binding.Subjects) {
+			boundElsewhere[binding.RoleRef.Name] = true
+			continue
+		}
+		resource := Resource{GroupVersionKind: rbacv1.SchemeGroupVersion.WithKind("ClusterRoleBinding"), Name: binding.Name}
+		if err := a.deleteObject(ctx, resource, binding); err != nil {
+			return nil, err
+		}
+		found = append(found, resource)
+	}
+
+	for i := range roles.Items {
+		role := &roles.Items[i]
+		namespace, annotated := role.Annotations[releaseNamespaceAnnotation]
+		if isPreDeleteHook(role.Annotations) || (annotated && namespace != a.Namespace) || (!annotated && boundElsewhere[role.Name]) {
+			continue
+		}
+		resource := Resource{GroupVersionKind: rbacv1.SchemeGroupVersion.WithKind("ClusterRole"), Name: role.Name}
+		if err := a.deleteObject(ctx, resource, role); err != nil {
+			return nil, err
+		}
+		found = append(found, resource)
+	}
+	return found, nil
+}
+
+// bindsReleaseOnly tells whether the subjects are service accounts of the namespace of the release
+func (a *Agent) bindsReleaseOnly(subjects []rbacv1.Subject) bool {
+	if len(subjects) == 0 {
+		return false
+	}
+	for _, subject := range subjects {
+		if subject.Kind != rbacv1.ServiceAccountKind || subject.Namespace != a.Namespace {
+			return false
+		}
+	}
+	return true
+}
+
+// listReleaseObjects returns the objects of the kind carrying the instance label of the release, except those owned by
+// another resource and those of the pre-delete hooks. In all-namespaces mode, the kinds the ClusterRole of the cleaner
+// does not grant are only listed in the namespace of the release.
+func (a *Agent) listReleaseObjects(ctx context.Context, gvk schema.GroupVersionKind, releaseName string) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	for _, namespace := range a.namespaces() {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		err := a.Client.List(ctx, list, client.InNamespace(namespace), client.MatchingLabels{instanceLabel: releaseName})
+		if err != nil && namespace == "" && apierrors.IsForbidden(err) {
+			err = a.Client.List(ctx, list, client.InNamespace(a.Namespace), client.MatchingLabels{instanceLabel: releaseName})
+		}
+		if err != nil {
+			if apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to list %s in namespace %s: %w", gvk.Kind, namespace, err)
+		}
+
+		for _, obj := range list.Items {
+			if len(obj.GetOwnerReferences()) > 0 || isPreDeleteHook(obj.GetAnnotations()) || !a.inRelease(&obj) {
+				continue
+			}
+			obj.SetGroupVersionKind(gvk)
+			objects = append(objects, obj)
+		}
+	}
+	return objects, nil
+}
+
+// deleteObject deletes the object of a resource, or only logs it in report-only mode
+func (a *Agent) deleteObject(ctx context.Context, resource Resource, obj runtime.Object) error {
+	if a.ReportOnly {
+		log.Printf("Would delete %s\n", resource)
+		return nil
+	}
+	log.Printf("Deleting %s\n", resource)
+	if err := a.Client.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete %s: %w", resource, err)
+	}
+	return nil
+}
+
+func isPreDeleteHook(annotations map[string]string) bool {
+	for _, hook := range strings.Split(annotations[hookAnnotation], ",") {
+		if strings.TrimSpace(hook) == "pre-delete" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func sweepAPIResources() ([]*metav1.APIResourceList, error) {
+	verbs := metav1.Verbs{"get", "list", "delete"}
+	return []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: verbs},
+				{Name: "pods", Kind: "Pod", Namespaced: true, Verbs: verbs},
+				{Name: "pods/log", Kind: "Pod", Namespaced: true, Verbs: metav1.Verbs{"get"}},
+				{Name: "serviceaccounts", Kind: "ServiceAccount", Namespaced: true, Verbs: verbs},
+				{Name: "namespaces", Kind: "Namespace", Verbs: verbs},
+			},
+		},
+		{
+			GroupVersion: "batch/v1",
+			APIResources: []metav1.APIResource{{Name: "jobs", Kind: "Job", Namespaced: true, Verbs: verbs}},
+		},
+		{
+			GroupVersion: "rbac.authorization.k8s.io/v1",
+			APIResources: []metav1.APIResource{{Name: "roles", Kind: "Role", Namespaced: true, Verbs: verbs}},
+		},
+	}, nil
+}
+
+func sweepObjects() []runtime.Object {
+	releaseLabels := map[string]string{instanceLabel: cleanerTestRelease}
+	return []runtime.Object{
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: CleanerTestNamespace, Name: "cleanrel-grafana-dashboards", Labels: releaseLabels}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: CleanerTestNamespace, Name: "other-grafana-dashboards", Labels: map[string]string{instanceLabel: "other"}}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "cleanrel-other-namespace", Labels: releaseLabels}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   CleanerTestNamespace,
+			Name:        "cleanrel-crd-upgrader-job-k8ssandra",
+			Labels:      releaseLabels,
+			Annotations: map[string]string{hookAnnotation: "pre-upgrade", "helm.sh/hook-delete-policy": "before-hook-creation"},
+		}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   CleanerTestNamespace,
+			Name:        "cleanrel-cleaner-job-k8ssandra",
+			Labels:      releaseLabels,
+			Annotations: map[string]string{hookAnnotation: "pre-delete"},
+		}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace:       CleanerTestNamespace,
+			Name:            "cleanrel-crd-upgrader-job-k8ssandra-x7k2p",
+			Labels:          releaseLabels,
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: "cleanrel-crd-upgrader-job-k8ssandra"}},
+		}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: CleanerTestNamespace, Name: "cleanrel-cleaner-k8ssandra", Labels: releaseLabels}},
+		&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Namespace: CleanerTestNamespace, Name: "cleanrel-cleaner-k8ssandra", Labels: releaseLabels}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "other",
+			Name:        "cleanrel-dc2-config",
+			Labels:      releaseLabels,
+			Annotations: map[string]string{releaseNamespaceAnnotation: CleanerTestNamespace},
+		}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "cleanrel-crd-upgrader-k8ssandra", Labels: releaseLabels}},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "cleanrel-crd-upgrader-k8ssandra", Labels: releaseLabels},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "cleanrel-crd-upgrader-k8ssandra"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: CleanerTestNamespace, Name: "cleanrel-crd-upgrader-k8ssandra"}},
+		},
+		// the RBAC of the cleaner, which runs with it
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{
+			Name:        "cleanrel-cleaner-k8ssandra",
+			Labels:      releaseLabels,
+			Annotations: map[string]string{hookAnnotation: "pre-delete"},
+		}},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "cleanrel-cleaner-k8ssandra",
+				Labels:      releaseLabels,
+				Annotations: map[string]string{hookAnnotation: "pre-delete"},
+			},
+			RoleRef:  rbacv1.RoleRef{Kind: "ClusterRole", Name: "cleanrel-cleaner-k8ssandra"},
+			Subjects: []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: CleanerTestNamespace, Name: "cleanrel-cleaner-k8ssandra"}},
+		},
+		// a release with the same name in another namespace
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "cleanrel-other-hook", Labels: releaseLabels}},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "cleanrel-other-hook", Labels: releaseLabels},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "cleanrel-other-hook"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "other", Name: "cleanrel-other-hook"}},
+		},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{
+			Name:        "cleanrel-other-role",
+			Labels:      releaseLabels,
+			Annotations: map[string]string{releaseNamespaceAnnotation: "other"},
+		}},
+	}
+}
+
+func sweepAgent(t *testing.T) *Agent {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return &Agent{
+		Client:       fake.NewFakeClientWithScheme(scheme, sweepObjects()...),
+		Namespace:    CleanerTestNamespace,
+		APIResources: sweepAPIResources,
+	}
+}
+
+func sweep(g *WithT, a *Agent) []string {
+	resources, err := a.Sweep(cleanerTestRelease)
+	g.Expect(err).ToNot(HaveOccurred())
+	names := make([]string, len(resources))
+	for i, resource := range resources {
+		names[i] = resource.String()
+	}
+	return names
+}
+
+func exists(g *WithT, c client.Client, obj runtime.Object, namespace, name string) bool {
+	err := c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, obj)
+	if apierrors.IsNotFound(err) {
+		return false
+	}
+	g.Expect(err).ToNot(HaveOccurred())
+	return true
+}
+
+func TestSweep(t *testing.T) {
+	g := NewWithT(t)
+	a := sweepAgent(t)
+
+	g.Expect(sweep(g, a)).To(Equal([]string{
+		"ConfigMap k8ssandra/cleanrel-grafana-dashboards",
+		"Job.batch k8ssandra/cleanrel-crd-upgrader-job-k8ssandra",
+	}))
+
+	g.Expect(exists(g, a.Client, &corev1.ConfigMap{}, CleanerTestNamespace, "cleanrel-grafana-dashboards")).To(BeFalse())
+	g.Expect(exists(g, a.Client, &batchv1.Job{}, CleanerTestNamespace, "cleanrel-crd-upgrader-job-k8ssandra")).To(BeFalse())
+
+	// the resources of other releases, other namespaces, the running hook, owned resources and RBAC are kept
+	g.Expect(exists(g, a.Client, &corev1.ConfigMap{}, CleanerTestNamespace, "other-grafana-dashboards")).To(BeTrue())
+	g.Expect(exists(g, a.Client, &corev1.ConfigMap{}, "other", "cleanrel-other-namespace")).To(BeTrue())
+	g.Expect(exists(g, a.Client, &corev1.ConfigMap{}, "other", "cleanrel-dc2-config")).To(BeTrue())
+	g.Expect(exists(g, a.Client, &rbacv1.ClusterRole{}, "", "cleanrel-crd-upgrader-k8ssandra")).To(BeTrue())
+	g.Expect(exists(g, a.Client, &batchv1.Job{}, CleanerTestNamespace, "cleanrel-cleaner-job-k8ssandra")).To(BeTrue())
+	g.Expect(exists(g, a.Client, &corev1.Pod{}, CleanerTestNamespace, "cleanrel-crd-upgrader-job-k8ssandra-x7k2p")).To(BeTrue())
+	g.Expect(exists(g, a.Client, &corev1.ServiceAccount{}, CleanerTestNamespace, "cleanrel-cleaner-k8ssandra")).To(BeTrue())
+	g.Expect(exists(g, a.Client, &rbacv1.Role{}, CleanerTestNamespace, "cleanrel-cleaner-k8ssandra")).To(BeTrue())
+}
+
+func TestSweepReportOnly(t *testing.T) {
+	g := NewWithT(t)
+	a := sweepAgent(t)
+	a.ReportOnly = true
+	a.ClusterScoped = true
+
+	g.Expect(sweep(g, a)).To(HaveLen(4))
+
+	g.Expect(exists(g, a.Client, &corev1.ConfigMap{}, CleanerTestNamespace, "cleanrel-grafana-dashboards")).To(BeTrue())
+	g.Expect(exists(g, a.Client, &batchv1.Job{}, CleanerTestNamespace, "cleanrel-crd-upgrader-job-k8ssandra")).To(BeTrue())
+	g.Expect(exists(g, a.Client, &rbacv1.ClusterRole{}, "", "cleanrel-crd-upgrader-k8ssandra")).To(BeTrue())
+}
+
+func TestSweepNamespaces(t *testing.T) {
+	g := NewWithT(t)
+
+	expected := []string{
+		"ConfigMap k8ssandra/cleanrel-grafana-dashboards",
+		"ConfigMap other/cleanrel-dc2-config",
+		"Job.batch k8ssandra/cleanrel-crd-upgrader-job-k8ssandra",
+	}
+
+	a := sweepAgent(t)
+	a.Namespaces = []string{"other"}
+	g.Expect(sweep(g, a)).To(Equal(expected))
+	// the resource of the release name without the annotation of the namespace of the release is kept
+	g.Expect(exists(g, a.Client, &corev1.ConfigMap{}, "other", "cleanrel-other-namespace")).To(BeTrue())
+
+	a = sweepAgent(t)
+	a.AllNamespaces = true
+	g.Expect(sweep(g, a)).To(Equal(expected))
+}
+
+// clusterRoleClient refuses to list the Jobs of every namespace, as the ClusterRole of the cleaner does not grant it
+// the types the release does not create
+type clusterRoleClient struct {
+	client.Client
+}
+
+func (c clusterRoleClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if list.GetObjectKind().GroupVersionKind().Group == "batch" && listOpts.Namespace == "" {
+		return apierrors.NewForbidden(batchv1.Resource("jobs"), "", fmt.Errorf("cluster-wide list refused"))
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+func TestSweepAllNamespacesNotGranted(t *testing.T) {
+	g := NewWithT(t)
+	a := sweepAgent(t)
+	a.AllNamespaces = true
+	a.Client = clusterRoleClient{a.Client}
+	g.Expect(a.Client.Create(context.Background(), &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "other",
+		Name:        "cleanrel-dc2-job",
+		Labels:      map[string]string{instanceLabel: cleanerTestRelease},
+		Annotations: map[string]string{releaseNamespaceAnnotation: CleanerTestNamespace},
+	}})).To(Succeed())
+
+	// the Jobs are only swept from the namespace of the release
+	g.Expect(sweep(g, a)).To(Equal([]string{
+		"ConfigMap k8ssandra/cleanrel-grafana-dashboards",
+		"ConfigMap other/cleanrel-dc2-config",
+		"Job.batch k8ssandra/cleanrel-crd-upgrader-job-k8ssandra",
+	}))
+	g.Expect(exists(g, a.Client, &batchv1.Job{}, "other", "cleanrel-dc2-job")).To(BeTrue())
+}
+
+func TestSweepClusterScoped(t *testing.T) {
+	g := NewWithT(t)
+	a := sweepAgent(t)
+	a.ClusterScoped = true
+
+	g.Expect(sweep(g, a)).To(Equal([]string{
+		"ClusterRole.rbac.authorization.k8s.io cleanrel-crd-upgrader-k8ssandra",
+		"ClusterRoleBinding.rbac.authorization.k8s.io cleanrel-crd-upgrader-k8ssandra",
+		"ConfigMap k8ssandra/cleanrel-grafana-dashboards",
+		"Job.batch k8ssandra/cleanrel-crd-upgrader-job-k8ssandra",
+	}))
+
+	g.Expect(exists(g, a.Client, &rbacv1.ClusterRoleBinding{}, "", "cleanrel-crd-upgrader-k8ssandra")).To(BeFalse())
+	// the cleaner keeps its own permissions
+	g.Expect(exists(g, a.Client, &rbacv1.ClusterRole{}, "", "cleanrel-cleaner-k8ssandra")).To(BeTrue())
+	g.Expect(exists(g, a.Client, &rbacv1.ClusterRoleBinding{}, "", "cleanrel-cleaner-k8ssandra")).To(BeTrue())
+	// the resources of the release with the same name in another namespace are kept
+	g.Expect(exists(g, a.Client, &rbacv1.ClusterRole{}, "", "cleanrel-other-hook")).To(BeTrue())
+	g.Expect(exists(g, a.Client, &rbacv1.ClusterRoleBinding{}, "", "cleanrel-other-hook")).To(BeTrue())
+	g.Expect(exists(g, a.Client, &rbacv1.ClusterRole{}, "", "cleanrel-other-role")).To(BeTrue())
+}
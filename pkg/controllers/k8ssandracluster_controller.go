@@ -0,0 +1,300 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	api "github.com/k8ssandra/k8ssandra/pkg/apis/k8ssandra/v1alpha1"
+)
+
+const (
+	// defaultStorageSize is the size of the data volumes of datacenters without storage config, as in the chart
+	defaultStorageSize = "5Gi"
+
+	// maxSystemDistributedReplicationFactor caps the replication factor of system_distributed set at bootstrap
+	maxSystemDistributedReplicationFactor = 5
+)
+
+// K8ssandraClusterReconciler creates a CassandraDatacenter for each datacenter of a K8ssandraCluster and a Stargate in
+// each datacenter where it is enabled, then pushes the status of the datacenters up into the K8ssandraCluster.
+//
+// The CassandraCluster layer of the operator design is not implemented, the CassandraDatacenters are owned by the
+// K8ssandraCluster. Datacenters removed from the spec are left in place: deleting a CassandraDatacenter deletes its
+// data, it has to be decommissioned first.
+type K8ssandraClusterReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=k8ssandra.io,resources=k8ssandraclusters,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=k8ssandra.io,resources=k8ssandraclusters/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=k8ssandra.io,resources=stargates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cassandra.datastax.com,resources=cassandradatacenters,verbs=get;list;watch;create;update;patch
+
+func (r *K8ssandraClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("k8ssandracluster", req.NamespacedName)
+
+	kc := &api.K8ssandraCluster{}
+	if err := r.Get(ctx, req.NamespacedName, kc); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	// The objects of a deleted K8ssandraCluster are garbage collected
+	if kc.DeletionTimestamp != nil || kc.Spec.Cassandra == nil {
+		return ctrl.Result{}, nil
+	}
+
+	status := api.K8ssandraClusterStatus{Datacenters: make(map[string]api.K8ssandraStatus)}
+	for i := range kc.Spec.Cassandra.Datacenters {
+		template := &kc.Spec.Cassandra.Datacenters[i]
+
+		desired, err := NewDatacenter(kc, template)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := controllerutil.SetControllerReference(kc, desired, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+		cassdc := &cassdcapi.CassandraDatacenter{}
+		created, err := reconcileObject(ctx, r.Client, desired, cassdc, func() {
+			cassdc.Spec = desired.Spec
+		})
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to reconcile CassandraDatacenter %s: %w", desired.Name, err)
+		}
+		dcStatus := api.K8ssandraStatus{}
+		if created {
+			log.Info("Created CassandraDatacenter", "datacenter", desired.Name)
+		} else {
+			dcStatus.Cassandra = cassdc.Status.DeepCopy()
+		}
+
+		if dcStatus.Stargate, err = r.reconcileStargate(ctx, log, kc, template); err != nil {
+			return ctrl.Result{}, err
+		}
+		status.Datacenters[template.Name] = dcStatus
+	}
+
+	if !equality.Semantic.DeepEqual(kc.Status, status) {
+		patch := client.MergeFrom(kc.DeepCopy())
+		kc.Status = status
+		if err := r.Status().Patch(ctx, kc, patch); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update the status of K8ssandraCluster %s: %w", req.NamespacedName, err)
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// reconcileStargate creates or updates the Stargate of the datacenter, or deletes it when Stargate is not enabled. It
+// returns the status of the Stargate, nil when it was just created or is not enabled.
+func (r *K8ssandraClusterReconciler) reconcileStargate(ctx context.Context, log logr.Logger, kc *api.K8ssandraCluster, template *api.CassandraDatacenterTemplate) (*api.StargateStatus, error) {
+	key := types.NamespacedName{Namespace: kc.Namespace, Name: StargateName(kc.Name, template.Name)}
+
+	stargateTemplate := StargateTemplateFor(kc.Spec.Stargate, template.Stargate)
+	if stargateTemplate == nil {
+		stargate := &api.Stargate{}
+		if err := r.Get(ctx, key, stargate); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+		if !metav1.IsControlledBy(stargate, kc) {
+			return nil, nil
+		}
+		log.Info("Deleting Stargate", "stargate", key.Name)
+		return nil, client.IgnoreNotFound(r.Delete(ctx, stargate))
+	}
+
+	desired := &api.Stargate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, Labels: objectLabels(kc.Name)},
+		Spec: api.StargateSpec{
+			StargateTemplate: *stargateTemplate,
+			DatacenterRef:    api.CassandraDatacenterRef{Name: template.Name},
+		},
+	}
+	if err := setHash(desired, desired.Spec); err != nil {
+		return nil, err
+	}
+	if err := controllerutil.SetControllerReference(kc, desired, r.Scheme); err != nil {
+		return nil, err
+	}
+	stargate := &api.Stargate{}
+	created, err := reconcileObject(ctx, r.Client, desired, stargate, func() {
+		stargate.Spec = desired.Spec
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile Stargate %s: %w", key.Name, err)
+	}
+	if created {
+		log.Info("Created Stargate", "stargate", key.Name)
+		return nil, nil
+	}
+	return stargate.Status.DeepCopy(), nil
+}
+
+func (r *K8ssandraClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&api.K8ssandraCluster{}).
+		Owns(&cassdcapi.CassandraDatacenter{}).
+		Owns(&api.Stargate{}).
+		Complete(r)
+}
+
+// StargateName returns the name of the Stargate of a datacenter of a K8ssandraCluster. Its Service is named like the
+// one of the chart for a release named after the K8ssandraCluster.
+func StargateName(cluster, dc string) string {
+	return fmt.Sprintf("%s-%s-stargate", cluster, dc)
+}
+
+// StargateTemplateFor returns the settings of the Stargate of a datacenter, the ones of the datacenter taking
+// precedence over the ones of the cluster, or nil when Stargate is not enabled in the datacenter
+func StargateTemplateFor(cluster, dc *api.StargateClusterTemplate) *api.StargateTemplate {
+	enabled := false
+	if cluster != nil && cluster.Enabled != nil {
+		enabled = *cluster.Enabled
+	}
+	if dc != nil && dc.Enabled != nil {
+		enabled = *dc.Enabled
+	}
+	if !enabled {
+		return nil
+	}
+
+	template := &api.StargateTemplate{}
+	if cluster != nil {
+		template = cluster.StargateTemplate.DeepCopy()
+	}
+	if dc == nil {
+		return template
+	}
+	override := dc.StargateTemplate.DeepCopy()
+	if override.Replicas != 0 {
+		template.Replicas = override.Replicas
+	}
+	if override.ContainerImage != "" {
+		template.ContainerImage = override.ContainerImage
+	}
+	if override.HeapSize != nil {
+		template.HeapSize = override.HeapSize
+	}
+	if override.Resources != nil {
+		template.Resources = override.Resources
+	}
+	if override.Affinity != nil {
+		template.Affinity = override.Affinity
+	}
+	if override.Tolerations != nil {
+		template.Tolerations = override.Tolerations
+	}
+	return template
+}
+
+// NewDatacenter returns the CassandraDatacenter of a datacenter of a K8ssandraCluster. The settings of the datacenter
+// take precedence over the ones of the cluster.
+func NewDatacenter(kc *api.K8ssandraCluster, template *api.CassandraDatacenterTemplate) (*cassdcapi.CassandraDatacenter, error) {
+	cluster := kc.Spec.Cassandra
+
+	version := cluster.ServerVersion
+	if template.ServerVersion != "" {
+		version = template.ServerVersion
+	}
+	storageConfig := cluster.StorageConfig
+	if template.StorageConfig != nil {
+		storageConfig = template.StorageConfig
+	}
+	if storageConfig == nil {
+		storageConfig = defaultStorageConfig()
+	}
+	resources := cluster.Resources
+	if template.Resources != nil {
+		resources = template.Resources
+	}
+
+	config, err := datacenterConfig(cluster.AuthEnabled(), version, template)
+	if err != nil {
+		return nil, err
+	}
+
+	cassdc := &cassdcapi.CassandraDatacenter{
+		ObjectMeta: metav1.ObjectMeta{Namespace: kc.Namespace, Name: template.Name, Labels: objectLabels(kc.Name)},
+		Spec: cassdcapi.CassandraDatacenterSpec{
+			ClusterName:         cluster.ClusterName,
+			ServerType:          "cassandra",
+			ServerVersion:       version,
+			ServerImage:         cluster.ServerImage,
+			Size:                template.Size,
+			Racks:               template.Racks,
+			StorageConfig:       *storageConfig.DeepCopy(),
+			SuperuserSecretName: cluster.SuperuserSecretName,
+			ManagementApiAuth:   cassdcapi.ManagementApiAuthConfig{Insecure: &cassdcapi.ManagementApiAuthInsecureConfig{}},
+			Config:              config,
+		},
+	}
+	if resources != nil {
+		cassdc.Spec.Resources = *resources.DeepCopy()
+	}
+	if err := setHash(cassdc, cassdc.Spec); err != nil {
+		return nil, err
+	}
+	return cassdc, nil
+}
+
+func defaultStorageConfig() *cassdcapi.StorageConfig {
+	return &cassdcapi.StorageConfig{
+		CassandraDataVolumeClaimSpec: &corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(defaultStorageSize)},
+			},
+		},
+	}
+}
+
+// datacenterConfig returns the config of a CassandraDatacenter, which enables authentication like the chart does
+func datacenterConfig(auth bool, version string, template *api.CassandraDatacenterTemplate) (json.RawMessage, error) {
+	cassandraYaml := map[string]interface{}{
+		"authenticator": "AllowAllAuthenticator",
+		"authorizer":    "AllowAllAuthorizer",
+		"role_manager":  "CassandraRoleManager",
+	}
+	config := map[string]interface{}{"cassandra-yaml": cassandraYaml}
+
+	if auth {
+		cassandraYaml["authenticator"] = "PasswordAuthenticator"
+		cassandraYaml["authorizer"] = "CassandraAuthorizer"
+
+		jvmOptions := "jvm-server-options"
+		if strings.HasPrefix(version, "3") {
+			jvmOptions = "jvm-options"
+		}
+		rf := template.Size
+		if rf > maxSystemDistributedReplicationFactor {
+			rf = maxSystemDistributedReplicationFactor
+		}
+		config[jvmOptions] = map[string]interface{}{
+			"additional-jvm-opts": []string{
+				fmt.Sprintf("-Dcassandra.system_distributed_replication_dc_names=%s", template.Name),
+				fmt.Sprintf("-Dcassandra.system_distributed_replication_per_dc=%d", rf),
+			},
+		}
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the config of datacenter %s: %w", template.Name, err)
+	}
+	return data, nil
+}
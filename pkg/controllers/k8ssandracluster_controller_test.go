@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/k8ssandra/k8ssandra/pkg/apis/k8ssandra/v1alpha1"
+)
+
+const testNamespace = "k8ssandra"
+
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = cassdcapi.AddToScheme(scheme)
+	_ = api.AddToScheme(scheme)
+	return scheme
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// setDatacenterReady sets the status cass-operator gives to a ready CassandraDatacenter
+func setDatacenterReady(cassdc *cassdcapi.CassandraDatacenter) {
+	cassdc.Status.ObservedGeneration = cassdc.Generation
+	cassdc.Status.CassandraOperatorProgress = cassdcapi.ProgressReady
+	cassdc.Status.SetCondition(cassdcapi.DatacenterCondition{
+		Type:               cassdcapi.DatacenterReady,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+	})
+}
+
+// newRolledOutStatefulSet returns a StatefulSet of the CassandraDatacenter running its latest revision with all its
+// pods ready
+func newRolledOutStatefulSet(cassdc *cassdcapi.CassandraDatacenter) *appsv1.StatefulSet {
+	replicas := int32(1)
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: cassdc.Namespace, Name: cassdc.Name + "-default-sts", Labels: cassdc.GetDatacenterLabels()},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status:     appsv1.StatefulSetStatus{Replicas: replicas, ReadyReplicas: replicas, UpdatedReplicas: replicas},
+	}
+}
+
+func newK8ssandraCluster() *api.K8ssandraCluster {
+	heapSize := resource.MustParse("512Mi")
+	return &api.K8ssandraCluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "test"},
+		Spec: api.K8ssandraClusterSpec{
+			Cassandra: &api.CassandraClusterTemplate{
+				ClusterName:   "test",
+				ServerVersion: "4.0.0",
+				Datacenters: []api.CassandraDatacenterTemplate{
+					{Name: "dc1", Size: 3},
+					{Name: "dc2", Size: 1, Stargate: &api.StargateClusterTemplate{
+						StargateTemplate: api.StargateTemplate{Replicas: 2},
+					}},
+				},
+			},
+			Stargate: &api.StargateClusterTemplate{
+				Enabled:          boolPtr(true),
+				StargateTemplate: api.StargateTemplate{HeapSize: &heapSize},
+			},
+		},
+	}
+}
+
+func newTestK8ssandraClusterReconciler(objects ...runtime.Object) *K8ssandraClusterReconciler {
+	scheme := newTestScheme()
+	return &K8ssandraClusterReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme, objects...),
+		Log:    ctrl.Log.WithName("test"),
+		Scheme: scheme,
+	}
+}
+
+func TestK8ssandraClusterReconcile(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	kc := newK8ssandraCluster()
+	r := newTestK8ssandraClusterReconciler(kc)
+	key := types.NamespacedName{Namespace: testNamespace, Name: "test"}
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	dc1 := &cassdcapi.CassandraDatacenter{}
+	g.Expect(r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "dc1"}, dc1)).To(Succeed())
+	g.Expect(dc1.Spec.ClusterName).To(Equal("test"))
+	g.Expect(dc1.Spec.ServerVersion).To(Equal("4.0.0"))
+	g.Expect(dc1.Spec.Size).To(Equal(int32(3)))
+	g.Expect(dc1.Labels).To(HaveKeyWithValue(instanceLabel, "test"))
+	g.Expect(metav1.IsControlledBy(dc1, kc)).To(BeTrue())
+	g.Expect(dc1.Spec.StorageConfig.CassandraDataVolumeClaimSpec).ToNot(BeNil())
+
+	config := map[string]map[string]interface{}{}
+	g.Expect(json.Unmarshal(dc1.Spec.Config, &config)).To(Succeed())
+	g.Expect(config["cassandra-yaml"]).To(HaveKeyWithValue("authenticator", "PasswordAuthenticator"))
+	g.Expect(config["jvm-server-options"]["additional-jvm-opts"]).To(ContainElement("-Dcassandra.system_distributed_replication_per_dc=3"))
+
+	sg1 := &api.Stargate{}
+	g.Expect(r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-stargate"}, sg1)).To(Succeed())
+	g.Expect(sg1.Spec.DatacenterRef.Name).To(Equal("dc1"))
+	g.Expect(sg1.Spec.HeapSize.String()).To(Equal("512Mi"))
+	sg2 := &api.Stargate{}
+	g.Expect(r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "test-dc2-stargate"}, sg2)).To(Succeed())
+	g.Expect(sg2.Spec.Replicas).To(Equal(int32(2)))
+	g.Expect(sg2.Spec.HeapSize.String()).To(Equal("512Mi"))
+
+	g.Expect(r.Get(ctx, key, kc)).To(Succeed())
+	g.Expect(kc.Status.Datacenters).To(HaveLen(2))
+
+	// cass-operator reports dc1 ready, its status is pushed up into the K8ssandraCluster
+	setDatacenterReady(dc1)
+	g.Expect(r.Status().Update(ctx, dc1)).To(Succeed())
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.Get(ctx, key, kc)).To(Succeed())
+	g.Expect(kc.Status.Datacenters["dc1"].Cassandra.CassandraOperatorProgress).To(Equal(cassdcapi.ProgressReady))
+
+	// changes of the K8ssandraCluster are propagated, disabling Stargate in dc2 deletes it
+	kc.Spec.Cassandra.Datacenters[0].Size = 6
+	kc.Spec.Cassandra.Datacenters[1].Stargate.Enabled = boolPtr(false)
+	g.Expect(r.Update(ctx, kc)).To(Succeed())
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "dc1"}, dc1)).To(Succeed())
+	g.Expect(dc1.Spec.Size).To(Equal(int32(6)))
+	g.Expect(dc1.Status.CassandraOperatorProgress).To(Equal(cassdcapi.ProgressReady))
+	err = r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "test-dc2-stargate"}, sg2)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestK8ssandraClusterReconcileKeepsUnownedStargate(t *testing.T) {
+	g := NewWithT(t)
+
+	kc := newK8ssandraCluster()
+	kc.Spec.Stargate = nil
+	unowned := &api.Stargate{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "test-dc1-stargate"}}
+	r := newTestK8ssandraClusterReconciler(kc, unowned)
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: "test"}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "test-dc1-stargate"}, unowned)).To(Succeed())
+}
+
+func TestK8ssandraClusterReconcileKeepsUnownedDatacenter(t *testing.T) {
+	g := NewWithT(t)
+
+	kc := newK8ssandraCluster()
+	unowned := &cassdcapi.CassandraDatacenter{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "dc1", Labels: map[string]string{managedByLabel: "Helm"}},
+		Spec:       cassdcapi.CassandraDatacenterSpec{ClusterName: "helm", Size: 1},
+	}
+	r := newTestK8ssandraClusterReconciler(kc, unowned)
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: "test"}})
+	g.Expect(err).To(MatchError(ContainSubstring("k8ssandra/dc1 already exists and is not controlled by K8ssandraCluster test")))
+
+	dc1 := &cassdcapi.CassandraDatacenter{}
+	g.Expect(r.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "dc1"}, dc1)).To(Succeed())
+	g.Expect(dc1.OwnerReferences).To(BeEmpty())
+	g.Expect(dc1.Spec.ClusterName).To(Equal("helm"))
+	g.Expect(dc1.Spec.Size).To(Equal(int32(1)))
+	g.Expect(dc1.Labels).To(HaveKeyWithValue(managedByLabel, "Helm"))
+}
+
+func TestStargateTemplateFor(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(StargateTemplateFor(nil, nil)).To(BeNil())
+	g.Expect(StargateTemplateFor(&api.StargateClusterTemplate{}, nil)).To(BeNil())
+
+	cluster := &api.StargateClusterTemplate{
+		Enabled:          boolPtr(true),
+		StargateTemplate: api.StargateTemplate{Replicas: 3, ContainerImage: "stargate:custom"},
+	}
+	g.Expect(StargateTemplateFor(cluster, nil)).To(Equal(&cluster.StargateTemplate))
+	g.Expect(StargateTemplateFor(cluster, &api.StargateClusterTemplate{Enabled: boolPtr(false)})).To(BeNil())
+	g.Expect(StargateTemplateFor(cluster, &api.StargateClusterTemplate{StargateTemplate: api.StargateTemplate{Replicas: 1}})).
+		To(Equal(&api.StargateTemplate{Replicas: 1, ContainerImage: "stargate:custom"}))
+
+	// a datacenter can enable Stargate on its own
+	g.Expect(StargateTemplateFor(nil, &api.StargateClusterTemplate{Enabled: boolPtr(true)})).To(Equal(&api.StargateTemplate{}))
+}
+
+func TestNewDatacenterWithoutAuth(t *testing.T) {
+	g := NewWithT(t)
+
+	kc := newK8ssandraCluster()
+	kc.Spec.Cassandra.ServerVersion = "3.11.10"
+	kc.Spec.Cassandra.Auth = &api.AuthConfig{Enabled: boolPtr(false)}
+
+	cassdc, err := NewDatacenter(kc, &kc.Spec.Cassandra.Datacenters[0])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cassdc.Annotations).To(HaveKey(ResourceHashAnnotation))
+
+	config := map[string]map[string]interface{}{}
+	g.Expect(json.Unmarshal(cassdc.Spec.Config, &config)).To(Succeed())
+	g.Expect(config["cassandra-yaml"]).To(HaveKeyWithValue("authenticator", "AllowAllAuthenticator"))
+	g.Expect(config).ToNot(HaveKey("jvm-options"))
+}
@@ -0,0 +1,405 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	api "github.com/k8ssandra/k8ssandra/pkg/apis/k8ssandra/v1alpha1"
+	"github.com/k8ssandra/k8ssandra/pkg/monitoring"
+)
+
+const (
+	// monitoringLabel holds the name of the MonitoringConfiguration which generated an object
+	monitoringLabel = "k8ssandra.io/monitoring-configuration"
+
+	defaultDashboardLabel = "grafana_dashboard"
+
+	// prometheusOperatorNotInstalled is the reason of the MonitoringReady condition while the CRDs of the Prometheus
+	// Operator are missing, the reconciliation is then retried every prometheusOperatorRequeueDelay as installing CRDs
+	// does not trigger it
+	prometheusOperatorNotInstalled = "PrometheusOperatorNotInstalled"
+	prometheusOperatorRequeueDelay = time.Minute
+)
+
+// MonitoringConfigurationReconciler generates the ServiceMonitors, PrometheusRules and Grafana dashboard ConfigMaps of
+// the CassandraDatacenters of a cluster. Changes of the CassandraDatacenters and Stargates trigger a reconciliation,
+// so that the datacenters added after the MonitoringConfiguration are monitored as well, and the objects of the
+// deleted ones are removed. The Prometheus Operator objects are skipped while its CRDs are not installed, until they are.
+type MonitoringConfigurationReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// Dashboards are the Grafana dashboards generated for each datacenter, by name
+	Dashboards map[string][]byte
+}
+
+// +kubebuilder:rbac:groups=k8ssandra.io,resources=monitoringconfigurations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=k8ssandra.io,resources=monitoringconfigurations/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=k8ssandra.io,resources=stargates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cassandra.datastax.com,resources=cassandradatacenters,verbs=get;list;watch
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors;prometheusrules,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+
+func (r *MonitoringConfigurationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("monitoringconfiguration", req.NamespacedName)
+
+	mc := &api.MonitoringConfiguration{}
+	if err := r.Get(ctx, req.NamespacedName, mc); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if mc.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	datacenters, err := r.datacenters(ctx, mc)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	stargates := &api.StargateList{}
+	if err := r.List(ctx, stargates, client.InNamespace(mc.Namespace)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list Stargates: %w", err)
+	}
+
+	status := mc.Status.DeepCopy()
+	status.Datacenters = make([]string, 0, len(datacenters))
+	condition := api.MonitoringCondition{Type: api.MonitoringReady, Status: corev1.ConditionTrue}
+
+	var serviceMonitors, rules []*unstructured.Unstructured
+	dashboards := make([]*corev1.ConfigMap, 0)
+	for i := range datacenters {
+		cassdc := &datacenters[i]
+		status.Datacenters = append(status.Datacenters, cassdc.Name)
+		if mc.Spec.PrometheusEnabled() {
+			monitors, rule, err := NewDatacenterMonitoring(mc, cassdc, stargates.Items)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			serviceMonitors = append(serviceMonitors, monitors...)
+			rules = append(rules, rule)
+		}
+		if mc.Spec.GrafanaEnabled() {
+			configMaps, err := NewDashboardConfigMaps(mc, cassdc, r.Dashboards)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			dashboards = append(dashboards, configMaps...)
+		}
+	}
+
+	for _, kind := range []struct {
+		gvk     schema.GroupVersionKind
+		desired []*unstructured.Unstructured
+	}{
+		{monitoring.ServiceMonitorGVK, serviceMonitors},
+		{monitoring.PrometheusRuleGVK, rules},
+	} {
+		if err := r.reconcilePrometheusObjects(ctx, mc, kind.gvk, kind.desired); err != nil {
+			if !meta.IsNoMatchError(err) {
+				return ctrl.Result{}, err
+			}
+			if !mc.Spec.PrometheusEnabled() {
+				continue
+			}
+			log.Info("Prometheus Operator CRD is not installed", "kind", kind.gvk.Kind)
+			condition.Status = corev1.ConditionFalse
+			condition.Reason = prometheusOperatorNotInstalled
+			condition.Message = "The ServiceMonitor and PrometheusRule CRDs of the Prometheus Operator are not installed"
+		}
+	}
+	if err := r.reconcileDashboards(ctx, mc, dashboards); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	now := metav1.Now()
+	condition.LastTransitionTime = &now
+	status.SetCondition(condition)
+	if !equality.Semantic.DeepEqual(&mc.Status, status) {
+		patch := client.MergeFrom(mc.DeepCopy())
+		mc.Status = *status
+		if err := r.Status().Patch(ctx, mc, patch); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update the status of MonitoringConfiguration %s: %w", req.NamespacedName, err)
+		}
+	}
+	if condition.Reason == prometheusOperatorNotInstalled {
+		return ctrl.Result{RequeueAfter: prometheusOperatorRequeueDelay}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// datacenters returns the CassandraDatacenters of the cluster of the MonitoringConfiguration, sorted by name
+func (r *MonitoringConfigurationReconciler) datacenters(ctx context.Context, mc *api.MonitoringConfiguration) ([]cassdcapi.CassandraDatacenter, error) {
+	list := &cassdcapi.CassandraDatacenterList{}
+	if err := r.List(ctx, list, client.InNamespace(mc.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list CassandraDatacenters: %w", err)
+	}
+	datacenters := make([]cassdcapi.CassandraDatacenter, 0, len(list.Items))
+	for _, cassdc := range list.Items {
+		if cassdc.Spec.ClusterName == mc.Spec.ClusterName && cassdc.DeletionTimestamp == nil {
+			datacenters = append(datacenters, cassdc)
+		}
+	}
+	sort.Slice(datacenters, func(i, j int) bool { return datacenters[i].Name < datacenters[j].Name })
+	return datacenters, nil
+}
+
+// reconcilePrometheusObjects creates or updates the desired objects of a Prometheus Operator kind and deletes the other
+// ones generated for the MonitoringConfiguration, like the ones of a deleted datacenter. It returns a no match error
+// when the CRD of the kind is not installed.
+func (r *MonitoringConfigurationReconciler) reconcilePrometheusObjects(ctx context.Context, mc *api.MonitoringConfiguration, gvk schema.GroupVersionKind, desired []*unstructured.Unstructured) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := r.List(ctx, list, client.InNamespace(mc.Namespace), client.MatchingLabels{monitoringLabel: mc.Name}); err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(desired))
+	for _, obj := range desired {
+		names[obj.GetName()] = true
+		if err := controllerutil.SetControllerReference(mc, obj, r.Scheme); err != nil {
+			return err
+		}
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(gvk)
+		if _, err := reconcileObject(ctx, r.Client, obj, existing, func() {
+			existing.Object["spec"] = obj.Object["spec"]
+		}); err != nil {
+			return fmt.Errorf("failed to reconcile %s %s: %w", gvk.Kind, obj.GetName(), err)
+		}
+	}
+
+	for i := range list.Items {
+		obj := &list.Items[i]
+		if names[obj.GetName()] || !metav1.IsControlledBy(obj, mc) {
+			continue
+		}
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete %s %s: %w", gvk.Kind, obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// reconcileDashboards creates or updates the desired dashboard ConfigMaps and deletes the other ones generated for the
+// MonitoringConfiguration
+func (r *MonitoringConfigurationReconciler) reconcileDashboards(ctx context.Context, mc *api.MonitoringConfiguration, desired []*corev1.ConfigMap) error {
+	list := &corev1.ConfigMapList{}
+	if err := r.List(ctx, list, client.InNamespace(mc.Namespace), client.MatchingLabels{monitoringLabel: mc.Name}); err != nil {
+		return fmt.Errorf("failed to list dashboard ConfigMaps: %w", err)
+	}
+
+	names := make(map[string]bool, len(desired))
+	for _, configMap := range desired {
+		names[configMap.Name] = true
+		if err := controllerutil.SetControllerReference(mc, configMap, r.Scheme); err != nil {
+			return err
+		}
+		existing := &corev1.ConfigMap{}
+		if _, err := reconcileObject(ctx, r.Client, configMap, existing, func() {
+			existing.Data = configMap.Data
+		}); err != nil {
+			return fmt.Errorf("failed to reconcile ConfigMap %s: %w", configMap.Name, err)
+		}
+	}
+
+	for i := range list.Items {
+		configMap := &list.Items[i]
+		if names[configMap.Name] || !metav1.IsControlledBy(configMap, mc) {
+			continue
+		}
+		if err := r.Delete(ctx, configMap); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete ConfigMap %s: %w", configMap.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *MonitoringConfigurationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The Prometheus Operator objects are not watched, the CRDs may not be installed
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&api.MonitoringConfiguration{}).
+		Owns(&corev1.ConfigMap{}).
+		Watches(&source.Kind{Type: &cassdcapi.CassandraDatacenter{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.monitoringOfDatacenter),
+		}).
+		Watches(&source.Kind{Type: &api.Stargate{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.monitoringOfNamespace),
+		}).
+		Complete(r)
+}
+
+// monitoringOfDatacenter maps a CassandraDatacenter to the MonitoringConfigurations of its cluster
+func (r *MonitoringConfigurationReconciler) monitoringOfDatacenter(obj handler.MapObject) []reconcile.Request {
+	cassdc, ok := obj.Object.(*cassdcapi.CassandraDatacenter)
+	if !ok {
+		return nil
+	}
+	return r.monitoringConfigurations(cassdc.Namespace, func(mc *api.MonitoringConfiguration) bool {
+		return mc.Spec.ClusterName == cassdc.Spec.ClusterName
+	})
+}
+
+// monitoringOfNamespace maps a Stargate to the MonitoringConfigurations of its namespace, its datacenter may not exist
+// yet
+func (r *MonitoringConfigurationReconciler) monitoringOfNamespace(obj handler.MapObject) []reconcile.Request {
+	return r.monitoringConfigurations(obj.Meta.GetNamespace(), func(*api.MonitoringConfiguration) bool { return true })
+}
+
+func (r *MonitoringConfigurationReconciler) monitoringConfigurations(namespace string, matches func(*api.MonitoringConfiguration) bool) []reconcile.Request {
+	list := &api.MonitoringConfigurationList{}
+	if err := r.List(context.Background(), list, client.InNamespace(namespace)); err != nil {
+		r.Log.Error(err, "Failed to list MonitoringConfigurations", "namespace", namespace)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0)
+	for i := range list.Items {
+		if matches(&list.Items[i]) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: list.Items[i].Name}})
+		}
+	}
+	return requests
+}
+
+// monitoringLabels returns the labels of the objects generated for a datacenter, extra being the labels configured
+// for their kind
+func monitoringLabels(mc *api.MonitoringConfiguration, cassdc *cassdcapi.CassandraDatacenter, extra map[string]string) map[string]string {
+	labels := make(map[string]string, len(extra)+5)
+	for k, v := range extra {
+		labels[k] = v
+	}
+	labels[managedByLabel] = managedBy
+	labels[partOfLabel] = "k8ssandra"
+	labels[monitoringLabel] = mc.Name
+	labels[cassdcapi.DatacenterLabel] = cassdc.Name
+	if instance, ok := mc.Labels[instanceLabel]; ok {
+		labels[instanceLabel] = instance
+	}
+	return labels
+}
+
+// NewDatacenterMonitoring returns the ServiceMonitors of the Cassandra nodes and of the Stargates of a datacenter, and
+// its PrometheusRule
+func NewDatacenterMonitoring(mc *api.MonitoringConfiguration, cassdc *cassdcapi.CassandraDatacenter, stargates []api.Stargate) ([]*unstructured.Unstructured, *unstructured.Unstructured, error) {
+	var extra map[string]string
+	var alerts *api.AlertsConfig
+	interval := monitoring.DefaultScrapeInterval
+	if mc.Spec.Prometheus != nil {
+		extra = mc.Spec.Prometheus.Labels
+		alerts = mc.Spec.Prometheus.Alerts
+		if mc.Spec.Prometheus.ScrapeInterval != "" {
+			interval = mc.Spec.Prometheus.ScrapeInterval
+		}
+	}
+	labels := monitoringLabels(mc, cassdc, extra)
+	clusterName := cassdc.Spec.ClusterName
+
+	cassandra, err := newPrometheusObject(monitoring.ServiceMonitorGVK, cassdc.Namespace, fmt.Sprintf("%s-%s-cassandra", mc.Name, cassdc.Name), labels,
+		monitoring.CassandraServiceMonitorSpec(clusterName, cassdc.Name, interval))
+	if err != nil {
+		return nil, nil, err
+	}
+	serviceMonitors := []*unstructured.Unstructured{cassandra}
+
+	for _, sg := range stargates {
+		if sg.Spec.DatacenterRef.Name != cassdc.Name {
+			continue
+		}
+		serviceMonitor, err := newPrometheusObject(monitoring.ServiceMonitorGVK, cassdc.Namespace, fmt.Sprintf("%s-%s", mc.Name, sg.Name), labels,
+			monitoring.StargateServiceMonitorSpec(sg.Name, clusterName, cassdc.Name, interval))
+		if err != nil {
+			return nil, nil, err
+		}
+		serviceMonitors = append(serviceMonitors, serviceMonitor)
+	}
+
+	rules, err := monitoring.DatacenterRules(alerts, clusterName, cassdc.Name, cassdc.Namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	rule, err := newPrometheusObject(monitoring.PrometheusRuleGVK, cassdc.Namespace, fmt.Sprintf("%s-%s", mc.Name, cassdc.Name), labels, rules)
+	if err != nil {
+		return nil, nil, err
+	}
+	return serviceMonitors, rule, nil
+}
+
+// newPrometheusObject returns a Prometheus Operator object with the given spec
+func newPrometheusObject(gvk schema.GroupVersionKind, namespace, name string, labels map[string]string, spec interface{}) (*unstructured.Unstructured, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the spec of %s %s: %w", gvk.Kind, name, err)
+	}
+	content := make(map[string]interface{})
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, err
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": content}}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	if err := setHash(obj, spec); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// NewDashboardConfigMaps returns a ConfigMap for each dashboard, picked up by the dashboards sidecar of Grafana. The
+// dashboards are scoped to the datacenter.
+func NewDashboardConfigMaps(mc *api.MonitoringConfiguration, cassdc *cassdcapi.CassandraDatacenter, dashboards map[string][]byte) ([]*corev1.ConfigMap, error) {
+	extra := map[string]string{defaultDashboardLabel: "true"}
+	if mc.Spec.Grafana != nil && len(mc.Spec.Grafana.DashboardLabels) > 0 {
+		extra = mc.Spec.Grafana.DashboardLabels
+	}
+
+	names := make([]string, 0, len(dashboards))
+	for name := range dashboards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	configMaps := make([]*corev1.ConfigMap, 0, len(names))
+	for _, name := range names {
+		configMapName := fmt.Sprintf("%s-%s-%s-dashboard", mc.Name, cassdc.Name, name)
+		dashboard, err := monitoring.DatacenterDashboard(dashboards[name], monitoring.DashboardUID(cassdc.Namespace, configMapName), cassdc.Spec.ClusterName, cassdc.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate dashboard %s: %w", name, err)
+		}
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: cassdc.Namespace,
+				Name:      configMapName,
+				Labels:    monitoringLabels(mc, cassdc, extra),
+			},
+			// The sidecar writes the dashboards of all the ConfigMaps in the same directory, keys must be unique
+			Data: map[string]string{configMapName + ".json": string(dashboard)},
+		}
+		if err := setHash(configMap, configMap.Data); err != nil {
+			return nil, err
+		}
+		configMaps = append(configMaps, configMap)
+	}
+	return configMaps, nil
+}
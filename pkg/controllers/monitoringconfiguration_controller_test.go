@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	api "github.com/k8ssandra/k8ssandra/pkg/apis/k8ssandra/v1alpha1"
+	"github.com/k8ssandra/k8ssandra/pkg/monitoring"
+)
+
+var testDashboards = map[string][]byte{
+	"overview": []byte(`{"title": "Cassandra Overview", "templating": {"list": [{"name": "cluster"}, {"name": "dc"}]}}`),
+}
+
+func newMonitoringConfiguration() *api.MonitoringConfiguration {
+	return &api.MonitoringConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "test", Labels: map[string]string{instanceLabel: "test"}},
+		Spec: api.MonitoringConfigurationSpec{
+			ClusterName: "test",
+			Prometheus:  &api.PrometheusConfig{Labels: map[string]string{"release": "test"}},
+		},
+	}
+}
+
+func newTestMonitoringConfigurationReconciler(objects ...runtime.Object) *MonitoringConfigurationReconciler {
+	scheme := newTestScheme()
+	// The fake client decodes the objects with the scheme, the Prometheus Operator kinds are handled as unstructured
+	for _, gvk := range []schema.GroupVersionKind{monitoring.ServiceMonitorGVK, monitoring.PrometheusRuleGVK} {
+		scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+		scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	}
+	return &MonitoringConfigurationReconciler{
+		Client:     fake.NewFakeClientWithScheme(scheme, objects...),
+		Log:        ctrl.Log.WithName("test"),
+		Scheme:     scheme,
+		Dashboards: testDashboards,
+	}
+}
+
+func getPrometheusObject(r *MonitoringConfigurationReconciler, gvk, name string) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	switch gvk {
+	case "ServiceMonitor":
+		obj.SetGroupVersionKind(monitoring.ServiceMonitorGVK)
+	default:
+		obj.SetGroupVersionKind(monitoring.PrometheusRuleGVK)
+	}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: name}, obj)
+	return obj, err
+}
+
+func TestMonitoringConfigurationReconcile(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	other := newCassandraDatacenter("other", "4.0.0")
+	other.Spec.ClusterName = "other"
+	r := newTestMonitoringConfigurationReconciler(newMonitoringConfiguration(), newCassandraDatacenter("dc1", "4.0.0"), other, newStargate("dc1"))
+	key := types.NamespacedName{Namespace: testNamespace, Name: "test"}
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	serviceMonitor, err := getPrometheusObject(r, "ServiceMonitor", "test-dc1-cassandra")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(serviceMonitor.GetLabels()).To(HaveKeyWithValue("release", "test"))
+	g.Expect(serviceMonitor.GetLabels()).To(HaveKeyWithValue(instanceLabel, "test"))
+	selector, _, _ := unstructured.NestedStringMap(serviceMonitor.Object, "spec", "selector", "matchLabels")
+	g.Expect(selector).To(HaveKeyWithValue(cassdcapi.ClusterLabel, "test"))
+	g.Expect(selector).To(HaveKeyWithValue(cassdcapi.DatacenterLabel, "dc1"))
+
+	serviceMonitor, err = getPrometheusObject(r, "ServiceMonitor", "test-test-dc1-stargate")
+	g.Expect(err).ToNot(HaveOccurred())
+	selector, _, _ = unstructured.NestedStringMap(serviceMonitor.Object, "spec", "selector", "matchLabels")
+	g.Expect(selector).To(Equal(map[string]string{"app": "test-dc1-stargate"}))
+
+	_, err = getPrometheusObject(r, "PrometheusRule", "test-dc1")
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = getPrometheusObject(r, "ServiceMonitor", "test-other-cassandra")
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+
+	configMap := &corev1.ConfigMap{}
+	g.Expect(r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-overview-dashboard"}, configMap)).To(Succeed())
+	g.Expect(configMap.Labels).To(HaveKeyWithValue(defaultDashboardLabel, "true"))
+	dashboard := map[string]interface{}{}
+	g.Expect(json.Unmarshal([]byte(configMap.Data["test-dc1-overview-dashboard.json"]), &dashboard)).To(Succeed())
+	g.Expect(dashboard["title"]).To(Equal("Cassandra Overview (test/dc1)"))
+
+	mc := &api.MonitoringConfiguration{}
+	g.Expect(r.Get(ctx, key, mc)).To(Succeed())
+	g.Expect(mc.Status.Datacenters).To(Equal([]string{"dc1"}))
+	g.Expect(mc.Status.GetConditionStatus(api.MonitoringReady)).To(Equal(corev1.ConditionTrue))
+
+	// a datacenter added after the MonitoringConfiguration is monitored as well
+	g.Expect(r.Create(ctx, newCassandraDatacenter("dc2", "4.0.0"))).To(Succeed())
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = getPrometheusObject(r, "ServiceMonitor", "test-dc2-cassandra")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "test-dc2-overview-dashboard"}, configMap)).To(Succeed())
+	g.Expect(r.Get(ctx, key, mc)).To(Succeed())
+	g.Expect(mc.Status.Datacenters).To(Equal([]string{"dc1", "dc2"}))
+
+	// the objects of a deleted datacenter are deleted
+	g.Expect(r.Delete(ctx, newCassandraDatacenter("dc1", "4.0.0"))).To(Succeed())
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = getPrometheusObject(r, "ServiceMonitor", "test-dc1-cassandra")
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+	_, err = getPrometheusObject(r, "PrometheusRule", "test-dc1")
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+	err = r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-overview-dashboard"}, configMap)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestMonitoringConfigurationReconcileDisabled(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	mc := newMonitoringConfiguration()
+	r := newTestMonitoringConfigurationReconciler(mc, newCassandraDatacenter("dc1", "4.0.0"))
+	key := types.NamespacedName{Namespace: testNamespace, Name: "test"}
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(r.Get(ctx, key, mc)).To(Succeed())
+	mc.Spec.Prometheus.Enabled = boolPtr(false)
+	mc.Spec.Grafana = &api.GrafanaConfig{Enabled: boolPtr(false)}
+	g.Expect(r.Update(ctx, mc)).To(Succeed())
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	_, err = getPrometheusObject(r, "ServiceMonitor", "test-dc1-cassandra")
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+	_, err = getPrometheusObject(r, "PrometheusRule", "test-dc1")
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+	err = r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-overview-dashboard"}, &corev1.ConfigMap{})
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
+
+// noPrometheusOperatorClient fails like the API server while the CRDs of the Prometheus Operator are not installed
+type noPrometheusOperatorClient struct {
+	client.Client
+}
+
+func (c noPrometheusOperatorClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	if gvk := list.GetObjectKind().GroupVersionKind(); gvk.Group == monitoring.ServiceMonitorGVK.Group {
+		return &meta.NoKindMatchError{GroupKind: gvk.GroupKind(), SearchedVersions: []string{gvk.Version}}
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+func TestMonitoringConfigurationReconcileWithoutPrometheusOperator(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	r := newTestMonitoringConfigurationReconciler(newMonitoringConfiguration(), newCassandraDatacenter("dc1", "4.0.0"))
+	r.Client = noPrometheusOperatorClient{r.Client}
+	key := types.NamespacedName{Namespace: testNamespace, Name: "test"}
+
+	// the reconciliation is retried until the CRDs are installed
+	result, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(prometheusOperatorRequeueDelay))
+	mc := &api.MonitoringConfiguration{}
+	g.Expect(r.Get(ctx, key, mc)).To(Succeed())
+	g.Expect(mc.Status.GetConditionStatus(api.MonitoringReady)).To(Equal(corev1.ConditionFalse))
+	g.Expect(r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-overview-dashboard"}, &corev1.ConfigMap{})).To(Succeed())
+
+	r.Client = r.Client.(noPrometheusOperatorClient).Client
+	result, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(BeZero())
+	_, err = getPrometheusObject(r, "ServiceMonitor", "test-dc1-cassandra")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.Get(ctx, key, mc)).To(Succeed())
+	g.Expect(mc.Status.GetConditionStatus(api.MonitoringReady)).To(Equal(corev1.ConditionTrue))
+}
+
+func TestNewDatacenterMonitoringScrapeInterval(t *testing.T) {
+	g := NewWithT(t)
+
+	mc := newMonitoringConfiguration()
+	mc.Spec.Prometheus.ScrapeInterval = "30s"
+	serviceMonitors, _, err := NewDatacenterMonitoring(mc, newCassandraDatacenter("dc1", "4.0.0"), nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(serviceMonitors).To(HaveLen(1))
+	endpoints, _, _ := unstructured.NestedSlice(serviceMonitors[0].Object, "spec", "endpoints")
+	g.Expect(endpoints[0]).To(HaveKeyWithValue("interval", "30s"))
+	g.Expect(serviceMonitors[0].GetAnnotations()).To(HaveKey(ResourceHashAnnotation))
+}
+
+func TestNewDatacenterMonitoringAlerts(t *testing.T) {
+	g := NewWithT(t)
+
+	mc := newMonitoringConfiguration()
+	mc.Spec.Prometheus.Alerts = &api.AlertsConfig{NodeDown: &api.AlertConfig{For: "1m"}}
+	_, rule, err := NewDatacenterMonitoring(mc, newCassandraDatacenter("dc1", "4.0.0"), nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	groups, _, _ := unstructured.NestedSlice(rule.Object, "spec", "groups")
+	g.Expect(groups).To(HaveLen(1))
+	rules := groups[0].(map[string]interface{})["rules"].([]interface{})
+	g.Expect(rules[0]).To(HaveKeyWithValue("alert", "CassandraNodeDown"))
+	g.Expect(rules[0]).To(HaveKeyWithValue("for", "1m"))
+	g.Expect(rules[0]).To(HaveKeyWithValue("expr", ContainSubstring(`cassandra_datastax_com_datacenter="dc1"`)))
+}
+
+func TestMonitoringOfDatacenter(t *testing.T) {
+	g := NewWithT(t)
+
+	other := newMonitoringConfiguration()
+	other.Name = "other"
+	other.Spec.ClusterName = "other"
+	r := newTestMonitoringConfigurationReconciler(newMonitoringConfiguration(), other)
+
+	cassdc := newCassandraDatacenter("dc1", "4.0.0")
+	requests := r.monitoringOfDatacenter(handler.MapObject{Meta: cassdc, Object: cassdc})
+	g.Expect(requests).To(HaveLen(1))
+	g.Expect(requests[0].Name).To(Equal("test"))
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ResourceHashAnnotation holds the hash of the desired state of the objects created by the operator. Objects are
+	// only updated when it changes, so that the fields defaulted by the API server or set by other controllers are
+	// not overwritten on each reconciliation.
+	ResourceHashAnnotation = "k8ssandra.io/resource-hash"
+
+	managedByLabel = "app.kubernetes.io/managed-by"
+	instanceLabel  = "app.kubernetes.io/instance"
+	partOfLabel    = "app.kubernetes.io/part-of"
+
+	// managedBy is the value of the managed-by label of the objects created by the operator
+	managedBy = "k8ssandra-operator"
+)
+
+type object interface {
+	runtime.Object
+	metav1.Object
+}
+
+// objectLabels returns the labels of the objects created for the named K8ssandraCluster. The instance label is the one
+// the chart sets to the release name, so that the k8ssandra-client commands taking a release name work with clusters
+// managed by the operator.
+func objectLabels(instance string) map[string]string {
+	return map[string]string{
+		managedByLabel: managedBy,
+		instanceLabel:  instance,
+		partOfLabel:    "k8ssandra",
+	}
+}
+
+// setHash stores the hash of the desired state of obj in its ResourceHashAnnotation
+func setHash(obj metav1.Object, state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to compute the hash of %s: %w", obj.GetName(), err)
+	}
+	sum := sha256.Sum256(data)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[ResourceHashAnnotation] = hex.EncodeToString(sum[:])
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// reconcileObject creates desired, or updates existing when the hash of desired differs from the one it was last
+// updated with. existing is filled with the current object and update copies the desired state into it. It returns
+// true when the object was created. An existing object which is not controlled by the controller of desired, such as
+// a CassandraDatacenter deployed by the chart, is left untouched and an error is returned.
+func reconcileObject(ctx context.Context, c client.Client, desired, existing object, update func()) (bool, error) {
+	key := types.NamespacedName{Namespace: desired.GetNamespace(), Name: desired.GetName()}
+	if err := c.Get(ctx, key, existing); err != nil {
+		if !errors.IsNotFound(err) {
+			return false, err
+		}
+		if err := c.Create(ctx, desired); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if owner := metav1.GetControllerOf(desired); owner != nil {
+		if controller := metav1.GetControllerOf(existing); controller == nil || controller.UID != owner.UID {
+			return false, fmt.Errorf("%s already exists and is not controlled by %s %s", key, owner.Kind, owner.Name)
+		}
+	}
+
+	hash := desired.GetAnnotations()[ResourceHashAnnotation]
+	if existing.GetAnnotations()[ResourceHashAnnotation] == hash {
+		return false, nil
+	}
+
+	update()
+	labels := existing.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for k, v := range desired.GetLabels() {
+		labels[k] = v
+	}
+	existing.SetLabels(labels)
+	annotations := existing.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[ResourceHashAnnotation] = hash
+	existing.SetAnnotations(annotations)
+	existing.SetOwnerReferences(desired.GetOwnerReferences())
+
+	return false, c.Update(ctx, existing)
+}
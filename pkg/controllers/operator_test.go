@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	api "github.com/k8ssandra/k8ssandra/pkg/apis/k8ssandra/v1alpha1"
+)
+
+const (
+	operatorTestNamespace = "k8ssandra-operator"
+	unownedTestNamespace  = "k8ssandra-unowned"
+)
+
+var _ = Describe("Creating a K8ssandraCluster", func() {
+	Specify("deploys Cassandra, then Stargate once the datacenter is ready", func() {
+		ctx := context.Background()
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: operatorTestNamespace}})).Should(Succeed())
+
+		By("creating a K8ssandraCluster with Stargate enabled")
+		kc := newK8ssandraCluster()
+		kc.Namespace = operatorTestNamespace
+		kc.Spec.Cassandra.Datacenters = kc.Spec.Cassandra.Datacenters[:1]
+		Expect(k8sClient.Create(ctx, kc)).Should(Succeed())
+
+		By("waiting for the CassandraDatacenter")
+		cassdc := &cassdcapi.CassandraDatacenter{}
+		dcKey := types.NamespacedName{Namespace: operatorTestNamespace, Name: "dc1"}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, dcKey, cassdc)
+		}, timeout, interval).Should(Succeed())
+		Expect(metav1.IsControlledBy(cassdc, kc)).To(BeTrue())
+
+		By("waiting for the Stargate and its Service")
+		stargateKey := types.NamespacedName{Namespace: operatorTestNamespace, Name: "test-dc1-stargate"}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, stargateKey, &api.Stargate{})
+		}, timeout, interval).Should(Succeed())
+		Eventually(func() error {
+			return k8sClient.Get(ctx, types.NamespacedName{Namespace: operatorTestNamespace, Name: "test-dc1-stargate-service"}, &corev1.Service{})
+		}, timeout, interval).Should(Succeed())
+		deploymentKey := types.NamespacedName{Namespace: operatorTestNamespace, Name: "test-dc1-stargate-default"}
+		err := k8sClient.Get(ctx, deploymentKey, &appsv1.Deployment{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+
+		By("reporting the CassandraDatacenter and its StatefulSet ready")
+		sts := newRolledOutStatefulSet(cassdc)
+		status := sts.Status
+		sts.Spec.ServiceName = cassdc.GetAllPodsServiceName()
+		sts.Spec.Selector = &metav1.LabelSelector{MatchLabels: cassdc.GetDatacenterLabels()}
+		sts.Spec.Template = corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: cassdc.GetDatacenterLabels()},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "cassandra", Image: "cassandra"}}},
+		}
+		Expect(k8sClient.Create(ctx, sts)).Should(Succeed())
+		sts.Status = status
+		Expect(k8sClient.Status().Update(ctx, sts)).Should(Succeed())
+		setDatacenterReady(cassdc)
+		Expect(k8sClient.Status().Update(ctx, cassdc)).Should(Succeed())
+
+		Eventually(func() error {
+			return k8sClient.Get(ctx, deploymentKey, &appsv1.Deployment{})
+		}, timeout, interval).Should(Succeed())
+		Eventually(func() api.StargateProgress {
+			sg := &api.Stargate{}
+			if err := k8sClient.Get(ctx, stargateKey, sg); err != nil {
+				return ""
+			}
+			return sg.Status.Progress
+		}, timeout, interval).Should(Equal(api.StargateProgressDeploying))
+		Eventually(func() cassdcapi.ProgressState {
+			result := &api.K8ssandraCluster{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: operatorTestNamespace, Name: "test"}, result); err != nil {
+				return ""
+			}
+			status := result.Status.Datacenters["dc1"].Cassandra
+			if status == nil {
+				return ""
+			}
+			return status.CassandraOperatorProgress
+		}, timeout, interval).Should(Equal(cassdcapi.ProgressReady))
+	})
+})
+
+var _ = Describe("Creating a K8ssandraCluster over an existing CassandraDatacenter", func() {
+	Specify("leaves the datacenter it does not control untouched", func() {
+		ctx := context.Background()
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: unownedTestNamespace}})).Should(Succeed())
+
+		By("creating a CassandraDatacenter deployed by the chart")
+		unowned := &cassdcapi.CassandraDatacenter{
+			ObjectMeta: metav1.ObjectMeta{Namespace: unownedTestNamespace, Name: "dc1", Labels: map[string]string{managedByLabel: "Helm"}},
+			Spec: cassdcapi.CassandraDatacenterSpec{
+				ClusterName:   "helm",
+				ServerType:    "cassandra",
+				ServerVersion: "3.11.10",
+				Size:          1,
+			},
+		}
+		Expect(k8sClient.Create(ctx, unowned)).Should(Succeed())
+
+		By("creating a K8ssandraCluster with a datacenter of the same name")
+		kc := newK8ssandraCluster()
+		kc.Namespace = unownedTestNamespace
+		kc.Spec.Cassandra.Datacenters = kc.Spec.Cassandra.Datacenters[:1]
+		kc.Spec.Stargate = nil
+		Expect(k8sClient.Create(ctx, kc)).Should(Succeed())
+
+		dcKey := types.NamespacedName{Namespace: unownedTestNamespace, Name: "dc1"}
+		Consistently(func() bool {
+			cassdc := &cassdcapi.CassandraDatacenter{}
+			if err := k8sClient.Get(ctx, dcKey, cassdc); err != nil {
+				return false
+			}
+			return len(cassdc.OwnerReferences) == 0 && cassdc.Spec.ClusterName == "helm" && cassdc.Spec.Size == 1
+		}, 2*time.Second, interval).Should(BeTrue())
+	})
+})
@@ -0,0 +1,483 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	api "github.com/k8ssandra/k8ssandra/pkg/apis/k8ssandra/v1alpha1"
+	"github.com/k8ssandra/k8ssandra/pkg/rollout"
+	"github.com/k8ssandra/k8ssandra/pkg/stargate"
+)
+
+const (
+	// DefaultStargateVersion is the version of the Stargate image derived from the Cassandra version
+	DefaultStargateVersion = "1.0.18"
+
+	// stargateAppLabel selects the pods of a Stargate, as in the chart
+	stargateAppLabel = "app"
+	// stargateRackLabel holds the rack of the pods of a Stargate Deployment
+	stargateRackLabel = "k8ssandra.io/rack"
+	// cassandraVersionAnnotation is set on the pod template of Stargate Deployments to the Cassandra version of the
+	// datacenter, so that their pods are rolled when it changes
+	cassandraVersionAnnotation = "k8ssandra.io/cassandra-version"
+
+	// zoneLabel is the node label cass-operator pins racks with a deprecated zone to
+	zoneLabel = "failure-domain.beta.kubernetes.io/zone"
+
+	stargateHealthPort  = 8084
+	stargateMetricsPort = 8085
+	cqlPort             = 9042
+
+	defaultStargateHeapSize   = "256Mi"
+	defaultStargateCPURequest = "200m"
+	defaultStargateCPULimit   = "1000m"
+
+	stargateProbeInitialDelaySeconds = 30
+)
+
+// StargateReconciler deploys a Stargate in its CassandraDatacenter, with a Deployment per rack of the datacenter. The
+// Service is created right away, the Deployments once cass-operator reports the datacenter ready: Stargate nodes fail
+// to start while no Cassandra node is up. The Deployments are left alone while cass-operator updates the datacenter,
+// so that Stargate is only rolled to a new Cassandra version once all the nodes run it. Changes of the
+// CassandraDatacenter trigger a reconciliation of the Stargates referencing it.
+type StargateReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=k8ssandra.io,resources=stargates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=k8ssandra.io,resources=stargates/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=cassandra.datastax.com,resources=cassandradatacenters,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+
+func (r *StargateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("stargate", req.NamespacedName)
+
+	sg := &api.Stargate{}
+	if err := r.Get(ctx, req.NamespacedName, sg); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if sg.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	service := NewStargateService(sg)
+	if err := controllerutil.SetControllerReference(sg, service, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+	existingService := &corev1.Service{}
+	if _, err := reconcileObject(ctx, r.Client, service, existingService, func() {
+		// The cluster IP is immutable
+		existingService.Spec.Ports = service.Spec.Ports
+		existingService.Spec.Selector = service.Spec.Selector
+	}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to reconcile Service %s: %w", service.Name, err)
+	}
+
+	status := sg.Status.DeepCopy()
+	deployments, err := r.deployments(ctx, sg)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	dcKey := types.NamespacedName{Namespace: sg.Namespace, Name: sg.Spec.DatacenterRef.Name}
+	dcReady := false
+	if err := r.Get(ctx, dcKey, cassdc); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		log.Info("Waiting for CassandraDatacenter to be created", "datacenter", dcKey.Name)
+	} else if dcReady, err = rollout.DatacenterReady(ctx, r.Client, cassdc); err != nil {
+		return ctrl.Result{}, err
+	} else if !dcReady {
+		log.Info("Waiting for CassandraDatacenter to be ready", "datacenter", dcKey.Name)
+	}
+
+	if dcReady {
+		if status.CassandraVersion != "" && status.CassandraVersion != cassdc.Spec.ServerVersion {
+			log.Info("Rolling Stargate to the new Cassandra version", "from", status.CassandraVersion, "to", cassdc.Spec.ServerVersion)
+		}
+		desired, err := NewStargateDeployments(sg, cassdc)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if deployments, err = r.reconcileDeployments(ctx, log, sg, desired, deployments); err != nil {
+			return ctrl.Result{}, err
+		}
+		status.CassandraVersion = cassdc.Spec.ServerVersion
+	}
+
+	SetStargateStatus(status, deployments, dcReady)
+	if !equality.Semantic.DeepEqual(&sg.Status, status) {
+		patch := client.MergeFrom(sg.DeepCopy())
+		sg.Status = *status
+		if err := r.Status().Patch(ctx, sg, patch); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update the status of Stargate %s: %w", req.NamespacedName, err)
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// deployments returns the Deployments of the Stargate
+func (r *StargateReconciler) deployments(ctx context.Context, sg *api.Stargate) ([]appsv1.Deployment, error) {
+	list := &appsv1.DeploymentList{}
+	if err := r.List(ctx, list, client.InNamespace(sg.Namespace), client.MatchingLabels{stargateAppLabel: sg.Name}); err != nil {
+		return nil, fmt.Errorf("failed to list the Deployments of Stargate %s: %w", sg.Name, err)
+	}
+	deployments := make([]appsv1.Deployment, 0, len(list.Items))
+	for _, deployment := range list.Items {
+		if metav1.IsControlledBy(&deployment, sg) {
+			deployments = append(deployments, deployment)
+		}
+	}
+	return deployments, nil
+}
+
+// reconcileDeployments creates or updates the desired Deployments and deletes the other ones, like the Deployment of
+// a rack removed from the datacenter. It returns the desired Deployments with their current status.
+func (r *StargateReconciler) reconcileDeployments(ctx context.Context, log logr.Logger, sg *api.Stargate, desired []*appsv1.Deployment, current []appsv1.Deployment) ([]appsv1.Deployment, error) {
+	names := make(map[string]bool, len(desired))
+	deployments := make([]appsv1.Deployment, 0, len(desired))
+	for _, deployment := range desired {
+		names[deployment.Name] = true
+		if err := controllerutil.SetControllerReference(sg, deployment, r.Scheme); err != nil {
+			return nil, err
+		}
+		existing := &appsv1.Deployment{}
+		created, err := reconcileObject(ctx, r.Client, deployment, existing, func() {
+			existing.Spec = deployment.Spec
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to reconcile Deployment %s: %w", deployment.Name, err)
+		}
+		if created {
+			log.Info("Created Stargate Deployment", "deployment", deployment.Name)
+			deployments = append(deployments, *deployment)
+		} else {
+			deployments = append(deployments, *existing)
+		}
+	}
+
+	for i := range current {
+		if names[current[i].Name] {
+			continue
+		}
+		log.Info("Deleting Stargate Deployment", "deployment", current[i].Name)
+		if err := r.Delete(ctx, &current[i]); client.IgnoreNotFound(err) != nil {
+			return nil, fmt.Errorf("failed to delete Deployment %s: %w", current[i].Name, err)
+		}
+	}
+	return deployments, nil
+}
+
+func (r *StargateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&api.Stargate{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.Service{}).
+		Watches(&source.Kind{Type: &cassdcapi.CassandraDatacenter{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.stargatesOfDatacenter),
+		}).
+		Complete(r)
+}
+
+// stargatesOfDatacenter maps a CassandraDatacenter to the Stargates which reference it
+func (r *StargateReconciler) stargatesOfDatacenter(obj handler.MapObject) []reconcile.Request {
+	stargates := &api.StargateList{}
+	if err := r.List(context.Background(), stargates, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list Stargates", "namespace", obj.Meta.GetNamespace())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0)
+	for _, sg := range stargates.Items {
+		if sg.Spec.DatacenterRef.Name == obj.Meta.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: sg.Namespace, Name: sg.Name}})
+		}
+	}
+	return requests
+}
+
+// StargateServiceName returns the name of the Service of a Stargate, which matches the one of the chart
+func StargateServiceName(sg *api.Stargate) string {
+	return sg.Name + "-service"
+}
+
+// NewStargateService returns the Service exposing the APIs of a Stargate and the CQL protocol
+func NewStargateService(sg *api.Stargate) *corev1.Service {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: sg.Namespace,
+			Name:      StargateServiceName(sg),
+			Labels:    stargateLabels(sg),
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeClusterIP,
+			Ports: []corev1.ServicePort{
+				servicePort("graphql", stargate.GraphQLPort),
+				servicePort("authorization", stargate.AuthPort),
+				servicePort("rest", stargate.RESTPort),
+				servicePort("health", stargateHealthPort),
+				servicePort("metrics", stargateMetricsPort),
+				servicePort("cassandra", cqlPort),
+			},
+			Selector: map[string]string{stargateAppLabel: sg.Name},
+		},
+	}
+	_ = setHash(service, service.Spec)
+	return service
+}
+
+func servicePort(name string, port int32) corev1.ServicePort {
+	return corev1.ServicePort{Name: name, Port: port, Protocol: corev1.ProtocolTCP}
+}
+
+// StargateImage returns the image of a Stargate joining a datacenter running the given Cassandra version
+func StargateImage(sg *api.Stargate, serverVersion string) string {
+	if sg.Spec.ContainerImage != "" {
+		return sg.Spec.ContainerImage
+	}
+	return fmt.Sprintf("stargateio/stargate-%s:v%s", strings.Replace(stargateClusterVersion(serverVersion), ".", "_", 1), DefaultStargateVersion)
+}
+
+// stargateClusterVersion returns the Cassandra version Stargate is built for, 3.11 or 4.0
+func stargateClusterVersion(serverVersion string) string {
+	if strings.HasPrefix(serverVersion, "3") {
+		return "3.11"
+	}
+	return "4.0"
+}
+
+// SetStargateStatus sets the progress, replica counts and Ready condition of a Stargate from its Deployments
+func SetStargateStatus(status *api.StargateStatus, deployments []appsv1.Deployment, dcReady bool) {
+	status.DeploymentRefs = make([]string, 0, len(deployments))
+	status.Replicas, status.ReadyReplicas, status.UpdatedReplicas, status.AvailableReplicas = 0, 0, 0, 0
+	rolledOut := true
+	for i := range deployments {
+		deployment := &deployments[i]
+		status.DeploymentRefs = append(status.DeploymentRefs, deployment.Name)
+		if deployment.Spec.Replicas != nil {
+			status.Replicas += *deployment.Spec.Replicas
+		}
+		status.ReadyReplicas += deployment.Status.ReadyReplicas
+		status.UpdatedReplicas += deployment.Status.UpdatedReplicas
+		status.AvailableReplicas += deployment.Status.AvailableReplicas
+		rolledOut = rolledOut && rollout.DeploymentRolledOut(deployment)
+	}
+	sort.Strings(status.DeploymentRefs)
+
+	condition := api.StargateCondition{Type: api.StargateReady, Status: corev1.ConditionFalse}
+	switch {
+	case len(deployments) == 0:
+		status.Progress = api.StargateProgressPending
+		condition.Reason = "DatacenterNotReady"
+		condition.Message = "Waiting for the CassandraDatacenter to be ready"
+	case !rolledOut:
+		status.Progress = api.StargateProgressDeploying
+		condition.Reason = "DeploymentsNotReady"
+		condition.Message = fmt.Sprintf("%d of %d replicas are ready", status.ReadyReplicas, status.Replicas)
+	default:
+		status.Progress = api.StargateProgressRunning
+		condition.Status = corev1.ConditionTrue
+		if !dcReady {
+			condition.Message = "The CassandraDatacenter is being updated, Stargate will be rolled once it is ready"
+		}
+	}
+	now := metav1.Now()
+	condition.LastTransitionTime = &now
+	status.SetCondition(condition)
+}
+
+// StargateDeploymentName returns the name of the Deployment of a Stargate in a rack
+func StargateDeploymentName(sg *api.Stargate, rack string) string {
+	return fmt.Sprintf("%s-%s", sg.Name, rack)
+}
+
+// rackReplicas spreads replicas evenly across racks, the first racks getting the remainder
+func rackReplicas(replicas int32, racks, rack int) int32 {
+	count := replicas / int32(racks)
+	if int32(rack) < replicas%int32(racks) {
+		count++
+	}
+	return count
+}
+
+// rackAffinity pins the pods of a rack to the nodes of the rack, like cass-operator does for the Cassandra pods
+func rackAffinity(cassdc *cassdcapi.CassandraDatacenter, rack cassdcapi.Rack) *corev1.Affinity {
+	labels := make(map[string]string)
+	for k, v := range cassdc.Spec.NodeAffinityLabels {
+		labels[k] = v
+	}
+	for k, v := range rack.NodeAffinityLabels {
+		labels[k] = v
+	}
+	if rack.Zone != "" {
+		labels[zoneLabel] = rack.Zone
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	requirements := make([]corev1.NodeSelectorRequirement, 0, len(keys))
+	for _, k := range keys {
+		requirements = append(requirements, corev1.NodeSelectorRequirement{Key: k, Operator: corev1.NodeSelectorOpIn, Values: []string{labels[k]}})
+	}
+	return &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: requirements}},
+			},
+		},
+	}
+}
+
+// NewStargateDeployments returns the Deployments of a Stargate, one per rack of its datacenter. They are ported from
+// the Deployment of the chart, without the init container waiting for Cassandra.
+func NewStargateDeployments(sg *api.Stargate, cassdc *cassdcapi.CassandraDatacenter) ([]*appsv1.Deployment, error) {
+	heapSize := resource.MustParse(defaultStargateHeapSize)
+	if sg.Spec.HeapSize != nil {
+		heapSize = *sg.Spec.HeapSize
+	}
+	heapMB := heapSize.Value() / (1024 * 1024)
+	if heapMB <= 0 {
+		return nil, fmt.Errorf("the heap size of Stargate %s must be at least 1Mi", sg.Name)
+	}
+
+	resources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(defaultStargateCPURequest),
+			corev1.ResourceMemory: *resource.NewQuantity(2*heapMB*1024*1024, resource.BinarySI),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(defaultStargateCPULimit),
+			corev1.ResourceMemory: *resource.NewQuantity(4*heapMB*1024*1024, resource.BinarySI),
+		},
+	}
+	if sg.Spec.Resources != nil {
+		resources = *sg.Spec.Resources.DeepCopy()
+	}
+
+	total := sg.Spec.Replicas
+	if total == 0 {
+		total = 1
+	}
+
+	racks := cassdc.GetRacks()
+	deployments := make([]*appsv1.Deployment, 0, len(racks))
+	for i, rack := range racks {
+		replicas := rackReplicas(total, len(racks), i)
+		labels := stargateLabels(sg)
+		labels[stargateRackLabel] = rack.Name
+		affinity := sg.Spec.Affinity
+		if affinity == nil {
+			affinity = rackAffinity(cassdc, rack)
+		}
+
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: sg.Namespace, Name: StargateDeploymentName(sg, rack.Name), Labels: labels},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{stargateAppLabel: sg.Name, stargateRackLabel: rack.Name}},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels:      labels,
+						Annotations: map[string]string{cassandraVersionAnnotation: cassdc.Spec.ServerVersion},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Name:            "stargate",
+							Image:           StargateImage(sg, cassdc.Spec.ServerVersion),
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Ports: []corev1.ContainerPort{
+								containerPort("graphql", stargate.GraphQLPort),
+								containerPort("authorization", stargate.AuthPort),
+								containerPort("rest", stargate.RESTPort),
+								containerPort("health", stargateHealthPort),
+								containerPort("metrics", stargateMetricsPort),
+								containerPort("http-schemaless", 8090),
+								containerPort("native", cqlPort),
+								containerPort("inter-node-msg", 8609),
+								containerPort("intra-node", 7000),
+								containerPort("tls-intra-node", 7001),
+							},
+							Resources: resources,
+							Env: []corev1.EnvVar{
+								{Name: "JAVA_OPTS", Value: fmt.Sprintf("-XX:+CrashOnOutOfMemoryError -Xms%dM -Xmx%dM", heapMB, heapMB)},
+								{Name: "CLUSTER_NAME", Value: cassdc.Spec.ClusterName},
+								{Name: "CLUSTER_VERSION", Value: stargateClusterVersion(cassdc.Spec.ServerVersion)},
+								{Name: "SEED", Value: fmt.Sprintf("%s.%s.svc", cassdc.GetSeedServiceName(), cassdc.Namespace)},
+								{Name: "DATACENTER_NAME", Value: cassdc.Name},
+								{Name: "RACK_NAME", Value: rack.Name},
+								{Name: "ENABLE_AUTH", Value: "true"},
+							},
+							LivenessProbe:  stargateProbe("/checker/liveness"),
+							ReadinessProbe: stargateProbe("/checker/readiness"),
+						}},
+						Affinity:    affinity,
+						Tolerations: sg.Spec.Tolerations,
+					},
+				},
+			},
+		}
+		if err := setHash(deployment, deployment.Spec); err != nil {
+			return nil, err
+		}
+		deployments = append(deployments, deployment)
+	}
+	return deployments, nil
+}
+
+func containerPort(name string, port int32) corev1.ContainerPort {
+	return corev1.ContainerPort{Name: name, ContainerPort: port, Protocol: corev1.ProtocolTCP}
+}
+
+func stargateProbe(path string) *corev1.Probe {
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{Path: path, Port: intstr.FromString("health")},
+		},
+		TimeoutSeconds:      10,
+		InitialDelaySeconds: stargateProbeInitialDelaySeconds,
+		FailureThreshold:    5,
+	}
+}
+
+func stargateLabels(sg *api.Stargate) map[string]string {
+	labels := map[string]string{
+		managedByLabel:   managedBy,
+		partOfLabel:      "k8ssandra",
+		stargateAppLabel: sg.Name,
+	}
+	if instance, ok := sg.Labels[instanceLabel]; ok {
+		labels[instanceLabel] = instance
+	}
+	return labels
+}
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	api "github.com/k8ssandra/k8ssandra/pkg/apis/k8ssandra/v1alpha1"
+)
+
+func newStargate(dc string) *api.Stargate {
+	return &api.Stargate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "test-" + dc + "-stargate", Labels: map[string]string{instanceLabel: "test"}},
+		Spec:       api.StargateSpec{DatacenterRef: api.CassandraDatacenterRef{Name: dc}},
+	}
+}
+
+func newCassandraDatacenter(name, version string) *cassdcapi.CassandraDatacenter {
+	return &cassdcapi.CassandraDatacenter{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: name},
+		Spec:       cassdcapi.CassandraDatacenterSpec{ClusterName: "test", ServerType: "cassandra", ServerVersion: version, Size: 3},
+	}
+}
+
+// setDeploymentRolledOut sets the status the Deployment controller gives to a rolled out Deployment
+func setDeploymentRolledOut(deployment *appsv1.Deployment) {
+	deployment.Status.ObservedGeneration = deployment.Generation
+	deployment.Status.Replicas = *deployment.Spec.Replicas
+	deployment.Status.UpdatedReplicas = *deployment.Spec.Replicas
+	deployment.Status.ReadyReplicas = *deployment.Spec.Replicas
+	deployment.Status.AvailableReplicas = *deployment.Spec.Replicas
+}
+
+func newTestStargateReconciler(objects ...runtime.Object) *StargateReconciler {
+	scheme := newTestScheme()
+	return &StargateReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme, objects...),
+		Log:    ctrl.Log.WithName("test"),
+		Scheme: scheme,
+	}
+}
+
+func TestStargateReconcile(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	cassdc := newCassandraDatacenter("dc1", "4.0.0")
+	r := newTestStargateReconciler(newStargate("dc1"), cassdc, newRolledOutStatefulSet(cassdc))
+	key := types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-stargate"}
+	deploymentKey := types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-stargate-default"}
+
+	// the Service is created right away, the Deployment waits for the datacenter
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+	service := &corev1.Service{}
+	g.Expect(r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-stargate-service"}, service)).To(Succeed())
+	g.Expect(service.Spec.Selector).To(Equal(map[string]string{"app": "test-dc1-stargate"}))
+	g.Expect(service.Labels).To(HaveKeyWithValue(instanceLabel, "test"))
+	deployment := &appsv1.Deployment{}
+	err = r.Get(ctx, deploymentKey, deployment)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+
+	sg := &api.Stargate{}
+	g.Expect(r.Get(ctx, key, sg)).To(Succeed())
+	g.Expect(sg.Status.Progress).To(Equal(api.StargateProgressPending))
+	g.Expect(sg.Status.GetConditionStatus(api.StargateReady)).To(Equal(corev1.ConditionFalse))
+
+	setDatacenterReady(cassdc)
+	g.Expect(r.Status().Update(ctx, cassdc)).To(Succeed())
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(r.Get(ctx, deploymentKey, deployment)).To(Succeed())
+	g.Expect(*deployment.Spec.Replicas).To(Equal(int32(1)))
+	g.Expect(deployment.Spec.Template.Labels).To(HaveKeyWithValue("app", "test-dc1-stargate"))
+	container := deployment.Spec.Template.Spec.Containers[0]
+	g.Expect(container.Image).To(Equal("stargateio/stargate-4_0:v" + DefaultStargateVersion))
+	g.Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "SEED", Value: "test-seed-service.k8ssandra.svc"}))
+	g.Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "CLUSTER_VERSION", Value: "4.0"}))
+	g.Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "RACK_NAME", Value: "default"}))
+	g.Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "JAVA_OPTS", Value: "-XX:+CrashOnOutOfMemoryError -Xms256M -Xmx256M"}))
+	g.Expect(container.Resources.Requests.Memory().String()).To(Equal("512Mi"))
+	g.Expect(container.Resources.Limits.Memory().String()).To(Equal("1Gi"))
+
+	g.Expect(r.Get(ctx, key, sg)).To(Succeed())
+	g.Expect(sg.Status.Progress).To(Equal(api.StargateProgressDeploying))
+	g.Expect(sg.Status.DeploymentRefs).To(Equal([]string{"test-dc1-stargate-default"}))
+	g.Expect(sg.Status.CassandraVersion).To(Equal("4.0.0"))
+
+	// the Deployment controller rolls the pods out
+	setDeploymentRolledOut(deployment)
+	g.Expect(r.Status().Update(ctx, deployment)).To(Succeed())
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.Get(ctx, key, sg)).To(Succeed())
+	g.Expect(sg.Status.Progress).To(Equal(api.StargateProgressRunning))
+	g.Expect(sg.Status.ReadyReplicas).To(Equal(int32(1)))
+	g.Expect(sg.Status.GetConditionStatus(api.StargateReady)).To(Equal(corev1.ConditionTrue))
+}
+
+func TestStargateReconcileVersionChange(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	cassdc := newCassandraDatacenter("dc1", "3.11.10")
+	cassdc.Generation = 1
+	setDatacenterReady(cassdc)
+	r := newTestStargateReconciler(newStargate("dc1"), cassdc, newRolledOutStatefulSet(cassdc))
+	key := types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-stargate"}
+	deploymentKey := types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-stargate-default"}
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+	deployment := &appsv1.Deployment{}
+	g.Expect(r.Get(ctx, deploymentKey, deployment)).To(Succeed())
+	g.Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("stargateio/stargate-3_11:v" + DefaultStargateVersion))
+
+	// cass-operator has not processed the upgrade yet, Stargate is left alone
+	g.Expect(r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "dc1"}, cassdc)).To(Succeed())
+	cassdc.Spec.ServerVersion = "4.0.0"
+	cassdc.Generation = 2
+	g.Expect(r.Update(ctx, cassdc)).To(Succeed())
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.Get(ctx, deploymentKey, deployment)).To(Succeed())
+	g.Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("stargateio/stargate-3_11:v" + DefaultStargateVersion))
+
+	// all the nodes were upgraded, Stargate is rolled
+	setDatacenterReady(cassdc)
+	g.Expect(r.Status().Update(ctx, cassdc)).To(Succeed())
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: key})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.Get(ctx, deploymentKey, deployment)).To(Succeed())
+	g.Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("stargateio/stargate-4_0:v" + DefaultStargateVersion))
+	g.Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue(cassandraVersionAnnotation, "4.0.0"))
+
+	sg := &api.Stargate{}
+	g.Expect(r.Get(ctx, key, sg)).To(Succeed())
+	g.Expect(sg.Status.CassandraVersion).To(Equal("4.0.0"))
+}
+
+func TestStargateReconcileRacks(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	cassdc := newCassandraDatacenter("dc1", "4.0.0")
+	cassdc.Spec.Racks = []cassdcapi.Rack{
+		{Name: "r1", NodeAffinityLabels: map[string]string{"topology.kubernetes.io/zone": "us-east-1a"}},
+		{Name: "r2", Zone: "us-east-1b"},
+		{Name: "r3"},
+	}
+	setDatacenterReady(cassdc)
+	sg := newStargate("dc1")
+	sg.Spec.Replicas = 4
+	// the Deployment created before Stargate was deployed per rack
+	single := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Namespace: testNamespace, Name: "test-dc1-stargate", Labels: map[string]string{stargateAppLabel: "test-dc1-stargate"},
+	}}
+	scheme := newTestScheme()
+	g.Expect(controllerutil.SetControllerReference(sg, single, scheme)).To(Succeed())
+	r := newTestStargateReconciler(sg, cassdc, newRolledOutStatefulSet(cassdc), single)
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-stargate"}})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	replicas := map[string]int32{"r1": 2, "r2": 1, "r3": 1}
+	for rack, count := range replicas {
+		deployment := &appsv1.Deployment{}
+		g.Expect(r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-stargate-" + rack}, deployment)).To(Succeed())
+		g.Expect(*deployment.Spec.Replicas).To(Equal(count))
+		g.Expect(deployment.Spec.Selector.MatchLabels).To(HaveKeyWithValue(stargateRackLabel, rack))
+		g.Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "RACK_NAME", Value: rack}))
+
+		affinity := deployment.Spec.Template.Spec.Affinity
+		switch rack {
+		case "r1":
+			g.Expect(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions).To(Equal([]corev1.NodeSelectorRequirement{
+				{Key: "topology.kubernetes.io/zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"us-east-1a"}},
+			}))
+		case "r2":
+			g.Expect(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions).To(Equal([]corev1.NodeSelectorRequirement{
+				{Key: zoneLabel, Operator: corev1.NodeSelectorOpIn, Values: []string{"us-east-1b"}},
+			}))
+		case "r3":
+			g.Expect(affinity).To(BeNil())
+		}
+	}
+
+	err = r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-stargate"}, single)
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+
+	g.Expect(r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-stargate"}, sg)).To(Succeed())
+	g.Expect(sg.Status.Replicas).To(Equal(int32(4)))
+	g.Expect(sg.Status.DeploymentRefs).To(Equal([]string{"test-dc1-stargate-r1", "test-dc1-stargate-r2", "test-dc1-stargate-r3"}))
+}
+
+func TestStargateReconcileWithoutDatacenter(t *testing.T) {
+	g := NewWithT(t)
+
+	r := newTestStargateReconciler(newStargate("dc1"))
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: "test-dc1-stargate"}})
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestStargatesOfDatacenter(t *testing.T) {
+	g := NewWithT(t)
+
+	cassdc := newCassandraDatacenter("dc1", "4.0.0")
+	r := newTestStargateReconciler(newStargate("dc1"), newStargate("dc2"))
+
+	requests := r.stargatesOfDatacenter(handler.MapObject{Meta: cassdc, Object: cassdc})
+	g.Expect(requests).To(HaveLen(1))
+	g.Expect(requests[0].Name).To(Equal("test-dc1-stargate"))
+}
+
+func TestStargateImage(t *testing.T) {
+	g := NewWithT(t)
+
+	sg := newStargate("dc1")
+	g.Expect(StargateImage(sg, "3.11.10")).To(Equal("stargateio/stargate-3_11:v" + DefaultStargateVersion))
+	g.Expect(StargateImage(sg, "4.0.0")).To(Equal("stargateio/stargate-4_0:v" + DefaultStargateVersion))
+
+	sg.Spec.ContainerImage = "stargate:custom"
+	g.Expect(StargateImage(sg, "4.0.0")).To(Equal("stargate:custom"))
+}
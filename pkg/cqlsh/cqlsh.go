@@ -0,0 +1,227 @@
+package cqlsh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8ssandra/k8ssandra/pkg/credentials"
+)
+
+const (
+	cassandraContainer = "cassandra"
+	cqlshPath          = "/opt/cassandra/bin/cqlsh"
+	nodetoolPath       = "/opt/cassandra/bin/nodetool"
+
+	instanceLabel = "app.kubernetes.io/instance"
+)
+
+// Session runs CQL statements with cqlsh inside a Cassandra pod, so that no CQL port has to be reachable from where
+// the client runs
+type Session struct {
+	config    *rest.Config
+	clientset kubernetes.Interface
+
+	Namespace   string
+	Pod         string
+	Credentials *credentials.Credentials
+}
+
+// NewSession returns a Session running cqlsh in the given pod, authenticating with creds if not nil
+func NewSession(config *rest.Config, namespace, pod string, creds *credentials.Credentials) (*Session, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{
+		config:      config,
+		clientset:   clientset,
+		Namespace:   namespace,
+		Pod:         pod,
+		Credentials: creds,
+	}, nil
+}
+
+// LookupSession returns a Session on a ready Cassandra pod of the release, authenticating as the superuser
+func LookupSession(ctx context.Context, c client.Client, config *rest.Config, namespace, releaseName string) (*Session, error) {
+	cassdc, err := LookupDatacenter(ctx, c, namespace, releaseName)
+	if err != nil {
+		return nil, err
+	}
+	return DatacenterSession(ctx, c, config, cassdc)
+}
+
+// DatacenterSession returns a Session on a ready pod of the CassandraDatacenter, authenticating as the superuser
+func DatacenterSession(ctx context.Context, c client.Client, config *rest.Config, cassdc *cassdcapi.CassandraDatacenter) (*Session, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(cassdc.Namespace), client.MatchingLabels(cassdc.GetDatacenterLabels())); err != nil {
+		return nil, fmt.Errorf("failed to list pods of CassandraDatacenter %s: %w", cassdc.Name, err)
+	}
+	pod := readyPod(pods.Items)
+	if pod == nil {
+		return nil, fmt.Errorf("CassandraDatacenter %s has no ready pod", cassdc.Name)
+	}
+
+	creds, err := credentials.FromSecret(ctx, c, cassdc.GetSuperuserSecretNamespacedName())
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSession(config, cassdc.Namespace, pod.Name, creds)
+}
+
+// LookupDatacenter returns the CassandraDatacenter deployed by a release
+func LookupDatacenter(ctx context.Context, c client.Client, namespace, releaseName string) (*cassdcapi.CassandraDatacenter, error) {
+	dcs := &cassdcapi.CassandraDatacenterList{}
+	if err := c.List(ctx, dcs, client.InNamespace(namespace), client.MatchingLabels{instanceLabel: releaseName}); err != nil {
+		return nil, fmt.Errorf("failed to list CassandraDatacenters of release %s: %w", releaseName, err)
+	}
+	if len(dcs.Items) == 0 {
+		return nil, fmt.Errorf("release %s has no CassandraDatacenter in namespace %s", releaseName, namespace)
+	}
+	return &dcs.Items[0], nil
+}
+
+func readyPod(pods []corev1.Pod) *corev1.Pod {
+	for i := range pods {
+		for _, status := range pods[i].Status.ContainerStatuses {
+			if status.Name == cassandraContainer && status.Ready {
+				return &pods[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Execute runs the statements and returns the output of cqlsh. Statements are sent on stdin so that several of them
+// can be run in one session.
+func (s *Session) Execute(ctx context.Context, statements string) (string, error) {
+	command := []string{cqlshPath}
+	if s.Credentials != nil {
+		command = append(command, "--username", s.Credentials.Username, "--password", s.Credentials.Password)
+	}
+
+	stdout, stderr, err := s.exec(ctx, command, strings.NewReader(statements))
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	// cqlsh reports failed statements on stderr without always exiting with an error when reading stdin
+	if errors := errorLines(stderr); err != nil || len(errors) > 0 {
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+		return stdout, fmt.Errorf("cqlsh failed on pod %s: %s", s.Pod, strings.Join(errors, "; "))
+	}
+	return stdout, nil
+}
+
+// Nodetool runs nodetool with the given arguments and returns its output. The session's credentials are used for
+// JMX authentication, the superuser is allowed to use JMX in k8ssandra releases.
+func (s *Session) Nodetool(ctx context.Context, args ...string) (string, error) {
+	command := []string{nodetoolPath}
+	if s.Credentials != nil {
+		command = append(command, "-u", s.Credentials.Username, "-pw", s.Credentials.Password)
+	}
+	command = append(command, args...)
+
+	stdout, stderr, err := s.exec(ctx, command, nil)
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if err != nil {
+		if errors := errorLines(stderr); len(errors) > 0 {
+			return stdout, fmt.Errorf("nodetool %s failed on pod %s: %s", strings.Join(args, " "), s.Pod, strings.Join(errors, "; "))
+		}
+		return stdout, fmt.Errorf("nodetool %s failed on pod %s: %w", strings.Join(args, " "), s.Pod, err)
+	}
+	return stdout, nil
+}
+
+// exec runs the command in the cassandra container of the session's pod and returns its standard and error outputs
+func (s *Session) exec(ctx context.Context, command []string, stdin io.Reader) (string, string, error) {
+	req := s.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(s.Namespace).
+		Name(s.Pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: cassandraContainer,
+			Command:   command,
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	exec, err := remotecommand.NewSPDYExecutor(s.config, "POST", req.URL())
+	if err != nil {
+		return "", "", err
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- exec.Stream(remotecommand.StreamOptions{
+			Stdin:  stdin,
+			Stdout: stdout,
+			Stderr: stderr,
+		})
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", "", ctx.Err()
+	case err := <-done:
+		return stdout.String(), stderr.String(), err
+	}
+}
+
+// errorLines returns the lines of the cqlsh error output, leaving out warnings
+func errorLines(stderr string) []string {
+	errors := make([]string, 0)
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "Warning") {
+			errors = append(errors, line)
+		}
+	}
+	return errors
+}
+
+// QueryJSON runs a SELECT JSON query and decodes each returned row into a map
+func (s *Session) QueryJSON(ctx context.Context, query string) ([]map[string]interface{}, error) {
+	output, err := s.Execute(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJSONRows(output)
+}
+
+// ParseJSONRows decodes the rows printed by cqlsh for a SELECT JSON query, one JSON object per line below the
+// [json] header
+func ParseJSONRows(output string) ([]map[string]interface{}, error) {
+	rows := make([]map[string]interface{}, 0)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		row := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			return nil, fmt.Errorf("failed to decode row %q: %w", line, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,38 @@
+package cqlsh
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseJSONRows(t *testing.T) {
+	g := NewWithT(t)
+
+	output := `
+ [json]
+----------------------------------------------
+ {"id": 1, "value": "smoke", "tags": ["a"]}
+ {"id": 2, "value": "{brace}", "tags": null}
+
+(2 rows)
+`
+	rows, err := ParseJSONRows(output)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(rows).To(HaveLen(2))
+	g.Expect(rows[0]).To(HaveKeyWithValue("value", "smoke"))
+	g.Expect(rows[1]).To(HaveKeyWithValue("value", "{brace}"))
+
+	rows, err = ParseJSONRows("\n [json]\n--------\n\n(0 rows)\n")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(rows).To(BeEmpty())
+}
+
+func TestErrorLines(t *testing.T) {
+	g := NewWithT(t)
+
+	stderr := "Warning: Using a password on the command line interface can be insecure.\n" +
+		"<stdin>:2:InvalidRequest: Error from server: code=2200 [Invalid query] message=\"unconfigured table t\"\n"
+	g.Expect(errorLines(stderr)).To(ConsistOf(ContainSubstring("unconfigured table t")))
+	g.Expect(errorLines("Warning: something\n")).To(BeEmpty())
+}
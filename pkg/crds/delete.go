@@ -0,0 +1,106 @@
+package crds
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CustomResource is a custom resource whose CRD would be deleted
+type CustomResource struct {
+	CRD       string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// DeleteCRDs deletes the installed CRDs among crds, as ChartCRDs returns them, and returns the names of the deleted
+// CRDs. Deleting a CRD deletes its custom resources in every namespace: unless forced, no CRD is deleted while custom
+// resources of any of them exist, and those blocking custom resources are returned.
+func DeleteCRDs(ctx context.Context, c client.Client, crds []unstructured.Unstructured, force bool) ([]string, []CustomResource, error) {
+	var installed []*apiextv1.CustomResourceDefinition
+	var blocking []CustomResource
+	for _, obj := range crds {
+		crd := &apiextv1.CustomResourceDefinition{}
+		if err := c.Get(ctx, client.ObjectKey{Name: obj.GetName()}, crd); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, nil, fmt.Errorf("failed to get CRD %s: %w", obj.GetName(), err)
+		}
+		installed = append(installed, crd)
+
+		resources, err := customResources(ctx, c, crd)
+		if err != nil {
+			return nil, nil, err
+		}
+		blocking = append(blocking, resources...)
+	}
+
+	if len(blocking) > 0 && !force {
+		return nil, blocking, nil
+	}
+
+	deleted := make([]string, 0, len(installed))
+	for _, crd := range installed {
+		log.Printf("Deleting %s\n", crd.Name)
+		if err := c.Delete(ctx, crd); err != nil && !apierrors.IsNotFound(err) {
+			return deleted, blocking, fmt.Errorf("failed to delete CRD %s: %w", crd.Name, err)
+		}
+		deleted = append(deleted, crd.Name)
+	}
+	return deleted, blocking, nil
+}
+
+// customResources returns the custom resources of a CRD in every namespace, sorted by namespace and name
+func customResources(ctx context.Context, c client.Client, crd *apiextv1.CustomResourceDefinition) ([]CustomResource, error) {
+	version := servedVersion(crd)
+	if version == "" {
+		return nil, nil
+	}
+	listKind := crd.Spec.Names.ListKind
+	if listKind == "" {
+		listKind = crd.Spec.Names.Kind + "List"
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{Group: crd.Spec.Group, Version: version, Kind: listKind})
+	if err := c.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("failed to list the %s custom resources: %w", crd.Spec.Names.Kind, err)
+	}
+
+	resources := make([]CustomResource, 0, len(list.Items))
+	for _, obj := range list.Items {
+		resources = append(resources, CustomResource{CRD: crd.Name, Kind: crd.Spec.Names.Kind, Namespace: obj.GetNamespace(), Name: obj.GetName()})
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Namespace != resources[j].Namespace {
+			return resources[i].Namespace < resources[j].Namespace
+		}
+		return resources[i].Name < resources[j].Name
+	})
+	return resources, nil
+}
+
+// servedVersion returns the storage version of a CRD if it is served, or else the first served version
+func servedVersion(crd *apiextv1.CustomResourceDefinition) string {
+	served := ""
+	for _, version := range crd.Spec.Versions {
+		if !version.Served {
+			continue
+		}
+		if version.Storage {
+			return version.Name
+		}
+		if served == "" {
+			served = version.Name
+		}
+	}
+	return served
+}
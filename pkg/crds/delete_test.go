@@ -0,0 +1,116 @@
+package crds
+
+import (
+	"context"
+	"testing"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	reaperapi "github.com/k8ssandra/reaper-operator/api/v1alpha1"
+	. "github.com/onsi/gomega"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func installedCRD(group, plural, kind string, versions ...apiextv1.CustomResourceDefinitionVersion) *apiextv1.CustomResourceDefinition {
+	return &apiextv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: plural + "." + group},
+		Spec: apiextv1.CustomResourceDefinitionSpec{
+			Group:    group,
+			Names:    apiextv1.CustomResourceDefinitionNames{Plural: plural, Kind: kind, ListKind: kind + "List"},
+			Versions: versions,
+		},
+	}
+}
+
+func chartCRD(name string) unstructured.Unstructured {
+	crd := unstructured.Unstructured{}
+	crd.SetAPIVersion("apiextensions.k8s.io/v1")
+	crd.SetKind("CustomResourceDefinition")
+	crd.SetName(name)
+	return crd
+}
+
+func deleteTestClient(g *WithT, objects ...runtime.Object) client.Client {
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(apiextv1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(cassdcapi.AddToScheme(scheme)).To(Succeed())
+	g.Expect(reaperapi.AddToScheme(scheme)).To(Succeed())
+
+	objects = append(objects,
+		installedCRD("cassandra.datastax.com", "cassandradatacenters", "CassandraDatacenter",
+			apiextv1.CustomResourceDefinitionVersion{Name: "v1beta1", Served: true, Storage: true}),
+		installedCRD("reaper.cassandra-reaper.io", "reapers", "Reaper",
+			apiextv1.CustomResourceDefinitionVersion{Name: "v1alpha1", Served: true, Storage: true}))
+	return fake.NewFakeClientWithScheme(scheme, objects...)
+}
+
+var chartCRDs = []unstructured.Unstructured{
+	chartCRD("cassandradatacenters.cassandra.datastax.com"),
+	chartCRD("reapers.reaper.cassandra-reaper.io"),
+	chartCRD("cassandrabackups.cassandra.k8ssandra.io"),
+}
+
+func crdExists(g *WithT, c client.Client, name string) bool {
+	err := c.Get(context.Background(), client.ObjectKey{Name: name}, &apiextv1.CustomResourceDefinition{})
+	if apierrors.IsNotFound(err) {
+		return false
+	}
+	g.Expect(err).ToNot(HaveOccurred())
+	return true
+}
+
+func TestDeleteCRDs(t *testing.T) {
+	g := NewWithT(t)
+	c := deleteTestClient(g)
+
+	deleted, blocking, err := DeleteCRDs(context.Background(), c, chartCRDs, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(blocking).To(BeEmpty())
+	g.Expect(deleted).To(Equal([]string{"cassandradatacenters.cassandra.datastax.com", "reapers.reaper.cassandra-reaper.io"}))
+	g.Expect(crdExists(g, c, "cassandradatacenters.cassandra.datastax.com")).To(BeFalse())
+	g.Expect(crdExists(g, c, "reapers.reaper.cassandra-reaper.io")).To(BeFalse())
+}
+
+func TestDeleteCRDsBlocked(t *testing.T) {
+	g := NewWithT(t)
+	c := deleteTestClient(g,
+		&cassdcapi.CassandraDatacenter{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "dc1"}},
+		&cassdcapi.CassandraDatacenter{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "dc1"}})
+
+	deleted, blocking, err := DeleteCRDs(context.Background(), c, chartCRDs, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(deleted).To(BeEmpty())
+	g.Expect(blocking).To(Equal([]CustomResource{
+		{CRD: "cassandradatacenters.cassandra.datastax.com", Kind: "CassandraDatacenter", Namespace: "team-a", Name: "dc1"},
+		{CRD: "cassandradatacenters.cassandra.datastax.com", Kind: "CassandraDatacenter", Namespace: "team-b", Name: "dc1"},
+	}))
+	// no CRD is deleted, not even those without custom resources
+	g.Expect(crdExists(g, c, "cassandradatacenters.cassandra.datastax.com")).To(BeTrue())
+	g.Expect(crdExists(g, c, "reapers.reaper.cassandra-reaper.io")).To(BeTrue())
+
+	deleted, blocking, err = DeleteCRDs(context.Background(), c, chartCRDs, true)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(blocking).To(HaveLen(2))
+	g.Expect(deleted).To(HaveLen(2))
+	g.Expect(crdExists(g, c, "cassandradatacenters.cassandra.datastax.com")).To(BeFalse())
+}
+
+func TestServedVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	crd := installedCRD("cassandra.datastax.com", "cassandradatacenters", "CassandraDatacenter",
+		apiextv1.CustomResourceDefinitionVersion{Name: "v1alpha1", Served: false},
+		apiextv1.CustomResourceDefinitionVersion{Name: "v1beta1", Served: true},
+		apiextv1.CustomResourceDefinitionVersion{Name: "v1", Served: true, Storage: true})
+	g.Expect(servedVersion(crd)).To(Equal("v1"))
+
+	crd.Spec.Versions[2].Served = false
+	g.Expect(servedVersion(crd)).To(Equal("v1beta1"))
+}
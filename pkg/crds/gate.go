@@ -0,0 +1,174 @@
+package crds
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8ssandra/k8ssandra/pkg/helmutil"
+	"github.com/k8ssandra/k8ssandra/pkg/rollout"
+)
+
+const (
+	instanceLabel = "app.kubernetes.io/instance"
+	nameLabel     = "app.kubernetes.io/name"
+)
+
+// Names of the operator subcharts, in the app.kubernetes.io/name label of their Deployments
+const (
+	CassOperator   = "cass-operator"
+	ReaperOperator = "reaper-operator"
+	MedusaOperator = "medusa-operator"
+)
+
+// Incompatibility is a version of the chart from which the resources of the chart need a minimum version of an
+// operator
+type Incompatibility struct {
+	Operator       string
+	MinimumVersion string
+	ChartVersion   string
+	// Source is the change of the chart which introduced the incompatibility
+	Source string
+}
+
+// KnownIncompatibilities are the incompatibilities between the chart and older operators, each one citing its source
+var KnownIncompatibilities = []Incompatibility{
+	{Operator: ReaperOperator, MinimumVersion: "0.3.1", ChartVersion: "1.2.0",
+		Source: "#224, the Reaper resource of the chart sets the autoScheduling settings added in reaper-operator 0.3.1"},
+}
+
+// RequiredVersions are the chart versions an upgrade cannot skip, releases of older versions have to be upgraded to
+// them first. The resources of the charts before 1.0.0 were renamed (#429, #435).
+var RequiredVersions = []string{"1.0.0"}
+
+// CheckUpgrade returns the reasons why a release cannot be upgraded to the target chart version: a downgrade or an
+// upgrade skipping a required version, CassandraDatacenters which are not ready or running a Cassandra version above
+// the cassandraVersion of the upgrade values, or operators deployed separately from the release with a known
+// incompatibility with the target version. It runs in the pre-upgrade hook before the CRDs are changed, unless the
+// checks are skipped. An empty cassandraVersion is not checked.
+func CheckUpgrade(ctx context.Context, c client.Client, namespace, releaseName, targetVersion, cassandraVersion string) ([]string, error) {
+	target, err := semver.NewVersion(targetVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target version %s: %w", targetVersion, err)
+	}
+
+	problems := make([]string, 0)
+	deployed, err := helmutil.DeployedRelease(c, namespace, releaseName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the deployed release %s: %w", releaseName, err)
+	}
+	if deployed != nil && deployed.Chart != nil && deployed.Chart.Metadata != nil {
+		problems = append(problems, CheckVersions(deployed.Chart.Metadata.Version, target)...)
+	}
+
+	dcs := &cassdcapi.CassandraDatacenterList{}
+	if err := c.List(ctx, dcs, client.InNamespace(namespace), client.MatchingLabels{instanceLabel: releaseName}); err != nil {
+		return nil, fmt.Errorf("failed to list CassandraDatacenters of release %s: %w", releaseName, err)
+	}
+	for i := range dcs.Items {
+		if ready, err := rollout.DatacenterReady(ctx, c, &dcs.Items[i]); err != nil {
+			return nil, err
+		} else if !ready {
+			problems = append(problems, fmt.Sprintf("CassandraDatacenter %s is not ready, wait for cass-operator to be done with it", dcs.Items[i].Name))
+		}
+	}
+	if cassandraVersion != "" {
+		problems = append(problems, CheckCassandraVersion(dcs.Items, cassandraVersion)...)
+	}
+
+	// the operators of the release are upgraded with it
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list Deployments: %w", err)
+	}
+	separate := make([]appsv1.Deployment, 0)
+	for _, deployment := range deployments.Items {
+		if deployment.Labels[instanceLabel] != releaseName {
+			separate = append(separate, deployment)
+		}
+	}
+	problems = append(problems, CheckOperators(separate, target)...)
+	return problems, nil
+}
+
+// CheckVersions refuses downgrades, which would downgrade the CRDs, and upgrades skipping a required version
+func CheckVersions(deployedVersion string, target *semver.Version) []string {
+	deployed, err := semver.NewVersion(deployedVersion)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid deployed version %s: %v", deployedVersion, err)}
+	}
+	if target.LessThan(deployed) {
+		return []string{fmt.Sprintf("downgrades are not supported, the release runs version %s of the chart", deployedVersion)}
+	}
+	for _, version := range RequiredVersions {
+		required := semver.MustParse(version)
+		if deployed.LessThan(required) && target.GreaterThan(required) {
+			return []string{fmt.Sprintf("the release runs version %s of the chart, upgrade it to %s first", deployedVersion, version)}
+		}
+	}
+	return nil
+}
+
+// CheckCassandraVersion refuses values whose cassandra.version is below the serverVersion of CassandraDatacenters, as
+// after k8ssandra-client upgrade-cassandra: the upgrade would roll their nodes back to an older Cassandra version,
+// which cannot read the files written by the newer one. Versions which cannot be parsed are not checked.
+func CheckCassandraVersion(dcs []cassdcapi.CassandraDatacenter, cassandraVersion string) []string {
+	version, err := semver.NewVersion(cassandraVersion)
+	if err != nil {
+		return nil
+	}
+	problems := make([]string, 0)
+	for _, dc := range dcs {
+		running, err := semver.NewVersion(dc.Spec.ServerVersion)
+		if err != nil {
+			continue
+		}
+		if version.LessThan(running) {
+			problems = append(problems, fmt.Sprintf("CassandraDatacenter %s runs Cassandra %s, set cassandra.version to it instead of %s which would roll its nodes back",
+				dc.Name, dc.Spec.ServerVersion, cassandraVersion))
+		}
+	}
+	return problems
+}
+
+// CheckOperators returns the operators with a known incompatibility with the target version. Operators whose image tag
+// is not a version are not checked.
+func CheckOperators(deployments []appsv1.Deployment, target *semver.Version) []string {
+	problems := make([]string, 0)
+	for _, deployment := range deployments {
+		name := deployment.Labels[nameLabel]
+		version := operatorVersion(&deployment)
+		if version == nil {
+			continue
+		}
+		for _, incompatibility := range KnownIncompatibilities {
+			if incompatibility.Operator != name || target.LessThan(semver.MustParse(incompatibility.ChartVersion)) {
+				continue
+			}
+			if version.LessThan(semver.MustParse(incompatibility.MinimumVersion)) {
+				problems = append(problems, fmt.Sprintf("%s %s of Deployment %s does not support version %s of the chart, upgrade it to %s first (%s)",
+					name, version, deployment.Name, target, incompatibility.MinimumVersion, incompatibility.Source))
+			}
+		}
+	}
+	return problems
+}
+
+// operatorVersion returns the version of the image tag of the operator container, or nil if it is not a version
+func operatorVersion(deployment *appsv1.Deployment) *semver.Version {
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		separator := strings.LastIndex(container.Image, ":")
+		if separator < 0 || strings.Contains(container.Image[separator:], "/") {
+			continue
+		}
+		if version, err := semver.NewVersion(container.Image[separator+1:]); err == nil {
+			return version
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package crds
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k8ssandra/k8ssandra/pkg/helmutil/helmtest"
+)
+
+func TestCheckVersions(t *testing.T) {
+	g := NewWithT(t)
+
+	tests := []struct {
+		deployed string
+		target   string
+		problem  string
+	}{
+		{deployed: "1.2.0", target: "1.3.0"},
+		{deployed: "1.2.0", target: "1.2.0"},
+		{deployed: "0.55.0", target: "1.0.0"},
+		{deployed: "1.3.0", target: "1.2.0", problem: "downgrades are not supported"},
+		{deployed: "0.55.0", target: "1.1.0", problem: "upgrade it to 1.0.0 first"},
+		{deployed: "main", target: "1.1.0", problem: "invalid deployed version main"},
+	}
+	for _, test := range tests {
+		problems := CheckVersions(test.deployed, semver.MustParse(test.target))
+		if test.problem == "" {
+			g.Expect(problems).To(BeEmpty(), "%s to %s", test.deployed, test.target)
+		} else {
+			g.Expect(problems).To(ConsistOf(ContainSubstring(test.problem)), "%s to %s", test.deployed, test.target)
+		}
+	}
+}
+
+func TestCheckCassandraVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	dc := func(name, serverVersion string) cassdcapi.CassandraDatacenter {
+		return cassdcapi.CassandraDatacenter{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       cassdcapi.CassandraDatacenterSpec{ServerVersion: serverVersion},
+		}
+	}
+	dcs := []cassdcapi.CassandraDatacenter{dc("dc1", "3.11.10"), dc("dc2", "4.0.0"), dc("dc3", "")}
+
+	g.Expect(CheckCassandraVersion(dcs, "4.0.0")).To(BeEmpty())
+	g.Expect(CheckCassandraVersion(dcs, "4.0.1")).To(BeEmpty())
+	g.Expect(CheckCassandraVersion(dcs, "3.11.10")).To(ConsistOf(ContainSubstring("CassandraDatacenter dc2 runs Cassandra 4.0.0")))
+	g.Expect(CheckCassandraVersion(dcs, "3.11.9")).To(HaveLen(2))
+	g.Expect(CheckCassandraVersion(dcs, "latest")).To(BeEmpty())
+}
+
+func operatorDeployment(releaseName, name, image string) appsv1.Deployment {
+	return appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      releaseName + "-" + name,
+			Labels:    map[string]string{instanceLabel: releaseName, nameLabel: name},
+		},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: name, Image: image}},
+		}}},
+	}
+}
+
+func TestCheckOperators(t *testing.T) {
+	g := NewWithT(t)
+
+	deployments := []appsv1.Deployment{
+		operatorDeployment("operators", CassOperator, "datastax/cass-operator:v1.6.0"),
+		operatorDeployment("operators", ReaperOperator, "k8ssandra/reaper-operator:v0.3.1"),
+		operatorDeployment("operators", MedusaOperator, "localhost:5000/k8ssandra/medusa-operator:latest"),
+		operatorDeployment("grafana", "grafana", "grafana/grafana:7.3.5"),
+	}
+	g.Expect(CheckOperators(deployments, semver.MustParse("1.3.0"))).To(BeEmpty())
+
+	deployments[1] = operatorDeployment("operators", ReaperOperator, "k8ssandra/reaper-operator:v0.2.0")
+	g.Expect(CheckOperators(deployments, semver.MustParse("1.1.0"))).To(BeEmpty())
+	g.Expect(CheckOperators(deployments, semver.MustParse("1.2.0"))).To(ConsistOf(
+		"reaper-operator 0.2.0 of Deployment operators-reaper-operator does not support version 1.2.0 of the chart, upgrade it to 0.3.1 first " +
+			"(#224, the Reaper resource of the chart sets the autoScheduling settings added in reaper-operator 0.3.1)"))
+}
+
+// releaseSecret returns a deployed release of the chart stored as helm stores it
+func releaseSecret(t *testing.T, chartVersion string) *corev1.Secret {
+	return helmtest.ReleaseSecret(t, "ns", &release.Release{
+		Name:    "k8ssandra",
+		Version: 1,
+		Info:    &release.Info{Status: release.StatusDeployed},
+		Chart:   &chart.Chart{Metadata: &chart.Metadata{Name: "k8ssandra", Version: chartVersion}},
+	})
+}
+
+func TestCheckUpgrade(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(cassdcapi.AddToScheme(scheme)).To(Succeed())
+
+	newDatacenter := func(name string, progress cassdcapi.ProgressState) *cassdcapi.CassandraDatacenter {
+		return &cassdcapi.CassandraDatacenter{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, Labels: map[string]string{instanceLabel: "k8ssandra"}},
+			Spec:       cassdcapi.CassandraDatacenterSpec{ClusterName: "k8ssandra", ServerVersion: "3.11.10"},
+			Status: cassdcapi.CassandraDatacenterStatus{
+				CassandraOperatorProgress: progress,
+				Conditions:                []cassdcapi.DatacenterCondition{{Type: cassdcapi.DatacenterReady, Status: corev1.ConditionTrue}},
+			},
+		}
+	}
+	ready := newDatacenter("dc1", cassdcapi.ProgressReady)
+	replicas := int32(1)
+	cassOperator := operatorDeployment("k8ssandra", CassOperator, "datastax/cass-operator:v1.7.1")
+	// the operators of the release are upgraded with it
+	releaseReaperOperator := operatorDeployment("k8ssandra", ReaperOperator, "k8ssandra/reaper-operator:v0.2.0")
+	objects := []runtime.Object{
+		releaseSecret(t, "1.2.0"),
+		ready,
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "k8ssandra-dc1-default-sts", Labels: ready.GetDatacenterLabels()},
+			Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+			Status:     appsv1.StatefulSetStatus{Replicas: replicas, ReadyReplicas: replicas, UpdatedReplicas: replicas},
+		},
+		&cassOperator,
+		&releaseReaperOperator,
+	}
+
+	c := fake.NewFakeClientWithScheme(scheme, objects...)
+	problems, err := CheckUpgrade(context.Background(), c, "ns", "k8ssandra", "1.3.0", "3.11.10")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(problems).To(BeEmpty())
+
+	reaperOperator := operatorDeployment("operators", ReaperOperator, "k8ssandra/reaper-operator:v0.2.0")
+	c = fake.NewFakeClientWithScheme(scheme, append(objects, newDatacenter("dc2", cassdcapi.ProgressUpdating), &reaperOperator)...)
+	problems, err = CheckUpgrade(context.Background(), c, "ns", "k8ssandra", "1.4.0", "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(problems).To(ConsistOf(
+		ContainSubstring("CassandraDatacenter dc2 is not ready"),
+		ContainSubstring("reaper-operator 0.2.0 of Deployment operators-reaper-operator does not support version 1.4.0 of the chart"),
+	))
+
+	// dc1 was upgraded by k8ssandra-client upgrade-cassandra
+	ready.Spec.ServerVersion = "4.0.0"
+	c = fake.NewFakeClientWithScheme(scheme, objects...)
+	problems, err = CheckUpgrade(context.Background(), c, "ns", "k8ssandra", "1.3.0", "3.11.10")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(problems).To(ConsistOf("CassandraDatacenter dc1 runs Cassandra 4.0.0, set cassandra.version to it instead of 3.11.10 which would roll its nodes back"))
+
+	problems, err = CheckUpgrade(context.Background(), c, "ns", "k8ssandra", "1.1.0", "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(problems).To(ContainElement(ContainSubstring("downgrades are not supported")))
+
+	_, err = CheckUpgrade(context.Background(), c, "ns", "k8ssandra", "latest", "")
+	g.Expect(err).To(HaveOccurred())
+}
@@ -58,34 +58,16 @@ func New(namespace string) (*Upgrader, error) {
 
 // Upgrade installs the missing CRDs or updates them if they exists already
 func (u *Upgrader) Upgrade(targetVersion string) ([]unstructured.Unstructured, error) {
-	extractDir, err := helmutil.GetChartTargetDir(targetVersion)
+	extractDir, err := FetchChart(targetVersion)
 	if err != nil {
 		return nil, err
 	}
 
-	// If the targetCacheDirectory does not exist, download the chart
-	if _, err := os.Stat(extractDir); os.IsNotExist(err) {
-		log.Printf("Downloading release %s from Helm repository", targetVersion)
-		extractDir, err = helmutil.DownloadChartRelease(targetVersion)
-		if err != nil {
-			return nil, err
-		}
-	} else if err != nil {
+	crds, err := ChartCRDs(extractDir)
+	if err != nil {
 		return nil, err
 	}
 
-	crds := make([]unstructured.Unstructured, 0)
-
-	// For each dir under the charts subdir, check the "crds/"
-	paths, _ := findCRDDirs(extractDir)
-
-	for _, path := range paths {
-		err = parseChartCRDs(&crds, path)
-		if err != nil {
-			return nil, err
-		}
-	}
-
 	for _, obj := range crds {
 		existingCrd := obj.DeepCopy()
 		err = u.client.Get(context.TODO(), client.ObjectKey{Name: obj.GetName()}, existingCrd)
@@ -110,6 +92,39 @@ func (u *Upgrader) Upgrade(targetVersion string) ([]unstructured.Unstructured, e
 	return crds, err
 }
 
+// FetchChart returns the directory of a release of the chart, which is downloaded from the Helm repository unless it was
+// already
+func FetchChart(version string) (string, error) {
+	extractDir, err := helmutil.GetChartTargetDir(version)
+	if err != nil {
+		return "", err
+	}
+
+	// If the targetCacheDirectory does not exist, download the chart
+	if _, err := os.Stat(extractDir); os.IsNotExist(err) {
+		log.Printf("Downloading release %s from Helm repository", version)
+		return helmutil.DownloadChartRelease(version)
+	} else if err != nil {
+		return "", err
+	}
+	return extractDir, nil
+}
+
+// ChartCRDs returns the CRDs of the chart and of its subcharts, from the crds directories under the chart directory
+func ChartCRDs(chartDir string) ([]unstructured.Unstructured, error) {
+	crds := make([]unstructured.Unstructured, 0)
+
+	// For each dir under the charts subdir, check the "crds/"
+	paths, _ := findCRDDirs(chartDir)
+
+	for _, path := range paths {
+		if err := parseChartCRDs(&crds, path); err != nil {
+			return nil, err
+		}
+	}
+	return crds, nil
+}
+
 func findCRDDirs(chartDir string) ([]string, error) {
 	dirs := make([]string, 0)
 	err := filepath.Walk(chartDir, func(path string, info os.FileInfo, err error) error {
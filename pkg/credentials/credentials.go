@@ -0,0 +1,45 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// UsernameKey is the key holding the username in the secrets generated by the k8ssandra chart
+	UsernameKey = "username"
+	// PasswordKey is the key holding the password in the secrets generated by the k8ssandra chart
+	PasswordKey = "password"
+)
+
+// Credentials is a username / password pair as stored in the k8ssandra user secrets
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// FromSecret reads the credentials stored in the given secret
+func FromSecret(ctx context.Context, c client.Client, key types.NamespacedName) (*Credentials, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, key, secret); err != nil {
+		return nil, fmt.Errorf("failed to get secret %s: %w", key, err)
+	}
+
+	username, found := secret.Data[UsernameKey]
+	if !found {
+		return nil, fmt.Errorf("secret %s has no %s key", key, UsernameKey)
+	}
+	password, found := secret.Data[PasswordKey]
+	if !found {
+		return nil, fmt.Errorf("secret %s has no %s key", key, PasswordKey)
+	}
+
+	return &Credentials{
+		Username: string(username),
+		Password: string(password),
+	}, nil
+}
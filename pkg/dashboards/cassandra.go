@@ -0,0 +1,285 @@
+package dashboards
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cassandraMetric is the metric whose labels are the values of the topology variables of the Cassandra dashboards,
+// MCAC exposes it for every node
+const cassandraMetric = "collectd_collectd_queue_length"
+
+var overviewDashboard = &definition{
+	name:      "overview",
+	title:     "Cassandra Overview",
+	tags:      []string{"cassandra"},
+	refresh:   "30s",
+	template:  "templates/cassandra/overview-dashboard.yaml",
+	configMap: "cassandra-overview-dashboard",
+	key:       "cassandra-overview.json",
+	instance:  "node",
+	variables: func(b *builder) []Variable {
+		return append([]Variable{b.datasourceVariable()}, b.topologyVariables(cassandraMetric, true)...)
+	},
+	panelSets: overviewPanelSets,
+}
+
+// requestErrors are the metrics of the failed requests, and their legends
+var requestErrors = [][2]string{
+	{"failures", "failures"},
+	{"timeouts", "timeouts"},
+	{"unavailables", "unavailable errors"},
+	{"unfinished_commit", "unfinished commit errors"},
+	{"condition_not_met", "condition not met errors"},
+	{"contention_histogram", "contention histogram errors"},
+}
+
+func overviewPanelSets(b *builder) []PanelSet {
+	sel := b.selector()
+	// Stargate exposes the latency quantiles of the requests it coordinates in seconds
+	stargateLatency := func(quantile, requestType string) string {
+		return fmt.Sprintf(`stargate_client_request_latency_quantile{%s} * 1000000`, b.selector(fmt.Sprintf(`quantile="%s"`, quantile), requestType))
+	}
+	latency := func(requestType string) []Target {
+		targets := make([]Target, 0, 6)
+		quantiles := []string{"0.98", "0.99", "0.999"}
+		for _, quantile := range quantiles {
+			targets = append(targets, target(fmt.Sprintf(
+				`histogram_quantile(%s, sum by (le, cluster) (rate(mcac_client_request_latency_bucket{%s}[5m])))`,
+				quantile, b.selector(fmt.Sprintf(`request_type="%s"`, requestType))), "p"+strings.TrimPrefix(quantile, "0.")))
+		}
+		for _, quantile := range quantiles {
+			targets = append(targets, target(stargateLatency(quantile, fmt.Sprintf(`request_type="%s"`, requestType)), "Stargate p"+strings.TrimPrefix(quantile, "0.")))
+		}
+		return targets
+	}
+	// requests returns the rate of the requests of a type coordinated by the nodes, or by Stargate with the stargate
+	// prefix
+	requests := func(prefix, requestType string) string {
+		return fmt.Sprintf(`sum by (cluster) (rate(%s_client_request_latency_total{%s}[1m:30s]))`, prefix, b.selector(fmt.Sprintf(`request_type="%s"`, requestType)))
+	}
+	errors := make([]Target, 0, 2*len(requestErrors))
+	for _, prefix := range []string{"mcac", "stargate"} {
+		legend := "{{request_type}}"
+		if prefix == "stargate" {
+			legend = "Stargate " + legend
+		}
+		for _, requestError := range requestErrors {
+			errors = append(errors, target(fmt.Sprintf(`sum by (cluster, request_type) (rate(%s_client_request_%s_total{%s}[1m:30s]))`, prefix, requestError[0], sel),
+				legend+" "+requestError[1]))
+		}
+	}
+	diskUsage := fmt.Sprintf(`1 - sum by (instance, df) (collectd_df_df_complex{%[1]s, type="free"}) / sum by (instance, df) (collectd_df_df_complex{%[1]s})`,
+		b.selector(`df!~".*lxcfs.*"`))
+	otherRequests := `request_type!~"write|read|.*-.*"`
+
+	return []PanelSet{
+		{
+			Name:  "requests",
+			Title: "Request Throughputs (Coordinator Perspective)",
+			Panels: []Panel{
+				graph("Request Throughputs", "rps", 8,
+					target(fmt.Sprintf(`sum by (cluster, request_type) (rate(mcac_client_request_latency_total{%s}[1m:30s]))`, sel), "{{request_type}}"),
+					target(fmt.Sprintf(`sum by (cluster, request_type) (rate(stargate_client_request_latency_total{%s}[1m:30s]))`, sel), "Stargate {{request_type}}")),
+				graph("Error throughputs", "rps", 8, errors...),
+				stat("Read / Write Distribution", "percentunit", 8,
+					target(fmt.Sprintf("(%s + %s) / (%s + %s + %s + %s)",
+						requests("mcac", "read"), requests("stargate", "read"),
+						requests("mcac", "read"), requests("mcac", "write"), requests("stargate", "read"), requests("stargate", "write")), "reads")),
+				graph("Read Latency (98 - 999th percentile)", "µs", 8, latency("read")...),
+				graph("Write Latency (98th - p999 Percentile)", "µs", 8, latency("write")...),
+				graph("Other Latencies", "µs", 8,
+					target(fmt.Sprintf(`histogram_quantile(0.99, sum by (le, request_type, cluster) (rate(mcac_client_request_latency_bucket{%s}[1m:30s])))`,
+						b.selector(otherRequests)), "p99 {{request_type}}"),
+					target(stargateLatency("0.99", otherRequests), "Stargate p99 {{request_type}}")),
+			},
+		},
+		{
+			Name:  "nodes",
+			Title: "Nodes Status",
+			Panels: []Panel{
+				nodeStatus(fmt.Sprintf(`max by (cluster, dc, rack, instance) (changes(mcac_thread_pools_completed_tasks{%s}[2m:30s])) > bool 0`,
+					b.selector(`pool_name="gossip_stage"`))),
+				stat("Nodes Count", "short", 4,
+					target(fmt.Sprintf(`count by (cluster) (max by (cluster, dc, rack, instance) (%s{%s}))`, cassandraMetric, sel), "nodes")),
+				graph("Nodes Status History", "short", 8,
+					target(fmt.Sprintf(`count by (cluster) (max by (cluster, dc, rack, instance) (%s{%s}))`, cassandraMetric, sel), "Total number of nodes"),
+					target(fmt.Sprintf(`sum by (cluster) (max by (cluster, dc, rack, instance) (changes(mcac_thread_pools_completed_tasks{%s}[2m:30s])) > bool 0)`,
+						b.selector(`pool_name="native"`)), "Nodes coordinating requests (native protocol)"),
+					target(fmt.Sprintf(`sum by (cluster) (max by (cluster, dc, rack, instance) (changes(mcac_thread_pools_completed_tasks{%s}[2m:30s])) > bool 0)`,
+						b.selector(`pool_name="gossip_stage"`)), "Nodes with internal activity (gossip protocol)")),
+			},
+		},
+		{
+			Name:  "data",
+			Title: "Data Status",
+			Panels: []Panel{
+				gauge("Disk Space Usage", "percentunit", 8, 0, 1, target(diskUsage, "{{instance}} {{df}}")),
+				graph("Cassandra cluster Data Size", "bytes", 8,
+					target(fmt.Sprintf(`sum by (cluster) (mcac_table_live_disk_space_used_total{%s})`, sel), "Live space"),
+					target(fmt.Sprintf(`sum by (cluster) (mcac_table_total_disk_space_used_total{%s})`, sel), "Total space")),
+				graph("SSTable Count", "short", 8,
+					target(fmt.Sprintf(`max by (cluster, keyspace, table) (mcac_table_live_ss_table_count{%s})`, sel), "{{keyspace}}.{{table}}"),
+					target(fmt.Sprintf(`max by (cluster) (mcac_table_live_ss_table_count{%s})`, sel), "max in cluster")),
+				graph("Pending Compactions", "short", 12,
+					aggregates(fmt.Sprintf(`sum by (cluster, dc, rack, instance) (mcac_table_pending_compactions{%s})`, sel))...),
+				graph("Pending Compactions per Table", "short", 12,
+					target(fmt.Sprintf(`max by (cluster, keyspace, table) (mcac_table_pending_compactions{%s})`, sel), "{{keyspace}}.{{table}}")),
+			},
+		},
+		{
+			Name:  "internals",
+			Title: "Cassandra Internals",
+			Panels: []Panel{
+				graph("Pending Tasks", "short", 8,
+					target(fmt.Sprintf(`sum by (cluster, pool_name) (mcac_thread_pools_pending_tasks{%s})`, sel), "{{pool_name}}")),
+				graph("Blocked Tasks", "short", 8,
+					target(fmt.Sprintf(`sum by (cluster, pool_name) (rate(mcac_thread_pools_total_blocked_tasks_total{%s}[1m:30s]))`, sel), "{{pool_name}}")),
+				graph("Dropped Messages", "short", 8,
+					target(fmt.Sprintf(`sum by (cluster, message_type) (rate(mcac_dropped_message_dropped_total{%s}[1m:30s]))`, sel), "{{message_type}}")),
+				graph("Active Tasks", "short", 12,
+					target(fmt.Sprintf(`sum by (cluster, pool_name) (mcac_thread_pools_active_tasks{%s})`, sel), "{{pool_name}}")),
+				graph("Hinted Handoff", "short", 12,
+					target(fmt.Sprintf(`sum by (cluster) (mcac_storage_total_hints_in_progress_total{%s})`, sel), "hints in progress")),
+			},
+		},
+		{
+			Name:  "os",
+			Title: "Hardware / Operating System",
+			Panels: []Panel{
+				graph("CPU Utilization", "percentunit", 8,
+					aggregates(fmt.Sprintf(`1 - sum by (cluster, dc, rack, instance) (rate(collectd_cpu_total{%[1]s}[1m:30s])) / sum by (cluster, dc, rack, instance) (rate(collectd_cpu_total{%[2]s}[1m:30s]))`,
+						b.selector(`type="idle"`), sel))...),
+				graph("Unix Load (1m rate)", "short", 8, aggregates(fmt.Sprintf(`collectd_load_shortterm{%s}`, sel))...),
+				graph("Memory Utilisation", "bytes", 8,
+					target(fmt.Sprintf(`min by (cluster) (sum by (cluster, dc, rack, instance) (collectd_memory{%s}))`, b.selector(`memory="free"`)), "min memory free"),
+					target(fmt.Sprintf(`max by (cluster, memory) (sum by (cluster, dc, rack, instance, memory) (collectd_memory{%s}))`, b.selector(`memory!="free"`)), "max memory {{memory}}")),
+				graph("Disk Read Throughput", "Bps", 8, aggregates(fmt.Sprintf(`rate(collectd_processes_disk_octets_read_total{%s}[1m:30s])`, sel))...),
+				graph("Disk Write Throughput", "Bps", 8, aggregates(fmt.Sprintf(`rate(collectd_processes_disk_octets_write_total{%s}[1m:30s])`, sel))...),
+				graph("Network I/O", "Bps", 8,
+					target(fmt.Sprintf(`sum by (cluster) (rate(collectd_interface_if_octets_tx_total{%s}[1m:30s]))`, sel), "outgoing"),
+					target(fmt.Sprintf(`sum by (cluster) (rate(collectd_interface_if_octets_rx_total{%s}[1m:30s]))`, sel), "incoming")),
+			},
+		},
+		{
+			Name:  "jvm",
+			Title: "JVM / Garbage Collection",
+			Panels: []Panel{
+				graph("Application Throughput (% time NOT doing GC)", "percentunit", 8,
+					aggregates(fmt.Sprintf(`1 - sum by (cluster, dc, rack, instance) (rate(mcac_jvm_gc_time{%s}[1m:30s])) / 1000`, sel))...),
+				graph("Garbage Collection Time", "ms", 8,
+					aggregates(fmt.Sprintf(`sum by (cluster, dc, rack, instance) (rate(mcac_jvm_gc_time{%s}[1m:30s]))`, sel))...),
+				graph("JVM Heap Memory Utilisation", "bytes", 8,
+					append(aggregates(fmt.Sprintf(`mcac_jvm_memory_used{%s}`, b.selector(`memory_type="heap"`))),
+						target(fmt.Sprintf(`min by (cluster) (mcac_jvm_memory_max{%s})`, b.selector(`memory_type="heap"`)), "heap memory available"))...),
+			},
+		},
+	}
+}
+
+// nodeStatus returns a panel showing whether each node is up, its query is 1 when it is and 0 otherwise
+func nodeStatus(expr string) Panel {
+	panel := stat("Nodes Status", "none", 12, target(expr, "{{instance}}"))
+	panel.FieldConfig.Defaults.Thresholds = &Thresholds{Mode: "absolute", Steps: []Threshold{{Color: "red"}, step("green", 1)}}
+	options := panel.Options.(StatOptions)
+	options.ColorMode = "background"
+	options.GraphMode = "none"
+	panel.Options = options
+	return panel
+}
+
+var condensedDashboard = &definition{
+	name:        "cassandra-condensed",
+	title:       "Cassandra Cluster Condensed",
+	description: "Single pane of glass for the most important Cassandra metrics",
+	tags:        []string{"cassandra"},
+	refresh:     "30s",
+	template:    "templates/cassandra/condensed-dashboard.yaml",
+	configMap:   "cassandra-condensed-dashboard",
+	key:         "cassandra-condensed.json",
+	instance:    "host",
+	variables: func(b *builder) []Variable {
+		by := []string{"Cluster:cluster"}
+		if b.opts.Datacenter {
+			by = append(by, "Datacenter:dc")
+		}
+		if b.opts.Rack {
+			by = append(by, "Rack:rack")
+		}
+		by = append(by, "Node:instance")
+
+		variables := []Variable{b.datasourceVariable(), customVariable("by", "Group By", by...), rateVariable()}
+		variables = append(variables, b.topologyVariables(cassandraMetric, true)...)
+		variables = append(variables,
+			b.queryVariable("keyspace", "Keyspace", `label_values(mcac_table_read_latency_total{cluster=~"$cluster"}, keyspace)`, true),
+			b.queryVariable("table", "Table", `label_values(mcac_table_read_latency_total{cluster=~"$cluster", keyspace=~"$keyspace"}, table)`, true),
+			customVariable("latency", "Percentile", "P999:0.999", "P99:0.99", "P98:0.98", "P95:0.95", "P90:0.90", "P75:0.75", "P50:0.50"))
+		return variables
+	},
+	panelSets: condensedPanelSets,
+}
+
+func condensedPanelSets(b *builder) []PanelSet {
+	sel := b.selector()
+	tables := b.selector(`keyspace=~"$keyspace"`, `table=~"$table"`)
+	sum := func(format, selector string) Target {
+		return target(fmt.Sprintf("sum(%s)", fmt.Sprintf(format, selector)), "")
+	}
+	tableLatency := func(metric, legend string) Target {
+		return target(fmt.Sprintf(`histogram_quantile($latency, sum by (le, $by) (rate(mcac_table_%s_bucket{%s}[$rate])))`, metric, tables), "{{$by}} "+legend)
+	}
+	nodesDown := stat("Nodes Down", "none", 2,
+		target(fmt.Sprintf(`count(absent(sum(rate(mcac_compaction_completed_tasks{%s}[5m])))) or vector(0)`, sel), ""))
+	nodesDown.FieldConfig.Defaults.Thresholds = &Thresholds{Mode: "absolute", Steps: []Threshold{{Color: "green"}, step("red", 1)}}
+
+	return []PanelSet{
+		{
+			Name:   "overview",
+			Title:  "Cluster Overview",
+			Height: 4,
+			Panels: []Panel{
+				stat("Nodes Up", "none", 2, target(fmt.Sprintf(`count(mcac_compaction_completed_tasks{%s} >= 0) or vector(0)`, sel), "")),
+				nodesDown,
+				stat("Compactions / $rate", "none", 2, sum(`rate(mcac_compaction_completed_tasks{%s}[$rate])`, sel)),
+				stat("CQL Requests / $rate", "none", 2, sum(`rate(mcac_client_request_latency_total{%s}[$rate])`, sel)),
+				stat("Dropped Messages / $rate", "none", 2, sum(`rate(mcac_table_dropped_mutations_total{%s}[$rate])`, sel)),
+				logo(4),
+				stat("CQL Clients", "none", 2, sum(`mcac_client_connected_native_clients{%s}`, sel)),
+				stat("Timeouts / $rate", "none", 2, sum(`rate(mcac_client_request_timeouts_total{%s}[$rate])`, sel)),
+				stat("Hints / $rate", "none", 2, sum(`rate(mcac_storage_hints_on_disk_total{%s}[$rate])`, sel)),
+				stat("Data Size", "bytes", 2, sum(`mcac_table_live_disk_space_used_total{%s}`, tables)),
+				stat("GC Time / $rate", "ms", 2, sum(`rate(mcac_jvm_gc_time{%s}[$rate])`, sel)),
+			},
+		},
+		{
+			Name:   "condensed",
+			Title:  "Condensed Metrics",
+			Height: 10,
+			Panels: []Panel{
+				graph("Requests Served / $by / $rate", "short", 8,
+					target(fmt.Sprintf(`sum by ($by, request_type) (rate(mcac_client_request_latency_total{%s}[$rate]))`, sel), "{{$by}} {{request_type}}"),
+					target(fmt.Sprintf(`sum by ($by) (mcac_client_connected_native_clients{%s})`, sel), "{{$by}} clients connected")),
+				graph("Coordinator $latency Latency / $by", "µs", 8,
+					target(fmt.Sprintf(`histogram_quantile($latency, sum by (le, request_type, $by) (rate(mcac_client_request_latency_bucket{%s}[$rate])))`, sel), "{{$by}} {{request_type}}")),
+				graph("Memtable Space $keyspace.$table / $by", "bytes", 8,
+					target(fmt.Sprintf(`sum by ($by) (mcac_table_memtable_off_heap_size{%s})`, tables), "{{$by}} off heap"),
+					target(fmt.Sprintf(`sum by ($by) (mcac_table_memtable_on_heap_size{%s})`, tables), "{{$by}} on heap"),
+					target(fmt.Sprintf(`sum by ($by) (idelta(mcac_table_memtable_switch_count_total{%s}[$rate]))`, tables), "{{$by}} flushes"),
+					target(fmt.Sprintf(`sum by ($by) (idelta(mcac_table_pending_flushes_total{%s}[$rate]))`, tables), "{{$by}} pending flushes")),
+				graph("Compactions $keyspace.$table / $by", "Bps", 8,
+					target(fmt.Sprintf(`sum by ($by) (rate(mcac_table_compaction_bytes_written_total{%s}[$rate]))`, tables), "{{$by}} bytes compacted"),
+					target(fmt.Sprintf(`sum by ($by) (mcac_table_pending_compactions{%s})`, tables), "{{$by}} pending compactions"),
+					target(fmt.Sprintf(`sum by ($by) (rate(mcac_compaction_completed_tasks{%s}[$rate]))`, sel), "{{$by}} completed compactions")),
+				graph("Table $latency Latency / $by", "µs", 8,
+					tableLatency("range_latency", "local range scan"),
+					tableLatency("read_latency", "local read"),
+					tableLatency("write_latency", "local write"),
+					tableLatency("coordinator_read_latency", "coordinator read"),
+					tableLatency("coordinator_scan_latency", "coordinator range scan")),
+				graph("Streaming / $by / $rate", "Bps", 8,
+					target(fmt.Sprintf(`sum by ($by) (rate(mcac_streaming_total_incoming_bytes_total{%s}[$rate]))`, sel), "{{$by}} incoming"),
+					target(fmt.Sprintf(`sum by ($by) (rate(mcac_streaming_total_outgoing_bytes_total{%s}[$rate]))`, sel), "{{$by}} outgoing")),
+			},
+		},
+	}
+}
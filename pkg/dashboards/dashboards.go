@@ -0,0 +1,488 @@
+// Package dashboards builds the Grafana dashboards of the chart from code. The JSON dashboards and the ConfigMap
+// templates provisioning them are generated with "make chart-templates", TestChartTemplates fails when the committed
+// files are not up to date.
+package dashboards
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultDatasource is the name of the Prometheus datasource of the Grafana of kube-prometheus-stack
+	DefaultDatasource = "Prometheus"
+
+	// datasourceVariableName is the name of the variable of the datasource, as MCAC names it
+	datasourceVariableName = "PROMETHEUS_DS"
+
+	// DashboardsDir is the directory of the JSON dashboards in the chart
+	DashboardsDir = "dashboards"
+
+	// gridWidth is the width of the grid the panels are laid on
+	gridWidth = 24
+	// defaultPanelHeight is the height of the panels of a panel set which does not set it
+	defaultPanelHeight = 8
+)
+
+// Options parameterize the generated dashboards
+type Options struct {
+	// Datasource is the name of the Prometheus datasource the dashboards query
+	Datasource string
+
+	// Datacenter adds a variable filtering the panels by datacenter
+	Datacenter bool
+
+	// Rack adds a variable filtering the panels by rack
+	Rack bool
+
+	// PanelSets are the names of the panel sets of the dashboards, keyed by the name of the dashboard. The dashboards
+	// which are not listed have all their panel sets.
+	PanelSets map[string][]string
+}
+
+// DefaultOptions returns the options of the dashboards of the chart
+func DefaultOptions() Options {
+	return Options{Datasource: DefaultDatasource, Datacenter: true, Rack: true}
+}
+
+// PanelSet is a row of panels of a dashboard
+type PanelSet struct {
+	// Name identifies the panel set in Options.PanelSets
+	Name  string
+	Title string
+	// Height is the height of the panels, defaults to 8
+	Height    int
+	Collapsed bool
+	Panels    []Panel
+}
+
+// definition defines a dashboard and the ConfigMap of the chart provisioning it
+type definition struct {
+	// name is the name of the JSON file of the dashboard in the chart, without its extension
+	name        string
+	title       string
+	description string
+	tags        []string
+	refresh     string
+
+	// instance is the name of the variable of the nodes, which MCAC names differently in each dashboard
+	instance string
+
+	variables func(*builder) []Variable
+	panelSets func(*builder) []PanelSet
+
+	// template is the path of the template of the ConfigMap in the chart
+	template string
+	// configMap is the name of the ConfigMap, after the release name
+	configMap string
+	// key is the key of the dashboard in the ConfigMap
+	key string
+	// condition renders the ConfigMap, besides provision_dashboards
+	condition string
+}
+
+// definitions are the dashboards of the chart
+var definitions = []*definition{overviewDashboard, condensedDashboard, systemMetricsDashboard, stargateDashboard}
+
+// Names returns the names of the dashboards
+func Names() []string {
+	names := make([]string, 0, len(definitions))
+	for _, d := range definitions {
+		names = append(names, d.name)
+	}
+	return names
+}
+
+// PanelSetNames returns the names of the panel sets of a dashboard
+func PanelSetNames(name string) ([]string, error) {
+	d := lookup(name)
+	if d == nil {
+		return nil, fmt.Errorf("unknown dashboard %s, the dashboards are %s", name, strings.Join(Names(), ", "))
+	}
+	names := make([]string, 0)
+	for _, set := range d.panelSets(d.newBuilder(DefaultOptions())) {
+		names = append(names, set.Name)
+	}
+	return names, nil
+}
+
+func lookup(name string) *definition {
+	for _, d := range definitions {
+		if d.name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// Generate returns the dashboards, keyed by their name
+func Generate(opts Options) (map[string]*Dashboard, error) {
+	if opts.Datasource == "" {
+		opts.Datasource = DefaultDatasource
+	}
+	for name := range opts.PanelSets {
+		if lookup(name) == nil {
+			return nil, fmt.Errorf("unknown dashboard %s, the dashboards are %s", name, strings.Join(Names(), ", "))
+		}
+	}
+
+	dashboards := make(map[string]*Dashboard)
+	for _, d := range definitions {
+		dashboard, err := d.build(d.newBuilder(opts))
+		if err != nil {
+			return nil, err
+		}
+		dashboards[d.name] = dashboard
+	}
+	return dashboards, nil
+}
+
+func (d *definition) build(b *builder) (*Dashboard, error) {
+	sets := d.panelSets(b)
+	if selected, ok := b.opts.PanelSets[d.name]; ok {
+		byName := make(map[string]PanelSet)
+		for _, set := range sets {
+			byName[set.Name] = set
+		}
+		sets = make([]PanelSet, 0, len(selected))
+		for _, name := range selected {
+			set, ok := byName[name]
+			if !ok {
+				names, _ := PanelSetNames(d.name)
+				return nil, fmt.Errorf("unknown panel set %s of dashboard %s, its panel sets are %s", name, d.name, strings.Join(names, ", "))
+			}
+			sets = append(sets, set)
+		}
+	}
+
+	tags := append([]string{"k8ssandra"}, d.tags...)
+	return &Dashboard{
+		Title:         d.title,
+		Description:   d.description,
+		Tags:          tags,
+		Editable:      true,
+		Refresh:       d.refresh,
+		SchemaVersion: schemaVersion,
+		Style:         "dark",
+		Time:          TimeRange{From: "now-1h", To: "now"},
+		Timezone:      "browser",
+		Templating:    Templating{List: d.variables(b)},
+		Annotations: Annotations{List: []Annotation{{
+			BuiltIn:    1,
+			Datasource: "-- Grafana --",
+			Enable:     true,
+			Hide:       true,
+			IconColor:  "rgba(0, 211, 255, 1)",
+			Name:       "Annotations & Alerts",
+			Type:       "dashboard",
+		}}},
+		Panels: b.layout(sets),
+	}, nil
+}
+
+// Marshal returns the JSON of a dashboard, as it is written in the chart
+func Marshal(dashboard *Dashboard) ([]byte, error) {
+	out := &bytes.Buffer{}
+	encoder := json.NewEncoder(out)
+	// Keep the PromQL comparisons readable
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(dashboard); err != nil {
+		return nil, fmt.Errorf("failed to marshal dashboard %s: %w", dashboard.Title, err)
+	}
+	return out.Bytes(), nil
+}
+
+// ChartFiles returns the JSON dashboards of the chart and the templates of the ConfigMaps provisioning them, keyed by
+// their path in the chart
+func ChartFiles(opts Options) (map[string][]byte, error) {
+	dashboards, err := Generate(opts)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	for _, d := range definitions {
+		data, err := Marshal(dashboards[d.name])
+		if err != nil {
+			return nil, err
+		}
+		path := DashboardsDir + "/" + d.name + ".json"
+		files[path] = data
+		files[d.template] = d.configMapTemplate(path)
+	}
+	return files, nil
+}
+
+// ChartFilePaths returns the sorted paths of the files generated in the chart
+func ChartFilePaths(files map[string][]byte) []string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (d *definition) configMapTemplate(path string) []byte {
+	condition := ".Values.monitoring.grafana.provision_dashboards"
+	if d.condition != "" {
+		condition = fmt.Sprintf("(and %s %s)", condition, d.condition)
+	}
+	return []byte(fmt.Sprintf(`{{- /* Generated from pkg/dashboards by "make chart-templates", do not edit */ -}}
+{{- if %s }}
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}-%s
+  labels:
+    grafana_dashboard: "true"
+{{ include "k8ssandra.labels" . | indent 4 }}
+data:
+  %s: |
+{{ .Files.Get %q | indent 4 }}
+{{- end }}
+`, condition, d.configMap, d.key, path))
+}
+
+// builder builds the variables and panels of a dashboard
+type builder struct {
+	opts     Options
+	instance string
+}
+
+func (d *definition) newBuilder(opts Options) *builder {
+	return &builder{opts: opts, instance: d.instance}
+}
+
+// selector returns the label matchers of the cluster, datacenter, rack and node variables, followed by the given
+// matchers
+func (b *builder) selector(matchers ...string) string {
+	all := []string{`cluster=~"$cluster"`}
+	if b.opts.Datacenter {
+		all = append(all, `dc=~"$dc"`)
+	}
+	if b.opts.Rack {
+		all = append(all, `rack=~"$rack"`)
+	}
+	all = append(all, fmt.Sprintf(`instance=~"$%s"`, b.instance))
+	return strings.Join(append(all, matchers...), ", ")
+}
+
+// datasourceVariable returns the hidden variable of the Prometheus datasource the panels query
+func (b *builder) datasourceVariable() Variable {
+	return Variable{
+		Name:    datasourceVariableName,
+		Type:    "datasource",
+		Query:   "prometheus",
+		Current: &VariableOption{Text: b.opts.Datasource, Value: b.opts.Datasource},
+		Options: []VariableOption{},
+		Hide:    variableHidden,
+		Refresh: variableRefreshOnDashboardLoad,
+	}
+}
+
+// topologyVariables returns the cluster, datacenter, rack and node variables, whose values are the ones of the label
+// of the given metric
+func (b *builder) topologyVariables(metric string, allInstances bool) []Variable {
+	variables := []Variable{b.queryVariable("cluster", "Cluster", fmt.Sprintf("label_values(%s, cluster)", metric), false)}
+	matchers := []string{`cluster=~"$cluster"`}
+	if b.opts.Datacenter {
+		variables = append(variables, b.queryVariable("dc", "Datacenter", fmt.Sprintf("label_values(%s{%s}, dc)", metric, strings.Join(matchers, ", ")), true))
+		matchers = append(matchers, `dc=~"$dc"`)
+	}
+	if b.opts.Rack {
+		variables = append(variables, b.queryVariable("rack", "Rack", fmt.Sprintf("label_values(%s{%s}, rack)", metric, strings.Join(matchers, ", ")), true))
+		matchers = append(matchers, `rack=~"$rack"`)
+	}
+	return append(variables, b.queryVariable(b.instance, strings.Title(b.instance), fmt.Sprintf("label_values(%s{%s}, instance)", metric, strings.Join(matchers, ", ")), allInstances))
+}
+
+func (b *builder) queryVariable(name, label, query string, includeAll bool) Variable {
+	variable := Variable{
+		Name:       name,
+		Label:      label,
+		Type:       "query",
+		Datasource: "$" + datasourceVariableName,
+		Query:      query,
+		Options:    []VariableOption{},
+		IncludeAll: includeAll,
+		Refresh:    variableRefreshOnTimeRangeChange,
+		Sort:       variableSortAlphabetical,
+	}
+	if includeAll {
+		variable.AllValue = ".*"
+	}
+	return variable
+}
+
+// customVariable returns a variable taking one of the given values, the first one by default. The text of a value is
+// the part before its colon, if it has one.
+func customVariable(name, label string, values ...string) Variable {
+	variable := Variable{Name: name, Label: label, Type: "custom", Options: []VariableOption{}}
+	queries := make([]string, 0, len(values))
+	for i, v := range values {
+		option := VariableOption{Text: v, Value: v}
+		if parts := strings.SplitN(v, ":", 2); len(parts) == 2 {
+			option = VariableOption{Text: parts[0], Value: parts[1]}
+		}
+		option.Selected = i == 0
+		variable.Options = append(variable.Options, option)
+		queries = append(queries, fmt.Sprintf("%s : %s", option.Text, option.Value))
+	}
+	variable.Query = strings.Join(queries, ",")
+	current := variable.Options[0]
+	variable.Current = &current
+	return variable
+}
+
+// rateVariable returns the variable of the range of the rates of the panels
+func rateVariable() Variable {
+	variable := customVariable("rate", "Rate", "1m", "5m", "10m", "30m", "1h", "6h", "12h", "1d", "7d", "14d", "30d")
+	variable.Type = "interval"
+	variable.Query = "1m,5m,10m,30m,1h,6h,12h,1d,7d,14d,30d"
+	variable.Refresh = variableRefreshOnTimeRangeChange
+	for i := range variable.Options {
+		variable.Options[i].Selected = variable.Options[i].Value == "5m"
+	}
+	variable.Current = &VariableOption{Selected: true, Text: "5m", Value: "5m"}
+	return variable
+}
+
+// layout assigns the ids and positions of the panels, and the datasource and ids of their queries. Each panel set is a
+// row, whose panels are laid left to right.
+func (b *builder) layout(sets []PanelSet) []Panel {
+	panels := make([]Panel, 0)
+	id := 1
+	y := 0
+	for _, set := range sets {
+		row := Panel{ID: id, Type: "row", Title: set.Title, GridPos: GridPos{H: 1, W: gridWidth, X: 0, Y: y}, Collapsed: set.Collapsed}
+		id++
+		y++
+
+		height := set.Height
+		if height == 0 {
+			height = defaultPanelHeight
+		}
+		x := 0
+		children := make([]Panel, 0, len(set.Panels))
+		for _, panel := range set.Panels {
+			if x+panel.GridPos.W > gridWidth {
+				x = 0
+				y += height
+			}
+			panel.ID = id
+			id++
+			if panel.Type != "text" {
+				panel.Datasource = "$" + datasourceVariableName
+			}
+			panel.GridPos = GridPos{H: height, W: panel.GridPos.W, X: x, Y: y}
+			x += panel.GridPos.W
+			for i := range panel.Targets {
+				panel.Targets[i].RefID = string(rune('A' + i))
+			}
+			children = append(children, panel)
+		}
+		if x > 0 {
+			y += height
+		}
+
+		if set.Collapsed {
+			row.Panels = children
+			panels = append(panels, row)
+		} else {
+			panels = append(panels, row)
+			panels = append(panels, children...)
+		}
+	}
+	return panels
+}
+
+// target returns a query of a panel
+func target(expr, legend string) Target {
+	return Target{Expr: expr, LegendFormat: legend}
+}
+
+// aggregates returns the queries of the maximum, minimum and average of expr over the nodes of the cluster
+func aggregates(expr string) []Target {
+	targets := make([]Target, 0, 3)
+	for _, aggregation := range []string{"max", "min", "avg"} {
+		targets = append(targets, target(fmt.Sprintf("%s by (cluster) (%s)", aggregation, expr), aggregation))
+	}
+	return targets
+}
+
+// graph returns a graph panel of the given width, whose y-axis has the given unit
+func graph(title, unit string, width int, targets ...Target) Panel {
+	return Panel{
+		Type:          "graph",
+		Title:         title,
+		GridPos:       GridPos{W: width},
+		Targets:       targets,
+		Lines:         true,
+		Linewidth:     1,
+		Fill:          1,
+		NullPointMode: "null",
+		Legend:        &Legend{Show: true},
+		Tooltip:       &Tooltip{Shared: true, Sort: 2, ValueType: "individual"},
+		Xaxis:         &Axis{Mode: "time", Show: true},
+		Yaxes:         []Axis{{Format: unit, LogBase: 1, Show: true}, {Format: "short", LogBase: 1, Show: false}},
+	}
+}
+
+// stat returns a stat panel of the given width, showing the last value of its query
+func stat(title, unit string, width int, t Target) Panel {
+	return Panel{
+		Type:        "stat",
+		Title:       title,
+		GridPos:     GridPos{W: width},
+		Targets:     []Target{t},
+		FieldConfig: &FieldConfig{Defaults: FieldDefaults{Unit: unit}, Overrides: []interface{}{}},
+		Options: StatOptions{
+			ReduceOptions: ReduceOptions{Calcs: []string{"lastNotNull"}},
+			ColorMode:     "value",
+			GraphMode:     "area",
+			Orientation:   "auto",
+		},
+	}
+}
+
+// gauge returns a bar gauge panel of the given width, showing the last value of each series of its query
+func gauge(title, unit string, width int, min, max float64, t Target) Panel {
+	return Panel{
+		Type:    "bargauge",
+		Title:   title,
+		GridPos: GridPos{W: width},
+		Targets: []Target{t},
+		FieldConfig: &FieldConfig{
+			Defaults: FieldDefaults{Unit: unit, Min: &min, Max: &max, Thresholds: &Thresholds{
+				Mode:  "absolute",
+				Steps: []Threshold{{Color: "green"}, step("orange", 0.75*max), step("red", 0.9*max)},
+			}},
+			Overrides: []interface{}{},
+		},
+		Options: StatOptions{
+			ReduceOptions: ReduceOptions{Calcs: []string{"lastNotNull"}},
+			DisplayMode:   "gradient",
+			Orientation:   "horizontal",
+		},
+	}
+}
+
+// logo returns a text panel of the given width showing the logo of Apache Cassandra, as the MCAC dashboards do
+func logo(width int) Panel {
+	return Panel{
+		Type:        "text",
+		GridPos:     GridPos{W: width},
+		Mode:        "html",
+		Content:     `<a href="https://cassandra.apache.org" target="new"><img src="https://cassandra.apache.org/img/cassandra_logo.png"/></a>`,
+		Transparent: true,
+	}
+}
+
+// step returns the threshold coloring the values above value
+func step(color string, value float64) Threshold {
+	return Threshold{Color: color, Value: &value}
+}
@@ -0,0 +1,253 @@
+package dashboards
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+var update = flag.Bool("update", false, "update the dashboards of the chart generated from this package")
+
+const chartDir = "../../charts/k8ssandra"
+
+// TestChartTemplates checks that the dashboards and ConfigMap templates of the chart are up to date, they are
+// regenerated with -update
+func TestChartTemplates(t *testing.T) {
+	g := NewWithT(t)
+
+	files, err := ChartFiles(DefaultOptions())
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, path := range ChartFilePaths(files) {
+		if *update {
+			g.Expect(ioutil.WriteFile(filepath.Join(chartDir, path), files[path], 0644)).To(Succeed())
+		}
+		committed, err := ioutil.ReadFile(filepath.Join(chartDir, path))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(string(committed)).To(Equal(string(files[path])), "%s is out of date, run make chart-templates", path)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	g := NewWithT(t)
+
+	dashboards, err := Generate(DefaultOptions())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(dashboards).To(HaveLen(len(Names())))
+
+	for name, dashboard := range dashboards {
+		ids := make(map[int]bool)
+		var check func(panels []Panel)
+		check = func(panels []Panel) {
+			for _, panel := range panels {
+				g.Expect(ids).ToNot(HaveKey(panel.ID), "duplicate panel id in %s", name)
+				ids[panel.ID] = true
+				g.Expect(panel.GridPos.X+panel.GridPos.W).To(BeNumerically("<=", gridWidth), "%s of %s overflows", panel.Title, name)
+				for _, target := range panel.Targets {
+					g.Expect(target.RefID).ToNot(BeEmpty())
+					g.Expect(balanced(target.Expr)).To(BeTrue(), "unbalanced query of %s of %s: %s", panel.Title, name, target.Expr)
+				}
+				check(panel.Panels)
+			}
+		}
+		check(dashboard.Panels)
+
+		// every variable used by the queries is defined
+		variables := make(map[string]bool)
+		for _, v := range dashboard.Templating.List {
+			variables[v.Name] = true
+		}
+		data, err := Marshal(dashboard)
+		g.Expect(err).ToNot(HaveOccurred())
+		for _, match := range regexp.MustCompile(`\$([A-Za-z_]+)`).FindAllStringSubmatch(string(data), -1) {
+			if match[1] != "labels" && match[1] != "value" {
+				g.Expect(variables).To(HaveKey(match[1]), "undefined variable $%s in %s", match[1], name)
+			}
+		}
+	}
+
+	overview := dashboards["overview"]
+	g.Expect(overview.Templating.List[0].Current.Value).To(Equal(DefaultDatasource))
+	g.Expect(overview.Panels[1].Targets[0].Expr).To(ContainSubstring(`{cluster=~"$cluster", dc=~"$dc", rack=~"$rack", instance=~"$node"}`))
+}
+
+func TestGenerateOptions(t *testing.T) {
+	g := NewWithT(t)
+
+	dashboards, err := Generate(Options{
+		Datasource: "Thanos",
+		Datacenter: false,
+		Rack:       false,
+		PanelSets:  map[string][]string{"overview": {"nodes", "requests"}},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	overview := dashboards["overview"]
+	g.Expect(overview.Templating.List[0].Current.Value).To(Equal("Thanos"))
+	names := make([]string, 0)
+	for _, v := range overview.Templating.List {
+		names = append(names, v.Name)
+	}
+	g.Expect(names).To(Equal([]string{"PROMETHEUS_DS", "cluster", "node"}))
+	rows := make([]string, 0)
+	for _, panel := range overview.Panels {
+		if panel.Type == "row" {
+			rows = append(rows, panel.Title)
+		}
+		for _, target := range panel.Targets {
+			g.Expect(target.Expr).ToNot(ContainSubstring("$dc"))
+		}
+	}
+	g.Expect(rows).To(Equal([]string{"Nodes Status", "Request Throughputs (Coordinator Perspective)"}))
+
+	_, err = Generate(Options{PanelSets: map[string][]string{"overview": {"unknown"}}})
+	g.Expect(err).To(MatchError(ContainSubstring("unknown panel set unknown of dashboard overview")))
+	_, err = Generate(Options{PanelSets: map[string][]string{"unknown": {}}})
+	g.Expect(err).To(HaveOccurred())
+}
+
+// mcacPanel is a panel of the MCAC dashboards, in their format of rows
+type mcacPanel struct {
+	Type    string `json:"type"`
+	Title   string `json:"title"`
+	Targets []struct {
+		Expr string `json:"expr"`
+	} `json:"targets"`
+}
+
+// retitled maps the titles of panels to the titles of their MCAC counterparts, where typos or duplicates are fixed
+var retitled = map[string]string{
+	"Disk Read Throughput":  "Disk Read Thoughput",
+	"Disk Write Throughput": "Disk Write Thoughput",
+	"Protocol Activity":     "TCP Connection Activity",
+}
+
+// fixedMetrics maps the metrics of the MCAC dashboards to those of the panels, where MCAC uses DSE metrics
+var fixedMetrics = map[string]string{
+	"dse_client_request_latency_total": "mcac_client_request_latency_total",
+}
+
+var metricPattern = regexp.MustCompile(`([a-zA-Z_:][a-zA-Z0-9_:]*)\{`)
+
+// TestMCACParity checks that the dashboards have the panels, queries, metrics and variables of the MCAC dashboards
+// they replace, as scripts/update-mcac-dashboards.sh downloads them into testdata/mcac
+func TestMCACParity(t *testing.T) {
+	g := NewWithT(t)
+
+	dashboards, err := Generate(DefaultOptions())
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, name := range []string{"cassandra-condensed", "overview", "system-metrics"} {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", "mcac", name+".json"))
+		g.Expect(err).ToNot(HaveOccurred())
+		mcac := struct {
+			Rows []struct {
+				Panels []mcacPanel `json:"panels"`
+			} `json:"rows"`
+			Templating struct {
+				List []struct {
+					Name string `json:"name"`
+				} `json:"list"`
+			} `json:"templating"`
+		}{}
+		g.Expect(json.Unmarshal(data, &mcac)).To(Succeed())
+		expected := make([]mcacPanel, 0)
+		for _, row := range mcac.Rows {
+			for _, panel := range row.Panels {
+				if panel.Type != "text" {
+					expected = append(expected, panel)
+				}
+			}
+		}
+		panels := make([]Panel, 0)
+		var flatten func(rowPanels []Panel)
+		flatten = func(rowPanels []Panel) {
+			for _, panel := range rowPanels {
+				switch panel.Type {
+				case "row":
+					flatten(panel.Panels)
+				case "text":
+				default:
+					panels = append(panels, panel)
+				}
+			}
+		}
+		flatten(dashboards[name].Panels)
+
+		g.Expect(panels).To(HaveLen(len(expected)), "panels of %s", name)
+		for i, panel := range panels {
+			title := panel.Title
+			if mcacTitle, ok := retitled[title]; ok {
+				title = mcacTitle
+			}
+			g.Expect(title).To(Equal(expected[i].Title), "panel %d of %s", i, name)
+			g.Expect(panel.Targets).To(HaveLen(len(expected[i].Targets)), "queries of %s of %s", panel.Title, name)
+			queries := make([]string, 0, len(panel.Targets))
+			for _, target := range panel.Targets {
+				queries = append(queries, target.Expr)
+			}
+			for _, target := range expected[i].Targets {
+				for _, match := range metricPattern.FindAllStringSubmatch(target.Expr, -1) {
+					metric := match[1]
+					if fixed, ok := fixedMetrics[metric]; ok {
+						metric = fixed
+					}
+					g.Expect(strings.Join(queries, "\n")).To(ContainSubstring(metric+"{"), "%s of %s", panel.Title, name)
+				}
+			}
+		}
+
+		variables := make([]string, 0)
+		for _, v := range dashboards[name].Templating.List {
+			variables = append(variables, v.Name)
+		}
+		for _, v := range mcac.Templating.List {
+			g.Expect(variables).To(ContainElement(v.Name), "variables of %s", name)
+		}
+	}
+}
+
+func TestChartFiles(t *testing.T) {
+	g := NewWithT(t)
+
+	files, err := ChartFiles(DefaultOptions())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ChartFilePaths(files)).To(Equal([]string{
+		"dashboards/cassandra-condensed.json",
+		"dashboards/overview.json",
+		"dashboards/stargate.json",
+		"dashboards/system-metrics.json",
+		"templates/cassandra/condensed-dashboard.yaml",
+		"templates/cassandra/overview-dashboard.yaml",
+		"templates/cassandra/system-metrics-dashboard.yaml",
+		"templates/stargate/api-dashboard.yaml",
+	}))
+	g.Expect(string(files["templates/stargate/api-dashboard.yaml"])).To(ContainSubstring(
+		"{{- if (and .Values.monitoring.grafana.provision_dashboards .Values.stargate.enabled) }}"))
+	g.Expect(json.Valid(files["dashboards/overview.json"])).To(BeTrue())
+}
+
+// balanced returns true when the parentheses, brackets and braces of a query are balanced
+func balanced(expr string) bool {
+	pairs := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	stack := make([]rune, 0)
+	quoted := false
+	for _, c := range expr {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(' || c == '[' || c == '{':
+			stack = append(stack, c)
+		case pairs[c] != 0:
+			if len(stack) == 0 || stack[len(stack)-1] != pairs[c] {
+				return false
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return len(stack) == 0 && !quoted
+}
@@ -0,0 +1,206 @@
+package dashboards
+
+// The types below are the subset of the Grafana dashboard model the dashboards of the chart use, as of schema
+// version 26 (Grafana 7.3).
+
+const schemaVersion = 26
+
+// Dashboard is a Grafana dashboard
+type Dashboard struct {
+	Title         string      `json:"title"`
+	Description   string      `json:"description,omitempty"`
+	Tags          []string    `json:"tags"`
+	Editable      bool        `json:"editable"`
+	GraphTooltip  int         `json:"graphTooltip"`
+	Refresh       string      `json:"refresh"`
+	SchemaVersion int         `json:"schemaVersion"`
+	Style         string      `json:"style"`
+	Time          TimeRange   `json:"time"`
+	Timezone      string      `json:"timezone"`
+	Templating    Templating  `json:"templating"`
+	Annotations   Annotations `json:"annotations"`
+	Panels        []Panel     `json:"panels"`
+	Version       int         `json:"version"`
+}
+
+// TimeRange is the default time range of a dashboard
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Templating holds the variables of a dashboard
+type Templating struct {
+	List []Variable `json:"list"`
+}
+
+// Annotations holds the annotation queries of a dashboard
+type Annotations struct {
+	List []Annotation `json:"list"`
+}
+
+// Annotation is an annotation query, the dashboards only have the built-in one showing the Grafana annotations
+type Annotation struct {
+	BuiltIn    int    `json:"builtIn"`
+	Datasource string `json:"datasource"`
+	Enable     bool   `json:"enable"`
+	Hide       bool   `json:"hide"`
+	IconColor  string `json:"iconColor"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+}
+
+// Variable is a template variable of a dashboard
+type Variable struct {
+	Name       string           `json:"name"`
+	Label      string           `json:"label,omitempty"`
+	Type       string           `json:"type"`
+	Datasource string           `json:"datasource,omitempty"`
+	Query      string           `json:"query"`
+	Current    *VariableOption  `json:"current,omitempty"`
+	Options    []VariableOption `json:"options"`
+	Hide       int              `json:"hide"`
+	IncludeAll bool             `json:"includeAll"`
+	AllValue   string           `json:"allValue,omitempty"`
+	Multi      bool             `json:"multi"`
+	Refresh    int              `json:"refresh"`
+	Regex      string           `json:"regex"`
+	Sort       int              `json:"sort"`
+}
+
+// VariableOption is a value of a variable
+type VariableOption struct {
+	Selected bool   `json:"selected"`
+	Text     string `json:"text"`
+	Value    string `json:"value"`
+}
+
+const (
+	// variableHidden hides a variable from the dashboard
+	variableHidden = 2
+	// variableRefreshOnDashboardLoad refreshes the values of a datasource variable when the dashboard is loaded
+	variableRefreshOnDashboardLoad = 1
+	// variableRefreshOnTimeRangeChange refreshes the values of a query variable when the time range changes
+	variableRefreshOnTimeRangeChange = 2
+	// variableSortAlphabetical sorts the values of a query variable
+	variableSortAlphabetical = 1
+)
+
+// Panel is a panel of a dashboard. Rows are panels too, holding their panels when they are collapsed.
+type Panel struct {
+	ID          int          `json:"id"`
+	Type        string       `json:"type"`
+	Title       string       `json:"title"`
+	Description string       `json:"description,omitempty"`
+	Datasource  string       `json:"datasource,omitempty"`
+	GridPos     GridPos      `json:"gridPos"`
+	Targets     []Target     `json:"targets,omitempty"`
+	FieldConfig *FieldConfig `json:"fieldConfig,omitempty"`
+	Options     interface{}  `json:"options,omitempty"`
+
+	// graph panels
+	Lines         bool     `json:"lines,omitempty"`
+	Linewidth     int      `json:"linewidth,omitempty"`
+	Fill          int      `json:"fill,omitempty"`
+	NullPointMode string   `json:"nullPointMode,omitempty"`
+	Legend        *Legend  `json:"legend,omitempty"`
+	Tooltip       *Tooltip `json:"tooltip,omitempty"`
+	Xaxis         *Axis    `json:"xaxis,omitempty"`
+	Yaxes         []Axis   `json:"yaxes,omitempty"`
+
+	// rows
+	Collapsed bool    `json:"collapsed,omitempty"`
+	Panels    []Panel `json:"panels,omitempty"`
+
+	// text panels
+	Mode        string `json:"mode,omitempty"`
+	Content     string `json:"content,omitempty"`
+	Transparent bool   `json:"transparent,omitempty"`
+}
+
+// GridPos is the position of a panel, on a grid 24 units wide
+type GridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Target is a Prometheus query of a panel
+type Target struct {
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat,omitempty"`
+	Instant      bool   `json:"instant,omitempty"`
+	RefID        string `json:"refId"`
+}
+
+// FieldConfig holds the display settings of the values of stat and bar gauge panels
+type FieldConfig struct {
+	Defaults  FieldDefaults `json:"defaults"`
+	Overrides []interface{} `json:"overrides"`
+}
+
+// FieldDefaults are the display settings of all the values of a panel
+type FieldDefaults struct {
+	Unit       string      `json:"unit,omitempty"`
+	Decimals   *int        `json:"decimals,omitempty"`
+	Min        *float64    `json:"min,omitempty"`
+	Max        *float64    `json:"max,omitempty"`
+	Thresholds *Thresholds `json:"thresholds,omitempty"`
+}
+
+// Thresholds color the values of a panel
+type Thresholds struct {
+	Mode  string      `json:"mode"`
+	Steps []Threshold `json:"steps"`
+}
+
+// Threshold is the color of the values above Value, the first threshold has no value
+type Threshold struct {
+	Color string   `json:"color"`
+	Value *float64 `json:"value"`
+}
+
+// StatOptions are the options of stat and bar gauge panels
+type StatOptions struct {
+	ReduceOptions ReduceOptions `json:"reduceOptions"`
+	ColorMode     string        `json:"colorMode,omitempty"`
+	GraphMode     string        `json:"graphMode,omitempty"`
+	DisplayMode   string        `json:"displayMode,omitempty"`
+	Orientation   string        `json:"orientation"`
+}
+
+// ReduceOptions define how the series of a stat panel are reduced to a value
+type ReduceOptions struct {
+	Calcs  []string `json:"calcs"`
+	Fields string   `json:"fields"`
+	Values bool     `json:"values"`
+}
+
+// Legend is the legend of a graph panel
+type Legend struct {
+	Show         bool `json:"show"`
+	Values       bool `json:"values"`
+	Current      bool `json:"current"`
+	Max          bool `json:"max"`
+	Avg          bool `json:"avg"`
+	AlignAsTable bool `json:"alignAsTable"`
+	RightSide    bool `json:"rightSide"`
+}
+
+// Tooltip is the tooltip of a graph panel
+type Tooltip struct {
+	Shared    bool   `json:"shared"`
+	Sort      int    `json:"sort"`
+	ValueType string `json:"value_type"`
+}
+
+// Axis is an axis of a graph panel
+type Axis struct {
+	Format  string   `json:"format,omitempty"`
+	Mode    string   `json:"mode,omitempty"`
+	LogBase int      `json:"logBase,omitempty"`
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	Show    bool     `json:"show"`
+}
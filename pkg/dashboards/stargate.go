@@ -0,0 +1,58 @@
+package dashboards
+
+import "fmt"
+
+// stargateMetric is the metric whose labels are the values of the service variable of the Stargate dashboard
+const stargateMetric = "restapi_io_dropwizard_jetty_MutableServletContextHandler_requests_count"
+
+var stargateDashboard = &definition{
+	name:      "stargate",
+	title:     "Stargate",
+	tags:      []string{"stargate"},
+	refresh:   "30s",
+	template:  "templates/stargate/api-dashboard.yaml",
+	configMap: "stargate-dashboard",
+	key:       "stargate.json",
+	condition: ".Values.stargate.enabled",
+	variables: func(b *builder) []Variable {
+		return []Variable{
+			b.datasourceVariable(),
+			b.queryVariable("service", "Stargate", fmt.Sprintf("label_values(%s, service)", stargateMetric), true),
+		}
+	},
+	panelSets: stargatePanelSets,
+}
+
+func stargatePanelSets(b *builder) []PanelSet {
+	// The Stargate metrics do not have the topology labels of the Cassandra metrics, they are selected by Service
+	sel := `service=~"$service"`
+	errors := func(title, status string) Panel {
+		panel := graph(title, "percentunit", 12,
+			target(fmt.Sprintf(`restapi_io_dropwizard_jetty_MutableServletContextHandler_percent_%s_1m{%s}`, status, sel), "{{pod}}"))
+		min, max := 0.0, 1.0
+		panel.Yaxes[0].Min, panel.Yaxes[0].Max = &min, &max
+		return panel
+	}
+
+	return []PanelSet{
+		{
+			Name:  "api",
+			Title: "REST API",
+			Panels: []Panel{
+				errors("4xx Errors", "4xx"),
+				errors("5xx Errors", "5xx"),
+				graph("HTTP Requests per Second", "reqps", 24,
+					target(fmt.Sprintf(`rate(%s{%s}[1m])`, stargateMetric, sel), "{{pod}}")),
+			},
+		},
+		{
+			Name:  "jvm",
+			Title: "JVM",
+			Panels: []Panel{
+				graph("JVM Heap Usage", "bytes", 24,
+					target(fmt.Sprintf(`jvm_memory_heap_used{%s}`, sel), "{{pod}} used"),
+					target(fmt.Sprintf(`jvm_memory_heap_max{%s}`, sel), "{{pod}} max")),
+			},
+		},
+	}
+}
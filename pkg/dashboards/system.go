@@ -0,0 +1,154 @@
+package dashboards
+
+import "fmt"
+
+var systemMetricsDashboard = &definition{
+	name:        "system-metrics",
+	title:       "System & Node Metrics",
+	description: "Operating system metrics and Apache Cassandra node information",
+	tags:        []string{"cassandra", "os"},
+	refresh:     "1m",
+	template:    "templates/cassandra/system-metrics-dashboard.yaml",
+	configMap:   "system-metrics-dashboard",
+	key:         "system-metrics.json",
+	instance:    "host",
+	variables: func(b *builder) []Variable {
+		variables := []Variable{b.datasourceVariable(), rateVariable()}
+		return append(variables, b.topologyVariables(cassandraMetric, false)...)
+	},
+	panelSets: systemMetricsPanelSets,
+}
+
+func systemMetricsPanelSets(b *builder) []PanelSet {
+	sel := b.selector()
+	// cpu returns the percentage of the time of each CPU spent in the given types
+	cpu := func(types, by string) string {
+		return fmt.Sprintf(`sum by (%s) (rate(collectd_cpu_total{%s}[$rate])) / ignoring (type) group_left sum by (cpu) (rate(collectd_cpu_total{%s}[$rate])) * 100`,
+			by, b.selector(fmt.Sprintf(`type=~"%s"`, types)), sel)
+	}
+	cores := fmt.Sprintf(`count(count by (cpu) (collectd_cpu_total{%s}))`, sel)
+	disks := b.selector(`disk=~".*[0-9]+"`)
+
+	return []PanelSet{
+		{
+			Name:   "gauges",
+			Title:  "Basic CPU / Mem / Disk Gauge",
+			Height: 4,
+			Panels: []Panel{
+				stat("CPU Busy", "percent", 4, target(fmt.Sprintf(`(1 - sum(rate(collectd_cpu_total{%s}[$rate])) / sum(rate(collectd_cpu_total{%s}[$rate]))) * 100`, b.selector(`type="idle"`), sel), "")),
+				stat("Memory Used", "percent", 4, target(fmt.Sprintf(`100 * (1 - sum(collectd_memory{%s}) / sum(collectd_memory{%s}))`, b.selector(`memory=~"free|cached|buffered"`), sel), "")),
+				stat("Swap Used", "percent", 4, target(fmt.Sprintf(`100 * sum(collectd_swap{%s}) / sum(collectd_swap{%s})`, b.selector(`swap="used"`), sel), "")),
+				stat("Disk Used", "percent", 4, target(fmt.Sprintf(`100 * sum(collectd_df_df_complex{%s}) / sum(collectd_df_df_complex{%s})`, b.selector(`df="root"`, `type="used"`), b.selector(`df="root"`)), "")),
+				stat("CPU System Load (1m avg)", "percent", 4, target(fmt.Sprintf(`avg(collectd_load_shortterm{%s}) / %s * 100`, sel, cores), "")),
+				stat("CPU System Load (5m avg)", "percent", 4, target(fmt.Sprintf(`avg(collectd_load_midterm{%s}) / %s * 100`, sel, cores), "")),
+			},
+		},
+		{
+			Name:   "info",
+			Title:  "Basic CPU / Mem / Disk Info",
+			Height: 3,
+			Panels: []Panel{
+				stat("CPU Cores", "short", 4, target(cores, "")),
+				stat("Total RAM", "bytes", 4, target(fmt.Sprintf(`sum(collectd_memory{%s})`, sel), "")),
+				stat("Total Swap", "bytes", 4, target(fmt.Sprintf(`sum(collectd_swap{%s})`, sel), "")),
+				stat("Total RootFS", "bytes", 4, target(fmt.Sprintf(`sum(collectd_df_df_complex{%s})`, b.selector(`df="root"`)), "")),
+				stat("System Load (1m avg)", "short", 4, target(fmt.Sprintf(`avg(collectd_load_shortterm{%s})`, sel), "")),
+				stat("System Uptime", "s", 4, target(fmt.Sprintf(`max(collectd_uptime{%s})`, sel), "")),
+			},
+		},
+		{
+			Name:  "cpu-memory",
+			Title: "Basic CPU / Mem Graph",
+			Panels: []Panel{
+				graph("CPU Basic", "percent", 12,
+					target(fmt.Sprintf(`sum(rate(collectd_cpu_total{%s}[$rate])) / sum(rate(collectd_cpu_total{%s}[$rate])) * 100`, b.selector(`type="system"`), sel), "busy system"),
+					target(fmt.Sprintf(`sum(rate(collectd_cpu_total{%s}[$rate])) / sum(rate(collectd_cpu_total{%s}[$rate])) * 100`, b.selector(`type="user"`), sel), "busy user"),
+					target(fmt.Sprintf(`sum(rate(collectd_cpu_total{%s}[$rate])) / sum(rate(collectd_cpu_total{%s}[$rate])) * 100`, b.selector(`type="wait"`), sel), "busy iowait"),
+					target(fmt.Sprintf(`sum(rate(collectd_cpu_total{%s}[$rate])) / sum(rate(collectd_cpu_total{%s}[$rate])) * 100`, b.selector(`type="softirq"`), sel), "busy irq"),
+					target(fmt.Sprintf(`sum(rate(collectd_cpu_total{%s}[$rate])) / sum(rate(collectd_cpu_total{%s}[$rate])) * 100`, b.selector(`type!~"idle|system|user|wait|softirq"`), sel), "busy other"),
+					target(fmt.Sprintf(`sum(rate(collectd_cpu_total{%s}[$rate])) / sum(rate(collectd_cpu_total{%s}[$rate])) * 100`, b.selector(`type="idle"`), sel), "idle")),
+				graph("Basic memory usage", "bytes", 12,
+					target(fmt.Sprintf(`sum(collectd_memory{%s})`, sel), "RAM total"),
+					target(fmt.Sprintf(`sum(collectd_memory{%s})`, b.selector(`memory="used"`)), "RAM used"),
+					target(fmt.Sprintf(`sum(collectd_memory{%s})`, b.selector(`memory=~"cached|buffered"`)), "RAM cache + buffer"),
+					target(fmt.Sprintf(`sum(collectd_memory{%s})`, b.selector(`memory="free"`)), "RAM free"),
+					target(fmt.Sprintf(`sum(collectd_swap{%s}) - sum(collectd_swap{%s})`, sel, b.selector(`swap="free"`)), "swap used")),
+			},
+		},
+		{
+			Name:  "network-disk",
+			Title: "Basic Network / Disk Graph",
+			Panels: []Panel{
+				graph("Network Traffic / Second", "bps", 12,
+					target(fmt.Sprintf(`rate(collectd_interface_if_octets_rx_total{%s}[$rate]) * 8`, sel), "{{interface}} receive"),
+					target(fmt.Sprintf(`rate(collectd_interface_if_octets_tx_total{%s}[$rate]) * 8`, sel), "{{interface}} send")),
+				graph("Network Packets / Second", "pps", 12,
+					target(fmt.Sprintf(`rate(collectd_interface_if_packets_rx_total{%s}[$rate])`, sel), "{{interface}} receive"),
+					target(fmt.Sprintf(`rate(collectd_interface_if_packets_tx_total{%s}[$rate])`, sel), "{{interface}} send")),
+				graph("Disk Activity / Second", "Bps", 12,
+					target(fmt.Sprintf(`rate(collectd_disk_disk_octets_read_total{%s}[$rate])`, disks), "{{disk}} read"),
+					target(fmt.Sprintf(`rate(collectd_disk_disk_octets_write_total{%s}[$rate])`, disks), "{{disk}} write")),
+				graph("Disk IOPS", "iops", 12,
+					target(fmt.Sprintf(`rate(collectd_disk_disk_ops_read_total{%s}[$rate])`, disks), "{{disk}} read"),
+					target(fmt.Sprintf(`rate(collectd_disk_disk_ops_write_total{%s}[$rate])`, disks), "{{disk}} write")),
+				graph("Disk Used", "decbytes", 12,
+					target(fmt.Sprintf(`sum by (df) (collectd_df_df_complex{%s})`, b.selector(`type="used"`)), "{{df}}")),
+				graph("Disk Queue Length", "short", 12,
+					target(fmt.Sprintf(`rate(collectd_disk_disk_io_time_weighted_io_time_total{%s}[$rate]) / 1000`, disks), "{{disk}}")),
+				graph("Disk Latency", "ms", 12,
+					target(fmt.Sprintf(`rate(collectd_disk_disk_time_read_total{%s}[$rate])`, disks), "{{disk}} read"),
+					target(fmt.Sprintf(`rate(collectd_disk_disk_time_write_total{%s}[$rate])`, disks), "{{disk}} write")),
+			},
+		},
+		{
+			Name:      "cpu-details",
+			Title:     "CPU Details",
+			Collapsed: true,
+			Panels: []Panel{
+				graph("CPU User", "percent", 12, target(cpu("user", "cpu"), "{{cpu}}")),
+				graph("CPU System", "percent", 12, target(cpu("system", "cpu"), "{{cpu}}")),
+				graph("CPU IOWait", "percent", 12, target(cpu("wait", "cpu"), "{{cpu}}")),
+				graph("CPU SoftIRQ", "percent", 12, target(cpu("softirq", "cpu"), "{{cpu}}")),
+				graph("CPU Other", "percent", 12, target(cpu("interrupt|nice|steal", "cpu, type"), "{{cpu}} {{type}}")),
+			},
+		},
+		{
+			Name:      "advanced",
+			Title:     "Advanced Details",
+			Collapsed: true,
+			Panels: []Panel{
+				graph("Context Switches / Second", "short", 12, target(fmt.Sprintf(`rate(collectd_contextswitch_total{%s}[$rate])`, sel), "context switches")),
+				graph("IRQ Activity", "short", 12, target(fmt.Sprintf(`rate(collectd_irq_total{%s}[$rate])`, b.selector(`irq!="LOC"`)), "{{irq}}")),
+				graph("NUMA Activity", "short", 12, target(fmt.Sprintf(`rate(collectd_numa_vmpage_action_total{%s}[$rate])`, sel), "{{numa}} {{type}}")),
+				graph("TCP Connection Activity", "short", 12, target(fmt.Sprintf(`collectd_tcpconns_tcp_connections{%s}`, sel), "{{tcpconns}} {{type}}")),
+				graph("Protocol Activity", "short", 12, target(fmt.Sprintf(`rate(collectd_protocols_protocol_counter_total{%s}[$rate])`, sel), "{{protocols}} {{type}}")),
+				graph("Processor Speeds", "hertz", 12, target(fmt.Sprintf(`collectd_cpufreq{%s}`, sel), "{{cpufreq}}")),
+				graph("Page Cache Activity", "short", 12,
+					target(fmt.Sprintf(`rate(collectd_vmem_vmpage_faults_majflt_total{%s}[$rate])`, sel), "major faults"),
+					target(fmt.Sprintf(`rate(collectd_vmem_vmpage_faults_minflt_total{%s}[$rate])`, sel), "minor faults"),
+					target(fmt.Sprintf(`rate(collectd_vmem_vmpage_action_total{%s}[$rate])`, sel), "{{vmem}}"),
+					target(fmt.Sprintf(`rate(collectd_vmem_vmpage_io_in_total{%s}[$rate])`, sel), "pages read"),
+					target(fmt.Sprintf(`rate(collectd_vmem_vmpage_io_out_total{%s}[$rate])`, sel), "pages written")),
+				graph("Page Cache Layout", "short", 12, target(fmt.Sprintf(`collectd_vmem_vmpage_number{%s}`, sel), "{{vmem}}")),
+				graph("Process Activity", "short", 12,
+					target(fmt.Sprintf(`collectd_processes_ps_count_threads{%s}`, sel), "threads"),
+					target(fmt.Sprintf(`collectd_processes_ps_count_processes{%s}`, sel), "processes"),
+					target(fmt.Sprintf(`collectd_processes_ps_state{%s}`, sel), "{{processes}} processes")),
+			},
+		},
+		{
+			Name:   "cassandra",
+			Title:  "Basic Cassandra Overview",
+			Height: 6,
+			Panels: []Panel{
+				stat("SSTable Count", "short", 4, target(fmt.Sprintf(`sum(mcac_table_live_ss_table_count{%s})`, sel), "")),
+				stat("Pending Compactions", "short", 4, target(fmt.Sprintf(`sum(mcac_compaction_pending_tasks{%s})`, sel), "")),
+				stat("Connected Clients", "short", 4, target(fmt.Sprintf(`sum(mcac_client_connected_native_clients{%s})`, sel), "")),
+				graph("GC Activity", "bytes", 12,
+					target(fmt.Sprintf(`sum(mcac_jvm_memory_max{%s})`, b.selector(`memory_type="total"`)), "JVM heap total"),
+					target(fmt.Sprintf(`sum(mcac_jvm_memory_used{%s})`, b.selector(`memory_type="non_heap"`)), "JVM non-heap used"),
+					target(fmt.Sprintf(`sum(mcac_jvm_memory_used{%s})`, b.selector(`memory_type="heap"`)), "JVM heap used")),
+			},
+		},
+	}
+}
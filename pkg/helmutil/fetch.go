@@ -17,6 +17,11 @@ const (
 	ManagedLabel      = "app.kubernetes.io/managed-by"
 	ManagedLabelValue = "Helm"
 	ReleaseAnnotation = "meta.helm.sh/release-name"
+	// ReleaseNamespaceAnnotation is the namespace of the release of a resource managed by helm
+	ReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+	// ResourcePolicyAnnotation set to KeepPolicy keeps helm from deleting a resource
+	ResourcePolicyAnnotation = "helm.sh/resource-policy"
+	KeepPolicy               = "keep"
 
 	RepoURL = "https://helm.k8ssandra.io/"
 	// ChartName is the name of k8ssandra's helm repo chart
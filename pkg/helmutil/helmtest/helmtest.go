@@ -0,0 +1,37 @@
+// Package helmtest provides the fixtures of the tests reading helm's storage
+package helmtest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReleaseSecret returns a revision of a release stored as helm stores it, gzipped and base64 encoded in a secret
+func ReleaseSecret(t *testing.T, namespace string, rel *release.Release) *corev1.Secret {
+	data, err := json.Marshal(rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	_, _ = w.Write(data)
+	_ = w.Close()
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("sh.helm.release.v1.%s.v%d", rel.Name, rel.Version),
+			Namespace: namespace,
+			Labels:    map[string]string{"owner": "helm", "name": rel.Name, "status": rel.Info.Status.String(), "version": fmt.Sprint(rel.Version)},
+		},
+		Type: "helm.sh/release.v1",
+		Data: map[string][]byte{"release": []byte(base64.StdEncoding.EncodeToString(compressed.Bytes()))},
+	}
+}
@@ -0,0 +1,117 @@
+package helmutil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// gzipMagic starts the releases helm stores compressed
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// DeployedRelease returns the deployed revision of a release from the secrets of helm's storage, or nil if the release
+// has no deployed revision
+func DeployedRelease(c client.Client, namespace, releaseName string) (*release.Release, error) {
+	secrets := &corev1.SecretList{}
+	if err := c.List(context.TODO(), secrets, client.InNamespace(namespace),
+		client.MatchingLabels{"owner": "helm", "name": releaseName, "status": release.StatusDeployed.String()}); err != nil {
+		return nil, err
+	}
+
+	var deployed *release.Release
+	for _, secret := range secrets.Items {
+		rel, err := decodeRelease(secret.Data["release"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode release secret %s: %w", secret.Name, err)
+		}
+		if deployed == nil || rel.Version > deployed.Version {
+			deployed = rel
+		}
+	}
+	return deployed, nil
+}
+
+// ErrNoReleaseStorage tells that no secret of helm's storage exists, as when HELM_DRIVER stores the releases in
+// ConfigMaps or an SQL database
+var ErrNoReleaseStorage = errors.New("no release found in the secrets of helm's storage")
+
+// Releases returns the releases of the namespace, or of every namespace if it is empty, from the secrets of helm's
+// storage, except the releases whose last revision was uninstalled keeping their history. ErrNoReleaseStorage is
+// returned when no secret of helm's storage is found.
+func Releases(c client.Client, namespace string) (map[types.NamespacedName]bool, error) {
+	secrets := &corev1.SecretList{}
+	if err := c.List(context.TODO(), secrets, client.InNamespace(namespace), client.MatchingLabels{"owner": "helm"}); err != nil {
+		return nil, err
+	}
+	if len(secrets.Items) == 0 {
+		return nil, ErrNoReleaseStorage
+	}
+
+	// the status of the last revision of each release
+	versions := make(map[types.NamespacedName]int)
+	statuses := make(map[types.NamespacedName]string)
+	for _, secret := range secrets.Items {
+		key := types.NamespacedName{Namespace: secret.Namespace, Name: secret.Labels["name"]}
+		version, err := strconv.Atoi(secret.Labels["version"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the version of release secret %s: %w", secret.Name, err)
+		}
+		if _, found := versions[key]; !found || version > versions[key] {
+			versions[key] = version
+			statuses[key] = secret.Labels["status"]
+		}
+	}
+
+	releases := make(map[types.NamespacedName]bool)
+	for key, status := range statuses {
+		if status != release.StatusUninstalled.String() {
+			releases[key] = true
+		}
+	}
+	return releases, nil
+}
+
+// ReleaseValues returns the values a release was rendered with, the values supplied by the user merged over the
+// values of the chart
+func ReleaseValues(rel *release.Release) (map[string]interface{}, error) {
+	if rel.Chart == nil {
+		return rel.Config, nil
+	}
+	return chartutil.CoalesceValues(rel.Chart, rel.Config)
+}
+
+// decodeRelease decodes a release as helm stores it, base64 encoded JSON which is gzipped by default
+func decodeRelease(data []byte) (*release.Release, error) {
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(decoded, gzipMagic) {
+		r, err := gzip.NewReader(bytes.NewReader(decoded))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		if decoded, err = ioutil.ReadAll(r); err != nil {
+			return nil, err
+		}
+	}
+
+	rel := &release.Release{}
+	if err := json.Unmarshal(decoded, rel); err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
@@ -0,0 +1,76 @@
+package helmutil
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k8ssandra/k8ssandra/pkg/helmutil/helmtest"
+)
+
+func TestDeployedRelease(t *testing.T) {
+	g := NewWithT(t)
+
+	newRelease := func(version int, status release.Status, chartVersion string) *release.Release {
+		return &release.Release{
+			Name:    "k8ssandra",
+			Version: version,
+			Info:    &release.Info{Status: status},
+			Chart:   &chart.Chart{Metadata: &chart.Metadata{Name: "k8ssandra", Version: chartVersion}},
+			Config:  map[string]interface{}{"medusa": map[string]interface{}{"enabled": true}},
+		}
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(Succeed())
+	c := fake.NewFakeClientWithScheme(scheme,
+		helmtest.ReleaseSecret(t, "ns", newRelease(1, release.StatusSuperseded, "1.1.0")),
+		helmtest.ReleaseSecret(t, "ns", newRelease(2, release.StatusDeployed, "1.2.0")),
+		helmtest.ReleaseSecret(t, "ns", newRelease(3, release.StatusFailed, "1.3.0")),
+		helmtest.ReleaseSecret(t, "other", newRelease(4, release.StatusDeployed, "1.3.0")))
+
+	deployed, err := DeployedRelease(c, "ns", "k8ssandra")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(deployed.Version).To(Equal(2))
+	g.Expect(deployed.Chart.Metadata.Version).To(Equal("1.2.0"))
+	g.Expect(deployed.Config).To(HaveKey("medusa"))
+
+	deployed, err = DeployedRelease(c, "ns", "other")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(deployed).To(BeNil())
+}
+
+func TestReleases(t *testing.T) {
+	g := NewWithT(t)
+
+	newRelease := func(name string, version int, status release.Status) *release.Release {
+		return &release.Release{Name: name, Version: version, Info: &release.Info{Status: status}}
+	}
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(Succeed())
+	c := fake.NewFakeClientWithScheme(scheme,
+		helmtest.ReleaseSecret(t, "ns", newRelease("k8ssandra", 1, release.StatusSuperseded)),
+		helmtest.ReleaseSecret(t, "ns", newRelease("k8ssandra", 2, release.StatusFailed)),
+		helmtest.ReleaseSecret(t, "ns", newRelease("uninstalled", 1, release.StatusSuperseded)),
+		helmtest.ReleaseSecret(t, "ns", newRelease("uninstalled", 2, release.StatusUninstalled)),
+		helmtest.ReleaseSecret(t, "other", newRelease("other", 1, release.StatusDeployed)))
+
+	releases, err := Releases(c, "ns")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(releases).To(Equal(map[types.NamespacedName]bool{{Namespace: "ns", Name: "k8ssandra"}: true}))
+
+	releases, err = Releases(c, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(releases).To(Equal(map[types.NamespacedName]bool{
+		{Namespace: "ns", Name: "k8ssandra"}: true,
+		{Namespace: "other", Name: "other"}:  true,
+	}))
+
+	_, err = Releases(c, "empty")
+	g.Expect(err).To(MatchError(ErrNoReleaseStorage))
+}
@@ -0,0 +1,283 @@
+// Package mgmtapi is a client of the Management API for Apache Cassandra, which runs next to Cassandra in the pods of
+// cass-operator and exposes the node operations of nodetool over HTTP.
+package mgmtapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// Port is the port of the Management API in the Cassandra container
+	Port = 8080
+
+	userAgent = "k8ssandra-client"
+)
+
+// Client calls the Management API of Cassandra pods. Its requests have no timeout, as operations such as cleanup and
+// compaction run for hours on large nodes: they are bounded by the context of each call.
+type Client struct {
+	httpClient *http.Client
+	podURL     func(pod *corev1.Pod) *url.URL
+}
+
+// NewClient returns a Client calling the Management API of each pod at the URL returned by podURL
+func NewClient(httpClient *http.Client, podURL func(pod *corev1.Pod) *url.URL) *Client {
+	return &Client{httpClient: httpClient, podURL: podURL}
+}
+
+// NewPodIPClient returns a Client calling the Management API at the IP of the pods, from inside the Kubernetes cluster
+func NewPodIPClient() *Client {
+	return NewClient(&http.Client{}, func(pod *corev1.Pod) *url.URL {
+		return &url.URL{Scheme: "http", Host: fmt.Sprintf("%s:%d", pod.Status.PodIP, Port)}
+	})
+}
+
+// NewProxyClient returns a Client calling the Management API through the pods/proxy subresource of the API server, from
+// outside of the Kubernetes cluster
+func NewProxyClient(config *rest.Config) (*Client, error) {
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, err
+	}
+	host, err := url.Parse(config.Host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API server URL %s: %w", config.Host, err)
+	}
+	if host.Scheme == "" {
+		host.Scheme = "https"
+	}
+	return NewClient(&http.Client{Transport: transport}, func(pod *corev1.Pod) *url.URL {
+		u := *host
+		u.Path = strings.TrimSuffix(u.Path, "/") + fmt.Sprintf("/api/v1/namespaces/%s/pods/%s:%d/proxy", pod.Namespace, pod.Name, Port)
+		return &u
+	}), nil
+}
+
+// Endpoint is a node of the cluster as seen by the gossip of a node
+type Endpoint struct {
+	Address    string
+	Datacenter string
+	Rack       string
+	HostID     string
+	// Status is the gossip status of the node, e.g. NORMAL, LEAVING or shutdown
+	Status string
+	Alive  bool
+	// Schema is the version of the schema of the node
+	Schema string
+}
+
+// NodeStatus is the status of the node of a pod
+type NodeStatus struct {
+	Pod     string
+	Address string
+	// Ready is true if the node accepts CQL connections
+	Ready   bool
+	Version string
+	// Endpoints are the nodes of the cluster as seen by the node, empty if the node is not ready
+	Endpoints []Endpoint
+}
+
+// Self returns the endpoint of the node itself, nil if the node is not ready
+func (s *NodeStatus) Self() *Endpoint {
+	for i := range s.Endpoints {
+		if s.Endpoints[i].Address == s.Address {
+			return &s.Endpoints[i]
+		}
+	}
+	return nil
+}
+
+// Alive returns the number of nodes seen as alive by the node
+func (s *NodeStatus) Alive() int {
+	alive := 0
+	for _, endpoint := range s.Endpoints {
+		if endpoint.Alive {
+			alive++
+		}
+	}
+	return alive
+}
+
+// SchemaVersions returns the schema versions of the nodes seen as alive by the node with their addresses, like
+// nodetool describecluster. The nodes which are not alive are listed under UNREACHABLE.
+func (s *NodeStatus) SchemaVersions() map[string][]string {
+	versions := make(map[string][]string)
+	for _, endpoint := range s.Endpoints {
+		version := endpoint.Schema
+		if !endpoint.Alive {
+			version = "UNREACHABLE"
+		}
+		versions[version] = append(versions[version], endpoint.Address)
+	}
+	return versions
+}
+
+// KeyspaceRequest selects the tables of an operation, all the tables of all the keyspaces if Keyspace is empty and
+// all the tables of Keyspace if Tables is empty
+type KeyspaceRequest struct {
+	Keyspace string   `json:"keyspace_name,omitempty"`
+	Tables   []string `json:"tables,omitempty"`
+}
+
+// Status returns the status of the node of the pod. A node which does not accept CQL connections is not ready, its
+// version and endpoints are left empty.
+func (c *Client) Status(ctx context.Context, pod *corev1.Pod) (*NodeStatus, error) {
+	status := &NodeStatus{Pod: pod.Name, Address: pod.Status.PodIP}
+	res, err := c.do(ctx, pod, http.MethodGet, "/api/v0/probes/readiness", nil)
+	if err != nil {
+		return nil, err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return status, nil
+	}
+	status.Ready = true
+
+	version, err := c.get(ctx, pod, "/api/v0/metadata/versions/release")
+	if err != nil {
+		return nil, err
+	}
+	status.Version = strings.Trim(strings.TrimSpace(string(version)), `"`)
+
+	body, err := c.get(ctx, pod, "/api/v0/metadata/endpoints")
+	if err != nil {
+		return nil, err
+	}
+	if status.Endpoints, err = parseEndpoints(body); err != nil {
+		return nil, fmt.Errorf("invalid endpoints of pod %s: %w", pod.Name, err)
+	}
+	return status, nil
+}
+
+// Drain flushes the memtables of the node and stops it from accepting connections, ahead of a restart
+func (c *Client) Drain(ctx context.Context, pod *corev1.Pod) error {
+	return c.post(ctx, pod, "/api/v0/ops/node/drain", nil)
+}
+
+// Flush flushes the memtables of the tables to SSTables
+func (c *Client) Flush(ctx context.Context, pod *corev1.Pod, request KeyspaceRequest) error {
+	return c.post(ctx, pod, "/api/v0/ops/tables/flush", request)
+}
+
+// Cleanup removes the data of the tables the node no longer owns, after nodes were added to the datacenter
+func (c *Client) Cleanup(ctx context.Context, pod *corev1.Pod, request KeyspaceRequest) error {
+	return c.post(ctx, pod, "/api/v0/ops/keyspace/cleanup", request)
+}
+
+// Compact runs a major compaction of the tables
+func (c *Client) Compact(ctx context.Context, pod *corev1.Pod, request KeyspaceRequest) error {
+	return c.post(ctx, pod, "/api/v0/ops/tables/compact", request)
+}
+
+// Snapshot takes a snapshot of the keyspaces, all the keyspaces if none is given
+func (c *Client) Snapshot(ctx context.Context, pod *corev1.Pod, name string, keyspaces []string) error {
+	return c.post(ctx, pod, "/api/v0/ops/node/snapshots", map[string]interface{}{
+		"snapshot_name": name,
+		"keyspaces":     keyspaces,
+	})
+}
+
+// parseEndpoints parses the endpoints of the gossip state, whose properties are all strings
+func parseEndpoints(body []byte) ([]Endpoint, error) {
+	states := struct {
+		Entity []map[string]string `json:"entity"`
+	}{}
+	if err := json.Unmarshal(body, &states); err != nil {
+		return nil, err
+	}
+	endpoints := make([]Endpoint, 0, len(states.Entity))
+	for _, state := range states.Entity {
+		// the status is followed by the tokens of the node, e.g. NORMAL,-1234
+		status := strings.SplitN(state["STATUS"], ",", 2)[0]
+		endpoints = append(endpoints, Endpoint{
+			Address:    state["ENDPOINT_IP"],
+			Datacenter: state["DC"],
+			Rack:       state["RACK"],
+			HostID:     state["HOST_ID"],
+			Status:     status,
+			Alive:      state["IS_ALIVE"] == "true",
+			Schema:     state["SCHEMA"],
+		})
+	}
+	return endpoints, nil
+}
+
+func (c *Client) get(ctx context.Context, pod *corev1.Pod, path string) ([]byte, error) {
+	res, err := c.do(ctx, pod, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if err := checkStatus(res, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("GET %s on pod %s failed: %w", path, pod.Name, err)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+func (c *Client) post(ctx context.Context, pod *corev1.Pod, path string, request interface{}) error {
+	var body io.Reader
+	if request != nil {
+		b, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	res, err := c.do(ctx, pod, http.MethodPost, path, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := checkStatus(res, http.StatusOK, http.StatusCreated, http.StatusAccepted); err != nil {
+		return fmt.Errorf("POST %s on pod %s failed: %w", path, pod.Name, err)
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, pod *corev1.Pod, method, path string, body io.Reader) (*http.Response, error) {
+	u := c.podURL(pod)
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call the Management API of pod %s: %w", pod.Name, err)
+	}
+	return res, nil
+}
+
+func checkStatus(res *http.Response, expectedStatuses ...int) error {
+	for _, status := range expectedStatuses {
+		if res.StatusCode == status {
+			return nil
+		}
+	}
+	return responseError(res)
+}
+
+func responseError(res *http.Response) error {
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil || len(b) == 0 {
+		return fmt.Errorf("%s (HTTP status %d)", http.StatusText(res.StatusCode), res.StatusCode)
+	}
+	return fmt.Errorf("%s (HTTP status %d)", strings.TrimSpace(string(b)), res.StatusCode)
+}
@@ -0,0 +1,212 @@
+package mgmtapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+const endpointsOutput = `{"entity": [
+	{"DC": "dc1", "ENDPOINT_IP": "10.0.0.1", "HOST_ID": "host-1", "IS_ALIVE": "true", "RACK": "default", "SCHEMA": "2207c2a9-f598-3971-986b-2926e09e239d", "STATUS": "NORMAL,-1234"},
+	{"DC": "dc1", "ENDPOINT_IP": "10.0.0.2", "HOST_ID": "host-2", "IS_ALIVE": "false", "RACK": "default", "STATUS": "shutdown,true"}
+]}`
+
+// fakeManagementAPI is a minimal stand-in for the Management API of one node, which records the operations it runs
+type fakeManagementAPI struct {
+	mu       sync.Mutex
+	ready    bool
+	requests []string
+}
+
+func (f *fakeManagementAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v0/probes/readiness":
+		if !f.ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v0/metadata/versions/release":
+		_, _ = w.Write([]byte("3.11.10"))
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v0/metadata/endpoints":
+		_, _ = w.Write([]byte(endpointsOutput))
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/v0/ops/"):
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(body), "missing") {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("Unknown keyspace/cf pair (missing.table)"))
+			return
+		}
+		f.requests = append(f.requests, strings.TrimSpace(r.URL.Path+" "+string(body)))
+		_, _ = w.Write([]byte("OK"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func testPod(name, ip string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "k8ssandra", Name: name},
+		Status:     corev1.PodStatus{PodIP: ip},
+	}
+}
+
+func newTestClient(t *testing.T) (*Client, *fakeManagementAPI) {
+	api := &fakeManagementAPI{ready: true}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(server.Client(), func(pod *corev1.Pod) *url.URL {
+		podURL := *u
+		return &podURL
+	}), api
+}
+
+func TestStatus(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c, api := newTestClient(t)
+
+	status, err := c.Status(ctx, testPod("test-dc1-default-sts-0", "10.0.0.1"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(status.Ready).To(BeTrue())
+	g.Expect(status.Version).To(Equal("3.11.10"))
+	g.Expect(status.Endpoints).To(Equal([]Endpoint{
+		{Address: "10.0.0.1", Datacenter: "dc1", Rack: "default", HostID: "host-1", Status: "NORMAL", Alive: true, Schema: "2207c2a9-f598-3971-986b-2926e09e239d"},
+		{Address: "10.0.0.2", Datacenter: "dc1", Rack: "default", HostID: "host-2", Status: "shutdown", Alive: false},
+	}))
+	g.Expect(status.Self()).To(Equal(&status.Endpoints[0]))
+	g.Expect(status.Alive()).To(Equal(1))
+	g.Expect(status.SchemaVersions()).To(Equal(map[string][]string{
+		"2207c2a9-f598-3971-986b-2926e09e239d": {"10.0.0.1"},
+		"UNREACHABLE":                          {"10.0.0.2"},
+	}))
+
+	api.ready = false
+	status, err = c.Status(ctx, testPod("test-dc1-default-sts-1", "10.0.0.2"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(status.Ready).To(BeFalse())
+	g.Expect(status.Self()).To(BeNil())
+}
+
+func TestOperations(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c, api := newTestClient(t)
+	pod := testPod("test-dc1-default-sts-0", "10.0.0.1")
+
+	g.Expect(c.Drain(ctx, pod)).To(Succeed())
+	g.Expect(c.Flush(ctx, pod, KeyspaceRequest{Keyspace: "ks", Tables: []string{"t1", "t2"}})).To(Succeed())
+	g.Expect(c.Cleanup(ctx, pod, KeyspaceRequest{})).To(Succeed())
+	g.Expect(c.Compact(ctx, pod, KeyspaceRequest{Keyspace: "ks"})).To(Succeed())
+	g.Expect(c.Snapshot(ctx, pod, "backup", []string{"ks"})).To(Succeed())
+	g.Expect(api.requests).To(Equal([]string{
+		"/api/v0/ops/node/drain",
+		`/api/v0/ops/tables/flush {"keyspace_name":"ks","tables":["t1","t2"]}`,
+		"/api/v0/ops/keyspace/cleanup {}",
+		`/api/v0/ops/tables/compact {"keyspace_name":"ks"}`,
+		`/api/v0/ops/node/snapshots {"keyspaces":["ks"],"snapshot_name":"backup"}`,
+	}))
+
+	err := c.Flush(ctx, pod, KeyspaceRequest{Keyspace: "missing", Tables: []string{"table"}})
+	g.Expect(err).To(MatchError("POST /api/v0/ops/tables/flush on pod test-dc1-default-sts-0 failed: Unknown keyspace/cf pair (missing.table) (HTTP status 500)"))
+}
+
+func TestProxyURL(t *testing.T) {
+	g := NewWithT(t)
+
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_, _ = w.Write([]byte(`{"entity": []}`))
+	}))
+	defer server.Close()
+
+	c, err := NewProxyClient(&rest.Config{Host: server.URL})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c.Drain(context.Background(), testPod("test-dc1-default-sts-0", "10.0.0.1"))).To(Succeed())
+	g.Expect(path).To(Equal("/api/v1/namespaces/k8ssandra/pods/test-dc1-default-sts-0:8080/proxy/api/v0/ops/node/drain"))
+}
+
+func TestLongOperation(t *testing.T) {
+	g := NewWithT(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+
+	// compactions outlast any fixed timeout, only the context of the call bounds them
+	c, err := NewProxyClient(&rest.Config{Host: server.URL})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c.httpClient.Timeout).To(BeZero())
+	g.Expect(NewPodIPClient().httpClient.Timeout).To(BeZero())
+	pod := testPod("test-dc1-default-sts-0", "10.0.0.1")
+	g.Expect(c.Compact(context.Background(), pod, KeyspaceRequest{})).To(Succeed())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	g.Expect(c.Compact(ctx, pod, KeyspaceRequest{})).To(MatchError(ContainSubstring(context.DeadlineExceeded.Error())))
+}
+
+func TestRun(t *testing.T) {
+	g := NewWithT(t)
+
+	pods := make([]*corev1.Pod, 0)
+	for i := 0; i < 6; i++ {
+		pods = append(pods, testPod(fmt.Sprintf("test-dc1-default-sts-%d", i), fmt.Sprintf("10.0.0.%d", i)))
+	}
+
+	var running, maxRunning int32
+	results := Run(context.Background(), pods, 2, func(ctx context.Context, pod *corev1.Pod) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		if pod.Name == "test-dc1-default-sts-3" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	g.Expect(maxRunning).To(Equal(int32(2)))
+	g.Expect(results).To(HaveLen(6))
+	for i, result := range results {
+		g.Expect(result.Pod).To(Equal(pods[i].Name))
+		if i == 3 {
+			g.Expect(result.Err).To(MatchError("failed"))
+		} else {
+			g.Expect(result.Err).ToNot(HaveOccurred())
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results = Run(ctx, pods, 2, func(ctx context.Context, pod *corev1.Pod) error { return nil })
+	for _, result := range results {
+		g.Expect(result.Err).To(MatchError(context.Canceled))
+	}
+}
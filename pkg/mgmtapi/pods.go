@@ -0,0 +1,73 @@
+package mgmtapi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Result is the outcome of an operation on a pod
+type Result struct {
+	Pod string
+	Err error
+}
+
+// DatacenterPods returns the pods of the CassandraDatacenter, sorted by name
+func DatacenterPods(ctx context.Context, c client.Client, key types.NamespacedName) ([]*corev1.Pod, error) {
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	if err := c.Get(ctx, key, cassdc); err != nil {
+		return nil, fmt.Errorf("failed to get CassandraDatacenter %s: %w", key, err)
+	}
+	list := &corev1.PodList{}
+	if err := c.List(ctx, list, client.InNamespace(key.Namespace), client.MatchingLabels(cassdc.GetDatacenterLabels())); err != nil {
+		return nil, fmt.Errorf("failed to list pods of CassandraDatacenter %s: %w", key, err)
+	}
+	if len(list.Items) == 0 {
+		return nil, fmt.Errorf("CassandraDatacenter %s has no pod", key)
+	}
+
+	pods := make([]*corev1.Pod, len(list.Items))
+	for i := range list.Items {
+		pods[i] = &list.Items[i]
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	return pods, nil
+}
+
+// Run runs the operation on the pods, on at most concurrency pods at a time, and returns its results in the order of
+// the pods. An operation is not started on the remaining pods once the context is done.
+func Run(ctx context.Context, pods []*corev1.Pod, concurrency int, operation func(ctx context.Context, pod *corev1.Pod) error) []Result {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]Result, len(pods))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, pod := range pods {
+		results[i].Pod = pod.Name
+		if ctx.Err() != nil {
+			results[i].Err = ctx.Err()
+			continue
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int, pod *corev1.Pod) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i].Err = operation(ctx, pod)
+		}(i, pod)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,334 @@
+package monitoring
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+
+	api "github.com/k8ssandra/k8ssandra/pkg/apis/k8ssandra/v1alpha1"
+)
+
+const (
+	severityCritical = "critical"
+	severityWarning  = "warning"
+
+	// chartAlertsValues is the path of the alerts settings in the values of the chart
+	chartAlertsValues = ".Values.monitoring.prometheus.alerts"
+)
+
+// DefaultAlertsConfig returns the default settings of the alerting rules, which are also the values of the chart
+func DefaultAlertsConfig() *api.AlertsConfig {
+	return &api.AlertsConfig{
+		NodeDown:           alertConfig(true, 0, "5m", severityCritical),
+		PendingCompactions: alertConfig(true, 100, "30m", severityWarning),
+		DroppedMutations:   alertConfig(true, 10, "5m", severityWarning),
+		ReadLatency:        &api.LatencyAlertConfig{AlertConfig: *alertConfig(true, 500, "10m", severityWarning), Quantile: "0.99"},
+		WriteLatency:       &api.LatencyAlertConfig{AlertConfig: *alertConfig(true, 200, "10m", severityWarning), Quantile: "0.99"},
+		DiskUsage:          alertConfig(true, 75, "15m", severityWarning),
+		HintedHandoffs:     alertConfig(true, 1000, "15m", severityWarning),
+		Repair:             alertConfig(true, 168, "1h", severityWarning),
+		BackupAge: &api.BackupAlertConfig{
+			AlertConfig: *alertConfig(false, 24, "1h", severityWarning),
+			Metric:      "k8ssandra_backup_last_success_timestamp_seconds",
+		},
+	}
+}
+
+func alertConfig(enabled bool, threshold int64, duration, severity string) *api.AlertConfig {
+	return &api.AlertConfig{Enabled: &enabled, Threshold: &threshold, For: duration, Severity: severity}
+}
+
+// alertSettings are the settings of an alert, the quantile and metric only apply to some alerts
+type alertSettings struct {
+	config   *api.AlertConfig
+	quantile string
+	metric   string
+}
+
+// alertDefinition defines an alerting rule. Its expression and annotations are templates delimited with [[ ]], so that
+// they are not confused with the Prometheus templates of the annotations. The label and value functions write the
+// Prometheus templates of a label of the alert and of its value.
+type alertDefinition struct {
+	// key is the name of the settings of the alert in AlertsConfig and in the values of the chart
+	key         string
+	alert       string
+	expr        string
+	summary     string
+	description string
+	settings    func(*api.AlertsConfig) alertSettings
+}
+
+// diskUsageSeries is the collectd_df_df_complex series of a type of the data volumes, as in the dashboards
+const diskUsageSeries = `collectd_df_df_complex{[[ .Selector ]],df!~".*lxcfs.*",type="%s"}`
+
+var alertDefinitions = []alertDefinition{
+	{
+		key:         "nodeDown",
+		alert:       "CassandraNodeDown",
+		expr:        `up{[[ .TargetSelector ]]} == 0`,
+		summary:     `Cassandra node [[ label "pod" ]] is down`,
+		description: `The metrics of Cassandra node [[ label "pod" ]] of datacenter [[ .Datacenter ]] could not be scraped for [[ .For ]].`,
+		settings:    func(c *api.AlertsConfig) alertSettings { return alertSettings{config: c.NodeDown} },
+	},
+	{
+		key:         "pendingCompactions",
+		alert:       "CassandraPendingCompactions",
+		expr:        `sum by (cluster, dc, rack, instance) (mcac_table_pending_compactions{[[ .Selector ]]}) > [[ .Threshold ]]`,
+		summary:     `Cassandra node [[ label "instance" ]] has [[ value ]] pending compactions`,
+		description: `Cassandra node [[ label "instance" ]] of datacenter [[ .Datacenter ]] has had more than [[ .Threshold ]] pending compactions for [[ .For ]], compactions are not keeping up with the writes.`,
+		settings:    func(c *api.AlertsConfig) alertSettings { return alertSettings{config: c.PendingCompactions} },
+	},
+	{
+		key:         "droppedMutations",
+		alert:       "CassandraDroppedMutations",
+		expr:        `sum by (cluster, dc, rack, instance) (increase(mcac_dropped_message_dropped_total{[[ .Selector ]],message_type="mutation"}[5m])) > [[ .Threshold ]]`,
+		summary:     `Cassandra node [[ label "instance" ]] dropped [[ value ]] mutations`,
+		description: `Cassandra node [[ label "instance" ]] of datacenter [[ .Datacenter ]] dropped more than [[ .Threshold ]] mutations over 5 minutes, the replicas are inconsistent until they are repaired.`,
+		settings:    func(c *api.AlertsConfig) alertSettings { return alertSettings{config: c.DroppedMutations} },
+	},
+	{
+		key:         "readLatency",
+		alert:       "CassandraReadLatencyHigh",
+		expr:        `histogram_quantile([[ .Quantile ]], sum by (cluster, dc, le) (rate(mcac_client_request_latency_bucket{[[ .Selector ]],request_type="read"}[5m]))) / 1000 > [[ .Threshold ]]`,
+		summary:     `The read latency of datacenter [[ .Datacenter ]] is [[ value ]]ms`,
+		description: `The [[ .Quantile ]] quantile of the coordinator read latency of datacenter [[ .Datacenter ]] has been above [[ .Threshold ]]ms for [[ .For ]].`,
+		settings: func(c *api.AlertsConfig) alertSettings {
+			if c.ReadLatency == nil {
+				return alertSettings{}
+			}
+			return alertSettings{config: &c.ReadLatency.AlertConfig, quantile: c.ReadLatency.Quantile}
+		},
+	},
+	{
+		key:         "writeLatency",
+		alert:       "CassandraWriteLatencyHigh",
+		expr:        `histogram_quantile([[ .Quantile ]], sum by (cluster, dc, le) (rate(mcac_client_request_latency_bucket{[[ .Selector ]],request_type="write"}[5m]))) / 1000 > [[ .Threshold ]]`,
+		summary:     `The write latency of datacenter [[ .Datacenter ]] is [[ value ]]ms`,
+		description: `The [[ .Quantile ]] quantile of the coordinator write latency of datacenter [[ .Datacenter ]] has been above [[ .Threshold ]]ms for [[ .For ]].`,
+		settings: func(c *api.AlertsConfig) alertSettings {
+			if c.WriteLatency == nil {
+				return alertSettings{}
+			}
+			return alertSettings{config: &c.WriteLatency.AlertConfig, quantile: c.WriteLatency.Quantile}
+		},
+	},
+	{
+		key:   "diskUsage",
+		alert: "CassandraDiskUsageHigh",
+		expr: fmt.Sprintf(`100 * (1 - %s / ignoring (type) (%s + ignoring (type) %s + ignoring (type) %s)) > [[ .Threshold ]]`,
+			fmt.Sprintf(diskUsageSeries, "free"), fmt.Sprintf(diskUsageSeries, "used"), fmt.Sprintf(diskUsageSeries, "reserved"), fmt.Sprintf(diskUsageSeries, "free")),
+		summary:     `Volume [[ label "df" ]] of Cassandra node [[ label "instance" ]] is [[ value ]]% full`,
+		description: `More than [[ .Threshold ]]% of volume [[ label "df" ]] of Cassandra node [[ label "instance" ]] of datacenter [[ .Datacenter ]] has been used for [[ .For ]], compactions may fail to complete.`,
+		settings:    func(c *api.AlertsConfig) alertSettings { return alertSettings{config: c.DiskUsage} },
+	},
+	{
+		key:         "hintedHandoffs",
+		alert:       "CassandraHintedHandoffs",
+		expr:        `sum by (cluster, dc, rack, instance) (mcac_storage_total_hints_in_progress_total{[[ .Selector ]]}) > [[ .Threshold ]]`,
+		summary:     `Cassandra node [[ label "instance" ]] has [[ value ]] hints in progress`,
+		description: `Cassandra node [[ label "instance" ]] of datacenter [[ .Datacenter ]] has had more than [[ .Threshold ]] hints in progress for [[ .For ]], some replicas are down or overloaded.`,
+		settings:    func(c *api.AlertsConfig) alertSettings { return alertSettings{config: c.HintedHandoffs} },
+	},
+	{
+		key:         "repair",
+		alert:       "CassandraRepairOverdue",
+		expr:        `max by (cluster, keyspace) (io_cassandrareaper_service_RepairRunner_millisSinceLastRepair{[[ .NamespaceSelector ]]}) / 3600000 > [[ .Threshold ]]`,
+		summary:     `Keyspace [[ label "keyspace" ]] was not repaired for [[ value ]] hours`,
+		description: `Reaper did not complete a repair of keyspace [[ label "keyspace" ]] of cluster [[ label "cluster" ]] for more than [[ .Threshold ]] hours. Its repairs fail or are not scheduled, deleted data may come back once gc_grace_seconds elapsed.`,
+		settings:    func(c *api.AlertsConfig) alertSettings { return alertSettings{config: c.Repair} },
+	},
+	{
+		key:         "backupAge",
+		alert:       "CassandraBackupTooOld",
+		expr:        `(time() - max([[ .Metric ]]{[[ .NamespaceSelector ]]})) / 3600 > [[ .Threshold ]]`,
+		summary:     `The last backup of cluster [[ .Cluster ]] is [[ value ]] hours old`,
+		description: `No backup of cluster [[ .Cluster ]] succeeded for more than [[ .Threshold ]] hours.`,
+		settings: func(c *api.AlertsConfig) alertSettings {
+			if c.BackupAge == nil {
+				return alertSettings{}
+			}
+			return alertSettings{config: &c.BackupAge.AlertConfig, metric: c.BackupAge.Metric}
+		},
+	},
+}
+
+// alertParams are the values of the templates of the alerting rules. They are the settings of the rules when they are
+// generated for a datacenter, and Helm expressions when the template of the chart is generated.
+type alertParams struct {
+	Cluster    string
+	Datacenter string
+	// Selector selects the MCAC metrics of the datacenter
+	Selector string
+	// TargetSelector selects the series of the targets of the Cassandra ServiceMonitor of the datacenter
+	TargetSelector string
+	// NamespaceSelector selects the metrics of the namespace, like the ones of Reaper
+	NamespaceSelector string
+
+	Threshold string
+	For       string
+	Quantile  string
+	Metric    string
+}
+
+func newAlertParams(clusterName, dc, namespace string) alertParams {
+	return alertParams{
+		Cluster:           clusterName,
+		Datacenter:        dc,
+		Selector:          fmt.Sprintf(`cluster="%s",dc="%s"`, clusterName, dc),
+		TargetSelector:    fmt.Sprintf(`%s="%s",%s="%s"`, labelName(cassdcapi.ClusterLabel), clusterName, labelName(cassdcapi.DatacenterLabel), dc),
+		NamespaceSelector: fmt.Sprintf(`namespace="%s"`, namespace),
+	}
+}
+
+// render renders a template of an alerting rule. Prometheus templates are escaped from Helm for the chart.
+func (d *alertDefinition) render(text string, params alertParams, chart bool) (string, error) {
+	prometheusTemplate := func(s string) string {
+		if chart {
+			return `{{ "{{" }} ` + s + ` }}`
+		}
+		return "{{ " + s + " }}"
+	}
+	tmpl, err := template.New(d.key).Delims("[[", "]]").Funcs(template.FuncMap{
+		"label": func(name string) string { return prometheusTemplate("$labels." + name) },
+		"value": func() string { return prometheusTemplate("$value") },
+	}).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	out := &bytes.Buffer{}
+	if err := tmpl.Execute(out, params); err != nil {
+		return "", fmt.Errorf("failed to render alert %s: %w", d.alert, err)
+	}
+	return out.String(), nil
+}
+
+func (d *alertDefinition) rule(params alertParams, severity string, chart bool) (Rule, error) {
+	rule := Rule{Alert: d.alert, For: params.For, Labels: map[string]string{"severity": severity}}
+	var err error
+	if rule.Expr, err = d.render(d.expr, params, chart); err != nil {
+		return rule, err
+	}
+	summary, err := d.render(d.summary, params, chart)
+	if err != nil {
+		return rule, err
+	}
+	description, err := d.render(d.description, params, chart)
+	if err != nil {
+		return rule, err
+	}
+	rule.Annotations = map[string]string{"summary": summary, "description": description}
+	return rule, nil
+}
+
+// mergeAlertSettings returns the settings of an alert, the unset ones taking the default value
+func mergeAlertSettings(settings, defaults alertSettings) (alertSettings, bool) {
+	config := *defaults.config
+	if settings.config != nil {
+		if settings.config.Enabled != nil {
+			config.Enabled = settings.config.Enabled
+		}
+		if settings.config.Threshold != nil {
+			config.Threshold = settings.config.Threshold
+		}
+		if settings.config.For != "" {
+			config.For = settings.config.For
+		}
+		if settings.config.Severity != "" {
+			config.Severity = settings.config.Severity
+		}
+	}
+	if settings.quantile == "" {
+		settings.quantile = defaults.quantile
+	}
+	if settings.metric == "" {
+		settings.metric = defaults.metric
+	}
+	settings.config = &config
+	return settings, *config.Enabled
+}
+
+// AlertingRules returns the enabled alerting rules of a datacenter. config overrides the default settings, it may be
+// nil.
+func AlertingRules(config *api.AlertsConfig, clusterName, dc, namespace string) ([]Rule, error) {
+	if config == nil {
+		config = &api.AlertsConfig{}
+	}
+	defaults := DefaultAlertsConfig()
+
+	rules := make([]Rule, 0, len(alertDefinitions))
+	for i := range alertDefinitions {
+		definition := &alertDefinitions[i]
+		settings, enabled := mergeAlertSettings(definition.settings(config), definition.settings(defaults))
+		if !enabled {
+			continue
+		}
+		params := newAlertParams(clusterName, dc, namespace)
+		params.Threshold = strconv.FormatInt(*settings.config.Threshold, 10)
+		params.For = settings.config.For
+		params.Quantile = settings.quantile
+		params.Metric = settings.metric
+		rule, err := definition.rule(params, settings.config.Severity, false)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// ChartRulesTemplate returns the template of the PrometheusRule of the chart, which renders the alerting rules with
+// the alerts values. It is kept in sync with the rules by TestChartTemplates.
+func ChartRulesTemplate() ([]byte, error) {
+	out := &bytes.Buffer{}
+	out.WriteString(`{{- /* Generated from pkg/monitoring by "make chart-templates", do not edit */ -}}
+{{- if and .Values.monitoring.prometheus.provision_service_monitors ` + chartAlertsValues + `.enabled }}
+apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  name: {{ .Release.Name }}-{{ include "k8ssandra.datacenterName" . }}-alerts
+  labels:
+    release: {{ .Release.Name }}
+{{ include "k8ssandra.labels" . | indent 4 }}
+spec:
+  groups:
+  - name: cassandra-{{ include "k8ssandra.clusterName" . }}-{{ include "k8ssandra.datacenterName" . }}
+    rules:
+`)
+
+	for i := range alertDefinitions {
+		definition := &alertDefinitions[i]
+		values := chartAlertsValues + "." + definition.key
+		params := newAlertParams(`{{ include "k8ssandra.clusterName" . }}`, `{{ include "k8ssandra.datacenterName" . }}`, `{{ .Release.Namespace }}`)
+		params.Threshold = "{{ " + values + ".threshold | int64 }}"
+		params.For = "{{ " + values + ".for }}"
+		params.Quantile = "{{ " + values + ".quantile }}"
+		params.Metric = "{{ " + values + ".metric }}"
+		rule, err := definition.rule(params, "{{ "+values+".severity }}", true)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(out, "{{- if %s.enabled }}\n", values)
+		fmt.Fprintf(out, "    - alert: %s\n", rule.Alert)
+		fmt.Fprintf(out, "      expr: %s\n", yamlQuote(rule.Expr))
+		fmt.Fprintf(out, "      for: %s\n", yamlQuote(rule.For))
+		out.WriteString("      labels:\n")
+		fmt.Fprintf(out, "        severity: %s\n", yamlQuote(rule.Labels["severity"]))
+		out.WriteString("      annotations:\n")
+		fmt.Fprintf(out, "        summary: %s\n", yamlQuote(rule.Annotations["summary"]))
+		fmt.Fprintf(out, "        description: %s\n", yamlQuote(rule.Annotations["description"]))
+		out.WriteString("{{- end }}\n")
+	}
+	out.WriteString("{{- end }}\n")
+	return out.Bytes(), nil
+}
+
+// yamlQuote returns s as a single-quoted YAML scalar. Unlike double-quoted scalars, their content is not escaped, so
+// that the Helm expressions they contain are left as they are.
+func yamlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
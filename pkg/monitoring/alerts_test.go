@@ -0,0 +1,131 @@
+package monitoring
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	api "github.com/k8ssandra/k8ssandra/pkg/apis/k8ssandra/v1alpha1"
+)
+
+var update = flag.Bool("update", false, "update the templates of the chart generated from this package")
+
+const chartRulesTemplate = chartDir + "/templates/prometheus/prometheus_rules.yaml"
+
+// TestChartTemplates checks that the templates of the chart generated from this package are up to date, they are
+// regenerated with -update
+func TestChartTemplates(t *testing.T) {
+	g := NewWithT(t)
+
+	rules, err := ChartRulesTemplate()
+	g.Expect(err).ToNot(HaveOccurred())
+	if *update {
+		g.Expect(ioutil.WriteFile(chartRulesTemplate, rules, 0644)).To(Succeed())
+	}
+	committed, err := ioutil.ReadFile(chartRulesTemplate)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(committed)).To(Equal(string(rules)), "%s is out of date, run make chart-templates", chartRulesTemplate)
+}
+
+// TestAlertsDefaultsMatchValues checks that the chart and the operator alert on the same defaults
+func TestAlertsDefaultsMatchValues(t *testing.T) {
+	g := NewWithT(t)
+
+	data, err := ioutil.ReadFile(chartDir + "/values.yaml")
+	g.Expect(err).ToNot(HaveOccurred())
+	values := struct {
+		Monitoring struct {
+			Prometheus struct {
+				Alerts api.AlertsConfig `json:"alerts"`
+			} `json:"prometheus"`
+		} `json:"monitoring"`
+	}{}
+	g.Expect(yaml.Unmarshal(data, &values)).To(Succeed())
+	g.Expect(&values.Monitoring.Prometheus.Alerts).To(Equal(DefaultAlertsConfig()))
+}
+
+func TestAlertingRules(t *testing.T) {
+	g := NewWithT(t)
+
+	rules, err := AlertingRules(nil, "test", "dc1", "k8ssandra")
+	g.Expect(err).ToNot(HaveOccurred())
+	alerts := make(map[string]Rule)
+	for _, rule := range rules {
+		alerts[rule.Alert] = rule
+	}
+	g.Expect(alerts).To(HaveLen(len(alertDefinitions) - 1))
+	g.Expect(alerts).ToNot(HaveKey("CassandraBackupTooOld"))
+	g.Expect(alerts["CassandraReadLatencyHigh"].Expr).To(Equal(
+		`histogram_quantile(0.99, sum by (cluster, dc, le) (rate(mcac_client_request_latency_bucket{cluster="test",dc="dc1",request_type="read"}[5m]))) / 1000 > 500`))
+	g.Expect(alerts["CassandraReadLatencyHigh"].For).To(Equal("10m"))
+	g.Expect(alerts["CassandraPendingCompactions"].Annotations["summary"]).To(Equal(
+		"Cassandra node {{ $labels.instance }} has {{ $value }} pending compactions"))
+	g.Expect(alerts["CassandraRepairOverdue"].Expr).To(ContainSubstring(`{namespace="k8ssandra"}`))
+
+	// unset settings keep their default value
+	rules, err = AlertingRules(&api.AlertsConfig{
+		NodeDown:     &api.AlertConfig{Enabled: boolPtr(false)},
+		ReadLatency:  &api.LatencyAlertConfig{AlertConfig: api.AlertConfig{Threshold: int64Ptr(50), Severity: "critical"}, Quantile: "0.95"},
+		BackupAge:    &api.BackupAlertConfig{AlertConfig: api.AlertConfig{Enabled: boolPtr(true)}, Metric: "backup_timestamp"},
+		WriteLatency: &api.LatencyAlertConfig{},
+	}, "test", "dc1", "k8ssandra")
+	g.Expect(err).ToNot(HaveOccurred())
+	alerts = make(map[string]Rule)
+	for _, rule := range rules {
+		alerts[rule.Alert] = rule
+	}
+	g.Expect(alerts).ToNot(HaveKey("CassandraNodeDown"))
+	g.Expect(alerts["CassandraReadLatencyHigh"].Expr).To(HavePrefix("histogram_quantile(0.95, "))
+	g.Expect(alerts["CassandraReadLatencyHigh"].Expr).To(HaveSuffix(" > 50"))
+	g.Expect(alerts["CassandraReadLatencyHigh"].For).To(Equal("10m"))
+	g.Expect(alerts["CassandraReadLatencyHigh"].Labels).To(Equal(map[string]string{"severity": "critical"}))
+	g.Expect(alerts["CassandraWriteLatencyHigh"].Expr).To(HaveSuffix(" > 200"))
+	g.Expect(alerts["CassandraBackupTooOld"].Expr).To(Equal(`(time() - max(backup_timestamp{namespace="k8ssandra"})) / 3600 > 24`))
+}
+
+// TestAlertingRulesPromtool evaluates the alerting rules against the synthetic series of testdata/alerts_test.yaml
+// with promtool
+func TestAlertingRulesPromtool(t *testing.T) {
+	g := NewWithT(t)
+
+	promtool, err := exec.LookPath("promtool")
+	if err != nil {
+		t.Skip("promtool is not installed, run scripts/install-promtool.sh")
+	}
+
+	spec, err := DatacenterRules(&api.AlertsConfig{
+		BackupAge: &api.BackupAlertConfig{AlertConfig: api.AlertConfig{Enabled: boolPtr(true), Threshold: int64Ptr(1), For: "10m"}},
+	}, "test", "dc1", "k8ssandra")
+	g.Expect(err).ToNot(HaveOccurred())
+	rules, err := yaml.Marshal(spec)
+	g.Expect(err).ToNot(HaveOccurred())
+	tests, err := ioutil.ReadFile("testdata/alerts_test.yaml")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	dir, err := ioutil.TempDir("", "alerts")
+	g.Expect(err).ToNot(HaveOccurred())
+	defer os.RemoveAll(dir)
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "rules.yaml"), rules, 0644)).To(Succeed())
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "alerts_test.yaml"), tests, 0644)).To(Succeed())
+
+	out := &bytes.Buffer{}
+	cmd := exec.Command(promtool, "test", "rules", filepath.Join(dir, "alerts_test.yaml"))
+	cmd.Stdout = out
+	cmd.Stderr = out
+	g.Expect(cmd.Run()).To(Succeed(), out.String())
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
@@ -0,0 +1,82 @@
+package monitoring
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// dashboardUIDLength is the length of the uids of the generated dashboards, Grafana allows up to 40 characters
+const dashboardUIDLength = 16
+
+// LoadDashboards reads the Grafana dashboards of a directory, like the dashboards directory of the chart. They are
+// returned by name, the name of a dashboard being its file name without the .json extension.
+func LoadDashboards(dir string) (map[string][]byte, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	dashboards := make(map[string][]byte, len(files))
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dashboard %s: %w", file, err)
+		}
+		if !json.Valid(data) {
+			return nil, fmt.Errorf("dashboard %s is not valid JSON", file)
+		}
+		dashboards[strings.TrimSuffix(filepath.Base(file), ".json")] = data
+	}
+	return dashboards, nil
+}
+
+// DashboardUID returns a stable uid for a dashboard generated in a namespace, so that the dashboards of datacenters
+// of different namespaces do not replace each other in Grafana
+func DashboardUID(namespace, name string) string {
+	sum := sha256.Sum256([]byte(namespace + "/" + name))
+	return hex.EncodeToString(sum[:])[:dashboardUIDLength]
+}
+
+// DatacenterDashboard returns a copy of a dashboard for a datacenter. Its title is suffixed with the cluster and the
+// datacenter, and its cluster and dc variables default to them.
+func DatacenterDashboard(dashboard []byte, uid, clusterName, dc string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(dashboard))
+	// Keep the numbers as they are written, ids would otherwise be turned into floats
+	decoder.UseNumber()
+	model := make(map[string]interface{})
+	if err := decoder.Decode(&model); err != nil {
+		return nil, fmt.Errorf("failed to parse dashboard: %w", err)
+	}
+
+	title, _ := model["title"].(string)
+	model["title"] = fmt.Sprintf("%s (%s/%s)", title, clusterName, dc)
+	model["uid"] = uid
+	delete(model, "id")
+
+	if templating, ok := model["templating"].(map[string]interface{}); ok {
+		variables, _ := templating["list"].([]interface{})
+		for _, v := range variables {
+			variable, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch variable["name"] {
+			case "cluster":
+				setVariable(variable, clusterName)
+			case "dc":
+				setVariable(variable, dc)
+			}
+		}
+	}
+
+	return json.MarshalIndent(model, "", "  ")
+}
+
+func setVariable(variable map[string]interface{}, value string) {
+	variable["current"] = map[string]interface{}{"selected": true, "text": value, "value": value}
+}
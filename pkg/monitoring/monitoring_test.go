@@ -0,0 +1,119 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+)
+
+const chartDir = "../../charts/k8ssandra"
+
+// TestMCACRelabelingsMatchChart checks that the relabelings of the Cassandra ServiceMonitor are the ones of the chart
+func TestMCACRelabelingsMatchChart(t *testing.T) {
+	g := NewWithT(t)
+
+	data, err := ioutil.ReadFile(chartDir + "/templates/prometheus/service_monitor.yaml")
+	g.Expect(err).ToNot(HaveOccurred())
+	lines := make([]string, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.Contains(line, "{{") {
+			lines = append(lines, line)
+		}
+	}
+	serviceMonitor := struct {
+		Spec ServiceMonitorSpec `json:"spec"`
+	}{}
+	g.Expect(yaml.Unmarshal([]byte(strings.Join(lines, "\n")), &serviceMonitor)).To(Succeed())
+
+	spec := CassandraServiceMonitorSpec("test", "dc1", DefaultScrapeInterval)
+	g.Expect(spec.Endpoints[0].MetricRelabelings).To(Equal(serviceMonitor.Spec.Endpoints[0].MetricRelabelings))
+	g.Expect(spec.Endpoints[0].Port).To(Equal(serviceMonitor.Spec.Endpoints[0].Port))
+	g.Expect(*spec.Endpoints[0].TargetPort).To(Equal(*serviceMonitor.Spec.Endpoints[0].TargetPort))
+	g.Expect(spec.TargetLabels).To(Equal(serviceMonitor.Spec.TargetLabels))
+}
+
+func TestStargateServiceMonitorSpec(t *testing.T) {
+	g := NewWithT(t)
+
+	spec := StargateServiceMonitorSpec("test-dc1-stargate", "test", "dc1", "30s")
+	g.Expect(spec.Selector.MatchLabels).To(Equal(map[string]string{"app": "test-dc1-stargate"}))
+	endpoint := spec.Endpoints[0]
+	g.Expect(endpoint.Interval).To(Equal("30s"))
+	g.Expect(endpoint.MetricRelabelings).To(ContainElement(RelabelConfig{
+		Regex:        stargateClientRequestRegex,
+		Replacement:  "dc1",
+		SourceLabels: []string{"__name__"},
+		TargetLabel:  "dc",
+	}))
+	g.Expect(endpoint.MetricRelabelings).To(ContainElement(RelabelConfig{
+		Regex:        `persistence_cassandra_(\d_\d+)_org_apache_cassandra_metrics_ClientRequest_(\w+)_CASRead.*`,
+		Replacement:  "cas_read",
+		SourceLabels: []string{"__name__"},
+		TargetLabel:  "request_type",
+	}))
+	g.Expect(endpoint.Relabelings[0].TargetLabel).To(Equal("rack"))
+}
+
+func TestDatacenterRules(t *testing.T) {
+	g := NewWithT(t)
+
+	spec, err := DatacenterRules(nil, "test", "dc1", "k8ssandra")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(spec.Groups).To(HaveLen(1))
+	g.Expect(spec.Groups[0].Name).To(Equal("cassandra-test-dc1"))
+	g.Expect(spec.Groups[0].Rules[0].Alert).To(Equal("CassandraNodeDown"))
+	g.Expect(spec.Groups[0].Rules[0].Expr).To(Equal(`up{cassandra_datastax_com_cluster="test",cassandra_datastax_com_datacenter="dc1"} == 0`))
+}
+
+func TestLoadDashboards(t *testing.T) {
+	g := NewWithT(t)
+
+	dashboards, err := LoadDashboards(chartDir + "/dashboards")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(dashboards).To(HaveKey("overview"))
+	g.Expect(dashboards).To(HaveKey("stargate"))
+
+	dashboards, err = LoadDashboards(t.Name())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(dashboards).To(BeEmpty())
+}
+
+func TestDatacenterDashboard(t *testing.T) {
+	g := NewWithT(t)
+
+	dashboards, err := LoadDashboards(chartDir + "/dashboards")
+	g.Expect(err).ToNot(HaveOccurred())
+	data, err := DatacenterDashboard(dashboards["overview"], DashboardUID("k8ssandra", "test-dc1-overview-dashboard"), "test", "dc1")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	dashboard := struct {
+		Title      string `json:"title"`
+		UID        string `json:"uid"`
+		Templating struct {
+			List []struct {
+				Name    string `json:"name"`
+				Current struct {
+					Value string `json:"value"`
+				} `json:"current"`
+			} `json:"list"`
+		} `json:"templating"`
+	}{}
+	g.Expect(json.Unmarshal(data, &dashboard)).To(Succeed())
+	g.Expect(dashboard.Title).To(Equal("Cassandra Overview (test/dc1)"))
+	g.Expect(dashboard.UID).To(HaveLen(dashboardUIDLength))
+	values := make(map[string]string)
+	for _, variable := range dashboard.Templating.List {
+		values[variable.Name] = variable.Current.Value
+	}
+	g.Expect(values).To(HaveKeyWithValue("cluster", "test"))
+	g.Expect(values).To(HaveKeyWithValue("dc", "dc1"))
+
+	g.Expect(DashboardUID("k8ssandra", "test-dc1-overview-dashboard")).ToNot(Equal(DashboardUID("other", "test-dc1-overview-dashboard")))
+
+	_, err = DatacenterDashboard([]byte("{"), "uid", "test", "dc1")
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,56 @@
+package monitoring
+
+import (
+	"fmt"
+
+	api "github.com/k8ssandra/k8ssandra/pkg/apis/k8ssandra/v1alpha1"
+)
+
+// PrometheusRuleSpec is the spec of a PrometheusRule
+type PrometheusRuleSpec struct {
+	Groups []RuleGroup `json:"groups"`
+}
+
+// RuleGroup is a group of Prometheus rules evaluated together
+type RuleGroup struct {
+	Name     string `json:"name"`
+	Interval string `json:"interval,omitempty"`
+	Rules    []Rule `json:"rules"`
+}
+
+// Rule is a Prometheus alerting or recording rule
+type Rule struct {
+	Alert       string            `json:"alert,omitempty"`
+	Record      string            `json:"record,omitempty"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// DatacenterRules returns the spec of the PrometheusRule of a datacenter, holding its alerting rules. alerts overrides
+// their default settings, it may be nil.
+func DatacenterRules(alerts *api.AlertsConfig, clusterName, dc, namespace string) (PrometheusRuleSpec, error) {
+	rules, err := AlertingRules(alerts, clusterName, dc, namespace)
+	if err != nil {
+		return PrometheusRuleSpec{}, err
+	}
+	return PrometheusRuleSpec{
+		Groups: []RuleGroup{{
+			Name:  fmt.Sprintf("cassandra-%s-%s", clusterName, dc),
+			Rules: rules,
+		}},
+	}, nil
+}
+
+// labelName returns the name of the Prometheus label a Kubernetes label is turned into by the targetLabels of a
+// ServiceMonitor
+func labelName(label string) string {
+	name := []byte(label)
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
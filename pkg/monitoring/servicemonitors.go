@@ -0,0 +1,225 @@
+// Package monitoring builds the Prometheus Operator ServiceMonitors and PrometheusRules and the Grafana dashboards of
+// the CassandraDatacenters of a cluster. It holds the subset of the Prometheus Operator API they use, so that the
+// module does not depend on the Prometheus Operator. The objects are the ones of the chart, parameterized with the
+// cluster and the datacenter.
+package monitoring
+
+import (
+	"fmt"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// DefaultScrapeInterval is the scrape interval of the ServiceMonitors when none is configured
+	DefaultScrapeInterval = "15s"
+
+	// mcacPort is the port of the Metrics Collector for Apache Cassandra agent in the Cassandra pods
+	mcacPort = 9103
+)
+
+var (
+	// ServiceMonitorGVK is the kind of the Prometheus Operator ServiceMonitors
+	ServiceMonitorGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}
+
+	// PrometheusRuleGVK is the kind of the Prometheus Operator PrometheusRules
+	PrometheusRuleGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PrometheusRule"}
+)
+
+// ServiceMonitorSpec is the spec of a ServiceMonitor
+type ServiceMonitorSpec struct {
+	Selector     LabelSelector `json:"selector"`
+	TargetLabels []string      `json:"targetLabels,omitempty"`
+	Endpoints    []Endpoint    `json:"endpoints"`
+}
+
+// LabelSelector selects the Services scraped by a ServiceMonitor
+type LabelSelector struct {
+	MatchLabels      map[string]string          `json:"matchLabels,omitempty"`
+	MatchExpressions []LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+}
+
+// LabelSelectorRequirement is a requirement of a LabelSelector
+type LabelSelectorRequirement struct {
+	Key      string   `json:"key"`
+	Operator string   `json:"operator"`
+	Values   []string `json:"values,omitempty"`
+}
+
+// Endpoint is a scraped endpoint of the Services selected by a ServiceMonitor
+type Endpoint struct {
+	Port              string          `json:"port,omitempty"`
+	TargetPort        *int            `json:"targetPort,omitempty"`
+	Path              string          `json:"path,omitempty"`
+	Scheme            string          `json:"scheme,omitempty"`
+	Interval          string          `json:"interval,omitempty"`
+	ScrapeTimeout     string          `json:"scrapeTimeout,omitempty"`
+	Relabelings       []RelabelConfig `json:"relabelings,omitempty"`
+	MetricRelabelings []RelabelConfig `json:"metricRelabelings,omitempty"`
+}
+
+// RelabelConfig is a Prometheus relabeling rule
+type RelabelConfig struct {
+	Action       string   `json:"action,omitempty"`
+	SourceLabels []string `json:"sourceLabels,omitempty"`
+	Separator    string   `json:"separator,omitempty"`
+	Regex        string   `json:"regex,omitempty"`
+	Replacement  string   `json:"replacement,omitempty"`
+	TargetLabel  string   `json:"targetLabel,omitempty"`
+}
+
+// CassandraServiceMonitorSpec returns the spec of the ServiceMonitor scraping the metrics of the Cassandra nodes of
+// a datacenter, exposed by the MCAC agent
+func CassandraServiceMonitorSpec(clusterName, dc, interval string) ServiceMonitorSpec {
+	port := mcacPort
+	return ServiceMonitorSpec{
+		Selector: LabelSelector{
+			MatchLabels: map[string]string{
+				"app.kubernetes.io/managed-by": "cass-operator",
+				cassdcapi.PromMetricsLabel:     "true",
+				cassdcapi.ClusterLabel:         clusterName,
+				cassdcapi.DatacenterLabel:      dc,
+			},
+		},
+		TargetLabels: []string{cassdcapi.ClusterLabel, cassdcapi.DatacenterLabel},
+		Endpoints: []Endpoint{{
+			Port:              "prometheus",
+			TargetPort:        &port,
+			Path:              "/metrics",
+			Scheme:            "http",
+			Interval:          interval,
+			ScrapeTimeout:     interval,
+			MetricRelabelings: mcacRelabelings,
+		}},
+	}
+}
+
+// StargateServiceMonitorSpec returns the spec of the ServiceMonitor scraping the metrics of the Stargate whose pods
+// have the given app label. Stargate does not label its Cassandra client request metrics with the cluster and the
+// datacenter as MCAC does, they are added so that the dashboards can filter them the same way. The rack is taken
+// from the label of the pods.
+func StargateServiceMonitorSpec(app, clusterName, dc, interval string) ServiceMonitorSpec {
+	relabelings := []RelabelConfig{
+		{Regex: stargateClientRequestRegex, Replacement: dc, SourceLabels: []string{"__name__"}, TargetLabel: "dc"},
+		{Regex: stargateClientRequestRegex, Replacement: clusterName, SourceLabels: []string{"__name__"}, TargetLabel: "cluster"},
+	}
+	for _, requestType := range stargateRequestTypes {
+		relabelings = append(relabelings, RelabelConfig{
+			Regex:        fmt.Sprintf(`persistence_cassandra_(\d_\d+)_org_apache_cassandra_metrics_ClientRequest_(\w+)_%s.*`, requestType.metric),
+			Replacement:  requestType.label,
+			SourceLabels: []string{"__name__"},
+			TargetLabel:  "request_type",
+		})
+	}
+	relabelings = append(relabelings, stargateRenames...)
+
+	return ServiceMonitorSpec{
+		Selector: LabelSelector{MatchLabels: map[string]string{"app": app}},
+		Endpoints: []Endpoint{{
+			Port:          "health",
+			Path:          "/metrics",
+			Scheme:        "http",
+			Interval:      interval,
+			ScrapeTimeout: interval,
+			Relabelings: []RelabelConfig{{
+				SourceLabels: []string{"__meta_kubernetes_pod_label_k8ssandra_io_rack"},
+				TargetLabel:  "rack",
+			}},
+			MetricRelabelings: relabelings,
+		}},
+	}
+}
+
+const stargateClientRequestRegex = `persistence_cassandra_(\d_\d+)_org_apache_cassandra_metrics_ClientRequest_.*`
+
+// stargateRequestTypes maps the request types of the Stargate metric names to the request_type label
+var stargateRequestTypes = []struct{ metric, label string }{
+	{"Read", "read"},
+	{"Write", "write"},
+	{"CASRead", "cas_read"},
+	{"CASWrite", "cas_write"},
+	{"RangeSlice", "range_slice"},
+	{"ViewWrite", "view_write"},
+}
+
+// stargateRenames renames the Stargate client request metrics
+var stargateRenames = []RelabelConfig{
+	stargateRename("Latency_(Read|Write|CASRead|CASWrite|RangeSlice|ViewWrite)_count", "stargate_client_request_latency_total"),
+	stargateRename("Latency_(Read|Write|CASRead|CASWrite|RangeSlice|ViewWrite)", "stargate_client_request_latency_quantile"),
+	stargateRename("Failures_(Read|Write|CASRead|CASWrite|RangeSlice|ViewWrite)_total", "stargate_client_request_failures_total"),
+	stargateRename("Timeouts_(Read|Write|CASRead|CASWrite|RangeSlice|ViewWrite)_total", "stargate_client_request_timeouts_total"),
+	stargateRename("Unavailables_(Read|Write|CASRead|CASWrite|RangeSlice|ViewWrite)_total", "stargate_client_request_unavailables_total"),
+	stargateRename("ConditionNotMet_(Read|Write|CASRead|CASWrite|RangeSlice|ViewWrite)", "stargate_client_request_condition_not_met_total"),
+	stargateRename("UnfinishedCommit_(Read|Write|CASRead|CASWrite|RangeSlice|ViewWrite)", "stargate_client_request_unfinished_commit_total"),
+	stargateRename("ContentionHistogram_(Read|Write|CASRead|CASWrite|RangeSlice|ViewWrite)_count", "stargate_client_request_contention_histogran_total"),
+}
+
+func stargateRename(metric, name string) RelabelConfig {
+	return RelabelConfig{
+		Regex:        `persistence_cassandra_(\d_\d+)_org_apache_cassandra_metrics_ClientRequest_` + metric,
+		Replacement:  name,
+		SourceLabels: []string{"__name__"},
+		TargetLabel:  "__name__",
+	}
+}
+
+// mcacRelabelings turn the metric names of MCAC into the ones of the dashboards, they must be kept in sync with the
+// ServiceMonitor of the chart
+var mcacRelabelings = []RelabelConfig{
+	{Action: "drop", SourceLabels: []string{"__name__"}, Regex: ".*rate_(mean|1m|5m|15m)"},
+	{SourceLabels: []string{"__name__"}, Regex: "(collectd_mcac_.+)", Replacement: "${1}", TargetLabel: "prom_name"},
+	{SourceLabels: []string{"prom_name"}, Regex: `.+_bucket_(\d+)`, Replacement: "${1}", TargetLabel: "le"},
+	{SourceLabels: []string{"prom_name"}, Regex: ".+_bucket_inf", Replacement: "+Inf", TargetLabel: "le"},
+	{SourceLabels: []string{"prom_name"}, Regex: `.*_histogram_p(\d+)`, Replacement: ".${1}", TargetLabel: "quantile"},
+	{SourceLabels: []string{"prom_name"}, Regex: ".*_histogram_min", Replacement: "0", TargetLabel: "quantile"},
+	{SourceLabels: []string{"prom_name"}, Regex: ".*_histogram_max", Replacement: "1", TargetLabel: "quantile"},
+	{Action: "drop", SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.table\.(\w+)`},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.table\.(\w+)\.(\w+)\.(\w+)`, Replacement: "${3}", TargetLabel: "table"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.table\.(\w+)\.(\w+)\.(\w+)`, Replacement: "${2}", TargetLabel: "keyspace"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.table\.(\w+)\.(\w+)\.(\w+)`, Replacement: "mcac_table_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.keyspace\.(\w+)\.(\w+)`, Replacement: "${2}", TargetLabel: "keyspace"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.keyspace\.(\w+)\.(\w+)`, Replacement: "mcac_keyspace_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.thread_pools\.(\w+)\.(\w+)\.(\w+).*`, Replacement: "${2}", TargetLabel: "pool_type"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.thread_pools\.(\w+)\.(\w+)\.(\w+).*`, Replacement: "${3}", TargetLabel: "pool_name"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.thread_pools\.(\w+)\.(\w+)\.(\w+).*`, Replacement: "mcac_thread_pools_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.client_request\.(\w+)\.(\w+)$`, Replacement: "${2}", TargetLabel: "request_type"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.client_request\.(\w+)\.(\w+)$`, Replacement: "mcac_client_request_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.client_request\.(\w+)\.(\w+)\.(\w+)$`, Replacement: "${3}", TargetLabel: "cl"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.client_request\.(\w+)\.(\w+)\.(\w+)$`, Replacement: "${2}", TargetLabel: "request_type"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.client_request\.(\w+)\.(\w+)\.(\w+)$`, Replacement: "mcac_client_request_${1}_cl", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.cache\.(\w+)\.(\w+)`, Replacement: "${2}", TargetLabel: "cache_name"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.cache\.(\w+)\.(\w+)`, Replacement: "mcac_cache_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.cql\.(\w+)`, Replacement: "mcac_cql_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.dropped_message\.(\w+)\.(\w+)`, Replacement: "${2}", TargetLabel: "message_type"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.dropped_message\.(\w+)\.(\w+)`, Replacement: "mcac_dropped_message_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.streaming\.(\w+)\.(.+)$`, Replacement: "${2}", TargetLabel: "peer_ip"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.streaming\.(\w+)\.(.+)$`, Replacement: "mcac_streaming_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.streaming\.(\w+)$`, Replacement: "mcac_streaming_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.commit_log\.(\w+)`, Replacement: "mcac_commit_log_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.compaction\.(\w+)`, Replacement: "mcac_compaction_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.storage\.(\w+)`, Replacement: "mcac_storage_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.batch\.(\w+)`, Replacement: "mcac_batch_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.client\.(\w+)`, Replacement: "mcac_client_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.buffer_pool\.(\w+)`, Replacement: "mcac_buffer_pool_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.index\.(\w+)`, Replacement: "mcac_sstable_index_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.hinted_hand_off_manager\.([^\-]+)-(\w+)`, Replacement: "${2}", TargetLabel: "peer_ip"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.hinted_hand_off_manager\.([^\-]+)-(\w+)`, Replacement: "mcac_hints_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.hints_service\.hints_delays\-(\w+)`, Replacement: "${1}", TargetLabel: "peer_ip"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.hints_service\.hints_delays\-(\w+)`, Replacement: "mcac_hints_hints_delays", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.hints_service\.([^\-]+)`, Replacement: "mcac_hints_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.memtable_pool\.(\w+)`, Replacement: "mcac_memtable_pool_${1}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `com\.datastax\.bdp\.type\.performance_objects\.name\.cql_slow_log\.metrics\.queries_latency`, Replacement: "mcac_cql_slow_log_query_latency", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `org\.apache\.cassandra\.metrics\.read_coordination\.(.*)`, Replacement: "$1", TargetLabel: "read_type"},
+	{SourceLabels: []string{"mcac"}, Regex: `jvm\.gc\.(\w+)\.(\w+)`, Replacement: "${1}", TargetLabel: "collector_type"},
+	{SourceLabels: []string{"mcac"}, Regex: `jvm\.gc\.(\w+)\.(\w+)`, Replacement: "mcac_jvm_gc_${2}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `jvm\.memory\.(\w+)\.(\w+)`, Replacement: "${1}", TargetLabel: "memory_type"},
+	{SourceLabels: []string{"mcac"}, Regex: `jvm\.memory\.(\w+)\.(\w+)`, Replacement: "mcac_jvm_memory_${2}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `jvm\.memory\.pools\.(\w+)\.(\w+)`, Replacement: "${2}", TargetLabel: "pool_name"},
+	{SourceLabels: []string{"mcac"}, Regex: `jvm\.memory\.pools\.(\w+)\.(\w+)`, Replacement: "mcac_jvm_memory_pool_${2}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `jvm\.fd\.usage`, Replacement: "mcac_jvm_fd_usage", TargetLabel: "__name__"},
+	{SourceLabels: []string{"mcac"}, Regex: `jvm\.buffers\.(\w+)\.(\w+)`, Replacement: "${1}", TargetLabel: "buffer_type"},
+	{SourceLabels: []string{"mcac"}, Regex: `jvm\.buffers\.(\w+)\.(\w+)`, Replacement: "mcac_jvm_buffer_${2}", TargetLabel: "__name__"},
+	{SourceLabels: []string{"__name__", "prom_name"}, Separator: ";", Regex: "(mcac_.*);.*(_micros_bucket|_bucket|_micros_count_total|_count_total|_total|_micros_sum|_sum|_stddev).*", Replacement: "${1}${2}", TargetLabel: "__name__"},
+	{Action: "labeldrop", Regex: "prom_name"},
+}
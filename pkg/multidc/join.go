@@ -0,0 +1,141 @@
+package multidc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8ssandra/k8ssandra/pkg/replication"
+	"github.com/k8ssandra/k8ssandra/pkg/rollout"
+	"github.com/k8ssandra/k8ssandra/pkg/scale"
+)
+
+// Joiner brings a new datacenter into an existing Cassandra cluster, once its release is installed with the seeds of
+// the existing datacenters
+type Joiner struct {
+	Client client.Client
+	// Session runs CQL statements on a node of the new datacenter
+	Session replication.Executor
+	// Nodetool runs nodetool on the pods of the new datacenter
+	Nodetool scale.NodetoolFunc
+
+	// Datacenter is the CassandraDatacenter of the new datacenter
+	Datacenter types.NamespacedName
+	// SourceDatacenter is the existing datacenter the data is streamed from
+	SourceDatacenter string
+	// Keyspaces are replicated to the new datacenter, on top of the system keyspaces
+	Keyspaces []string
+	// ReplicationFactor of the keyspaces in the new datacenter, replication.TargetFactor if 0
+	ReplicationFactor int
+
+	// Timeout bounds the wait for the new datacenter to be ready
+	Timeout time.Duration
+}
+
+// Join waits for the new datacenter to be ready and to see the source datacenter, alters the replication of the
+// keyspaces to include the new datacenter, then rebuilds its nodes one at a time from the source datacenter
+func (j *Joiner) Join(ctx context.Context) error {
+	cassdc, err := j.waitForDatacenter(ctx)
+	if err != nil {
+		return err
+	}
+
+	pods, err := j.pods(ctx, cassdc)
+	if err != nil {
+		return err
+	}
+	if err := j.checkSource(ctx, pods[0]); err != nil {
+		return err
+	}
+
+	rf := j.ReplicationFactor
+	if rf == 0 {
+		rf = replication.TargetFactor(cassdc)
+	}
+	if rf > int(cassdc.Spec.Size) {
+		return fmt.Errorf("replication factor %d is higher than the %d nodes of datacenter %s", rf, cassdc.Spec.Size, cassdc.Name)
+	}
+
+	keyspaces := append(append([]string{}, replication.SystemKeyspaces...), j.Keyspaces...)
+	altered, err := replication.AddDatacenter(ctx, j.Session, keyspaces, cassdc.Name, rf)
+	if len(altered) > 0 {
+		log.Printf("Replicated keyspaces %s to datacenter %s with a replication factor of %d", strings.Join(altered, ", "), cassdc.Name, rf)
+	}
+	if err != nil {
+		return err
+	}
+
+	return Rebuild(ctx, j.Nodetool, pods, j.SourceDatacenter)
+}
+
+// Rebuild streams the data of the source datacenter to each pod, one pod at a time
+func Rebuild(ctx context.Context, nodetool scale.NodetoolFunc, pods []string, sourceDatacenter string) error {
+	start := time.Now()
+	for i, pod := range pods {
+		log.Printf("Rebuilding pod %s from datacenter %s (%d/%d)", pod, sourceDatacenter, i+1, len(pods))
+		podStart := time.Now()
+		if _, err := nodetool(ctx, pod, "rebuild", "--", sourceDatacenter); err != nil {
+			return fmt.Errorf("rebuild of pod %s failed, run it again once the cause is fixed: %w", pod, err)
+		}
+		log.Printf("Rebuilt pod %s in %s", pod, time.Since(podStart).Round(time.Second))
+	}
+	log.Printf("Rebuilt %d pods in %s", len(pods), time.Since(start).Round(time.Second))
+	return nil
+}
+
+// checkSource checks that the source datacenter is part of the ring seen by the pod, which is not the case if the
+// seeds were not exchanged
+func (j *Joiner) checkSource(ctx context.Context, pod string) error {
+	output, err := j.Nodetool(ctx, pod, "status")
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "Datacenter: "+j.SourceDatacenter {
+			return nil
+		}
+	}
+	return fmt.Errorf("datacenter %s is not in the ring seen by pod %s, check that the seeds of the existing datacenters were published and are reachable", j.SourceDatacenter, pod)
+}
+
+func (j *Joiner) waitForDatacenter(ctx context.Context) (*cassdcapi.CassandraDatacenter, error) {
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	err := wait.PollImmediate(rollout.PollInterval, j.Timeout, func() (bool, error) {
+		if err := j.Client.Get(ctx, j.Datacenter, cassdc); err != nil {
+			return false, err
+		}
+		return rollout.DatacenterReady(ctx, j.Client, cassdc)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CassandraDatacenter %s is not ready: %w", j.Datacenter, err)
+	}
+	if cassdc.Name == j.SourceDatacenter {
+		return nil, fmt.Errorf("the source datacenter must be another datacenter than %s", cassdc.Name)
+	}
+	return cassdc, nil
+}
+
+func (j *Joiner) pods(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter) ([]string, error) {
+	pods := &corev1.PodList{}
+	if err := j.Client.List(ctx, pods, client.InNamespace(cassdc.Namespace), client.MatchingLabels(cassdc.GetDatacenterLabels())); err != nil {
+		return nil, fmt.Errorf("failed to list pods of CassandraDatacenter %s: %w", cassdc.Name, err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("CassandraDatacenter %s has no pod", cassdc.Name)
+	}
+	names := make([]string, len(pods.Items))
+	for i, pod := range pods.Items {
+		names[i] = pod.Name
+	}
+	sort.Strings(names)
+	return names, nil
+}
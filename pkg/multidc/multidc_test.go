@@ -0,0 +1,171 @@
+package multidc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k8ssandra/k8ssandra/pkg/rollout"
+)
+
+const testNamespace = "k8ssandra"
+
+const keyspacesOutput = `
+ [json]
+------
+ {"keyspace_name": "system_auth", "replication": {"class": "org.apache.cassandra.locator.NetworkTopologyStrategy", "dc1": "3"}}
+ {"keyspace_name": "system_traces", "replication": {"class": "org.apache.cassandra.locator.SimpleStrategy", "replication_factor": "2"}}
+ {"keyspace_name": "app", "replication": {"class": "org.apache.cassandra.locator.NetworkTopologyStrategy", "dc1": "3"}}
+`
+
+// the session runs on a node of dc2, which sees the three nodes of dc1 and the other node of dc2 as peers
+const localOutput = `
+ [json]
+------
+ {"data_center": "dc2"}
+`
+
+const peersOutput = `
+ [json]
+------
+ {"data_center": "dc1"}
+ {"data_center": "dc1"}
+ {"data_center": "dc1"}
+ {"data_center": "dc2"}
+`
+
+type fakeSession struct {
+	statements []string
+}
+
+func (s *fakeSession) Execute(ctx context.Context, statements string) (string, error) {
+	switch {
+	case strings.Contains(statements, "system.local"):
+		return localOutput, nil
+	case strings.Contains(statements, "system.peers"):
+		return peersOutput, nil
+	case strings.HasPrefix(statements, "SELECT"):
+		return keyspacesOutput, nil
+	}
+	s.statements = append(s.statements, statements)
+	return "", nil
+}
+
+func datacenter(name, release string, size int32) *cassdcapi.CassandraDatacenter {
+	return &cassdcapi.CassandraDatacenter{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: name, Labels: map[string]string{instanceLabel: release}},
+		Spec:       cassdcapi.CassandraDatacenterSpec{ClusterName: "test", Size: size},
+		Status: cassdcapi.CassandraDatacenterStatus{
+			CassandraOperatorProgress: cassdcapi.ProgressReady,
+			Conditions:                []cassdcapi.DatacenterCondition{{Type: cassdcapi.DatacenterReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func cassandraPod(dc *cassdcapi.CassandraDatacenter, index int, seed bool) *corev1.Pod {
+	labels := dc.GetDatacenterLabels()
+	if seed {
+		labels[cassdcapi.SeedNodeLabel] = "true"
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: fmt.Sprintf("test-%s-default-sts-%d", dc.Name, index), Labels: labels},
+		Status:     corev1.PodStatus{PodIP: fmt.Sprintf("10.0.%d.%d", len(dc.Name), index)},
+	}
+}
+
+func newTestClient(objects ...runtime.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = cassdcapi.AddToScheme(scheme)
+	return fake.NewFakeClientWithScheme(scheme, objects...)
+}
+
+func TestSeeds(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	dc1 := datacenter("dc1", "source", 3)
+	c := newTestClient(dc1, cassandraPod(dc1, 0, true), cassandraPod(dc1, 1, true), cassandraPod(dc1, 2, false))
+
+	seeds, err := Seeds(ctx, c, testNamespace, "source")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(seeds).To(Equal(&SeedList{ClusterName: "test", Datacenters: []string{"dc1"}, Addresses: []string{"10.0.3.0", "10.0.3.1"}}))
+
+	_, err = Seeds(ctx, c, testNamespace, "other")
+	g.Expect(err).To(MatchError(ContainSubstring("has no CassandraDatacenter")))
+
+	key := types.NamespacedName{Namespace: testNamespace, Name: SeedsConfigMapName("test")}
+	g.Expect(PublishSeeds(ctx, c, key, seeds)).To(Succeed())
+	seeds.Addresses = []string{"10.0.3.2"}
+	g.Expect(PublishSeeds(ctx, c, key, seeds)).To(Succeed())
+
+	cm := &corev1.ConfigMap{}
+	g.Expect(c.Get(ctx, key, cm)).To(Succeed())
+	g.Expect(cm.Data).To(Equal(map[string]string{SeedsKey: "10.0.3.2", ClusterNameKey: "test", DatacentersKey: "dc1"}))
+
+	seeds.ClusterName = "other"
+	g.Expect(PublishSeeds(ctx, c, key, seeds)).To(MatchError(ContainSubstring("holds the seeds of cluster test")))
+}
+
+func TestJoin(t *testing.T) {
+	g := NewWithT(t)
+
+	dc2 := datacenter("dc2", "target", 2)
+	replicas := int32(2)
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "test-dc2-default-sts", Labels: dc2.GetDatacenterLabels()},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status:     appsv1.StatefulSetStatus{Replicas: 2, ReadyReplicas: 2, UpdatedReplicas: 2},
+	}
+	c := newTestClient(dc2, sts, cassandraPod(dc2, 1, false), cassandraPod(dc2, 0, true))
+
+	ring := "Datacenter: dc2\n==============\nUN  10.0.3.0\n"
+	commands := make([]string, 0)
+	nodetool := func(ctx context.Context, pod string, args ...string) (string, error) {
+		commands = append(commands, pod+" "+strings.Join(args, " "))
+		return ring, nil
+	}
+
+	session := &fakeSession{}
+	rollout.PollInterval = 10 * time.Millisecond
+	joiner := &Joiner{
+		Client:           c,
+		Session:          session,
+		Nodetool:         nodetool,
+		Datacenter:       types.NamespacedName{Namespace: testNamespace, Name: "dc2"},
+		SourceDatacenter: "dc1",
+		Keyspaces:        []string{"app"},
+		Timeout:          time.Second,
+	}
+
+	// the new datacenter does not see the source datacenter until the seeds are exchanged
+	g.Expect(joiner.Join(context.Background())).To(MatchError(ContainSubstring("datacenter dc1 is not in the ring")))
+	g.Expect(session.statements).To(BeEmpty())
+
+	ring = "Datacenter: dc1\n==============\nUN  10.0.3.0\nDatacenter: dc2\n==============\nUN  10.0.4.0\n"
+	commands = commands[:0]
+	g.Expect(joiner.Join(context.Background())).To(Succeed())
+	g.Expect(session.statements).To(Equal([]string{
+		`ALTER KEYSPACE "system_auth" WITH replication = {'class': 'NetworkTopologyStrategy', 'dc1': 3, 'dc2': 2};`,
+		`ALTER KEYSPACE "system_traces" WITH replication = {'class': 'NetworkTopologyStrategy', 'dc1': 2, 'dc2': 2};`,
+		`ALTER KEYSPACE "app" WITH replication = {'class': 'NetworkTopologyStrategy', 'dc1': 3, 'dc2': 2};`,
+	}))
+	g.Expect(commands).To(Equal([]string{
+		"test-dc2-default-sts-0 status",
+		"test-dc2-default-sts-0 rebuild -- dc1",
+		"test-dc2-default-sts-1 rebuild -- dc1",
+	}))
+}
@@ -0,0 +1,117 @@
+package multidc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// SeedsKey holds the comma separated addresses of the seed nodes in a seeds ConfigMap. The chart adds them to the
+	// additionalSeeds of the CassandraDatacenter when cassandra.additionalSeedsConfigMap names the ConfigMap.
+	SeedsKey = "seeds"
+	// ClusterNameKey holds the name of the Cassandra cluster the seeds belong to
+	ClusterNameKey = "clusterName"
+	// DatacentersKey holds the comma separated names of the datacenters the seeds belong to
+	DatacentersKey = "datacenters"
+
+	managedByLabel      = "app.kubernetes.io/managed-by"
+	managedByLabelValue = "k8ssandra-client"
+	instanceLabel       = "app.kubernetes.io/instance"
+)
+
+// SeedList are the seed nodes of the datacenters of a release
+type SeedList struct {
+	ClusterName string
+	Datacenters []string
+	Addresses   []string
+}
+
+// SeedsConfigMapName returns the default name of the ConfigMap holding the seeds of a cluster
+func SeedsConfigMapName(clusterName string) string {
+	return clusterName + "-seeds"
+}
+
+// Seeds returns the addresses of the seed pods of the CassandraDatacenters deployed by a release. The addresses are
+// pod IPs, which must be routable from the Kubernetes cluster of the datacenter joining the Cassandra cluster.
+func Seeds(ctx context.Context, c client.Client, namespace, releaseName string) (*SeedList, error) {
+	dcs := &cassdcapi.CassandraDatacenterList{}
+	if err := c.List(ctx, dcs, client.InNamespace(namespace), client.MatchingLabels{instanceLabel: releaseName}); err != nil {
+		return nil, fmt.Errorf("failed to list CassandraDatacenters of release %s: %w", releaseName, err)
+	}
+	if len(dcs.Items) == 0 {
+		return nil, fmt.Errorf("release %s has no CassandraDatacenter in namespace %s", releaseName, namespace)
+	}
+
+	seeds := &SeedList{ClusterName: dcs.Items[0].Spec.ClusterName}
+	for _, dc := range dcs.Items {
+		seeds.Datacenters = append(seeds.Datacenters, dc.Name)
+
+		labels := dc.GetDatacenterLabels()
+		labels[cassdcapi.SeedNodeLabel] = "true"
+		pods := &corev1.PodList{}
+		if err := c.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabels(labels)); err != nil {
+			return nil, fmt.Errorf("failed to list seed pods of CassandraDatacenter %s: %w", dc.Name, err)
+		}
+		for _, pod := range pods.Items {
+			if pod.Status.PodIP != "" {
+				seeds.Addresses = append(seeds.Addresses, pod.Status.PodIP)
+			}
+		}
+	}
+	sort.Strings(seeds.Datacenters)
+	sort.Strings(seeds.Addresses)
+
+	if len(seeds.Addresses) == 0 {
+		return nil, fmt.Errorf("release %s has no seed pod with an IP yet", releaseName)
+	}
+	return seeds, nil
+}
+
+// PublishSeeds writes the seeds to a ConfigMap, creating it if needed. The ConfigMap is typically written to the
+// Kubernetes cluster and namespace of the release of another datacenter.
+func PublishSeeds(ctx context.Context, c client.Client, key types.NamespacedName, seeds *SeedList) error {
+	data := map[string]string{
+		SeedsKey:       strings.Join(seeds.Addresses, ","),
+		ClusterNameKey: seeds.ClusterName,
+		DatacentersKey: strings.Join(seeds.Datacenters, ","),
+	}
+
+	cm := &corev1.ConfigMap{}
+	err := c.Get(ctx, key, cm)
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+				Labels:    map[string]string{managedByLabel: managedByLabelValue},
+			},
+			Data: data,
+		}
+		if err := c.Create(ctx, cm); err != nil {
+			return fmt.Errorf("failed to create ConfigMap %s: %w", key, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get ConfigMap %s: %w", key, err)
+	}
+
+	if cm.Data[ClusterNameKey] != "" && cm.Data[ClusterNameKey] != seeds.ClusterName {
+		return fmt.Errorf("ConfigMap %s holds the seeds of cluster %s, not %s", key, cm.Data[ClusterNameKey], seeds.ClusterName)
+	}
+	patch := client.MergeFrom(cm.DeepCopy())
+	cm.Data = data
+	if err := c.Patch(ctx, cm, patch); err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s: %w", key, err)
+	}
+	return nil
+}
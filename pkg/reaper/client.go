@@ -0,0 +1,159 @@
+package reaper
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+
+	reapergo "github.com/k8ssandra/reaper-client-go/reaper"
+
+	"github.com/k8ssandra/k8ssandra/pkg/credentials"
+)
+
+const (
+	// DefaultOwner is the owner recorded in Reaper for the repairs created by k8ssandra-client
+	DefaultOwner = "k8ssandra-client"
+
+	userAgent = "k8ssandra-client"
+)
+
+// Client is a Reaper REST client. It wraps reaper-client-go and adds the endpoints it does not cover yet:
+// authentication, cluster registration with JMX credentials and repair schedule management.
+type Client struct {
+	reapergo.Client
+
+	baseURL    *url.URL
+	httpClient *http.Client
+}
+
+// NewClient returns a Client for the Reaper instance listening at baseURL. If uiCredentials are given, the client
+// logs in before returning; Reaper instances without authentication enabled are accepted as well.
+func NewClient(ctx context.Context, baseURL *url.URL, uiCredentials *credentials.Credentials) (*Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reaper keeps the authenticated session in a cookie, both clients must share it
+	httpClient := &http.Client{
+		Timeout: 30 * time.Second,
+		Jar:     jar,
+	}
+
+	c := &Client{
+		Client:     reapergo.NewClient(baseURL, reapergo.WithHttpClient(httpClient), reapergo.WithUserAgent(userAgent)),
+		baseURL:    baseURL,
+		httpClient: httpClient,
+	}
+
+	if uiCredentials != nil {
+		if err := c.login(ctx, uiCredentials); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Client) login(ctx context.Context, creds *credentials.Credentials) error {
+	form := url.Values{}
+	form.Set("username", creds.Username)
+	form.Set("password", creds.Password)
+	form.Set("rememberMe", "false")
+
+	res, err := c.do(ctx, http.MethodPost, "/login", nil, form)
+	if err != nil {
+		return fmt.Errorf("failed to log in to Reaper: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		// Authentication is not enabled in this Reaper instance
+		return nil
+	default:
+		return fmt.Errorf("failed to log in to Reaper: %w", responseError(res))
+	}
+}
+
+// EnsureCluster registers the cluster in Reaper through the given seed host unless it is registered already. JMX
+// credentials are sent along when given, so that Reaper can connect to clusters with JMX authentication enabled.
+func (c *Client) EnsureCluster(ctx context.Context, clusterName, seedHost string, jmxCredentials *credentials.Credentials) error {
+	names, err := c.GetClusterNames(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == clusterName {
+			return nil
+		}
+	}
+
+	if jmxCredentials == nil {
+		return c.AddCluster(ctx, clusterName, seedHost)
+	}
+
+	params := url.Values{}
+	params.Set("seedHost", seedHost)
+	params.Set("jmxUsername", jmxCredentials.Username)
+	params.Set("jmxPassword", jmxCredentials.Password)
+
+	res, err := c.do(ctx, http.MethodPut, "/cluster/auth/"+url.PathEscape(clusterName), params, nil)
+	if err != nil {
+		return fmt.Errorf("failed to register cluster %s: %w", clusterName, err)
+	}
+	defer res.Body.Close()
+
+	if err := checkStatus(res, http.StatusOK, http.StatusCreated, http.StatusNoContent); err != nil {
+		return fmt.Errorf("failed to register cluster %s: %w", clusterName, err)
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, params, form url.Values) (*http.Response, error) {
+	u := c.baseURL.ResolveReference(&url.URL{Path: path})
+	if params != nil {
+		u.RawQuery = params.Encode()
+	}
+
+	var body string
+	if form != nil {
+		body = form.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Accept", "application/json;q=0.9,text/plain")
+	req.Header.Set("User-Agent", userAgent)
+
+	return c.httpClient.Do(req)
+}
+
+func checkStatus(res *http.Response, expectedStatuses ...int) error {
+	for _, status := range expectedStatuses {
+		if res.StatusCode == status {
+			return nil
+		}
+	}
+	return responseError(res)
+}
+
+func responseError(res *http.Response) error {
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil || len(b) == 0 {
+		return fmt.Errorf("%s (HTTP status %d)", http.StatusText(res.StatusCode), res.StatusCode)
+	}
+	return fmt.Errorf("%s (HTTP status %d)", strings.TrimSpace(string(b)), res.StatusCode)
+}
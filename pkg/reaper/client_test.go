@@ -0,0 +1,211 @@
+package reaper
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8ssandra/k8ssandra/pkg/credentials"
+)
+
+const (
+	sessionCookie = "JSESSIONID"
+	sessionId     = "reaper-session"
+)
+
+// fakeReaper is a minimal stand-in for Reaper's REST API which requires authentication
+type fakeReaper struct {
+	mu        sync.Mutex
+	clusters  []string
+	schedules map[uuid.UUID]*RepairSchedule
+	requests  []*http.Request
+}
+
+func newFakeReaper() *fakeReaper {
+	return &fakeReaper{schedules: make(map[uuid.UUID]*RepairSchedule)}
+}
+
+func (f *fakeReaper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_ = r.ParseForm()
+	f.requests = append(f.requests, r)
+
+	if r.URL.Path == "/login" {
+		if r.PostForm.Get("username") != "reaper" || r.PostForm.Get("password") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: sessionId, Path: "/"})
+		return
+	}
+
+	if cookie, err := r.Cookie(sessionCookie); err != nil || cookie.Value != sessionId {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/cluster":
+		_ = json.NewEncoder(w).Encode(f.clusters)
+	case r.Method == http.MethodPut && r.URL.Path == "/cluster/auth/test-cluster":
+		f.clusters = append(f.clusters, "test-cluster")
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && r.URL.Path == "/repair_schedule/cluster/test-cluster":
+		schedules := make([]*RepairSchedule, 0)
+		for _, schedule := range f.schedules {
+			schedules = append(schedules, schedule)
+		}
+		_ = json.NewEncoder(w).Encode(schedules)
+	case r.Method == http.MethodPost && r.URL.Path == "/repair_schedule":
+		schedule := &RepairSchedule{
+			Id:                uuid.New(),
+			Owner:             r.Form.Get("owner"),
+			State:             RepairScheduleStateActive,
+			ClusterName:       r.Form.Get("clusterName"),
+			KeyspaceName:      r.Form.Get("keyspace"),
+			Tables:            splitParam(r.Form.Get("tables")),
+			Datacenters:       splitParam(r.Form.Get("datacenters")),
+			Intensity:         0.9,
+			RepairParallelism: "DATACENTER_AWARE",
+		}
+		if intensity := r.Form.Get("intensity"); intensity != "" {
+			schedule.Intensity, _ = strconv.ParseFloat(intensity, 64)
+		}
+		if parallelism := r.Form.Get("repairParallelism"); parallelism != "" {
+			schedule.RepairParallelism = parallelism
+		}
+		schedule.DaysBetween, _ = strconv.Atoi(r.Form.Get("scheduleDaysBetween"))
+		schedule.SegmentCountPerNode, _ = strconv.Atoi(r.Form.Get("segmentCountPerNode"))
+		f.schedules[schedule.Id] = schedule
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(schedule)
+	case r.Method == http.MethodPut:
+		schedule, found := f.schedules[uuid.MustParse(r.URL.Path[len("/repair_schedule/"):])]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		schedule.State = RepairScheduleState(r.Form.Get("state"))
+		_ = json.NewEncoder(w).Encode(schedule)
+	case r.Method == http.MethodDelete:
+		id := uuid.MustParse(r.URL.Path[len("/repair_schedule/"):])
+		schedule, found := f.schedules[id]
+		if !found || schedule.Owner != r.Form.Get("owner") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if schedule.State != RepairScheduleStatePaused {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		delete(f.schedules, id)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func splitParam(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+func newTestClient(t *testing.T, fake *fakeReaper, creds *credentials.Credentials) (*Client, error) {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(context.Background(), u, creds)
+}
+
+func TestLogin(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := newTestClient(t, newFakeReaper(), &credentials.Credentials{Username: "reaper", Password: "wrong"})
+	g.Expect(err).To(HaveOccurred())
+
+	c, err := newTestClient(t, newFakeReaper(), &credentials.Credentials{Username: "reaper", Password: "secret"})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	_, err = c.GetClusterNames(context.Background())
+	g.Expect(err).ToNot(HaveOccurred(), "the session cookie should be shared with the reaper-client-go client")
+}
+
+func TestEnsureCluster(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	fake := newFakeReaper()
+	c, err := newTestClient(t, fake, &credentials.Credentials{Username: "reaper", Password: "secret"})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	jmx := &credentials.Credentials{Username: "jmx", Password: "jmx-secret"}
+	g.Expect(c.EnsureCluster(ctx, "test-cluster", "test-dc-service", jmx)).To(Succeed())
+	g.Expect(fake.clusters).To(ConsistOf("test-cluster"))
+
+	register := fake.requests[len(fake.requests)-1]
+	g.Expect(register.Form.Get("seedHost")).To(Equal("test-dc-service"))
+	g.Expect(register.Form.Get("jmxUsername")).To(Equal("jmx"))
+	g.Expect(register.Form.Get("jmxPassword")).To(Equal("jmx-secret"))
+
+	// not registering the cluster twice
+	requestCount := len(fake.requests)
+	g.Expect(c.EnsureCluster(ctx, "test-cluster", "test-dc-service", jmx)).To(Succeed())
+	g.Expect(fake.requests).To(HaveLen(requestCount + 1))
+}
+
+func TestRepairSchedules(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	fake := newFakeReaper()
+	c, err := newTestClient(t, fake, &credentials.Credentials{Username: "reaper", Password: "secret"})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	options := &RepairScheduleCreateOptions{
+		Tables:              []string{"t1", "t2"},
+		Intensity:           0.5,
+		SegmentCountPerNode: 16,
+		RepairParallelism:   "DATACENTER_AWARE",
+		DaysBetween:         7,
+	}
+	schedule, err := c.CreateRepairSchedule(ctx, "test-cluster", "ks", DefaultOwner, options)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(schedule.KeyspaceName).To(Equal("ks"))
+
+	create := fake.requests[len(fake.requests)-1]
+	g.Expect(create.Form.Get("tables")).To(Equal("t1,t2"))
+	g.Expect(create.Form.Get("intensity")).To(Equal("0.5"))
+	g.Expect(create.Form.Get("segmentCountPerNode")).To(Equal("16"))
+	g.Expect(create.Form.Get("repairParallelism")).To(Equal("DATACENTER_AWARE"))
+	g.Expect(create.Form.Get("scheduleDaysBetween")).To(Equal("7"))
+	g.Expect(create.Form.Get("incrementalRepair")).To(BeEmpty())
+
+	schedules, err := c.ListRepairSchedules(ctx, "test-cluster")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(schedules).To(HaveLen(1))
+	g.Expect(schedules[0].Id).To(Equal(schedule.Id))
+
+	// pausing active schedules before deleting them
+	g.Expect(c.DeleteRepairSchedule(ctx, schedule.Id, DefaultOwner)).To(Succeed())
+
+	schedules, err = c.ListRepairSchedules(ctx, "test-cluster")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(schedules).To(BeEmpty())
+}
@@ -0,0 +1,100 @@
+package reaper
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	reaperapi "github.com/k8ssandra/reaper-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8ssandra/k8ssandra/pkg/credentials"
+)
+
+const (
+	reaperPort = 8080
+)
+
+// Connection holds what is needed to talk to the Reaper instance deployed by a k8ssandra release
+type Connection struct {
+	URL *url.URL
+
+	// ClusterName is the name under which the release's Cassandra cluster is registered in Reaper
+	ClusterName string
+
+	// SeedHost is the service used to register the cluster in Reaper
+	SeedHost string
+
+	// UICredentials are used to log in to Reaper's REST API. They are read from the reaper user secret and are nil
+	// if Cassandra authentication is disabled in the release.
+	UICredentials *credentials.Credentials
+
+	// JmxCredentials are used to register the cluster. They are read from the reaper JMX secret.
+	JmxCredentials *credentials.Credentials
+}
+
+// ReaperName returns the name of the Reaper resource created by the k8ssandra chart
+func ReaperName(releaseName string) string {
+	return releaseName + "-reaper"
+}
+
+// ServiceURL returns the in-cluster URL of the Reaper REST API
+func ServiceURL(reaper *reaperapi.Reaper) *url.URL {
+	return &url.URL{
+		Scheme: "http",
+		Host:   fmt.Sprintf("%s-reaper-service.%s:%d", reaper.Name, reaper.Namespace, reaperPort),
+	}
+}
+
+// LookupConnection resolves the Reaper connection details of a release from its Reaper resource, the
+// CassandraDatacenter Reaper is bound to and the reaper user and JMX secrets.
+func LookupConnection(ctx context.Context, c client.Client, namespace, releaseName string) (*Connection, error) {
+	reaperKey := types.NamespacedName{Namespace: namespace, Name: ReaperName(releaseName)}
+	reaper := &reaperapi.Reaper{}
+	if err := c.Get(ctx, reaperKey, reaper); err != nil {
+		return nil, fmt.Errorf("failed to get Reaper %s, is Reaper enabled in the release?: %w", reaperKey, err)
+	}
+
+	conn := &Connection{
+		URL: ServiceURL(reaper),
+	}
+
+	if backend := reaper.Spec.ServerConfig.CassandraBackend; backend != nil {
+		dcNamespace := backend.CassandraDatacenter.Namespace
+		if dcNamespace == "" {
+			dcNamespace = namespace
+		}
+		dcKey := types.NamespacedName{Namespace: dcNamespace, Name: backend.CassandraDatacenter.Name}
+		cassdc := &cassdcapi.CassandraDatacenter{}
+		if err := c.Get(ctx, dcKey, cassdc); err != nil {
+			return nil, fmt.Errorf("failed to get CassandraDatacenter %s: %w", dcKey, err)
+		}
+		conn.ClusterName = cassdc.Spec.ClusterName
+		conn.SeedHost = fmt.Sprintf("%s.%s", cassdc.GetDatacenterServiceName(), cassdc.Namespace)
+
+		if backend.CassandraUserSecretName != "" {
+			creds, err := credentials.FromSecret(ctx, c, types.NamespacedName{Namespace: namespace, Name: backend.CassandraUserSecretName})
+			if err != nil {
+				return nil, err
+			}
+			conn.UICredentials = creds
+		}
+	}
+
+	if reaper.Spec.ServerConfig.JmxUserSecretName != "" {
+		creds, err := credentials.FromSecret(ctx, c, types.NamespacedName{Namespace: namespace, Name: reaper.Spec.ServerConfig.JmxUserSecretName})
+		if err != nil {
+			return nil, err
+		}
+		conn.JmxCredentials = creds
+	}
+
+	return conn, nil
+}
+
+// Connect returns a logged in Client for the connection
+func (conn *Connection) Connect(ctx context.Context) (*Client, error) {
+	return NewClient(ctx, conn.URL, conn.UICredentials)
+}
@@ -0,0 +1,256 @@
+package reaper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultDaysBetween = 7
+)
+
+var repairParallelisms = []string{"SEQUENTIAL", "PARALLEL", "DATACENTER_AWARE"}
+
+// ScheduleSpec is the declarative definition of a repair schedule, as set in the reaper.repairSchedules.schedules
+// chart value. Zero values are left to Reaper's defaults.
+type ScheduleSpec struct {
+	Keyspace    string   `json:"keyspace"`
+	Tables      []string `json:"tables,omitempty"`
+	Datacenters []string `json:"datacenters,omitempty"`
+	// helm --set passes decimals as strings, the intensity accepts both
+	Intensity           float64 `json:"intensity,omitempty" schema:"number,string"`
+	RepairParallelism   string  `json:"repairParallelism,omitempty"`
+	IncrementalRepair   bool    `json:"incrementalRepair,omitempty"`
+	SegmentCountPerNode int     `json:"segmentCountPerNode,omitempty"`
+	RepairThreadCount   int     `json:"repairThreadCount,omitempty"`
+	DaysBetween         int     `json:"daysBetween,omitempty"`
+	TriggerTime         string  `json:"triggerTime,omitempty"`
+}
+
+// UnmarshalJSON accepts an intensity given as a string as well as a number
+func (s *ScheduleSpec) UnmarshalJSON(data []byte) error {
+	type scheduleSpec ScheduleSpec
+	spec := struct {
+		*scheduleSpec
+		Intensity json.Number `json:"intensity,omitempty"`
+	}{scheduleSpec: (*scheduleSpec)(s)}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return err
+	}
+	if spec.Intensity == "" {
+		s.Intensity = 0
+		return nil
+	}
+	intensity, err := spec.Intensity.Float64()
+	if err != nil {
+		return fmt.Errorf("repair schedule %s: invalid intensity %s", s.key(), spec.Intensity)
+	}
+	s.Intensity = intensity
+	return nil
+}
+
+// ScheduleReconcileResult lists the changes made by ReconcileSchedules
+type ScheduleReconcileResult struct {
+	Created   []string
+	Updated   []string
+	Deleted   []string
+	Unchanged []string
+}
+
+// ScheduleOwner returns the owner set on the repair schedules managed from the values of a release. Only schedules
+// with this owner are touched by ReconcileSchedules, schedules created by other means are left alone.
+func ScheduleOwner(releaseName string) string {
+	return "k8ssandra-" + releaseName
+}
+
+// Validate checks that the spec is accepted by Reaper
+func (s *ScheduleSpec) Validate() error {
+	if s.Keyspace == "" {
+		return fmt.Errorf("repair schedule has no keyspace")
+	}
+	if s.Intensity < 0 || s.Intensity > 1 {
+		return fmt.Errorf("repair schedule %s: intensity must be in (0.0, 1.0]", s.key())
+	}
+	if s.SegmentCountPerNode < 0 || s.SegmentCountPerNode > 1000 {
+		return fmt.Errorf("repair schedule %s: segmentCountPerNode must be in (0, 1000]", s.key())
+	}
+	if s.RepairThreadCount < 0 || s.RepairThreadCount > 4 {
+		return fmt.Errorf("repair schedule %s: repairThreadCount must be in (0, 4]", s.key())
+	}
+	if s.DaysBetween < 0 {
+		return fmt.Errorf("repair schedule %s: daysBetween must be positive", s.key())
+	}
+	if s.RepairParallelism != "" {
+		valid := false
+		for _, parallelism := range repairParallelisms {
+			if s.RepairParallelism == parallelism {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("repair schedule %s: repairParallelism must be one of %s", s.key(), strings.Join(repairParallelisms, ", "))
+		}
+	}
+	if s.IncrementalRepair && s.RepairParallelism != "" && s.RepairParallelism != "PARALLEL" {
+		return fmt.Errorf("repair schedule %s: incremental repairs require PARALLEL repairParallelism", s.key())
+	}
+	return nil
+}
+
+// key identifies the schedule of a keyspace and set of tables
+func (s *ScheduleSpec) key() string {
+	return scheduleKey(s.Keyspace, s.Tables)
+}
+
+func (s *ScheduleSpec) daysBetween() int {
+	if s.DaysBetween == 0 {
+		return defaultDaysBetween
+	}
+	return s.DaysBetween
+}
+
+// matches returns true if the existing schedule has the settings of the spec. Settings left to Reaper's defaults in
+// the spec are not compared, nor is the trigger time which only applies to the first run.
+func (s *ScheduleSpec) matches(schedule *RepairSchedule) bool {
+	if s.daysBetween() != schedule.DaysBetween || s.IncrementalRepair != schedule.IncrementalRepair {
+		return false
+	}
+	if s.Intensity != 0 && s.Intensity != schedule.Intensity {
+		return false
+	}
+	if s.RepairParallelism != "" && s.RepairParallelism != schedule.RepairParallelism {
+		return false
+	}
+	if s.SegmentCountPerNode != 0 && s.SegmentCountPerNode != schedule.SegmentCountPerNode {
+		return false
+	}
+	if s.RepairThreadCount != 0 && s.RepairThreadCount != schedule.RepairThreadCount {
+		return false
+	}
+	return sortedEquals(s.Datacenters, schedule.Datacenters)
+}
+
+func (s *ScheduleSpec) createOptions() *RepairScheduleCreateOptions {
+	return &RepairScheduleCreateOptions{
+		Tables:              s.Tables,
+		Datacenters:         s.Datacenters,
+		Intensity:           s.Intensity,
+		RepairParallelism:   s.RepairParallelism,
+		IncrementalRepair:   s.IncrementalRepair,
+		SegmentCountPerNode: s.SegmentCountPerNode,
+		RepairThreadCount:   s.RepairThreadCount,
+		DaysBetween:         s.daysBetween(),
+		TriggerTime:         s.TriggerTime,
+	}
+}
+
+// ReconcileSchedules creates, recreates and deletes the repair schedules of the cluster which belong to owner so that
+// they match specs. Reaper has no endpoint to modify a schedule's settings, so outdated schedules are deleted and
+// created again.
+func (c *Client) ReconcileSchedules(ctx context.Context, clusterName, owner string, specs []ScheduleSpec) (*ScheduleReconcileResult, error) {
+	desired := make(map[string]*ScheduleSpec, len(specs))
+	for i := range specs {
+		spec := &specs[i]
+		if err := spec.Validate(); err != nil {
+			return nil, err
+		}
+		if _, found := desired[spec.key()]; found {
+			return nil, fmt.Errorf("repair schedule %s is defined more than once", spec.key())
+		}
+		desired[spec.key()] = spec
+	}
+
+	schedules, err := c.ListRepairSchedules(ctx, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ScheduleReconcileResult{}
+	kept := make(map[string]bool)
+
+	for i := range schedules {
+		schedule := &schedules[i]
+		if schedule.Owner != owner {
+			continue
+		}
+
+		key := scheduleKey(schedule.KeyspaceName, schedule.Tables)
+		spec, found := desired[key]
+		if found && !kept[key] && spec.matches(schedule) {
+			kept[key] = true
+			result.Unchanged = append(result.Unchanged, key)
+			continue
+		}
+
+		if err := c.DeleteRepairSchedule(ctx, schedule.Id, owner); err != nil {
+			return result, err
+		}
+		if !found || kept[key] {
+			result.Deleted = append(result.Deleted, key)
+		}
+	}
+
+	for _, key := range sortedKeys(desired) {
+		if kept[key] {
+			continue
+		}
+		spec := desired[key]
+		if _, err := c.CreateRepairSchedule(ctx, clusterName, spec.Keyspace, owner, spec.createOptions()); err != nil {
+			return result, err
+		}
+		if wasDeleted(schedules, owner, key) {
+			result.Updated = append(result.Updated, key)
+		} else {
+			result.Created = append(result.Created, key)
+		}
+	}
+
+	return result, nil
+}
+
+// wasDeleted returns true if a schedule with the key and owner existed before the reconciliation
+func wasDeleted(schedules []RepairSchedule, owner, key string) bool {
+	for i := range schedules {
+		if schedules[i].Owner == owner && scheduleKey(schedules[i].KeyspaceName, schedules[i].Tables) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func scheduleKey(keyspace string, tables []string) string {
+	if len(tables) == 0 {
+		return keyspace
+	}
+	sorted := append([]string{}, tables...)
+	sort.Strings(sorted)
+	return keyspace + "/" + strings.Join(sorted, ",")
+}
+
+func sortedKeys(specs map[string]*ScheduleSpec) []string {
+	keys := make([]string, 0, len(specs))
+	for key := range specs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedEquals(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]string{}, a...)
+	sortedB := append([]string{}, b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package reaper
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/k8ssandra/k8ssandra/pkg/credentials"
+)
+
+func TestReconcileSchedules(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	fake := newFakeReaper()
+	c, err := newTestClient(t, fake, &credentials.Credentials{Username: "reaper", Password: "secret"})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	owner := ScheduleOwner("test")
+
+	// a schedule created outside of the values must survive the reconciliations
+	manual, err := c.CreateRepairSchedule(ctx, "test-cluster", "manual_ks", DefaultOwner, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	specs := []ScheduleSpec{
+		{Keyspace: "ks1", Intensity: 0.5, DaysBetween: 3},
+		{Keyspace: "ks2", Tables: []string{"t2", "t1"}, SegmentCountPerNode: 16},
+	}
+	result, err := c.ReconcileSchedules(ctx, "test-cluster", owner, specs)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Created).To(ConsistOf("ks1", "ks2/t1,t2"))
+	g.Expect(result.Updated).To(BeEmpty())
+	g.Expect(result.Deleted).To(BeEmpty())
+
+	// reconciling again changes nothing
+	result, err = c.ReconcileSchedules(ctx, "test-cluster", owner, specs)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Unchanged).To(ConsistOf("ks1", "ks2/t1,t2"))
+	g.Expect(result.Created).To(BeEmpty())
+
+	// changing the settings of ks1 and removing ks2
+	specs = []ScheduleSpec{
+		{Keyspace: "ks1", Intensity: 0.8, DaysBetween: 3},
+	}
+	result, err = c.ReconcileSchedules(ctx, "test-cluster", owner, specs)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Updated).To(ConsistOf("ks1"))
+	g.Expect(result.Deleted).To(ConsistOf("ks2/t1,t2"))
+
+	schedules, err := c.ListRepairSchedules(ctx, "test-cluster")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(schedules).To(HaveLen(2))
+	for _, schedule := range schedules {
+		if schedule.Owner == owner {
+			g.Expect(schedule.KeyspaceName).To(Equal("ks1"))
+			g.Expect(schedule.Intensity).To(Equal(0.8))
+		} else {
+			g.Expect(schedule.Id).To(Equal(manual.Id))
+		}
+	}
+}
+
+func TestReconcileSchedulesValidation(t *testing.T) {
+	g := NewWithT(t)
+
+	invalid := []ScheduleSpec{
+		{},
+		{Keyspace: "ks", Intensity: 1.5},
+		{Keyspace: "ks", SegmentCountPerNode: 1001},
+		{Keyspace: "ks", RepairParallelism: "EVERYWHERE"},
+		{Keyspace: "ks", IncrementalRepair: true, RepairParallelism: "SEQUENTIAL"},
+		{Keyspace: "ks", DaysBetween: -1},
+	}
+	for _, spec := range invalid {
+		g.Expect(spec.Validate()).ToNot(Succeed(), "%+v should be rejected", spec)
+	}
+
+	fake := newFakeReaper()
+	c, err := newTestClient(t, fake, &credentials.Credentials{Username: "reaper", Password: "secret"})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	duplicates := []ScheduleSpec{
+		{Keyspace: "ks", Tables: []string{"a", "b"}},
+		{Keyspace: "ks", Tables: []string{"b", "a"}},
+	}
+	_, err = c.ReconcileSchedules(context.Background(), "test-cluster", ScheduleOwner("test"), duplicates)
+	g.Expect(err).To(MatchError(ContainSubstring("more than once")))
+}
+
+func TestScheduleSpecUnmarshal(t *testing.T) {
+	g := NewWithT(t)
+
+	specs := make([]ScheduleSpec, 0)
+	g.Expect(json.Unmarshal([]byte(`[{"keyspace":"ks1","intensity":"0.5","daysBetween":3},{"keyspace":"ks2","intensity":0.75},{"keyspace":"ks3"}]`), &specs)).To(Succeed())
+	g.Expect(specs).To(Equal([]ScheduleSpec{
+		{Keyspace: "ks1", Intensity: 0.5, DaysBetween: 3},
+		{Keyspace: "ks2", Intensity: 0.75},
+		{Keyspace: "ks3"},
+	}))
+
+	g.Expect(json.Unmarshal([]byte(`[{"keyspace":"ks1","intensity":"high"}]`), &specs)).ToNot(Succeed())
+}
@@ -0,0 +1,180 @@
+package reaper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// RepairScheduleState is the state of a repair schedule in Reaper
+type RepairScheduleState string
+
+const (
+	RepairScheduleStateActive = RepairScheduleState("ACTIVE")
+	RepairScheduleStatePaused = RepairScheduleState("PAUSED")
+)
+
+// RepairSchedule is a repair schedule as returned by Reaper
+type RepairSchedule struct {
+	Id                  uuid.UUID           `json:"id"`
+	Owner               string              `json:"owner"`
+	State               RepairScheduleState `json:"state"`
+	ClusterName         string              `json:"cluster_name"`
+	KeyspaceName        string              `json:"keyspace_name"`
+	Tables              []string            `json:"column_families"`
+	Datacenters         []string            `json:"datacenters"`
+	Intensity           float64             `json:"intensity"`
+	RepairParallelism   string              `json:"repair_parallelism"`
+	IncrementalRepair   bool                `json:"incremental_repair"`
+	SegmentCountPerNode int                 `json:"segment_count_per_node"`
+	RepairThreadCount   int                 `json:"repair_thread_count"`
+	DaysBetween         int                 `json:"scheduled_days_between"`
+	NextActivation      string              `json:"next_activation"`
+}
+
+// RepairScheduleCreateOptions are the settings of a new repair schedule. Zero values are left to Reaper's defaults.
+type RepairScheduleCreateOptions struct {
+	// Tables restricts the repairs to the given tables, all the tables of the keyspace are repaired if empty
+	Tables []string
+
+	// Datacenters restricts the repairs to the given datacenters
+	Datacenters []string
+
+	// Intensity must be in range (0.0, 1.0]
+	Intensity float64
+
+	// RepairParallelism is one of SEQUENTIAL, PARALLEL or DATACENTER_AWARE
+	RepairParallelism string
+
+	IncrementalRepair bool
+
+	// SegmentCountPerNode must be >0 and <=1000
+	SegmentCountPerNode int
+
+	RepairThreadCount int
+
+	// DaysBetween is the number of days between two runs of the schedule
+	DaysBetween int
+
+	// TriggerTime is the time of the first run, formatted as yyyy-MM-ddTHH:mm:ss. Reaper picks the next
+	// midnight if empty.
+	TriggerTime string
+}
+
+// ListRepairSchedules returns the repair schedules of the given cluster
+func (c *Client) ListRepairSchedules(ctx context.Context, clusterName string) ([]RepairSchedule, error) {
+	res, err := c.do(ctx, http.MethodGet, "/repair_schedule/cluster/"+url.PathEscape(clusterName), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch repair schedules: %w", err)
+	}
+	defer res.Body.Close()
+
+	if err := checkStatus(res, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to fetch repair schedules: %w", err)
+	}
+
+	schedules := make([]RepairSchedule, 0)
+	if err := json.NewDecoder(res.Body).Decode(&schedules); err != nil {
+		return nil, fmt.Errorf("failed to decode repair schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// CreateRepairSchedule creates a repair schedule for the keyspace and returns it
+func (c *Client) CreateRepairSchedule(ctx context.Context, clusterName, keyspace, owner string, options *RepairScheduleCreateOptions) (*RepairSchedule, error) {
+	params := url.Values{}
+	params.Set("clusterName", clusterName)
+	params.Set("keyspace", keyspace)
+	params.Set("owner", owner)
+
+	if options != nil {
+		if len(options.Tables) > 0 {
+			params.Set("tables", strings.Join(options.Tables, ","))
+		}
+		if len(options.Datacenters) > 0 {
+			params.Set("datacenters", strings.Join(options.Datacenters, ","))
+		}
+		if options.Intensity > 0 {
+			params.Set("intensity", strconv.FormatFloat(options.Intensity, 'f', -1, 64))
+		}
+		if options.RepairParallelism != "" {
+			params.Set("repairParallelism", options.RepairParallelism)
+		}
+		if options.IncrementalRepair {
+			params.Set("incrementalRepair", "true")
+		}
+		if options.SegmentCountPerNode > 0 {
+			params.Set("segmentCountPerNode", strconv.Itoa(options.SegmentCountPerNode))
+		}
+		if options.RepairThreadCount > 0 {
+			params.Set("repairThreadCount", strconv.Itoa(options.RepairThreadCount))
+		}
+		if options.DaysBetween > 0 {
+			params.Set("scheduleDaysBetween", strconv.Itoa(options.DaysBetween))
+		}
+		if options.TriggerTime != "" {
+			params.Set("scheduleTriggerTime", options.TriggerTime)
+		}
+	}
+
+	res, err := c.do(ctx, http.MethodPost, "/repair_schedule", params, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repair schedule for %s/%s: %w", clusterName, keyspace, err)
+	}
+	defer res.Body.Close()
+
+	if err := checkStatus(res, http.StatusCreated, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to create repair schedule for %s/%s: %w", clusterName, keyspace, err)
+	}
+
+	schedule := &RepairSchedule{}
+	if err := json.NewDecoder(res.Body).Decode(schedule); err != nil {
+		return nil, fmt.Errorf("failed to decode repair schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// SetRepairScheduleState pauses or resumes a repair schedule
+func (c *Client) SetRepairScheduleState(ctx context.Context, scheduleId uuid.UUID, state RepairScheduleState) error {
+	params := url.Values{}
+	params.Set("state", string(state))
+
+	res, err := c.do(ctx, http.MethodPut, "/repair_schedule/"+scheduleId.String(), params, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update repair schedule %s: %w", scheduleId, err)
+	}
+	defer res.Body.Close()
+
+	if err := checkStatus(res, http.StatusOK, http.StatusNoContent); err != nil {
+		return fmt.Errorf("failed to update repair schedule %s: %w", scheduleId, err)
+	}
+	return nil
+}
+
+// DeleteRepairSchedule deletes a repair schedule. Reaper only deletes paused schedules, so active ones are paused
+// first. The owner must match the one the schedule was created with.
+func (c *Client) DeleteRepairSchedule(ctx context.Context, scheduleId uuid.UUID, owner string) error {
+	if err := c.SetRepairScheduleState(ctx, scheduleId, RepairScheduleStatePaused); err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("owner", owner)
+
+	res, err := c.do(ctx, http.MethodDelete, "/repair_schedule/"+scheduleId.String(), params, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete repair schedule %s: %w", scheduleId, err)
+	}
+	defer res.Body.Close()
+
+	if err := checkStatus(res, http.StatusOK, http.StatusAccepted, http.StatusNoContent); err != nil {
+		return fmt.Errorf("failed to delete repair schedule %s: %w", scheduleId, err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package replication
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8ssandra/k8ssandra/pkg/rollout"
+)
+
+const (
+	instanceLabel = "app.kubernetes.io/instance"
+
+	// maxReplicationFactor is the replication factor of datacenters of that size or more
+	maxReplicationFactor = 3
+)
+
+// ReleaseKeyspaces are the keyspaces created by the components of a k8ssandra release: Reaper's storage and
+// Stargate's auth tables
+var ReleaseKeyspaces = []string{"reaper_db", "data_endpoint_auth"}
+
+// DefaultKeyspaces returns the keyspaces reconciled by default, the system keyspaces and the ones of the release
+func DefaultKeyspaces() []string {
+	return append(append([]string{}, SystemKeyspaces...), ReleaseKeyspaces...)
+}
+
+// TargetFactor returns the replication factor of a datacenter: its size up to 3. Fewer racks than that only log a
+// warning, as lowering the factor to the number of racks would trade durability for rack awareness.
+func TargetFactor(cassdc *cassdcapi.CassandraDatacenter) int {
+	rf := int(cassdc.Spec.Size)
+	if rf > maxReplicationFactor {
+		rf = maxReplicationFactor
+	}
+	if racks := len(cassdc.GetRacks()); racks > 1 && racks < rf {
+		log.Printf("Datacenter %s has %d racks for a replication factor of %d, some racks hold several replicas of the same data",
+			cassdc.Name, racks, rf)
+	}
+	return rf
+}
+
+// Reconciler makes the replication of keyspaces follow the CassandraDatacenters deployed by a release
+type Reconciler struct {
+	Client      client.Client
+	Session     Executor
+	Namespace   string
+	ReleaseName string
+
+	// Keyspaces to reconcile, the ones which do not exist are ignored
+	Keyspaces []string
+	// DryRun logs the statements instead of running them
+	DryRun bool
+	// AllowDecrease lowers the replication factors above their target, which are kept otherwise
+	AllowDecrease bool
+}
+
+// ReconcileResult lists the keyspaces of each outcome of a reconciliation
+type ReconcileResult struct {
+	Altered   []string
+	Unchanged []string
+	Missing   []string
+}
+
+// Reconcile raises the NetworkTopologyStrategy replication factor of each datacenter of the release in each keyspace to
+// its TargetFactor. Factors above their target are only lowered with AllowDecrease, as the replicas of a keyspace may
+// be needed by the consistency levels of its clients. Datacenters which are not part of the release, like the ones of
+// another Kubernetes cluster, keep their replication factor. SimpleStrategy keyspaces are switched to
+// NetworkTopologyStrategy, keeping replicas in every datacenter of the cluster as SimpleStrategy places them regardless
+// of datacenters: each one gets the replication factor of the keyspace, up to its number of nodes.
+func (r *Reconciler) Reconcile(ctx context.Context) (*ReconcileResult, error) {
+	targets, err := r.Targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current, err := Read(ctx, r.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	var clusterNodes map[string]int
+	result := &ReconcileResult{Altered: []string{}, Unchanged: []string{}, Missing: []string{}}
+	for _, name := range r.Keyspaces {
+		keyspace := Find(current, name)
+		if keyspace == nil {
+			result.Missing = append(result.Missing, name)
+			continue
+		}
+
+		factors := make(map[string]int)
+		switch keyspace.Class {
+		case NetworkTopologyStrategy:
+			for dc, rf := range keyspace.Factors {
+				factors[dc] = rf
+			}
+		case SimpleStrategy:
+			if clusterNodes == nil {
+				if clusterNodes, err = ClusterDatacenters(ctx, r.Session); err != nil {
+					return result, err
+				}
+			}
+			for dc, nodes := range clusterNodes {
+				factors[dc] = keyspace.Factors["replication_factor"]
+				if nodes < factors[dc] {
+					factors[dc] = nodes
+				}
+			}
+		default:
+			return result, fmt.Errorf("keyspace %s uses %s, its replication cannot be changed", name, keyspace.Class)
+		}
+
+		changed := keyspace.Class != NetworkTopologyStrategy
+		for dc, rf := range targets {
+			switch {
+			case factors[dc] == rf:
+			case factors[dc] > rf && !r.AllowDecrease:
+				log.Printf("Keeping replication factor %d of datacenter %s in keyspace %s, above its target %d: decreases must be allowed",
+					factors[dc], dc, name, rf)
+			default:
+				factors[dc] = rf
+				changed = true
+			}
+		}
+		if !changed {
+			result.Unchanged = append(result.Unchanged, name)
+			continue
+		}
+
+		statement := AlterKeyspaceStatement(name, factors)
+		if r.DryRun {
+			log.Printf("Would run: %s", statement)
+		} else {
+			log.Printf("Running: %s", statement)
+			if _, err := r.Session.Execute(ctx, statement); err != nil {
+				return result, fmt.Errorf("failed to alter the replication of keyspace %s: %w", name, err)
+			}
+		}
+		result.Altered = append(result.Altered, name)
+	}
+	return result, nil
+}
+
+// Targets returns the target replication factor of each datacenter of the release
+func (r *Reconciler) Targets(ctx context.Context) (map[string]int, error) {
+	dcs, err := r.datacenters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	targets := make(map[string]int, len(dcs))
+	for i := range dcs {
+		targets[dcs[i].Name] = TargetFactor(&dcs[i])
+	}
+	return targets, nil
+}
+
+// Datacenters returns the sorted names of the datacenters of the release
+func (r *Reconciler) Datacenters(ctx context.Context) ([]string, error) {
+	dcs, err := r.datacenters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(dcs))
+	for i, dc := range dcs {
+		names[i] = dc.Name
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// WaitForDatacenters waits until all the datacenters of the release are ready, so that the new replicas can be
+// placed right away
+func (r *Reconciler) WaitForDatacenters(ctx context.Context, timeout time.Duration) error {
+	err := wait.PollImmediate(rollout.PollInterval, timeout, func() (bool, error) {
+		dcs, err := r.datacenters(ctx)
+		if err != nil {
+			return false, err
+		}
+		for i := range dcs {
+			if ready, err := rollout.DatacenterReady(ctx, r.Client, &dcs[i]); err != nil || !ready {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("the CassandraDatacenters of release %s are not ready: %w", r.ReleaseName, err)
+	}
+	return nil
+}
+
+func (r *Reconciler) datacenters(ctx context.Context) ([]cassdcapi.CassandraDatacenter, error) {
+	dcs := &cassdcapi.CassandraDatacenterList{}
+	if err := r.Client.List(ctx, dcs, client.InNamespace(r.Namespace), client.MatchingLabels{instanceLabel: r.ReleaseName}); err != nil {
+		return nil, fmt.Errorf("failed to list CassandraDatacenters of release %s: %w", r.ReleaseName, err)
+	}
+	if len(dcs.Items) == 0 {
+		return nil, fmt.Errorf("release %s has no CassandraDatacenter in namespace %s", r.ReleaseName, r.Namespace)
+	}
+	return dcs.Items, nil
+}
@@ -0,0 +1,148 @@
+package replication
+
+import (
+	"context"
+	"testing"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testNamespace = "k8ssandra"
+
+func datacenter(name string, size int32, racks ...string) *cassdcapi.CassandraDatacenter {
+	cassdc := &cassdcapi.CassandraDatacenter{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: name, Labels: map[string]string{instanceLabel: "test"}},
+		Spec:       cassdcapi.CassandraDatacenterSpec{ClusterName: "test", Size: size},
+	}
+	for _, rack := range racks {
+		cassdc.Spec.Racks = append(cassdc.Spec.Racks, cassdcapi.Rack{Name: rack})
+	}
+	return cassdc
+}
+
+func newTestReconciler(schema *fakeSchema, objects ...runtime.Object) *Reconciler {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = cassdcapi.AddToScheme(scheme)
+
+	return &Reconciler{
+		Client:      fake.NewFakeClientWithScheme(scheme, objects...),
+		Session:     schema,
+		Namespace:   testNamespace,
+		ReleaseName: "test",
+		Keyspaces:   DefaultKeyspaces(),
+	}
+}
+
+func TestTargetFactor(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(TargetFactor(datacenter("dc1", 1))).To(Equal(1))
+	g.Expect(TargetFactor(datacenter("dc1", 6))).To(Equal(3))
+	g.Expect(TargetFactor(datacenter("dc1", 6, "r1", "r2", "r3"))).To(Equal(3))
+	g.Expect(TargetFactor(datacenter("dc1", 4, "r1", "r2"))).To(Equal(3))
+	g.Expect(TargetFactor(datacenter("dc1", 2, "r1", "r2", "r3"))).To(Equal(2))
+}
+
+func TestReconcile(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	schema := newFakeSchema()
+	// dc3 lives in another Kubernetes cluster, its replication is left alone
+	schema.keyspaces["reaper_db"] = map[string]string{"class": "org.apache.cassandra.locator.NetworkTopologyStrategy", "dc1": "3", "dc3": "2"}
+	schema.nodes = []string{"dc1", "dc1", "dc1", "dc2"}
+	r := newTestReconciler(schema, datacenter("dc1", 3), datacenter("dc2", 1))
+
+	result, err := r.Reconcile(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Altered).To(Equal([]string{"system_auth", "system_distributed", "reaper_db"}))
+	g.Expect(result.Missing).To(Equal([]string{"system_traces", "data_endpoint_auth"}))
+
+	keyspaces, err := Read(ctx, schema)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(Find(keyspaces, "system_auth").Factors).To(Equal(map[string]int{"dc1": 3, "dc2": 1}))
+	g.Expect(Find(keyspaces, "system_distributed").Class).To(Equal(NetworkTopologyStrategy))
+	g.Expect(Find(keyspaces, "reaper_db").Factors).To(Equal(map[string]int{"dc1": 3, "dc2": 1, "dc3": 2}))
+
+	// a second run has nothing to do
+	schema.statements = nil
+	result, err = r.Reconcile(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Altered).To(BeEmpty())
+	g.Expect(result.Unchanged).To(Equal([]string{"system_auth", "system_distributed", "reaper_db"}))
+	g.Expect(schema.statements).To(BeEmpty())
+}
+
+func TestReconcileDecrease(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	schema := newFakeSchema()
+	schema.keyspaces["reaper_db"] = map[string]string{"class": "org.apache.cassandra.locator.NetworkTopologyStrategy", "dc1": "3", "dc2": "3"}
+	r := newTestReconciler(schema, datacenter("dc1", 1), datacenter("dc2", 3))
+	r.Keyspaces = []string{"system_auth", "reaper_db"}
+
+	// dc1 was scaled down, its factors are kept until decreases are allowed
+	result, err := r.Reconcile(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Altered).To(Equal([]string{"system_auth"}))
+	g.Expect(result.Unchanged).To(Equal([]string{"reaper_db"}))
+	keyspaces, err := Read(ctx, schema)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(Find(keyspaces, "system_auth").Factors).To(Equal(map[string]int{"dc1": 3, "dc2": 3}))
+	g.Expect(Find(keyspaces, "reaper_db").Factors).To(Equal(map[string]int{"dc1": 3, "dc2": 3}))
+
+	r.AllowDecrease = true
+	result, err = r.Reconcile(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Altered).To(Equal([]string{"system_auth", "reaper_db"}))
+	keyspaces, err = Read(ctx, schema)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(Find(keyspaces, "system_auth").Factors).To(Equal(map[string]int{"dc1": 1, "dc2": 3}))
+	g.Expect(Find(keyspaces, "reaper_db").Factors).To(Equal(map[string]int{"dc1": 1, "dc2": 3}))
+}
+
+func TestReconcileSimpleStrategy(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	schema := newFakeSchema()
+	// dc3 lives in another Kubernetes cluster and holds replicas of the SimpleStrategy keyspace too
+	schema.nodes = []string{"dc1", "dc1", "dc1", "dc3", "dc3"}
+	r := newTestReconciler(schema, datacenter("dc1", 3), datacenter("dc2", 1))
+	r.Keyspaces = []string{"system_distributed"}
+
+	result, err := r.Reconcile(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Altered).To(Equal([]string{"system_distributed"}))
+	keyspaces, err := Read(ctx, schema)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(Find(keyspaces, "system_distributed").Class).To(Equal(NetworkTopologyStrategy))
+	g.Expect(Find(keyspaces, "system_distributed").Factors).To(Equal(map[string]int{"dc1": 3, "dc2": 1, "dc3": 2}))
+}
+
+func TestReconcileDryRun(t *testing.T) {
+	g := NewWithT(t)
+
+	schema := newFakeSchema()
+	r := newTestReconciler(schema, datacenter("dc1", 3), datacenter("dc2", 3))
+	r.DryRun = true
+
+	result, err := r.Reconcile(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Altered).To(Equal([]string{"system_auth", "system_distributed"}))
+	g.Expect(schema.statements).To(BeEmpty())
+}
+
+func TestReconcileNoDatacenter(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := newTestReconciler(newFakeSchema()).Reconcile(context.Background())
+	g.Expect(err).To(MatchError(ContainSubstring("has no CassandraDatacenter")))
+}
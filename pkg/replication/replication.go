@@ -0,0 +1,179 @@
+package replication
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/k8ssandra/k8ssandra/pkg/cqlsh"
+)
+
+const (
+	NetworkTopologyStrategy = "NetworkTopologyStrategy"
+	SimpleStrategy          = "SimpleStrategy"
+
+	keyspacesQuery        = "SELECT JSON keyspace_name, replication FROM system_schema.keyspaces;"
+	localDatacenterQuery  = "SELECT JSON data_center FROM system.local;"
+	peersDatacentersQuery = "SELECT JSON data_center FROM system.peers;"
+)
+
+// SystemKeyspaces are the keyspaces created by Cassandra whose replication has to follow the datacenters of the
+// cluster
+var SystemKeyspaces = []string{"system_auth", "system_distributed", "system_traces"}
+
+// Executor runs CQL statements
+type Executor interface {
+	Execute(ctx context.Context, statements string) (string, error)
+}
+
+// KeyspaceReplication is the replication of a keyspace as stored in system_schema.keyspaces
+type KeyspaceReplication struct {
+	Keyspace string
+	// Class is the short name of the replication strategy, e.g. NetworkTopologyStrategy
+	Class string
+	// Factors holds the replication factor of each datacenter for NetworkTopologyStrategy, and the replication_factor
+	// key for SimpleStrategy
+	Factors map[string]int
+}
+
+// Read returns the replication settings of all the keyspaces, sorted by name
+func Read(ctx context.Context, session Executor) ([]KeyspaceReplication, error) {
+	output, err := session.Execute(ctx, keyspacesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the replication of the keyspaces: %w", err)
+	}
+	rows, err := cqlsh.ParseJSONRows(output)
+	if err != nil {
+		return nil, err
+	}
+
+	keyspaces := make([]KeyspaceReplication, 0, len(rows))
+	for _, row := range rows {
+		name, _ := row["keyspace_name"].(string)
+		replication, _ := row["replication"].(map[string]interface{})
+		keyspace := KeyspaceReplication{Keyspace: name, Factors: make(map[string]int)}
+		for k, v := range replication {
+			value, _ := v.(string)
+			if k == "class" {
+				keyspace.Class = value[strings.LastIndex(value, ".")+1:]
+				continue
+			}
+			factor, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid replication factor %q for %s in keyspace %s", value, k, name)
+			}
+			keyspace.Factors[k] = factor
+		}
+		keyspaces = append(keyspaces, keyspace)
+	}
+	sort.Slice(keyspaces, func(i, j int) bool {
+		return keyspaces[i].Keyspace < keyspaces[j].Keyspace
+	})
+	return keyspaces, nil
+}
+
+// ClusterDatacenters returns the number of nodes of each datacenter of the cluster, as the node of the session and its
+// peers see them, including the datacenters deployed by other releases or outside of Kubernetes
+func ClusterDatacenters(ctx context.Context, session Executor) (map[string]int, error) {
+	nodes := make(map[string]int)
+	for _, query := range []string{localDatacenterQuery, peersDatacentersQuery} {
+		output, err := session.Execute(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the datacenters of the cluster: %w", err)
+		}
+		rows, err := cqlsh.ParseJSONRows(output)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			if dc, _ := row["data_center"].(string); dc != "" {
+				nodes[dc]++
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// Find returns the replication of the named keyspace, or nil if it is not in keyspaces
+func Find(keyspaces []KeyspaceReplication, name string) *KeyspaceReplication {
+	for i := range keyspaces {
+		if keyspaces[i].Keyspace == name {
+			return &keyspaces[i]
+		}
+	}
+	return nil
+}
+
+// AlterKeyspaceStatement returns the CQL statement setting the NetworkTopologyStrategy replication factor of each
+// datacenter of factors. Datacenters are listed in alphabetical order to keep statements stable.
+func AlterKeyspaceStatement(keyspace string, factors map[string]int) string {
+	dcs := make([]string, 0, len(factors))
+	for dc := range factors {
+		dcs = append(dcs, dc)
+	}
+	sort.Strings(dcs)
+
+	settings := []string{fmt.Sprintf("'class': '%s'", NetworkTopologyStrategy)}
+	for _, dc := range dcs {
+		settings = append(settings, fmt.Sprintf("'%s': %d", strings.ReplaceAll(dc, "'", "''"), factors[dc]))
+	}
+	return fmt.Sprintf(`ALTER KEYSPACE "%s" WITH replication = {%s};`, strings.ReplaceAll(keyspace, `"`, `""`), strings.Join(settings, ", "))
+}
+
+// AddDatacenter replicates each of the keyspaces to dc with replication factor rf, keeping the replication of the
+// other datacenters. SimpleStrategy keyspaces are switched to NetworkTopologyStrategy, keeping their replication factor,
+// capped to the number of nodes, in every datacenter of the cluster as Reconcile does. Keyspaces which do not exist are
+// skipped. It returns the altered keyspaces.
+func AddDatacenter(ctx context.Context, session Executor, keyspaces []string, dc string, rf int) ([]string, error) {
+	current, err := Read(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	var clusterNodes map[string]int
+
+	altered := make([]string, 0)
+	for _, name := range keyspaces {
+		keyspace := Find(current, name)
+		if keyspace == nil {
+			continue
+		}
+
+		factors := make(map[string]int)
+		switch keyspace.Class {
+		case NetworkTopologyStrategy:
+			for k, v := range keyspace.Factors {
+				factors[k] = v
+			}
+		case SimpleStrategy:
+			if clusterNodes == nil {
+				if clusterNodes, err = ClusterDatacenters(ctx, session); err != nil {
+					return altered, err
+				}
+			}
+			for existingDC, nodes := range clusterNodes {
+				if existingDC == dc {
+					continue
+				}
+				factors[existingDC] = keyspace.Factors["replication_factor"]
+				if nodes < factors[existingDC] {
+					factors[existingDC] = nodes
+				}
+			}
+		default:
+			return altered, fmt.Errorf("keyspace %s uses %s, its replication cannot be changed", name, keyspace.Class)
+		}
+		if factors[dc] == rf {
+			continue
+		}
+		factors[dc] = rf
+
+		if _, err := session.Execute(ctx, AlterKeyspaceStatement(name, factors)); err != nil {
+			return altered, fmt.Errorf("failed to alter the replication of keyspace %s: %w", name, err)
+		}
+		altered = append(altered, name)
+	}
+	return altered, nil
+}
@@ -0,0 +1,145 @@
+package replication
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// fakeSchema answers the keyspaces query from its keyspaces, the datacenters queries from the datacenter of each of its
+// nodes, the first one being the local node, and applies the ALTER KEYSPACE statements to the keyspaces
+type fakeSchema struct {
+	keyspaces  map[string]map[string]string
+	nodes      []string
+	statements []string
+}
+
+var alterPattern = regexp.MustCompile(`^ALTER KEYSPACE "(.+)" WITH replication = \{(.+)\};$`)
+
+func (f *fakeSchema) Execute(ctx context.Context, statements string) (string, error) {
+	if statements == keyspacesQuery {
+		names := make([]string, 0, len(f.keyspaces))
+		for name := range f.keyspaces {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		output := " [json]\n------\n"
+		for _, name := range names {
+			settings := make([]string, 0)
+			for k, v := range f.keyspaces[name] {
+				settings = append(settings, fmt.Sprintf("%q: %q", k, v))
+			}
+			output += fmt.Sprintf(` {"keyspace_name": %q, "replication": {%s}}`+"\n", name, strings.Join(settings, ", "))
+		}
+		return output, nil
+	}
+	if statements == localDatacenterQuery || statements == peersDatacentersQuery {
+		nodes := f.nodes[1:]
+		if statements == localDatacenterQuery {
+			nodes = f.nodes[:1]
+		}
+		output := " [json]\n------\n"
+		for _, dc := range nodes {
+			output += fmt.Sprintf(` {"data_center": %q}`+"\n", dc)
+		}
+		return output, nil
+	}
+
+	f.statements = append(f.statements, statements)
+	match := alterPattern.FindStringSubmatch(statements)
+	if match == nil {
+		return "", fmt.Errorf("unexpected statement %s", statements)
+	}
+	replication := make(map[string]string)
+	for _, setting := range strings.Split(match[2], ", ") {
+		kv := strings.SplitN(setting, ": ", 2)
+		replication[strings.Trim(kv[0], "'")] = strings.Trim(kv[1], "'")
+	}
+	replication["class"] = "org.apache.cassandra.locator." + replication["class"]
+	f.keyspaces[match[1]] = replication
+	return "", nil
+}
+
+func newFakeSchema() *fakeSchema {
+	return &fakeSchema{keyspaces: map[string]map[string]string{
+		"system":             {"class": "org.apache.cassandra.locator.LocalStrategy"},
+		"system_auth":        {"class": "org.apache.cassandra.locator.NetworkTopologyStrategy", "dc1": "3"},
+		"system_distributed": {"class": "org.apache.cassandra.locator.SimpleStrategy", "replication_factor": "3"},
+		"app":                {"class": "org.apache.cassandra.locator.NetworkTopologyStrategy", "dc1": "2", "dc2": "3"},
+	}, nodes: []string{"dc1", "dc1", "dc1"}}
+}
+
+func TestRead(t *testing.T) {
+	g := NewWithT(t)
+
+	keyspaces, err := Read(context.Background(), newFakeSchema())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(keyspaces).To(Equal([]KeyspaceReplication{
+		{Keyspace: "app", Class: NetworkTopologyStrategy, Factors: map[string]int{"dc1": 2, "dc2": 3}},
+		{Keyspace: "system", Class: "LocalStrategy", Factors: map[string]int{}},
+		{Keyspace: "system_auth", Class: NetworkTopologyStrategy, Factors: map[string]int{"dc1": 3}},
+		{Keyspace: "system_distributed", Class: SimpleStrategy, Factors: map[string]int{"replication_factor": 3}},
+	}))
+	g.Expect(Find(keyspaces, "system_auth").Factors).To(HaveKeyWithValue("dc1", 3))
+	g.Expect(Find(keyspaces, "missing")).To(BeNil())
+}
+
+func TestClusterDatacenters(t *testing.T) {
+	g := NewWithT(t)
+
+	schema := newFakeSchema()
+	schema.nodes = []string{"dc2", "dc1", "dc1", "dc2", "dc3"}
+	nodes, err := ClusterDatacenters(context.Background(), schema)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(nodes).To(Equal(map[string]int{"dc1": 2, "dc2": 2, "dc3": 1}))
+}
+
+func TestAlterKeyspaceStatement(t *testing.T) {
+	g := NewWithT(t)
+
+	statement := AlterKeyspaceStatement("system_auth", map[string]int{"dc2": 3, "dc1": 1})
+	g.Expect(statement).To(Equal(`ALTER KEYSPACE "system_auth" WITH replication = {'class': 'NetworkTopologyStrategy', 'dc1': 1, 'dc2': 3};`))
+}
+
+func TestAddDatacenter(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	schema := newFakeSchema()
+	altered, err := AddDatacenter(ctx, schema, []string{"system_auth", "system_distributed", "system_traces", "app"}, "dc2", 3)
+	g.Expect(err).ToNot(HaveOccurred())
+	// system_traces does not exist and app is already replicated to dc2
+	g.Expect(altered).To(Equal([]string{"system_auth", "system_distributed"}))
+
+	keyspaces, err := Read(ctx, schema)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(Find(keyspaces, "system_auth").Factors).To(Equal(map[string]int{"dc1": 3, "dc2": 3}))
+	g.Expect(Find(keyspaces, "system_distributed").Class).To(Equal(NetworkTopologyStrategy))
+	g.Expect(Find(keyspaces, "system_distributed").Factors).To(Equal(map[string]int{"dc1": 3, "dc2": 3}))
+
+	_, err = AddDatacenter(ctx, schema, []string{"system"}, "dc2", 3)
+	g.Expect(err).To(MatchError(ContainSubstring("uses LocalStrategy")))
+}
+
+func TestAddDatacenterToSeveralDatacenters(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	schema := newFakeSchema()
+	schema.nodes = []string{"dc3", "dc1", "dc1", "dc1", "dc2", "dc2", "dc3", "dc3", "dc3"}
+	altered, err := AddDatacenter(ctx, schema, []string{"system_distributed"}, "dc3", 3)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(altered).To(Equal([]string{"system_distributed"}))
+
+	// each existing datacenter keeps replicas, dc2 as many as its nodes
+	keyspaces, err := Read(ctx, schema)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(Find(keyspaces, "system_distributed").Class).To(Equal(NetworkTopologyStrategy))
+	g.Expect(Find(keyspaces, "system_distributed").Factors).To(Equal(map[string]int{"dc1": 3, "dc2": 2, "dc3": 3}))
+}
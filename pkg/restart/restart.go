@@ -0,0 +1,326 @@
+// Package restart restarts the Cassandra nodes of a CassandraDatacenter one at a time, e.g. to reload config files or
+// JVM options. As cass-operator does when it updates a rack, the pod template of the StatefulSet of each rack is
+// annotated, one rack after the other, and the rollout of the StatefulSet is driven pod by pod with its rolling update
+// partition. A pod is only restarted once the previous one rejoined the cluster as up and normal and all the nodes are
+// healthy again. When they are not within PauseTimeout, the restart is aborted with the partition left in place, so
+// that no other pod is restarted. Running the restart again resumes it: the StatefulSets annotated with the same
+// restart time and rolled out are skipped, the one in progress is resumed.
+//
+// The Management API drains each node in the preStop hook of its pod. The Drain option runs nodetool drain
+// beforehand, so that a drain failure stops the restart before the pod is deleted.
+package restart
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8ssandra/k8ssandra/pkg/rollout"
+	"github.com/k8ssandra/k8ssandra/pkg/scale"
+)
+
+const cassandraContainer = "cassandra"
+
+// Restarter restarts the nodes of CassandraDatacenters one at a time
+type Restarter struct {
+	Client client.Client
+	// Nodetool runs the health checks and the drains
+	Nodetool scale.NodetoolFunc
+
+	// Timeout bounds the wait for the StatefulSet controller to pick up a restart, and for each restarted node to
+	// rejoin the cluster
+	Timeout time.Duration
+	// PauseTimeout bounds the wait for all the nodes to be healthy again after each restarted node
+	PauseTimeout time.Duration
+}
+
+// Options select what is restarted and how
+type Options struct {
+	// Rack restricts the restart to a rack, all the racks are restarted in the order of the datacenter if empty
+	Rack string
+	// Drain runs nodetool drain on each node before restarting its pod
+	Drain bool
+}
+
+// Restart restarts the pods of the CassandraDatacenter, or of one of its racks, one at a time. It refuses to start if
+// the datacenter is not ready, unless a previous restart is resumed, or some nodes of the cluster are not up and
+// normal. After each pod it waits for the node to rejoin the cluster as up and normal, then pauses until all the nodes
+// are healthy again, aborting the restart if they are still not once PauseTimeout expired.
+func (r *Restarter) Restart(ctx context.Context, key types.NamespacedName, opts Options) error {
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	if err := r.Client.Get(ctx, key, cassdc); err != nil {
+		return fmt.Errorf("failed to get CassandraDatacenter %s: %w", key, err)
+	}
+	statefulSets, err := r.statefulSets(ctx, cassdc, opts.Rack)
+	if err != nil {
+		return err
+	}
+
+	restartedAt := ""
+	for _, sts := range statefulSets {
+		if restartInProgress(sts) {
+			restartedAt = sts.Spec.Template.Annotations[rollout.RestartedAtAnnotation]
+		}
+	}
+	resumed := restartedAt != ""
+	if !resumed {
+		restartedAt = time.Now().Format(time.RFC3339)
+		if ready, err := rollout.DatacenterReady(ctx, r.Client, cassdc); err != nil {
+			return err
+		} else if !ready {
+			return fmt.Errorf("CassandraDatacenter %s is not ready, wait for cass-operator to be done with it", key)
+		}
+	}
+	nodes, err := r.checkNodes(ctx, cassdc, 0)
+	if err != nil {
+		return fmt.Errorf("refusing to restart CassandraDatacenter %s: %w", key, err)
+	}
+
+	start := time.Now()
+	restarted := 0
+	for _, sts := range statefulSets {
+		if resumed && !restartInProgress(sts) && sts.Spec.Template.Annotations[rollout.RestartedAtAnnotation] == restartedAt {
+			continue
+		}
+		count, err := r.restartStatefulSet(ctx, cassdc, sts, restartedAt, nodes, opts.Drain)
+		restarted += count
+		if err != nil {
+			return fmt.Errorf("the restart of CassandraDatacenter %s was aborted: %w", key, err)
+		}
+	}
+	log.Printf("Restarted %d pods of CassandraDatacenter %s in %s", restarted, key, time.Since(start).Round(time.Second))
+	return nil
+}
+
+// restartStatefulSet annotates the pod template of the StatefulSet with restartedAt, unless a restart of it is in
+// progress already, then lowers its partition one pod at a time, from the highest ordinal as the StatefulSet
+// controller does. It returns the number of restarted pods.
+func (r *Restarter) restartStatefulSet(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter, sts *appsv1.StatefulSet, restartedAt string, nodes int, drain bool) (int, error) {
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+
+	if restartInProgress(sts) {
+		log.Printf("Resuming the restart of StatefulSet %s", sts.Name)
+	} else {
+		log.Printf("Restarting StatefulSet %s", sts.Name)
+		patch := client.MergeFrom(sts.DeepCopy())
+		if sts.Spec.Template.Annotations == nil {
+			sts.Spec.Template.Annotations = make(map[string]string)
+		}
+		sts.Spec.Template.Annotations[rollout.RestartedAtAnnotation] = restartedAt
+		setRollingUpdatePartition(sts, replicas)
+		if err := r.Client.Patch(ctx, sts, patch); err != nil {
+			return 0, fmt.Errorf("failed to restart StatefulSet %s: %w", sts.Name, err)
+		}
+	}
+	revision, err := r.waitForUpdateRevision(ctx, sts)
+	if err != nil {
+		return 0, err
+	}
+
+	restarted := 0
+	for ordinal := replicas - 1; ordinal >= 0; ordinal-- {
+		podKey := types.NamespacedName{Namespace: sts.Namespace, Name: fmt.Sprintf("%s-%d", sts.Name, ordinal)}
+		pod := &corev1.Pod{}
+		if err := r.Client.Get(ctx, podKey, pod); err != nil {
+			return restarted, fmt.Errorf("failed to get pod %s: %w", podKey.Name, err)
+		}
+		if pod.Labels[appsv1.ControllerRevisionHashLabelKey] == revision {
+			continue
+		}
+
+		podStart := time.Now()
+		if drain {
+			log.Printf("Draining pod %s", pod.Name)
+			if _, err := r.Nodetool(ctx, pod.Name, "drain"); err != nil {
+				return restarted, fmt.Errorf("failed to drain pod %s: %w", pod.Name, err)
+			}
+		}
+		if err := r.setPartition(ctx, sts, ordinal); err != nil {
+			return restarted, err
+		}
+		if err := r.waitForRestartedPod(ctx, podKey, revision); err != nil {
+			return restarted, err
+		}
+		restarted++
+		if err := r.waitForNodes(ctx, cassdc, nodes); err != nil {
+			return restarted, fmt.Errorf("stopped the restart of StatefulSet %s after pod %s, run the restart again to resume it once the nodes are healthy: %w",
+				sts.Name, pod.Name, err)
+		}
+		log.Printf("Restarted pod %s in %s", pod.Name, time.Since(podStart).Round(time.Second))
+	}
+	return restarted, r.setPartition(ctx, sts, 0)
+}
+
+// restartInProgress returns true if the StatefulSet was annotated by a restart which did not roll out all its pods
+func restartInProgress(sts *appsv1.StatefulSet) bool {
+	_, restarted := sts.Spec.Template.Annotations[rollout.RestartedAtAnnotation]
+	return restarted && sts.Status.UpdateRevision != sts.Status.CurrentRevision
+}
+
+func setRollingUpdatePartition(sts *appsv1.StatefulSet, partition int32) {
+	sts.Spec.UpdateStrategy.Type = appsv1.RollingUpdateStatefulSetStrategyType
+	sts.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition}
+}
+
+// setPartition lets the StatefulSet controller restart the pods from the given ordinal
+func (r *Restarter) setPartition(ctx context.Context, sts *appsv1.StatefulSet, partition int32) error {
+	patch := client.MergeFrom(sts.DeepCopy())
+	setRollingUpdatePartition(sts, partition)
+	if err := r.Client.Patch(ctx, sts, patch); err != nil {
+		return fmt.Errorf("failed to set the partition of StatefulSet %s: %w", sts.Name, err)
+	}
+	return nil
+}
+
+// waitForUpdateRevision waits for the StatefulSet controller to create the revision of the restarted pod template and
+// returns it
+func (r *Restarter) waitForUpdateRevision(ctx context.Context, sts *appsv1.StatefulSet) (string, error) {
+	key := types.NamespacedName{Namespace: sts.Namespace, Name: sts.Name}
+	err := wait.PollImmediate(rollout.PollInterval, r.Timeout, func() (bool, error) {
+		if err := r.Client.Get(ctx, key, sts); err != nil {
+			return false, err
+		}
+		return sts.Status.ObservedGeneration >= sts.Generation && sts.Status.UpdateRevision != sts.Status.CurrentRevision, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("the StatefulSet controller did not pick up the restart of StatefulSet %s: %w", sts.Name, err)
+	}
+	return sts.Status.UpdateRevision, nil
+}
+
+// waitForRestartedPod waits for the pod to run the restarted revision, to be ready and its node to be up and normal
+func (r *Restarter) waitForRestartedPod(ctx context.Context, key types.NamespacedName, revision string) error {
+	err := wait.PollImmediate(rollout.PollInterval, r.Timeout, func() (bool, error) {
+		pod := &corev1.Pod{}
+		if err := r.Client.Get(ctx, key, pod); err != nil {
+			if errors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		if pod.Labels[appsv1.ControllerRevisionHashLabelKey] != revision || !podReady(pod) {
+			return false, nil
+		}
+
+		output, err := r.Nodetool(ctx, pod.Name, "status")
+		if err != nil {
+			log.Printf("Waiting for pod %s: %v", pod.Name, err)
+			return false, nil
+		}
+		for _, node := range rollout.ParseNodetoolStatus(output) {
+			if node.Address == pod.Status.PodIP && node.State == rollout.NodeUpNormal {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("pod %s did not rejoin the cluster as up and normal: %w", key.Name, err)
+	}
+	return nil
+}
+
+// waitForNodes pauses until the expected number of nodes of the cluster are all up and normal, for at most
+// PauseTimeout
+func (r *Restarter) waitForNodes(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter, expected int) error {
+	_, unhealthy := r.checkNodes(ctx, cassdc, expected)
+	if unhealthy == nil {
+		return nil
+	}
+	log.Printf("Pausing until the nodes are healthy again: %v", unhealthy)
+
+	err := wait.PollImmediate(rollout.PollInterval, r.PauseTimeout, func() (bool, error) {
+		_, unhealthy = r.checkNodes(ctx, cassdc, expected)
+		return unhealthy == nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("the nodes were not healthy again within %s: %w", r.PauseTimeout, unhealthy)
+	}
+	log.Printf("All the nodes are up and normal again")
+	return nil
+}
+
+// checkNodes checks on a ready pod that all the nodes of the cluster are up and normal, and that at least expected
+// nodes are reported. It returns the number of nodes.
+func (r *Restarter) checkNodes(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter, expected int) (int, error) {
+	pods := &corev1.PodList{}
+	if err := r.Client.List(ctx, pods, client.InNamespace(cassdc.Namespace), client.MatchingLabels(cassdc.GetDatacenterLabels())); err != nil {
+		return 0, fmt.Errorf("failed to list pods of CassandraDatacenter %s: %w", cassdc.Name, err)
+	}
+	var ready *corev1.Pod
+	for i := range pods.Items {
+		if podReady(&pods.Items[i]) {
+			ready = &pods.Items[i]
+			break
+		}
+	}
+	if ready == nil {
+		return 0, fmt.Errorf("CassandraDatacenter %s has no ready pod", cassdc.Name)
+	}
+
+	output, err := r.Nodetool(ctx, ready.Name, "status")
+	if err != nil {
+		return 0, err
+	}
+	nodes := rollout.ParseNodetoolStatus(output)
+	return len(nodes), rollout.CheckNodesUp(nodes, expected)
+}
+
+// statefulSets returns the StatefulSets of the racks of the CassandraDatacenter in the order of its racks, or the one
+// of the given rack
+func (r *Restarter) statefulSets(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter, rack string) ([]*appsv1.StatefulSet, error) {
+	labels := cassdc.GetDatacenterLabels()
+	if rack != "" {
+		labels[cassdcapi.RackLabel] = rack
+	}
+	list := &appsv1.StatefulSetList{}
+	if err := r.Client.List(ctx, list, client.InNamespace(cassdc.Namespace), client.MatchingLabels(labels)); err != nil {
+		return nil, fmt.Errorf("failed to list StatefulSets of CassandraDatacenter %s: %w", cassdc.Name, err)
+	}
+	if len(list.Items) == 0 {
+		if rack != "" {
+			return nil, fmt.Errorf("CassandraDatacenter %s has no rack %s", cassdc.Name, rack)
+		}
+		return nil, fmt.Errorf("CassandraDatacenter %s has no StatefulSet", cassdc.Name)
+	}
+
+	racks := make(map[string]int)
+	for i, rack := range cassdc.Spec.Racks {
+		racks[rack.Name] = i
+	}
+	statefulSets := make([]*appsv1.StatefulSet, len(list.Items))
+	for i := range list.Items {
+		statefulSets[i] = &list.Items[i]
+	}
+	sort.Slice(statefulSets, func(i, j int) bool {
+		ri, rj := racks[statefulSets[i].Labels[cassdcapi.RackLabel]], racks[statefulSets[j].Labels[cassdcapi.RackLabel]]
+		if ri != rj {
+			return ri < rj
+		}
+		return statefulSets[i].Name < statefulSets[j].Name
+	})
+	return statefulSets, nil
+}
+
+// podReady returns true if the Cassandra container of the pod is ready
+func podReady(pod *corev1.Pod) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == cassandraContainer {
+			return status.Ready
+		}
+	}
+	return false
+}
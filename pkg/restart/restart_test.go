@@ -0,0 +1,360 @@
+package restart
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k8ssandra/k8ssandra/pkg/rollout"
+)
+
+const testNamespace = "k8ssandra"
+
+var (
+	dcKey = types.NamespacedName{Namespace: testNamespace, Name: "dc1"}
+	racks = []string{"r1", "r2", "r3"}
+	// nodes are the pods of the datacenter, rack r1 has two
+	nodes = []node{{"r1", 0}, {"r1", 1}, {"r2", 0}, {"r3", 0}}
+)
+
+type node struct {
+	rack    string
+	ordinal int
+}
+
+func (n node) podName() string {
+	return fmt.Sprintf("%s-%d", stsName(n.rack), n.ordinal)
+}
+
+func stsName(rack string) string {
+	return fmt.Sprintf("test-dc1-%s-sts", rack)
+}
+
+// fakeNodetool answers nodetool status with the states of the nodes and records the other commands
+type fakeNodetool struct {
+	sync.Mutex
+	states   map[string]string
+	commands []string
+}
+
+func (n *fakeNodetool) run(ctx context.Context, pod string, args ...string) (string, error) {
+	n.Lock()
+	defer n.Unlock()
+	if args[0] == "status" {
+		output := "Datacenter: dc1\n--  Address  Load  Tokens  Owns  Host ID  Rack\n"
+		for i, node := range nodes {
+			address := fmt.Sprintf("10.0.0.%d", i)
+			state := n.states[address]
+			if state == "" {
+				state = "UN"
+			}
+			output += fmt.Sprintf("%s  %s  100 KiB  256  100.0%%  host-%d  %s\n", state, address, i, node.rack)
+		}
+		return output, nil
+	}
+	n.commands = append(n.commands, pod+": "+strings.Join(args, " "))
+	return "", nil
+}
+
+func (n *fakeNodetool) setState(address, state string) {
+	n.Lock()
+	defer n.Unlock()
+	n.states[address] = state
+}
+
+func (n *fakeNodetool) recorded() []string {
+	n.Lock()
+	defer n.Unlock()
+	return append([]string{}, n.commands...)
+}
+
+func testDatacenter() *cassdcapi.CassandraDatacenter {
+	cassdc := &cassdcapi.CassandraDatacenter{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "dc1"},
+		Spec:       cassdcapi.CassandraDatacenterSpec{ClusterName: "test", Size: int32(len(nodes))},
+		Status: cassdcapi.CassandraDatacenterStatus{
+			CassandraOperatorProgress: cassdcapi.ProgressReady,
+			Conditions:                []cassdcapi.DatacenterCondition{{Type: cassdcapi.DatacenterReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	// the racks are listed in the reverse order of their names, they are restarted in the order of the datacenter
+	for i := len(racks) - 1; i >= 0; i-- {
+		cassdc.Spec.Racks = append(cassdc.Spec.Racks, cassdcapi.Rack{Name: racks[i]})
+	}
+	return cassdc
+}
+
+func cassandraPod(index int, revision string) *corev1.Pod {
+	labels := testDatacenter().GetDatacenterLabels()
+	labels[cassdcapi.RackLabel] = nodes[index].rack
+	labels[appsv1.ControllerRevisionHashLabelKey] = revision
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace,
+			Name:      nodes[index].podName(),
+			Labels:    labels,
+			UID:       types.UID(fmt.Sprintf("uid-%d-%s", index, revision)),
+		},
+		Status: corev1.PodStatus{
+			PodIP:             fmt.Sprintf("10.0.0.%d", index),
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "cassandra", Ready: true}},
+		},
+	}
+}
+
+func newTestRestarter(t *testing.T) (*Restarter, *fakeNodetool, client.Client) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = cassdcapi.AddToScheme(scheme)
+
+	cassdc := testDatacenter()
+	objects := []runtime.Object{cassdc}
+	for _, rack := range racks {
+		labels := cassdc.GetDatacenterLabels()
+		labels[cassdcapi.RackLabel] = rack
+		replicas := int32(0)
+		for _, node := range nodes {
+			if node.rack == rack {
+				replicas++
+			}
+		}
+		objects = append(objects, &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: stsName(rack), Labels: labels},
+			Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+			Status: appsv1.StatefulSetStatus{
+				Replicas: replicas, ReadyReplicas: replicas, UpdatedReplicas: replicas, CurrentRevision: "rev-0", UpdateRevision: "rev-0",
+			},
+		})
+	}
+	for i := range nodes {
+		objects = append(objects, cassandraPod(i, "rev-0"))
+	}
+	c := fake.NewFakeClientWithScheme(scheme, objects...)
+
+	rollout.PollInterval = 10 * time.Millisecond
+	nodetool := &fakeNodetool{states: make(map[string]string)}
+	restarter := &Restarter{Client: c, Nodetool: nodetool.run, Timeout: 5 * time.Second, PauseTimeout: 200 * time.Millisecond}
+	return restarter, nodetool, c
+}
+
+// operate plays the StatefulSet controller's part: it creates a new revision when the pod template of a StatefulSet
+// is annotated, and recreates its pods from the partition on with it, from the highest ordinal, each one once the
+// previous one rejoined the cluster. It stops when the context is done, and returns the pods it recreated.
+func operate(ctx context.Context, t *testing.T, c client.Client, nodetool *fakeNodetool) <-chan []string {
+	recreated := make(chan []string, 1)
+	go func() {
+		names := make([]string, 0)
+		defer func() { recreated <- names }()
+		for ctx.Err() == nil {
+			for _, rack := range racks {
+				if !recreate(ctx, t, c, nodetool, rack, &names) {
+					return
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	return recreated
+}
+
+// recreate rolls the pods of the StatefulSet of the rack at most one at a time, it returns false on errors
+func recreate(ctx context.Context, t *testing.T, c client.Client, nodetool *fakeNodetool, rack string, names *[]string) bool {
+	sts := &appsv1.StatefulSet{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: stsName(rack)}, sts); err != nil {
+		t.Error(err)
+		return false
+	}
+	if _, restarted := sts.Spec.Template.Annotations[rollout.RestartedAtAnnotation]; !restarted {
+		return true
+	}
+	if sts.Status.UpdateRevision == "rev-0" {
+		sts.Status.UpdateRevision = "rev-1"
+		return c.Status().Update(ctx, sts) == nil
+	}
+
+	partition := int32(0)
+	if sts.Spec.UpdateStrategy.RollingUpdate != nil && sts.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
+		partition = *sts.Spec.UpdateStrategy.RollingUpdate.Partition
+	}
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if nodes[i].rack != rack || int32(nodes[i].ordinal) < partition {
+			continue
+		}
+		pod := &corev1.Pod{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: nodes[i].podName()}, pod); err != nil {
+			t.Error(err)
+			return false
+		}
+		if pod.Labels[appsv1.ControllerRevisionHashLabelKey] == "rev-1" {
+			continue
+		}
+		address := fmt.Sprintf("10.0.0.%d", i)
+		nodetool.setState(address, "DN")
+		if err := c.Delete(ctx, pod); err != nil {
+			t.Error(err)
+			return false
+		}
+		*names = append(*names, pod.Name)
+		time.Sleep(20 * time.Millisecond)
+		if err := c.Create(ctx, cassandraPod(i, "rev-1")); err != nil {
+			t.Error(err)
+			return false
+		}
+		nodetool.setState(address, "UN")
+		return true
+	}
+	if partition == 0 && sts.Status.CurrentRevision != "rev-1" {
+		sts.Status.CurrentRevision = "rev-1"
+		return c.Status().Update(ctx, sts) == nil
+	}
+	return true
+}
+
+// partition returns the partition of the StatefulSet of the rack
+func partition(g *WithT, c client.Client, rack string) int32 {
+	sts := &appsv1.StatefulSet{}
+	g.Expect(c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: stsName(rack)}, sts)).To(Succeed())
+	if sts.Spec.UpdateStrategy.RollingUpdate == nil || sts.Spec.UpdateStrategy.RollingUpdate.Partition == nil {
+		return 0
+	}
+	return *sts.Spec.UpdateStrategy.RollingUpdate.Partition
+}
+
+func TestRestart(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	restarter, nodetool, c := newTestRestarter(t)
+	recreated := operate(ctx, t, c, nodetool)
+	g.Expect(restarter.Restart(ctx, dcKey, Options{})).To(Succeed())
+	cancel()
+
+	// the racks are restarted in the order of the datacenter, the pods of a rack from the highest ordinal
+	g.Expect(<-recreated).To(Equal([]string{"test-dc1-r3-sts-0", "test-dc1-r2-sts-0", "test-dc1-r1-sts-1", "test-dc1-r1-sts-0"}))
+	for _, rack := range racks {
+		g.Expect(partition(g, c, rack)).To(Equal(int32(0)))
+	}
+	// the preStop hook of the pods drains the nodes
+	g.Expect(nodetool.recorded()).To(BeEmpty())
+}
+
+func TestRestartRack(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	restarter, nodetool, c := newTestRestarter(t)
+	recreated := operate(ctx, t, c, nodetool)
+	g.Expect(restarter.Restart(ctx, dcKey, Options{Rack: "r1", Drain: true})).To(Succeed())
+	cancel()
+
+	g.Expect(<-recreated).To(Equal([]string{"test-dc1-r1-sts-1", "test-dc1-r1-sts-0"}))
+	g.Expect(nodetool.recorded()).To(Equal([]string{"test-dc1-r1-sts-1: drain", "test-dc1-r1-sts-0: drain"}))
+
+	g.Expect(restarter.Restart(context.Background(), dcKey, Options{Rack: "r4"})).To(MatchError("CassandraDatacenter dc1 has no rack r4"))
+}
+
+func TestRestartNotPickedUp(t *testing.T) {
+	g := NewWithT(t)
+
+	restarter, _, _ := newTestRestarter(t)
+	restarter.Timeout = 100 * time.Millisecond
+	g.Expect(restarter.Restart(context.Background(), dcKey, Options{})).To(
+		MatchError(ContainSubstring("the StatefulSet controller did not pick up the restart of StatefulSet test-dc1-r3-sts")))
+}
+
+func TestRestartRefused(t *testing.T) {
+	g := NewWithT(t)
+
+	restarter, nodetool, c := newTestRestarter(t)
+	nodetool.setState("10.0.0.1", "DN")
+	g.Expect(restarter.Restart(context.Background(), dcKey, Options{})).To(
+		MatchError(ContainSubstring("refusing to restart CassandraDatacenter k8ssandra/dc1: not all nodes are up and normal: 10.0.0.1 in dc1 is DN")))
+
+	sts := &appsv1.StatefulSet{}
+	g.Expect(c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: stsName("r3")}, sts)).To(Succeed())
+	g.Expect(sts.Spec.Template.Annotations).ToNot(HaveKey(rollout.RestartedAtAnnotation))
+}
+
+// rejoined tells whether the pod of the node was recreated
+func rejoined(c client.Client, index int) bool {
+	pod := &corev1.Pod{}
+	err := c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: nodes[index].podName()}, pod)
+	return err == nil && pod.UID == cassandraPod(index, "rev-1").UID
+}
+
+// downNode is the status of a node of another datacenter which is down
+const downNode = "DN  10.0.1.0  100 KiB  256  100.0%  host-9  r1\n"
+
+func TestRestartPausesForHealth(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	restarter, nodetool, c := newTestRestarter(t)
+	recreated := operate(ctx, t, c, nodetool)
+
+	// a node of another datacenter goes down when the first pod is recreated and comes back while the restart pauses
+	var downUntil time.Time
+	restarter.Nodetool = func(ctx context.Context, pod string, args ...string) (string, error) {
+		output, err := nodetool.run(ctx, pod, args...)
+		if downUntil.IsZero() && rejoined(c, 3) {
+			downUntil = time.Now().Add(50 * time.Millisecond)
+		}
+		if time.Now().Before(downUntil) {
+			output += downNode
+		}
+		return output, err
+	}
+	g.Expect(restarter.Restart(ctx, dcKey, Options{})).To(Succeed())
+	cancel()
+	g.Expect(<-recreated).To(HaveLen(len(nodes)))
+}
+
+func TestRestartAbortsAndResumes(t *testing.T) {
+	g := NewWithT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	restarter, nodetool, c := newTestRestarter(t)
+	recreated := operate(ctx, t, c, nodetool)
+
+	// a node of another datacenter goes down when the second pod of r1 is recreated
+	down := true
+	restarter.Nodetool = func(ctx context.Context, pod string, args ...string) (string, error) {
+		output, err := nodetool.run(ctx, pod, args...)
+		if down && rejoined(c, 1) {
+			output += downNode
+		}
+		return output, err
+	}
+	err := restarter.Restart(ctx, dcKey, Options{})
+	g.Expect(err).To(MatchError(ContainSubstring("stopped the restart of StatefulSet test-dc1-r1-sts after pod test-dc1-r1-sts-1")))
+	g.Expect(err).To(MatchError(ContainSubstring("the nodes were not healthy again within 200ms: not all nodes are up and normal: 10.0.1.0 in dc1 is DN")))
+
+	// the partition keeps the StatefulSet controller from restarting the other pod of the rack
+	time.Sleep(100 * time.Millisecond)
+	g.Expect(partition(g, c, "r1")).To(Equal(int32(1)))
+	g.Expect(rejoined(c, 0)).To(BeFalse())
+
+	// once the node is back, the restart resumes without restarting the pods again
+	down = false
+	g.Expect(restarter.Restart(ctx, dcKey, Options{})).To(Succeed())
+	cancel()
+	g.Expect(<-recreated).To(Equal([]string{"test-dc1-r3-sts-0", "test-dc1-r2-sts-0", "test-dc1-r1-sts-1", "test-dc1-r1-sts-0"}))
+}
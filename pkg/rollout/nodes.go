@@ -0,0 +1,124 @@
+package rollout
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// NodeUpNormal is the nodetool status state of a node which is up and serving its token ranges
+	NodeUpNormal = "UN"
+
+	unreachableSchema = "UNREACHABLE"
+)
+
+// nodeLine matches the lines of nodetool status describing a node, which start with the state of the node
+var nodeLine = regexp.MustCompile(`^[UD][NLJM]\s`)
+
+// schemaVersionLine matches the schema versions of nodetool describecluster, e.g. 2207c2a9-...: [10.0.0.1, 10.0.0.2]
+var schemaVersionLine = regexp.MustCompile(`^\s+(\S+): \[(.*)\]$`)
+
+// NodeStatus is a node as reported by nodetool status
+type NodeStatus struct {
+	Datacenter string
+	Address    string
+	State      string
+	HostID     string
+	Rack       string
+}
+
+// ParseNodetoolStatus returns the nodes of the output of nodetool status
+func ParseNodetoolStatus(output string) []NodeStatus {
+	nodes := make([]NodeStatus, 0)
+	datacenter := ""
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Datacenter: ") {
+			datacenter = strings.TrimPrefix(line, "Datacenter: ")
+			continue
+		}
+		if !nodeLine.MatchString(line) {
+			continue
+		}
+		// the load has a unit, or is ? for nodes which are down, so the host ID and the rack are counted from the end
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		nodes = append(nodes, NodeStatus{
+			Datacenter: datacenter,
+			State:      fields[0],
+			Address:    fields[1],
+			HostID:     fields[len(fields)-2],
+			Rack:       fields[len(fields)-1],
+		})
+	}
+	return nodes
+}
+
+// CheckNodesUp returns an error listing the nodes which are not up and normal, or if fewer than expected nodes are
+// reported
+func CheckNodesUp(nodes []NodeStatus, expected int) error {
+	problems := make([]string, 0)
+	for _, node := range nodes {
+		if node.State != NodeUpNormal {
+			problems = append(problems, fmt.Sprintf("%s in %s is %s", node.Address, node.Datacenter, node.State))
+		}
+	}
+	if len(nodes) < expected {
+		problems = append(problems, fmt.Sprintf("%d nodes are reported instead of %d", len(nodes), expected))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("not all nodes are up and normal: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// ParseSchemaVersions returns the schema versions of the output of nodetool describecluster, with the addresses of
+// the nodes having each version. The unreachable nodes are listed under UNREACHABLE.
+func ParseSchemaVersions(output string) map[string][]string {
+	versions := make(map[string][]string)
+	inVersions := false
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "Schema versions:" {
+			inVersions = true
+			continue
+		}
+		if !inVersions || strings.TrimSpace(line) == "" {
+			continue
+		}
+		match := schemaVersionLine.FindStringSubmatch(line)
+		if match == nil {
+			break
+		}
+		addresses := make([]string, 0)
+		for _, address := range strings.Split(match[2], ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+		versions[match[1]] = addresses
+	}
+	return versions
+}
+
+// CheckSchemaAgreement returns an error if the nodes do not all have the same schema version
+func CheckSchemaAgreement(versions map[string][]string) error {
+	if unreachable := versions[unreachableSchema]; len(unreachable) > 0 {
+		return fmt.Errorf("the schema version of nodes %s is unknown, they are unreachable", strings.Join(unreachable, ", "))
+	}
+	if len(versions) > 1 {
+		disagreements := make([]string, 0, len(versions))
+		for version, addresses := range versions {
+			disagreements = append(disagreements, fmt.Sprintf("%s on %s", version, strings.Join(addresses, ", ")))
+		}
+		sort.Strings(disagreements)
+		return fmt.Errorf("the nodes do not agree on the schema: %s", strings.Join(disagreements, "; "))
+	}
+	if len(versions) == 0 {
+		return fmt.Errorf("no schema version reported")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package rollout
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const statusOutput = `Datacenter: dc1
+===============
+Status=Up/Down
+|/ State=Normal/Leaving/Joining/Moving
+--  Address     Load       Tokens       Owns (effective)  Host ID                               Rack
+UN  10.244.1.4  108.45 KiB  256          100.0%            7a1b5d6c-1e9f-4c3a-9a57-0c2d1f0b9a11  r1
+UN  10.244.2.3  96.12 KiB  256          100.0%            2c4f8e0a-5b3d-4e7f-8a21-3d6b9c1e7f22  r2
+
+Datacenter: dc2
+===============
+Status=Up/Down
+|/ State=Normal/Leaving/Joining/Moving
+--  Address     Load       Tokens       Owns (effective)  Host ID                               Rack
+DN  10.245.1.7  ?          256          100.0%            9e3a7c5b-2d1f-4b8e-b6a4-5f0c8d2e1a33  r1
+UJ  10.245.2.8  12 KiB     256          ?                 1f6d2b8c-7e4a-4c9d-8b35-6a1e0f3c2b44  r2
+`
+
+const describeClusterOutput = `Cluster Information:
+	Name: test
+	Snitch: org.apache.cassandra.locator.SimpleSnitch
+	DynamicEndPointSnitch: enabled
+	Partitioner: org.apache.cassandra.dht.Murmur3Partitioner
+	Schema versions:
+		2207c2a9-f598-3971-986b-2926e09e239d: [10.244.1.4, 10.244.2.3]
+
+		UNREACHABLE: [10.245.1.7]
+
+Stats for all nodes:
+	Live: 3
+`
+
+func TestParseNodetoolStatus(t *testing.T) {
+	g := NewWithT(t)
+
+	nodes := ParseNodetoolStatus(statusOutput)
+	g.Expect(nodes).To(Equal([]NodeStatus{
+		{Datacenter: "dc1", Address: "10.244.1.4", State: "UN", HostID: "7a1b5d6c-1e9f-4c3a-9a57-0c2d1f0b9a11", Rack: "r1"},
+		{Datacenter: "dc1", Address: "10.244.2.3", State: "UN", HostID: "2c4f8e0a-5b3d-4e7f-8a21-3d6b9c1e7f22", Rack: "r2"},
+		{Datacenter: "dc2", Address: "10.245.1.7", State: "DN", HostID: "9e3a7c5b-2d1f-4b8e-b6a4-5f0c8d2e1a33", Rack: "r1"},
+		{Datacenter: "dc2", Address: "10.245.2.8", State: "UJ", HostID: "1f6d2b8c-7e4a-4c9d-8b35-6a1e0f3c2b44", Rack: "r2"},
+	}))
+
+	g.Expect(CheckNodesUp(nodes[:2], 2)).To(Succeed())
+	g.Expect(CheckNodesUp(nodes[:2], 3)).To(MatchError("not all nodes are up and normal: 2 nodes are reported instead of 3"))
+	g.Expect(CheckNodesUp(nodes, 4)).To(MatchError("not all nodes are up and normal: 10.245.1.7 in dc2 is DN, 10.245.2.8 in dc2 is UJ"))
+}
+
+func TestParseSchemaVersions(t *testing.T) {
+	g := NewWithT(t)
+
+	versions := ParseSchemaVersions(describeClusterOutput)
+	g.Expect(versions).To(Equal(map[string][]string{
+		"2207c2a9-f598-3971-986b-2926e09e239d": {"10.244.1.4", "10.244.2.3"},
+		"UNREACHABLE":                          {"10.245.1.7"},
+	}))
+	g.Expect(CheckSchemaAgreement(versions)).To(MatchError("the schema version of nodes 10.245.1.7 is unknown, they are unreachable"))
+
+	delete(versions, "UNREACHABLE")
+	g.Expect(CheckSchemaAgreement(versions)).To(Succeed())
+
+	versions["5a54ebe8-3c1d-3b5f-9d24-8a6f2a9c1b77"] = []string{"10.245.1.7"}
+	g.Expect(CheckSchemaAgreement(versions)).To(MatchError(
+		"the nodes do not agree on the schema: 2207c2a9-f598-3971-986b-2926e09e239d on 10.244.1.4, 10.244.2.3; 5a54ebe8-3c1d-3b5f-9d24-8a6f2a9c1b77 on 10.245.1.7"))
+	g.Expect(CheckSchemaAgreement(map[string][]string{})).To(HaveOccurred())
+}
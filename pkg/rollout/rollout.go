@@ -0,0 +1,138 @@
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// RestartedAtAnnotation is set on the pod template of restarted Deployments, like kubectl rollout restart does
+	RestartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+)
+
+// PollInterval is the interval at which rollouts are checked
+var PollInterval = 5 * time.Second
+
+// RestartDeployment triggers a rolling restart of the Deployment's pods
+func RestartDeployment(ctx context.Context, c client.Client, key types.NamespacedName) error {
+	deployment := &appsv1.Deployment{}
+	if err := c.Get(ctx, key, deployment); err != nil {
+		return fmt.Errorf("failed to get Deployment %s: %w", key, err)
+	}
+
+	patch := client.MergeFrom(deployment.DeepCopy())
+	if deployment.Spec.Template.Annotations == nil {
+		deployment.Spec.Template.Annotations = make(map[string]string)
+	}
+	deployment.Spec.Template.Annotations[RestartedAtAnnotation] = time.Now().Format(time.RFC3339)
+
+	if err := c.Patch(ctx, deployment, patch); err != nil {
+		return fmt.Errorf("failed to restart Deployment %s: %w", key, err)
+	}
+	return nil
+}
+
+// WaitForDeployment waits until all the replicas of the Deployment run its latest pod template and are available
+func WaitForDeployment(ctx context.Context, c client.Client, key types.NamespacedName, timeout time.Duration) error {
+	err := wait.PollImmediate(PollInterval, timeout, func() (bool, error) {
+		deployment := &appsv1.Deployment{}
+		if err := c.Get(ctx, key, deployment); err != nil {
+			return false, err
+		}
+		return DeploymentRolledOut(deployment), nil
+	})
+	if err != nil {
+		return fmt.Errorf("Deployment %s did not roll out: %w", key, err)
+	}
+	return nil
+}
+
+// DeploymentRolledOut returns true if the Deployment controller has rolled out the latest pod template
+func DeploymentRolledOut(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	return status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas == replicas &&
+		status.Replicas == replicas &&
+		status.AvailableReplicas == replicas
+}
+
+// RestartDatacenter asks cass-operator for a rolling restart of the CassandraDatacenter and returns the time of the
+// request, to be passed to WaitForDatacenter
+func RestartDatacenter(ctx context.Context, c client.Client, key types.NamespacedName) (time.Time, error) {
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	if err := c.Get(ctx, key, cassdc); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get CassandraDatacenter %s: %w", key, err)
+	}
+
+	// LastRollingRestart has a one second precision
+	requestedAt := time.Now().Truncate(time.Second)
+	patch := client.MergeFrom(cassdc.DeepCopy())
+	cassdc.Spec.RollingRestartRequested = true
+	if err := c.Patch(ctx, cassdc, patch); err != nil {
+		return time.Time{}, fmt.Errorf("failed to request a rolling restart of CassandraDatacenter %s: %w", key, err)
+	}
+	return requestedAt, nil
+}
+
+// WaitForDatacenter waits until cass-operator has processed the rolling restart requested at requestedAt and all
+// the StatefulSets of the CassandraDatacenter are rolled out and ready
+func WaitForDatacenter(ctx context.Context, c client.Client, key types.NamespacedName, requestedAt time.Time, timeout time.Duration) error {
+	err := wait.PollImmediate(PollInterval, timeout, func() (bool, error) {
+		cassdc := &cassdcapi.CassandraDatacenter{}
+		if err := c.Get(ctx, key, cassdc); err != nil {
+			return false, err
+		}
+		if cassdc.Spec.RollingRestartRequested || cassdc.Status.LastRollingRestart.Before(&metav1.Time{Time: requestedAt}) {
+			return false, nil
+		}
+		return DatacenterReady(ctx, c, cassdc)
+	})
+	if err != nil {
+		return fmt.Errorf("CassandraDatacenter %s did not complete its rolling restart: %w", key, err)
+	}
+	return nil
+}
+
+// DatacenterReady returns true if cass-operator has processed the latest spec of the CassandraDatacenter and reports
+// it ready, and all its StatefulSets run their latest revision with all their pods ready. The hooks, the commands and
+// the operator all wait for a datacenter with it.
+func DatacenterReady(ctx context.Context, c client.Client, cassdc *cassdcapi.CassandraDatacenter) (bool, error) {
+	if cassdc.Status.ObservedGeneration < cassdc.Generation ||
+		cassdc.Status.CassandraOperatorProgress != cassdcapi.ProgressReady ||
+		cassdc.GetConditionStatus(cassdcapi.DatacenterReady) != corev1.ConditionTrue {
+		return false, nil
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := c.List(ctx, statefulSets, client.InNamespace(cassdc.Namespace), client.MatchingLabels(cassdc.GetDatacenterLabels())); err != nil {
+		return false, err
+	}
+	if len(statefulSets.Items) == 0 {
+		return false, nil
+	}
+	for _, sts := range statefulSets.Items {
+		replicas := int32(1)
+		if sts.Spec.Replicas != nil {
+			replicas = *sts.Spec.Replicas
+		}
+		status := sts.Status
+		if status.ObservedGeneration < sts.Generation || status.UpdateRevision != status.CurrentRevision ||
+			status.ReadyReplicas != replicas || status.UpdatedReplicas != replicas {
+			return false, nil
+		}
+	}
+	return true, nil
+}
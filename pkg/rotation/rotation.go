@@ -0,0 +1,295 @@
+package rotation
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8ssandra/k8ssandra/pkg/cqlsh"
+	"github.com/k8ssandra/k8ssandra/pkg/credentials"
+	"github.com/k8ssandra/k8ssandra/pkg/reaper"
+	"github.com/k8ssandra/k8ssandra/pkg/rollout"
+	"github.com/k8ssandra/k8ssandra/pkg/stargate"
+)
+
+// User is one of the CQL users whose credentials are generated by the k8ssandra chart
+type User string
+
+const (
+	UserSuperuser = User("superuser")
+	UserReaper    = User("reaper")
+	UserMedusa    = User("medusa")
+	UserStargate  = User("stargate")
+
+	passwordLength   = 20
+	passwordAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	medusaContainer = "medusa"
+)
+
+// Users lists the users which can be rotated
+var Users = []User{UserSuperuser, UserReaper, UserMedusa, UserStargate}
+
+// Executor runs CQL statements
+type Executor interface {
+	Execute(ctx context.Context, statements string) (string, error)
+}
+
+// SessionFactory returns an Executor authenticating with the given credentials
+type SessionFactory func(creds *credentials.Credentials) (Executor, error)
+
+// CqlshSessionFactory returns a SessionFactory running cqlsh in the pod of the given session
+func CqlshSessionFactory(session *cqlsh.Session) SessionFactory {
+	return func(creds *credentials.Credentials) (Executor, error) {
+		s := *session
+		s.Credentials = creds
+		return &s, nil
+	}
+}
+
+// Rotator rotates the passwords of the users of a k8ssandra release
+type Rotator struct {
+	Client      client.Client
+	Namespace   string
+	ReleaseName string
+	Session     SessionFactory
+
+	// Timeout bounds the wait for each consumer to restart
+	Timeout time.Duration
+}
+
+// Rotate generates a new password for the user, changes it in Cassandra and in the user's secret, then restarts the
+// components reading the secret. secretName overrides the secret of the user, for releases using custom secrets.
+//
+// The role is altered first. If updating the secret or logging in with the new password fails, the old password is
+// restored in both places. Consumers are restarted once the new password is in place: Deployments first, then the
+// Cassandra pods, waiting for each one to be ready before moving to the next one.
+func (r *Rotator) Rotate(ctx context.Context, user User, secretName string) error {
+	cassdc, err := cqlsh.LookupDatacenter(ctx, r.Client, r.Namespace, r.ReleaseName)
+	if err != nil {
+		return err
+	}
+
+	secretKey, err := r.secretKey(ctx, cassdc, user, secretName)
+	if err != nil {
+		return err
+	}
+	oldCreds, err := credentials.FromSecret(ctx, r.Client, secretKey)
+	if err != nil {
+		return err
+	}
+	superuserCreds, err := credentials.FromSecret(ctx, r.Client, cassdc.GetSuperuserSecretNamespacedName())
+	if err != nil {
+		return err
+	}
+
+	password, err := GeneratePassword()
+	if err != nil {
+		return err
+	}
+	newCreds := &credentials.Credentials{Username: oldCreds.Username, Password: password}
+
+	log.Printf("Changing the password of role %s", oldCreds.Username)
+	if err := r.alterRole(ctx, superuserCreds, newCreds); err != nil {
+		return fmt.Errorf("failed to change the password of role %s, nothing was changed: %w", oldCreds.Username, err)
+	}
+
+	// The superuser authenticates with its new password from now on
+	if user == UserSuperuser {
+		superuserCreds = newCreds
+	}
+
+	log.Printf("Updating secret %s", secretKey)
+	if err := r.updateSecret(ctx, secretKey, password); err != nil {
+		return r.rollback(ctx, superuserCreds, oldCreds, nil, fmt.Errorf("failed to update secret %s: %w", secretKey, err))
+	}
+
+	if err := r.checkLogin(ctx, newCreds); err != nil {
+		return r.rollback(ctx, superuserCreds, oldCreds, &secretKey, fmt.Errorf("failed to log in with the new password of %s: %w", oldCreds.Username, err))
+	}
+
+	return r.restartConsumers(ctx, cassdc, user)
+}
+
+// rollback restores the old password in Cassandra and, if secretKey is not nil, in the secret. It returns cause,
+// along with any error met while rolling back.
+func (r *Rotator) rollback(ctx context.Context, superuserCreds, oldCreds *credentials.Credentials, secretKey *types.NamespacedName, cause error) error {
+	log.Printf("Rolling back: %v", cause)
+
+	if err := r.alterRole(ctx, superuserCreds, oldCreds); err != nil {
+		return fmt.Errorf("%v; rollback of role %s failed: %w", cause, oldCreds.Username, err)
+	}
+	if secretKey != nil {
+		if err := r.updateSecret(ctx, *secretKey, oldCreds.Password); err != nil {
+			return fmt.Errorf("%v; rollback of secret %s failed: %w", cause, secretKey, err)
+		}
+	}
+	return fmt.Errorf("%w, the old password was restored", cause)
+}
+
+func (r *Rotator) alterRole(ctx context.Context, superuserCreds, creds *credentials.Credentials) error {
+	session, err := r.Session(superuserCreds)
+	if err != nil {
+		return err
+	}
+	_, err = session.Execute(ctx, AlterRoleStatement(creds))
+	return err
+}
+
+func (r *Rotator) checkLogin(ctx context.Context, creds *credentials.Credentials) error {
+	session, err := r.Session(creds)
+	if err != nil {
+		return err
+	}
+	_, err = session.Execute(ctx, "SELECT release_version FROM system.local;")
+	return err
+}
+
+func (r *Rotator) updateSecret(ctx context.Context, key types.NamespacedName, password string) error {
+	secret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, key, secret); err != nil {
+		return err
+	}
+	patch := client.MergeFrom(secret.DeepCopy())
+	secret.Data[credentials.PasswordKey] = []byte(password)
+	return r.Client.Patch(ctx, secret, patch)
+}
+
+// secretKey returns the secret holding the credentials of the user, checking that the CassandraDatacenter manages
+// the user through it
+func (r *Rotator) secretKey(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter, user User, secretName string) (types.NamespacedName, error) {
+	if user == UserSuperuser {
+		key := cassdc.GetSuperuserSecretNamespacedName()
+		if secretName != "" && secretName != key.Name {
+			return types.NamespacedName{}, fmt.Errorf("the superuser secret of CassandraDatacenter %s is %s, not %s", cassdc.Name, key.Name, secretName)
+		}
+		return key, nil
+	}
+
+	if secretName == "" {
+		switch user {
+		case UserReaper:
+			secretName = cassdc.Spec.ClusterName + "-reaper"
+		case UserMedusa:
+			secretName = cassdc.Spec.ClusterName + "-medusa"
+		case UserStargate:
+			secretName = stargate.UserSecretName(cassdc.Spec.ClusterName)
+		default:
+			return types.NamespacedName{}, fmt.Errorf("unknown user %s, expected one of %s", user, usersString())
+		}
+	}
+
+	for _, u := range cassdc.Spec.Users {
+		if u.SecretName == secretName {
+			return types.NamespacedName{Namespace: cassdc.Namespace, Name: secretName}, nil
+		}
+	}
+	return types.NamespacedName{}, fmt.Errorf("secret %s is not a user of CassandraDatacenter %s, is %s enabled in the release?", secretName, cassdc.Name, user)
+}
+
+// restartConsumers restarts the components reading the credentials of the user so that they pick the new password
+func (r *Rotator) restartConsumers(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter, user User) error {
+	deployments := make([]types.NamespacedName, 0)
+	restartDatacenter := false
+
+	switch user {
+	case UserSuperuser:
+		// The Cassandra pods read the superuser credentials for JMX
+		restartDatacenter = true
+	case UserReaper:
+		deployments = append(deployments, types.NamespacedName{Namespace: r.Namespace, Name: reaper.ReaperName(r.ReleaseName)})
+	case UserStargate:
+		deployments = append(deployments, types.NamespacedName{Namespace: r.Namespace, Name: fmt.Sprintf("%s-%s-stargate", r.ReleaseName, cassdc.Name)})
+	case UserMedusa:
+		restartDatacenter = hasContainer(cassdc, medusaContainer)
+	}
+
+	for _, key := range deployments {
+		if err := r.Client.Get(ctx, key, &appsv1.Deployment{}); apierrors.IsNotFound(err) {
+			log.Printf("Deployment %s not found, skipping its restart", key)
+			continue
+		}
+		log.Printf("Restarting Deployment %s", key)
+		if err := rollout.RestartDeployment(ctx, r.Client, key); err != nil {
+			return err
+		}
+		if err := rollout.WaitForDeployment(ctx, r.Client, key, r.Timeout); err != nil {
+			return err
+		}
+	}
+
+	if restartDatacenter {
+		key := types.NamespacedName{Namespace: cassdc.Namespace, Name: cassdc.Name}
+		log.Printf("Restarting CassandraDatacenter %s", key)
+		requestedAt, err := rollout.RestartDatacenter(ctx, r.Client, key)
+		if err != nil {
+			return err
+		}
+		if err := rollout.WaitForDatacenter(ctx, r.Client, key, requestedAt, r.Timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasContainer(cassdc *cassdcapi.CassandraDatacenter, name string) bool {
+	if cassdc.Spec.PodTemplateSpec == nil {
+		return false
+	}
+	for _, container := range cassdc.Spec.PodTemplateSpec.Spec.Containers {
+		if container.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AlterRoleStatement returns the CQL statement setting the password of the role
+func AlterRoleStatement(creds *credentials.Credentials) string {
+	return fmt.Sprintf(`ALTER ROLE "%s" WITH PASSWORD = '%s';`,
+		strings.ReplaceAll(creds.Username, `"`, `""`),
+		strings.ReplaceAll(creds.Password, `'`, `''`))
+}
+
+// GeneratePassword returns a random alphanumeric password, like the ones generated by the chart
+func GeneratePassword() (string, error) {
+	password := make([]byte, passwordLength)
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(password), nil
+}
+
+// ParseUser returns the User with the given name
+func ParseUser(name string) (User, error) {
+	for _, user := range Users {
+		if string(user) == name {
+			return user, nil
+		}
+	}
+	return "", fmt.Errorf("unknown user %s, expected one of %s", name, usersString())
+}
+
+func usersString() string {
+	names := make([]string, len(Users))
+	for i, user := range Users {
+		names[i] = string(user)
+	}
+	return strings.Join(names, ", ")
+}
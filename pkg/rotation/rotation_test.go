@@ -0,0 +1,192 @@
+package rotation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k8ssandra/k8ssandra/pkg/credentials"
+	"github.com/k8ssandra/k8ssandra/pkg/rollout"
+)
+
+const (
+	testNamespace = "k8ssandra"
+	testRelease   = "test"
+)
+
+// fakeCassandra records the statements run by the rotator and the password of each role
+type fakeCassandra struct {
+	passwords  map[string]string
+	statements []string
+
+	// failAlter makes ALTER ROLE statements fail
+	failAlter bool
+	// failLogin makes logins with a new password fail, as if the change did not propagate
+	failLogin bool
+	original  map[string]string
+}
+
+type fakeSession struct {
+	cassandra *fakeCassandra
+	creds     *credentials.Credentials
+}
+
+func (s *fakeSession) Execute(ctx context.Context, statements string) (string, error) {
+	f := s.cassandra
+	if f.passwords[s.creds.Username] != s.creds.Password {
+		return "", fmt.Errorf("Bad credentials for %s", s.creds.Username)
+	}
+	if f.failLogin && f.original[s.creds.Username] != s.creds.Password {
+		return "", fmt.Errorf("Bad credentials for %s", s.creds.Username)
+	}
+	f.statements = append(f.statements, statements)
+
+	if strings.HasPrefix(statements, "ALTER ROLE") {
+		if f.failAlter {
+			return "", fmt.Errorf("Unauthorized")
+		}
+		var role, password string
+		_, _ = fmt.Sscanf(statements, `ALTER ROLE %s WITH PASSWORD = %s`, &role, &password)
+		f.passwords[strings.Trim(role, `"`)] = strings.TrimSuffix(strings.Trim(password, `'`), `';`)
+	}
+	return "", nil
+}
+
+func newFakeCassandra() *fakeCassandra {
+	passwords := map[string]string{"test-superuser": "superpass", "test-reaper": "reaperpass"}
+	original := map[string]string{}
+	for role, password := range passwords {
+		original[role] = password
+	}
+	return &fakeCassandra{passwords: passwords, original: original}
+}
+
+func userSecret(name, username, password string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: name},
+		Data: map[string][]byte{
+			credentials.UsernameKey: []byte(username),
+			credentials.PasswordKey: []byte(password),
+		},
+	}
+}
+
+func newTestRotator(t *testing.T, cassandra *fakeCassandra) (*Rotator, client.Client) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = cassdcapi.AddToScheme(scheme)
+
+	replicas := int32(1)
+	objects := []runtime.Object{
+		&cassdcapi.CassandraDatacenter{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testNamespace,
+				Name:      "dc1",
+				Labels:    map[string]string{"app.kubernetes.io/instance": testRelease},
+			},
+			Spec: cassdcapi.CassandraDatacenterSpec{
+				ClusterName:         testRelease,
+				SuperuserSecretName: "test-superuser",
+				Users:               []cassdcapi.CassandraUser{{SecretName: "test-reaper", Superuser: true}},
+			},
+		},
+		userSecret("test-superuser", "test-superuser", "superpass"),
+		userSecret("test-reaper", "test-reaper", "reaperpass"),
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "test-reaper"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+		},
+	}
+	c := fake.NewFakeClientWithScheme(scheme, objects...)
+
+	rollout.PollInterval = 10 * time.Millisecond
+	return &Rotator{
+		Client:      c,
+		Namespace:   testNamespace,
+		ReleaseName: testRelease,
+		Session: func(creds *credentials.Credentials) (Executor, error) {
+			return &fakeSession{cassandra: cassandra, creds: creds}, nil
+		},
+		Timeout: time.Second,
+	}, c
+}
+
+func readSecret(g *WithT, c client.Client, name string) *credentials.Credentials {
+	creds, err := credentials.FromSecret(context.Background(), c, types.NamespacedName{Namespace: testNamespace, Name: name})
+	g.Expect(err).ToNot(HaveOccurred())
+	return creds
+}
+
+func TestRotate(t *testing.T) {
+	g := NewWithT(t)
+
+	cassandra := newFakeCassandra()
+	rotator, c := newTestRotator(t, cassandra)
+
+	g.Expect(rotator.Rotate(context.Background(), UserReaper, "")).To(Succeed())
+
+	creds := readSecret(g, c, "test-reaper")
+	g.Expect(creds.Password).ToNot(Equal("reaperpass"))
+	g.Expect(creds.Password).To(HaveLen(passwordLength))
+	g.Expect(cassandra.passwords["test-reaper"]).To(Equal(creds.Password))
+	g.Expect(cassandra.passwords["test-superuser"]).To(Equal("superpass"))
+
+	deployment := &appsv1.Deployment{}
+	g.Expect(c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: "test-reaper"}, deployment)).To(Succeed())
+	g.Expect(deployment.Spec.Template.Annotations).To(HaveKey(rollout.RestartedAtAnnotation))
+}
+
+func TestRotateAlterFailure(t *testing.T) {
+	g := NewWithT(t)
+
+	cassandra := newFakeCassandra()
+	cassandra.failAlter = true
+	rotator, c := newTestRotator(t, cassandra)
+
+	err := rotator.Rotate(context.Background(), UserReaper, "")
+	g.Expect(err).To(MatchError(ContainSubstring("nothing was changed")))
+	g.Expect(readSecret(g, c, "test-reaper").Password).To(Equal("reaperpass"))
+}
+
+func TestRotateRollback(t *testing.T) {
+	g := NewWithT(t)
+
+	cassandra := newFakeCassandra()
+	cassandra.failLogin = true
+	rotator, c := newTestRotator(t, cassandra)
+
+	err := rotator.Rotate(context.Background(), UserReaper, "")
+	g.Expect(err).To(MatchError(ContainSubstring("the old password was restored")))
+	g.Expect(readSecret(g, c, "test-reaper").Password).To(Equal("reaperpass"))
+	g.Expect(cassandra.passwords["test-reaper"]).To(Equal("reaperpass"))
+}
+
+func TestRotateUnknownSecret(t *testing.T) {
+	g := NewWithT(t)
+
+	rotator, _ := newTestRotator(t, newFakeCassandra())
+
+	err := rotator.Rotate(context.Background(), UserStargate, "")
+	g.Expect(err).To(MatchError(ContainSubstring("is stargate enabled in the release?")))
+}
+
+func TestAlterRoleStatement(t *testing.T) {
+	g := NewWithT(t)
+
+	statement := AlterRoleStatement(&credentials.Credentials{Username: `we"ird`, Password: "it's"})
+	g.Expect(statement).To(Equal(`ALTER ROLE "we""ird" WITH PASSWORD = 'it''s';`))
+}
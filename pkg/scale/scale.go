@@ -0,0 +1,281 @@
+package scale
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8ssandra/k8ssandra/pkg/cqlsh"
+	"github.com/k8ssandra/k8ssandra/pkg/replication"
+	"github.com/k8ssandra/k8ssandra/pkg/rollout"
+)
+
+const (
+	// Pod states set by cass-operator in the cassandra.datastax.com/node-state label
+	nodeStateStarted         = "Started"
+	nodeStateDecommissioning = "Decommissioning"
+)
+
+// NodetoolFunc runs nodetool in the Cassandra container of a pod
+type NodetoolFunc func(ctx context.Context, pod string, args ...string) (string, error)
+
+// CqlshNodetool returns a NodetoolFunc running nodetool with the credentials of the given session
+func CqlshNodetool(session *cqlsh.Session) NodetoolFunc {
+	return func(ctx context.Context, pod string, args ...string) (string, error) {
+		s := *session
+		s.Pod = pod
+		return s.Nodetool(ctx, args...)
+	}
+}
+
+// Violation is a keyspace whose replication factor would exceed the number of nodes it can be placed on
+type Violation struct {
+	Keyspace          string
+	ReplicationFactor int
+	Nodes             int
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s has a replication factor of %d for %d nodes", v.Keyspace, v.ReplicationFactor, v.Nodes)
+}
+
+// Scaler changes the number of nodes of a CassandraDatacenter
+type Scaler struct {
+	Client client.Client
+	// Session runs the CQL queries checking the replication of the keyspaces
+	Session replication.Executor
+	// Nodetool runs the cleanup of the nodes after a scale-up
+	Nodetool NodetoolFunc
+
+	// Timeout bounds the wait for cass-operator to bootstrap or decommission the nodes
+	Timeout time.Duration
+}
+
+// Result describes a completed scale operation
+type Result struct {
+	PreviousSize int32
+	Size         int32
+	// PreviousPods are the pods of the CassandraDatacenter before it was scaled, the ones that need a cleanup after a
+	// scale-up
+	PreviousPods []string
+}
+
+// ScaledUp returns true if nodes were added to the datacenter
+func (r *Result) ScaledUp() bool {
+	return r.Size > r.PreviousSize
+}
+
+// Scale sets the size of the CassandraDatacenter and waits for cass-operator to bootstrap or decommission the nodes,
+// logging the progress of each pod. Scale-downs are refused if a keyspace would have a replication factor higher than
+// the number of nodes left in the datacenter.
+func (s *Scaler) Scale(ctx context.Context, key types.NamespacedName, size int32) (*Result, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("invalid size %d, use helm uninstall to remove a datacenter", size)
+	}
+
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	if err := s.Client.Get(ctx, key, cassdc); err != nil {
+		return nil, fmt.Errorf("failed to get CassandraDatacenter %s: %w", key, err)
+	}
+	result := &Result{PreviousSize: cassdc.Spec.Size, Size: size}
+	if size == cassdc.Spec.Size {
+		log.Printf("CassandraDatacenter %s already has %d nodes", key, size)
+		return result, nil
+	}
+
+	if ready, err := rollout.DatacenterReady(ctx, s.Client, cassdc); err != nil {
+		return nil, err
+	} else if !ready {
+		return nil, fmt.Errorf("CassandraDatacenter %s is not ready, wait for cass-operator to be done with it", key)
+	}
+
+	if size < cassdc.Spec.Size {
+		if err := s.CheckScaleDown(ctx, cassdc, size); err != nil {
+			return nil, err
+		}
+	}
+
+	pods, err := s.pods(ctx, cassdc)
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		result.PreviousPods = append(result.PreviousPods, pod.Name)
+	}
+	sort.Strings(result.PreviousPods)
+
+	log.Printf("Scaling CassandraDatacenter %s from %d to %d nodes", key, cassdc.Spec.Size, size)
+	patch := client.MergeFrom(cassdc.DeepCopy())
+	cassdc.Spec.Size = size
+	if err := s.Client.Patch(ctx, cassdc, patch); err != nil {
+		return nil, fmt.Errorf("failed to set the size of CassandraDatacenter %s: %w", key, err)
+	}
+
+	if err := s.wait(ctx, key, size); err != nil {
+		return nil, err
+	}
+	log.Printf("CassandraDatacenter %s has %d nodes", key, size)
+	return result, nil
+}
+
+// CheckScaleDown returns an error listing the keyspaces whose replication factor would exceed the number of nodes
+// after scaling the CassandraDatacenter down to size
+func (s *Scaler) CheckScaleDown(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter, size int32) error {
+	keyspaces, err := replication.Read(ctx, s.Session)
+	if err != nil {
+		return err
+	}
+
+	// SimpleStrategy places replicas on the nodes of all the datacenters of the cluster
+	clusterNodes := int(size)
+	dcs := &cassdcapi.CassandraDatacenterList{}
+	if err := s.Client.List(ctx, dcs, client.InNamespace(cassdc.Namespace)); err != nil {
+		return fmt.Errorf("failed to list CassandraDatacenters: %w", err)
+	}
+	for _, dc := range dcs.Items {
+		if dc.Name != cassdc.Name && dc.Spec.ClusterName == cassdc.Spec.ClusterName {
+			clusterNodes += int(dc.Spec.Size)
+		}
+	}
+
+	violations := CheckReplication(keyspaces, cassdc.Name, int(size), clusterNodes)
+	if len(violations) > 0 {
+		messages := make([]string, len(violations))
+		for i, v := range violations {
+			messages[i] = v.String()
+		}
+		return fmt.Errorf("refusing to scale CassandraDatacenter %s down to %d nodes, lower the replication factor of these keyspaces first: %s",
+			cassdc.Name, size, strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// Cleanup runs nodetool cleanup on each pod, one after the other, to remove the data the nodes no longer own after
+// a scale-up
+func (s *Scaler) Cleanup(ctx context.Context, pods []string) error {
+	for _, pod := range pods {
+		log.Printf("Running nodetool cleanup on pod %s", pod)
+		start := time.Now()
+		if _, err := s.Nodetool(ctx, pod, "cleanup"); err != nil {
+			return err
+		}
+		log.Printf("Cleanup of pod %s done in %s", pod, time.Since(start).Round(time.Second))
+	}
+	return nil
+}
+
+// wait waits for cass-operator to bring the CassandraDatacenter to size started nodes, logging pod state changes
+func (s *Scaler) wait(ctx context.Context, key types.NamespacedName, size int32) error {
+	states := make(map[string]string)
+	err := wait.PollImmediate(rollout.PollInterval, s.Timeout, func() (bool, error) {
+		cassdc := &cassdcapi.CassandraDatacenter{}
+		if err := s.Client.Get(ctx, key, cassdc); err != nil {
+			return false, err
+		}
+		pods, err := s.pods(ctx, cassdc)
+		if err != nil {
+			return false, err
+		}
+
+		started := logProgress(states, pods)
+		if cassdc.Status.ObservedGeneration < cassdc.Generation || started != int(size) || len(pods) != int(size) {
+			return false, nil
+		}
+		return rollout.DatacenterReady(ctx, s.Client, cassdc)
+	})
+	if err != nil {
+		return fmt.Errorf("CassandraDatacenter %s did not reach %d nodes: %w", key, size, err)
+	}
+	return nil
+}
+
+func (s *Scaler) pods(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter) ([]corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := s.Client.List(ctx, pods, client.InNamespace(cassdc.Namespace), client.MatchingLabels(cassdc.GetDatacenterLabels())); err != nil {
+		return nil, fmt.Errorf("failed to list pods of CassandraDatacenter %s: %w", cassdc.Name, err)
+	}
+	return pods.Items, nil
+}
+
+// logProgress logs the pods whose state changed since the last call, and returns the number of started pods.
+// states holds the last known state of each pod.
+func logProgress(states map[string]string, pods []corev1.Pod) int {
+	started := 0
+	seen := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		seen[pod.Name] = true
+		state := podState(&pod)
+		if state == nodeStateStarted {
+			started++
+		}
+		if previous, found := states[pod.Name]; !found || previous != state {
+			log.Printf("Pod %s: %s", pod.Name, state)
+			states[pod.Name] = state
+		}
+	}
+
+	names := make([]string, 0)
+	for name := range states {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.Printf("Pod %s: removed", name)
+		delete(states, name)
+	}
+	return started
+}
+
+// podState returns the node state set by cass-operator, only reporting a pod as started once Cassandra is ready
+func podState(pod *corev1.Pod) string {
+	state := pod.Labels[cassdcapi.CassNodeState]
+	if state == "" {
+		state = string(pod.Status.Phase)
+	}
+	if state == nodeStateStarted && !cassandraReady(pod) {
+		return "Started, not ready"
+	}
+	if pod.DeletionTimestamp != nil && state != nodeStateDecommissioning {
+		return "Terminating"
+	}
+	return state
+}
+
+func cassandraReady(pod *corev1.Pod) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == "cassandra" {
+			return status.Ready
+		}
+	}
+	return false
+}
+
+// CheckReplication returns the keyspaces which cannot be placed on dcNodes nodes in datacenter dc, with clusterNodes
+// nodes across all the datacenters of the cluster. Keyspaces using LocalStrategy or EverywhereStrategy always fit.
+func CheckReplication(keyspaces []replication.KeyspaceReplication, dc string, dcNodes, clusterNodes int) []Violation {
+	violations := make([]Violation, 0)
+	for _, keyspace := range keyspaces {
+		switch keyspace.Class {
+		case replication.NetworkTopologyStrategy:
+			if rf := keyspace.Factors[dc]; rf > dcNodes {
+				violations = append(violations, Violation{Keyspace: keyspace.Keyspace, ReplicationFactor: rf, Nodes: dcNodes})
+			}
+		case replication.SimpleStrategy:
+			if rf := keyspace.Factors["replication_factor"]; rf > clusterNodes {
+				violations = append(violations, Violation{Keyspace: keyspace.Keyspace, ReplicationFactor: rf, Nodes: clusterNodes})
+			}
+		}
+	}
+	return violations
+}
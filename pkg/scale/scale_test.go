@@ -0,0 +1,214 @@
+package scale
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k8ssandra/k8ssandra/pkg/replication"
+	"github.com/k8ssandra/k8ssandra/pkg/rollout"
+)
+
+const testNamespace = "k8ssandra"
+
+var dcKey = types.NamespacedName{Namespace: testNamespace, Name: "dc1"}
+
+const keyspacesOutput = `
+ [json]
+------------------------------------------------------------------------------------------------------------
+ {"keyspace_name": "system_auth", "replication": {"class": "org.apache.cassandra.locator.NetworkTopologyStrategy", "dc1": "3"}}
+ {"keyspace_name": "system", "replication": {"class": "org.apache.cassandra.locator.LocalStrategy"}}
+ {"keyspace_name": "app", "replication": {"class": "org.apache.cassandra.locator.NetworkTopologyStrategy", "dc1": "2", "dc2": "3"}}
+ {"keyspace_name": "legacy", "replication": {"class": "org.apache.cassandra.locator.SimpleStrategy", "replication_factor": "2"}}
+
+(4 rows)
+`
+
+type fakeSession struct {
+	output string
+}
+
+func (s *fakeSession) Execute(ctx context.Context, statements string) (string, error) {
+	return s.output, nil
+}
+
+func pod(name, state string, ready bool) *corev1.Pod {
+	cassdc := &cassdcapi.CassandraDatacenter{ObjectMeta: metav1.ObjectMeta{Name: "dc1"}, Spec: cassdcapi.CassandraDatacenterSpec{ClusterName: "test"}}
+	labels := cassdc.GetDatacenterLabels()
+	labels[cassdcapi.CassNodeState] = state
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: name, Labels: labels},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "cassandra", Ready: ready}},
+		},
+	}
+}
+
+func statefulSet(replicas int32) *appsv1.StatefulSet {
+	cassdc := &cassdcapi.CassandraDatacenter{ObjectMeta: metav1.ObjectMeta{Name: "dc1"}, Spec: cassdcapi.CassandraDatacenterSpec{ClusterName: "test"}}
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "test-dc1-default-sts", Labels: cassdc.GetDatacenterLabels()},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status:     appsv1.StatefulSetStatus{Replicas: replicas, ReadyReplicas: replicas, UpdatedReplicas: replicas},
+	}
+}
+
+func newTestScaler(t *testing.T, size int32, nodetool NodetoolFunc) (*Scaler, client.Client) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = cassdcapi.AddToScheme(scheme)
+
+	objects := []runtime.Object{
+		&cassdcapi.CassandraDatacenter{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "dc1"},
+			Spec:       cassdcapi.CassandraDatacenterSpec{ClusterName: "test", Size: size},
+			Status: cassdcapi.CassandraDatacenterStatus{
+				CassandraOperatorProgress: cassdcapi.ProgressReady,
+				Conditions:                []cassdcapi.DatacenterCondition{{Type: cassdcapi.DatacenterReady, Status: corev1.ConditionTrue}},
+			},
+		},
+		statefulSet(size),
+	}
+	for i := int32(0); i < size; i++ {
+		objects = append(objects, pod(fmt.Sprintf("test-dc1-default-sts-%d", i), nodeStateStarted, true))
+	}
+	c := fake.NewFakeClientWithScheme(scheme, objects...)
+
+	rollout.PollInterval = 10 * time.Millisecond
+	return &Scaler{
+		Client:   c,
+		Session:  &fakeSession{output: keyspacesOutput},
+		Nodetool: nodetool,
+		Timeout:  5 * time.Second,
+	}, c
+}
+
+// operate plays cass-operator's part once the size of the CassandraDatacenter changed: it adds the missing pods, or
+// removes the extra ones, and updates the StatefulSet
+func operate(t *testing.T, c client.Client, from, to int32) {
+	ctx := context.Background()
+	for {
+		cassdc := &cassdcapi.CassandraDatacenter{}
+		if err := c.Get(ctx, dcKey, cassdc); err != nil {
+			t.Error(err)
+			return
+		}
+		if cassdc.Spec.Size == to {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	for i := from; i < to; i++ {
+		if err := c.Create(ctx, pod(fmt.Sprintf("test-dc1-default-sts-%d", i), nodeStateStarted, true)); err != nil {
+			t.Error(err)
+		}
+	}
+	for i := to; i < from; i++ {
+		p := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: fmt.Sprintf("test-dc1-default-sts-%d", i)}}
+		if err := c.Delete(ctx, p); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := c.Update(ctx, statefulSet(to)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckReplication(t *testing.T) {
+	g := NewWithT(t)
+
+	keyspaces, err := replication.Read(context.Background(), &fakeSession{output: keyspacesOutput})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(CheckReplication(keyspaces, "dc1", 3, 3)).To(BeEmpty())
+	g.Expect(CheckReplication(keyspaces, "dc1", 2, 2)).To(Equal([]Violation{{Keyspace: "system_auth", ReplicationFactor: 3, Nodes: 2}}))
+	g.Expect(CheckReplication(keyspaces, "dc1", 1, 1)).To(HaveLen(3))
+	// the other datacenters hold replicas of SimpleStrategy keyspaces
+	g.Expect(CheckReplication(keyspaces, "dc2", 1, 4)).To(Equal([]Violation{{Keyspace: "app", ReplicationFactor: 3, Nodes: 1}}))
+}
+
+func TestScaleUp(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	cleaned := make([]string, 0)
+	scaler, c := newTestScaler(t, 3, func(ctx context.Context, pod string, args ...string) (string, error) {
+		g.Expect(args).To(Equal([]string{"cleanup"}))
+		cleaned = append(cleaned, pod)
+		return "", nil
+	})
+
+	go operate(t, c, 3, 4)
+	result, err := scaler.Scale(ctx, dcKey, 4)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.ScaledUp()).To(BeTrue())
+	g.Expect(result.PreviousPods).To(Equal([]string{"test-dc1-default-sts-0", "test-dc1-default-sts-1", "test-dc1-default-sts-2"}))
+
+	g.Expect(scaler.Cleanup(ctx, result.PreviousPods)).To(Succeed())
+	g.Expect(cleaned).To(Equal(result.PreviousPods))
+}
+
+func TestScaleDown(t *testing.T) {
+	g := NewWithT(t)
+
+	scaler, c := newTestScaler(t, 4, nil)
+
+	go operate(t, c, 4, 3)
+	result, err := scaler.Scale(context.Background(), dcKey, 3)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.ScaledUp()).To(BeFalse())
+
+	pods := &corev1.PodList{}
+	g.Expect(c.List(context.Background(), pods)).To(Succeed())
+	g.Expect(pods.Items).To(HaveLen(3))
+}
+
+func TestScaleDownRefused(t *testing.T) {
+	g := NewWithT(t)
+
+	scaler, c := newTestScaler(t, 3, nil)
+
+	_, err := scaler.Scale(context.Background(), dcKey, 2)
+	g.Expect(err).To(MatchError(ContainSubstring("system_auth has a replication factor of 3 for 2 nodes")))
+
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	g.Expect(c.Get(context.Background(), dcKey, cassdc)).To(Succeed())
+	g.Expect(cassdc.Spec.Size).To(Equal(int32(3)))
+}
+
+func TestScaleNotReady(t *testing.T) {
+	g := NewWithT(t)
+
+	scaler, c := newTestScaler(t, 3, nil)
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	g.Expect(c.Get(context.Background(), dcKey, cassdc)).To(Succeed())
+	cassdc.Status.CassandraOperatorProgress = cassdcapi.ProgressUpdating
+	g.Expect(c.Update(context.Background(), cassdc)).To(Succeed())
+
+	_, err := scaler.Scale(context.Background(), dcKey, 4)
+	g.Expect(err).To(MatchError(ContainSubstring("is not ready")))
+}
+
+func TestLogProgress(t *testing.T) {
+	g := NewWithT(t)
+
+	states := make(map[string]string)
+	g.Expect(logProgress(states, []corev1.Pod{*pod("a", nodeStateStarted, true), *pod("b", "Starting", false)})).To(Equal(1))
+	g.Expect(states).To(Equal(map[string]string{"a": nodeStateStarted, "b": "Starting"}))
+
+	g.Expect(logProgress(states, []corev1.Pod{*pod("b", nodeStateStarted, false)})).To(Equal(0))
+	g.Expect(states).To(Equal(map[string]string{"b": "Started, not ready"}))
+}
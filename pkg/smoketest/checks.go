@@ -0,0 +1,336 @@
+package smoketest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	reaperapi "github.com/k8ssandra/reaper-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8ssandra/k8ssandra/pkg/cqlsh"
+	"github.com/k8ssandra/k8ssandra/pkg/credentials"
+	"github.com/k8ssandra/k8ssandra/pkg/reaper"
+	"github.com/k8ssandra/k8ssandra/pkg/stargate"
+)
+
+const (
+	smokeTable = "smoke"
+	smokeValue = "k8ssandra"
+
+	prometheusPort = 9090
+)
+
+var serviceMonitorListKind = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitorList"}
+
+// Executor runs CQL statements and queries
+type Executor interface {
+	Execute(ctx context.Context, statements string) (string, error)
+	QueryJSON(ctx context.Context, query string) ([]map[string]interface{}, error)
+}
+
+// Release is a k8ssandra release to smoke test. The URL overrides are needed when running outside of the Kubernetes
+// cluster, where service names do not resolve.
+type Release struct {
+	Client    client.Client
+	Config    *rest.Config
+	Namespace string
+	Name      string
+
+	// CQLSession opens the session of the CQL check, a cqlsh session on a ready pod of the datacenter when nil
+	CQLSession func(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter) (Executor, error)
+	// StargateEndpoints override the endpoints of the Stargate service
+	StargateEndpoints *stargate.Endpoints
+	// StargateSecret overrides the stargate user secret created by the chart
+	StargateSecret string
+	// ReaperURL overrides the Reaper service URL
+	ReaperURL *url.URL
+	// PrometheusURL overrides the URL of the Prometheus instance deployed by kube-prometheus-stack
+	PrometheusURL string
+}
+
+// Checks returns the smoke tests of the release: a CQL round trip, a Stargate REST round trip, the registration of
+// the cluster in Reaper and the state of the Prometheus targets. Checks of disabled components are skipped.
+func (r *Release) Checks() []Check {
+	return []Check{
+		{Name: "cql", Run: r.checkCQL},
+		{Name: "stargate", Run: r.checkStargate},
+		{Name: "reaper", Run: r.checkReaper},
+		{Name: "prometheus", Run: r.checkPrometheus},
+	}
+}
+
+// smokeKeyspace returns a keyspace name which is unique to the run
+func smokeKeyspace(prefix string) string {
+	return fmt.Sprintf("%s_smoketest_%d", prefix, time.Now().Unix())
+}
+
+func (r *Release) checkCQL(ctx context.Context) error {
+	cassdc, err := cqlsh.LookupDatacenter(ctx, r.Client, r.Namespace, r.Name)
+	if err != nil {
+		return err
+	}
+	session, err := r.cqlSession(ctx, cassdc)
+	if err != nil {
+		return err
+	}
+
+	keyspace := smokeKeyspace("cql")
+	statements := fmt.Sprintf(`CREATE KEYSPACE %[1]s WITH replication = {'class': 'NetworkTopologyStrategy', '%[2]s': 1};
+CREATE TABLE %[1]s.%[3]s (id int PRIMARY KEY, value text);
+INSERT INTO %[1]s.%[3]s (id, value) VALUES (1, '%[4]s');
+`, keyspace, cassdc.Name, smokeTable, smokeValue)
+
+	defer func() {
+		_, _ = session.Execute(context.Background(), fmt.Sprintf("DROP KEYSPACE IF EXISTS %s;", keyspace))
+	}()
+
+	if _, err := session.Execute(ctx, statements); err != nil {
+		return err
+	}
+
+	rows, err := session.QueryJSON(ctx, fmt.Sprintf("SELECT JSON value FROM %s.%s WHERE id = 1;", keyspace, smokeTable))
+	if err != nil {
+		return err
+	}
+	if len(rows) != 1 || rows[0]["value"] != smokeValue {
+		return fmt.Errorf("read %v from %s.%s, expected the value written", rows, keyspace, smokeTable)
+	}
+	return nil
+}
+
+func (r *Release) cqlSession(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter) (Executor, error) {
+	if r.CQLSession != nil {
+		return r.CQLSession(ctx, cassdc)
+	}
+	return cqlsh.DatacenterSession(ctx, r.Client, r.Config, cassdc)
+}
+
+func (r *Release) checkStargate(ctx context.Context) error {
+	cassdc, err := cqlsh.LookupDatacenter(ctx, r.Client, r.Namespace, r.Name)
+	if err != nil {
+		return err
+	}
+
+	serviceKey := types.NamespacedName{Namespace: r.Namespace, Name: stargate.ServiceName(r.Name, cassdc.Name)}
+	if err := r.Client.Get(ctx, serviceKey, &corev1.Service{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return Skip("Stargate is not enabled")
+		}
+		return err
+	}
+
+	secretName := r.StargateSecret
+	if secretName == "" {
+		secretName = stargate.UserSecretName(cassdc.Spec.ClusterName)
+	}
+	var conn *stargate.Connection
+	err = r.Client.Get(ctx, types.NamespacedName{Namespace: r.Namespace, Name: secretName}, &corev1.Secret{})
+	switch {
+	case apierrors.IsNotFound(err):
+		// The stargate user secret only exists if authentication is enabled, any user is accepted otherwise
+		superuser, err := credentials.FromSecret(ctx, r.Client, cassdc.GetSuperuserSecretNamespacedName())
+		if err != nil {
+			return err
+		}
+		conn = &stargate.Connection{
+			Endpoints:   stargate.EndpointsForHost("http", fmt.Sprintf("%s.%s", serviceKey.Name, serviceKey.Namespace)),
+			Credentials: superuser,
+		}
+	case err != nil:
+		return fmt.Errorf("failed to get secret %s: %w", secretName, err)
+	default:
+		if conn, err = stargate.LookupConnection(ctx, r.Client, r.Namespace, r.Name, r.StargateSecret); err != nil {
+			return err
+		}
+	}
+	if r.StargateEndpoints != nil {
+		conn.Endpoints = *r.StargateEndpoints
+	}
+	sg := conn.Connect()
+
+	keyspace := smokeKeyspace("stargate")
+	err = sg.CreateKeyspace(ctx, &stargate.Keyspace{
+		Name:        keyspace,
+		Datacenters: []stargate.DatacenterReplicas{{Name: cassdc.Name, Replicas: 1}},
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = sg.DeleteKeyspace(context.Background(), keyspace)
+	}()
+
+	table := &stargate.Table{
+		Name: smokeTable,
+		ColumnDefinitions: []stargate.ColumnDefinition{
+			{Name: "id", TypeDefinition: "int"},
+			{Name: "value", TypeDefinition: "text"},
+		},
+		PrimaryKey: stargate.PrimaryKey{PartitionKey: []string{"id"}},
+	}
+	if err := sg.CreateTable(ctx, keyspace, table); err != nil {
+		return err
+	}
+	if err := sg.AddRow(ctx, keyspace, smokeTable, map[string]interface{}{"id": 1, "value": smokeValue}); err != nil {
+		return err
+	}
+
+	rows, err := sg.GetRows(ctx, keyspace, smokeTable, "1")
+	if err != nil {
+		return err
+	}
+	if rows.Count != 1 || rows.Data[0]["value"] != smokeValue {
+		return fmt.Errorf("read %v from %s.%s, expected the value written", rows.Data, keyspace, smokeTable)
+	}
+	return nil
+}
+
+func (r *Release) checkReaper(ctx context.Context) error {
+	reaperKey := types.NamespacedName{Namespace: r.Namespace, Name: reaper.ReaperName(r.Name)}
+	if err := r.Client.Get(ctx, reaperKey, &reaperapi.Reaper{}); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return Skip("Reaper is not enabled")
+		}
+		return err
+	}
+
+	conn, err := reaper.LookupConnection(ctx, r.Client, r.Namespace, r.Name)
+	if err != nil {
+		return err
+	}
+	if r.ReaperURL != nil {
+		conn.URL = r.ReaperURL
+	}
+
+	rc, err := conn.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	clusters, err := rc.GetClusterNames(ctx)
+	if err != nil {
+		return err
+	}
+	for _, cluster := range clusters {
+		if cluster == conn.ClusterName {
+			return nil
+		}
+	}
+	return fmt.Errorf("cluster %s is not registered in Reaper, registered clusters: %v", conn.ClusterName, clusters)
+}
+
+// prometheusTarget is an active target as returned by the Prometheus targets API
+type prometheusTarget struct {
+	ScrapePool string `json:"scrapePool"`
+	ScrapeURL  string `json:"scrapeUrl"`
+	Health     string `json:"health"`
+	LastError  string `json:"lastError"`
+}
+
+func (r *Release) checkPrometheus(ctx context.Context) error {
+	monitors := &unstructured.UnstructuredList{}
+	monitors.SetGroupVersionKind(serviceMonitorListKind)
+	if err := r.Client.List(ctx, monitors, client.InNamespace(r.Namespace), client.MatchingLabels{"release": r.Name}); err != nil {
+		if meta.IsNoMatchError(err) {
+			return Skip("the ServiceMonitor CRD is not installed")
+		}
+		return err
+	}
+	if len(monitors.Items) == 0 {
+		return Skip("the release has no ServiceMonitor")
+	}
+
+	prometheusURL, err := r.prometheusURL(ctx)
+	if err != nil {
+		return err
+	}
+	targets, err := activeTargets(ctx, prometheusURL)
+	if err != nil {
+		return err
+	}
+
+	problems := make([]string, 0)
+	for _, monitor := range monitors.Items {
+		monitorTargets := serviceMonitorTargets(targets, monitor.GetNamespace(), monitor.GetName())
+		if len(monitorTargets) == 0 {
+			problems = append(problems, fmt.Sprintf("ServiceMonitor %s has no active target", monitor.GetName()))
+		}
+		for _, target := range monitorTargets {
+			if target.Health != "up" {
+				problems = append(problems, fmt.Sprintf("target %s of ServiceMonitor %s is %s: %s", target.ScrapeURL, monitor.GetName(), target.Health, target.LastError))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// prometheusURL returns the URL of the Prometheus instance deployed by the kube-prometheus-stack subchart
+func (r *Release) prometheusURL(ctx context.Context) (string, error) {
+	if r.PrometheusURL != "" {
+		return strings.TrimSuffix(r.PrometheusURL, "/"), nil
+	}
+
+	services := &corev1.ServiceList{}
+	labels := client.MatchingLabels{"app": "kube-prometheus-stack-prometheus", "release": r.Name}
+	if err := r.Client.List(ctx, services, client.InNamespace(r.Namespace), labels); err != nil {
+		return "", err
+	}
+	if len(services.Items) == 0 {
+		return "", fmt.Errorf("no Prometheus service found for release %s, set the Prometheus URL explicitly", r.Name)
+	}
+	return fmt.Sprintf("http://%s.%s:%d", services.Items[0].Name, r.Namespace, prometheusPort), nil
+}
+
+func activeTargets(ctx context.Context, prometheusURL string) ([]prometheusTarget, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, prometheusURL+"/api/v1/targets?state=active", nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Prometheus targets: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch Prometheus targets: unexpected status code %d", res.StatusCode)
+	}
+
+	body := struct {
+		Data struct {
+			ActiveTargets []prometheusTarget `json:"activeTargets"`
+		} `json:"data"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode Prometheus targets: %w", err)
+	}
+	return body.Data.ActiveTargets, nil
+}
+
+// serviceMonitorTargets returns the targets scraped for a ServiceMonitor. Depending on the Prometheus operator
+// version, scrape pools are named serviceMonitor/<namespace>/<name>/<endpoint> or <namespace>/<name>/<endpoint>.
+func serviceMonitorTargets(targets []prometheusTarget, namespace, name string) []prometheusTarget {
+	prefix := namespace + "/" + name + "/"
+	matching := make([]prometheusTarget, 0)
+	for _, target := range targets {
+		if strings.HasPrefix(strings.TrimPrefix(target.ScrapePool, "serviceMonitor/"), prefix) {
+			matching = append(matching, target)
+		}
+	}
+	return matching
+}
@@ -0,0 +1,350 @@
+package smoketest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	reaperapi "github.com/k8ssandra/reaper-operator/api/v1alpha1"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k8ssandra/k8ssandra/pkg/stargate"
+)
+
+const (
+	testNamespace = "k8ssandra"
+	testRelease   = "test"
+)
+
+func newTestClient(g *WithT, objects ...runtime.Object) client.Client {
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(cassdcapi.AddToScheme(scheme)).To(Succeed())
+	g.Expect(reaperapi.AddToScheme(scheme)).To(Succeed())
+	// ServiceMonitors are only read as unstructured objects
+	scheme.AddKnownTypeWithName(serviceMonitorListKind.GroupVersion().WithKind("ServiceMonitor"), &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(serviceMonitorListKind, &unstructured.UnstructuredList{})
+	return fake.NewFakeClientWithScheme(scheme, objects...)
+}
+
+func newDatacenter() *cassdcapi.CassandraDatacenter {
+	return &cassdcapi.CassandraDatacenter{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace,
+			Name:      "dc1",
+			Labels:    map[string]string{"app.kubernetes.io/instance": testRelease},
+		},
+		Spec: cassdcapi.CassandraDatacenterSpec{ClusterName: testRelease},
+	}
+}
+
+func newSecret(name, username, password string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: name},
+		Data:       map[string][]byte{"username": []byte(username), "password": []byte(password)},
+	}
+}
+
+// fakeCQL records the statements it runs and answers every query with rows
+type fakeCQL struct {
+	statements []string
+	rows       []map[string]interface{}
+	err        error
+}
+
+func (f *fakeCQL) Execute(ctx context.Context, statements string) (string, error) {
+	f.statements = append(f.statements, statements)
+	if f.err != nil && !strings.HasPrefix(statements, "DROP") {
+		return "", f.err
+	}
+	return "", nil
+}
+
+func (f *fakeCQL) QueryJSON(ctx context.Context, query string) ([]map[string]interface{}, error) {
+	f.statements = append(f.statements, query)
+	return f.rows, nil
+}
+
+func TestCheckCQL(t *testing.T) {
+	g := NewWithT(t)
+
+	cql := &fakeCQL{rows: []map[string]interface{}{{"value": smokeValue}}}
+	release := &Release{
+		Client:    newTestClient(g, newDatacenter()),
+		Namespace: testNamespace,
+		Name:      testRelease,
+		CQLSession: func(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter) (Executor, error) {
+			return cql, nil
+		},
+	}
+
+	g.Expect(release.checkCQL(context.Background())).To(Succeed())
+	g.Expect(cql.statements).To(HaveLen(3))
+	g.Expect(cql.statements[0]).To(ContainSubstring("'NetworkTopologyStrategy', 'dc1': 1"))
+	g.Expect(cql.statements[0]).To(ContainSubstring("VALUES (1, 'k8ssandra')"))
+	g.Expect(cql.statements[2]).To(HavePrefix("DROP KEYSPACE IF EXISTS cql_smoketest_"))
+
+	// the keyspace is dropped when the check fails
+	cql.statements = nil
+	cql.rows = nil
+	g.Expect(release.checkCQL(context.Background())).To(MatchError(ContainSubstring("expected the value written")))
+	g.Expect(cql.statements[len(cql.statements)-1]).To(HavePrefix("DROP KEYSPACE"))
+
+	cql.statements = nil
+	cql.err = errors.New("Unavailable")
+	g.Expect(release.checkCQL(context.Background())).To(MatchError("Unavailable"))
+	g.Expect(cql.statements).To(HaveLen(2))
+
+	release.Client = newTestClient(g)
+	g.Expect(release.checkCQL(context.Background())).To(MatchError(ContainSubstring("has no CassandraDatacenter")))
+}
+
+// fakeStargate serves the auth and REST APIs on a single listener, keeping the rows of each keyspace
+type fakeStargate struct {
+	mu        sync.Mutex
+	keyspaces map[string][]map[string]interface{}
+	// loseRows drops the rows written, as if the write did not reach the read replicas
+	loseRows bool
+}
+
+func (f *fakeStargate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/v1/auth" {
+		creds := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&creds)
+		if creds["username"] != "stargate" || creds["password"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]string{"authToken": "token"})
+		return
+	}
+	if r.Header.Get("X-Cassandra-Token") != "token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/v2/"), "/")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v2/schemas/keyspaces":
+		keyspace := stargate.Keyspace{}
+		_ = json.NewDecoder(r.Body).Decode(&keyspace)
+		f.keyspaces[keyspace.Name] = nil
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPost && len(segments) == 4 && segments[0] == "schemas":
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete && len(segments) == 3 && segments[0] == "schemas":
+		delete(f.keyspaces, segments[2])
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && len(segments) == 3 && segments[0] == "keyspaces":
+		row := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&row)
+		if !f.loseRows {
+			f.keyspaces[segments[1]] = append(f.keyspaces[segments[1]], row)
+		}
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && len(segments) == 4 && segments[0] == "keyspaces":
+		rows := f.keyspaces[segments[1]]
+		_ = json.NewEncoder(w).Encode(stargate.Rows{Count: len(rows), Data: rows})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestCheckStargate(t *testing.T) {
+	g := NewWithT(t)
+
+	sg := &fakeStargate{keyspaces: make(map[string][]map[string]interface{})}
+	server := httptest.NewServer(sg)
+	defer server.Close()
+
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: stargate.ServiceName(testRelease, "dc1")}}
+	release := &Release{
+		Client:            newTestClient(g, newDatacenter(), service, newSecret(stargate.UserSecretName(testRelease), "stargate", "secret")),
+		Namespace:         testNamespace,
+		Name:              testRelease,
+		StargateEndpoints: &stargate.Endpoints{Auth: server.URL, REST: server.URL, GraphQL: server.URL},
+	}
+
+	g.Expect(release.checkStargate(context.Background())).To(Succeed())
+	g.Expect(sg.keyspaces).To(BeEmpty())
+
+	sg.loseRows = true
+	g.Expect(release.checkStargate(context.Background())).To(MatchError(ContainSubstring("expected the value written")))
+	g.Expect(sg.keyspaces).To(BeEmpty())
+
+	release.Client = newTestClient(g, newDatacenter())
+	err := release.checkStargate(context.Background())
+	g.Expect(err).To(BeAssignableToTypeOf(&SkipError{}))
+	g.Expect(err).To(MatchError("Stargate is not enabled"))
+}
+
+// forbiddenSecretClient refuses to get a secret, as for a user missing the permission
+type forbiddenSecretClient struct {
+	client.Client
+	name string
+}
+
+func (c forbiddenSecretClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if _, ok := obj.(*corev1.Secret); ok && key.Name == c.name {
+		return apierrors.NewForbidden(corev1.Resource("secrets"), key.Name, fmt.Errorf("access denied"))
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func TestCheckStargateWithoutUserSecret(t *testing.T) {
+	g := NewWithT(t)
+
+	sg := &fakeStargate{keyspaces: make(map[string][]map[string]interface{})}
+	server := httptest.NewServer(sg)
+	defer server.Close()
+
+	// without authentication there is no Stargate user secret, the superuser is used
+	cassdc := newDatacenter()
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: stargate.ServiceName(testRelease, "dc1")}}
+	release := &Release{
+		Client:            newTestClient(g, cassdc, service, newSecret(cassdc.GetSuperuserSecretNamespacedName().Name, "stargate", "secret")),
+		Namespace:         testNamespace,
+		Name:              testRelease,
+		StargateEndpoints: &stargate.Endpoints{Auth: server.URL, REST: server.URL, GraphQL: server.URL},
+	}
+	g.Expect(release.checkStargate(context.Background())).To(Succeed())
+
+	// other failures to read the secret are not mistaken for a missing secret
+	release.Client = forbiddenSecretClient{Client: release.Client, name: stargate.UserSecretName(testRelease)}
+	g.Expect(release.checkStargate(context.Background())).To(MatchError(ContainSubstring("access denied")))
+}
+
+// fakeReaper serves the login and cluster list of Reaper's REST API
+type fakeReaper struct {
+	clusters []string
+}
+
+func (f *fakeReaper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/login":
+		_ = r.ParseForm()
+		if r.PostForm.Get("username") != "reaper" || r.PostForm.Get("password") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "session", Path: "/"})
+	case r.Method == http.MethodGet && r.URL.Path == "/cluster":
+		if cookie, err := r.Cookie("JSESSIONID"); err != nil || cookie.Value != "session" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(f.clusters)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestCheckReaper(t *testing.T) {
+	g := NewWithT(t)
+
+	fr := &fakeReaper{clusters: []string{"other", testRelease}}
+	server := httptest.NewServer(fr)
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	reaper := &reaperapi.Reaper{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testRelease + "-reaper"},
+		Spec: reaperapi.ReaperSpec{
+			ServerConfig: reaperapi.ServerConfig{
+				CassandraBackend: &reaperapi.CassandraBackend{
+					CassandraDatacenter:     reaperapi.CassandraDatacenterRef{Name: "dc1"},
+					CassandraUserSecretName: "test-reaper",
+				},
+			},
+		},
+	}
+	release := &Release{
+		Client:    newTestClient(g, newDatacenter(), reaper, newSecret("test-reaper", "reaper", "secret")),
+		Namespace: testNamespace,
+		Name:      testRelease,
+		ReaperURL: serverURL,
+	}
+
+	g.Expect(release.checkReaper(context.Background())).To(Succeed())
+
+	fr.clusters = []string{"other"}
+	g.Expect(release.checkReaper(context.Background())).To(MatchError(
+		"cluster test is not registered in Reaper, registered clusters: [other]"))
+
+	release.Client = newTestClient(g, newDatacenter())
+	err = release.checkReaper(context.Background())
+	g.Expect(err).To(BeAssignableToTypeOf(&SkipError{}))
+	g.Expect(err).To(MatchError("Reaper is not enabled"))
+}
+
+func newServiceMonitor(name string) *unstructured.Unstructured {
+	monitor := &unstructured.Unstructured{}
+	monitor.SetGroupVersionKind(serviceMonitorListKind.GroupVersion().WithKind("ServiceMonitor"))
+	monitor.SetNamespace(testNamespace)
+	monitor.SetName(name)
+	monitor.SetLabels(map[string]string{"release": testRelease})
+	return monitor
+}
+
+func TestCheckPrometheus(t *testing.T) {
+	g := NewWithT(t)
+
+	targets := []prometheusTarget{
+		{ScrapePool: "serviceMonitor/k8ssandra/test-prometheus-k8ssandra/0", ScrapeURL: "http://10.0.0.1:9103/metrics", Health: "up"},
+		{ScrapePool: "serviceMonitor/k8ssandra/test-prometheus-k8ssandra/0", ScrapeURL: "http://10.0.0.2:9103/metrics", Health: "up"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/targets" || r.URL.Query().Get("state") != "active" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"status":"success","data":{"activeTargets":`)
+		_ = json.NewEncoder(w).Encode(targets)
+		_, _ = fmt.Fprint(w, `}}`)
+	}))
+	defer server.Close()
+
+	release := &Release{
+		Client:        newTestClient(g, newServiceMonitor("test-prometheus-k8ssandra")),
+		Namespace:     testNamespace,
+		Name:          testRelease,
+		PrometheusURL: server.URL + "/",
+	}
+	g.Expect(release.checkPrometheus(context.Background())).To(Succeed())
+
+	targets[1].Health = "down"
+	targets[1].LastError = "connection refused"
+	g.Expect(release.checkPrometheus(context.Background())).To(MatchError(
+		"target http://10.0.0.2:9103/metrics of ServiceMonitor test-prometheus-k8ssandra is down: connection refused"))
+
+	release.Client = newTestClient(g, newServiceMonitor("test-prometheus-k8ssandra"), newServiceMonitor("test-prometheus-dc1-stargate"))
+	targets[1].Health = "up"
+	g.Expect(release.checkPrometheus(context.Background())).To(MatchError(
+		"ServiceMonitor test-prometheus-dc1-stargate has no active target"))
+
+	release.Client = newTestClient(g)
+	err := release.checkPrometheus(context.Background())
+	g.Expect(err).To(BeAssignableToTypeOf(&SkipError{}))
+	g.Expect(err).To(MatchError("the release has no ServiceMonitor"))
+}
@@ -0,0 +1,89 @@
+package smoketest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Check is a single smoke test
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// SkipError is returned by checks which do not apply to the release, such as the Stargate check when Stargate is
+// disabled
+type SkipError struct {
+	Reason string
+}
+
+func (e *SkipError) Error() string {
+	return e.Reason
+}
+
+// Skip returns a SkipError with a formatted reason
+func Skip(format string, args ...interface{}) error {
+	return &SkipError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Result is the outcome of a Check. Err is nil if the check passed.
+type Result struct {
+	Name     string
+	Duration time.Duration
+	Skipped  bool
+	Err      error
+}
+
+// Report holds the results of a smoke test run
+type Report struct {
+	Suite     string
+	Timestamp time.Time
+	Duration  time.Duration
+	Results   []Result
+}
+
+// Run runs the checks one after the other, each one with the given timeout, and returns their results
+func Run(ctx context.Context, suite string, timeout time.Duration, checks []Check) *Report {
+	report := &Report{Suite: suite, Timestamp: time.Now()}
+
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		err := check.Run(checkCtx)
+		cancel()
+
+		result := Result{Name: check.Name, Duration: time.Since(start), Err: err}
+		var skip *SkipError
+		if errors.As(err, &skip) {
+			result.Skipped = true
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	report.Duration = time.Since(report.Timestamp)
+	return report
+}
+
+// Failures returns the number of failed checks
+func (r *Report) Failures() int {
+	failures := 0
+	for _, result := range r.Results {
+		if result.Err != nil && !result.Skipped {
+			failures++
+		}
+	}
+	return failures
+}
+
+// Skipped returns the number of skipped checks
+func (r *Report) Skipped() int {
+	skipped := 0
+	for _, result := range r.Results {
+		if result.Skipped {
+			skipped++
+		}
+	}
+	return skipped
+}
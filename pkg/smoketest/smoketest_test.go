@@ -0,0 +1,62 @@
+package smoketest
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRunAndWriteJUnit(t *testing.T) {
+	g := NewWithT(t)
+
+	checks := []Check{
+		{Name: "passing", Run: func(ctx context.Context) error { return nil }},
+		{Name: "failing", Run: func(ctx context.Context) error { return fmt.Errorf("keyspace <ks> not found") }},
+		{Name: "skipped", Run: func(ctx context.Context) error { return Skip("%s is not enabled", "Stargate") }},
+		{Name: "timeout", Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	}
+
+	report := Run(context.Background(), "k8ssandra-smoketest", 10*time.Millisecond, checks)
+	g.Expect(report.Results).To(HaveLen(4))
+	g.Expect(report.Failures()).To(Equal(2))
+	g.Expect(report.Skipped()).To(Equal(1))
+
+	out := &bytes.Buffer{}
+	g.Expect(report.WriteJUnit(out)).To(Succeed())
+
+	parsed := junitTestSuites{}
+	g.Expect(xml.Unmarshal(out.Bytes(), &parsed)).To(Succeed())
+	g.Expect(parsed.Suites).To(HaveLen(1))
+
+	suite := parsed.Suites[0]
+	g.Expect(suite.Name).To(Equal("k8ssandra-smoketest"))
+	g.Expect(suite.Tests).To(Equal(4))
+	g.Expect(suite.Failures).To(Equal(2))
+	g.Expect(suite.Skipped).To(Equal(1))
+	g.Expect(suite.TestCases[0].Failure).To(BeNil())
+	g.Expect(suite.TestCases[1].Failure.Message).To(Equal("keyspace <ks> not found"))
+	g.Expect(suite.TestCases[2].Skipped.Message).To(Equal("Stargate is not enabled"))
+	g.Expect(suite.TestCases[3].Failure.Message).To(ContainSubstring("deadline exceeded"))
+}
+
+func TestServiceMonitorTargets(t *testing.T) {
+	g := NewWithT(t)
+
+	targets := []prometheusTarget{
+		{ScrapePool: "serviceMonitor/k8ssandra/test-prometheus-k8ssandra/0", Health: "up"},
+		{ScrapePool: "k8ssandra/test-prometheus-dc1-stargate/0", Health: "down"},
+		{ScrapePool: "serviceMonitor/k8ssandra/test-prometheus-k8ssandra-other/0", Health: "up"},
+	}
+
+	g.Expect(serviceMonitorTargets(targets, "k8ssandra", "test-prometheus-k8ssandra")).To(ConsistOf(targets[0]))
+	g.Expect(serviceMonitorTargets(targets, "k8ssandra", "test-prometheus-dc1-stargate")).To(ConsistOf(targets[1]))
+	g.Expect(serviceMonitorTargets(targets, "other", "test-prometheus-k8ssandra")).To(BeEmpty())
+}
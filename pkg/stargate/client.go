@@ -0,0 +1,230 @@
+package stargate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	resty "github.com/go-resty/resty/v2"
+
+	"github.com/k8ssandra/k8ssandra/pkg/credentials"
+)
+
+const (
+	// GraphQLPort is the port of the GraphQL API, including the GraphQL schema API
+	GraphQLPort = 8080
+	// AuthPort is the port of the auth API, which exchanges credentials for tokens
+	AuthPort = 8081
+	// RESTPort is the port of the REST v2 and Document APIs
+	RESTPort = 8082
+
+	tokenHeader = "X-Cassandra-Token"
+	userAgent   = "k8ssandra-client"
+
+	defaultTimeout = 30 * time.Second
+	// defaultRetryCount is the total number of attempts made by resty, including the first one
+	defaultRetryCount    = 4
+	defaultRetryWaitTime = 500 * time.Millisecond
+)
+
+// idempotentMethods are the methods of the requests which are retried
+var idempotentMethods = map[string]bool{http.MethodGet: true, http.MethodPut: true, http.MethodDelete: true}
+
+// Endpoints are the base URLs of the Stargate APIs, without trailing slash
+type Endpoints struct {
+	Auth    string
+	REST    string
+	GraphQL string
+}
+
+// EndpointsForHost returns the endpoints of a Stargate instance listening on its default ports on host
+func EndpointsForHost(scheme, host string) Endpoints {
+	return Endpoints{
+		Auth:    fmt.Sprintf("%s://%s:%d", scheme, host, AuthPort),
+		REST:    fmt.Sprintf("%s://%s:%d", scheme, host, RESTPort),
+		GraphQL: fmt.Sprintf("%s://%s:%d", scheme, host, GraphQLPort),
+	}
+}
+
+// APIError is returned when Stargate answers with an unexpected status code
+type APIError struct {
+	StatusCode  int
+	Description string
+}
+
+func (e *APIError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("unexpected status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Description)
+}
+
+// IsNotFound returns true if err is an APIError for a missing keyspace, table, row or document
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Client is a client of the Stargate auth, REST v2, Document and GraphQL schema APIs. It authenticates lazily,
+// caches the token and authenticates again when Stargate rejects it. GET, PUT and DELETE requests failing with a network
+// error or a 5xx status code are retried, POST requests are not as they may have been applied.
+type Client struct {
+	endpoints   Endpoints
+	credentials *credentials.Credentials
+	rest        *resty.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewClient returns a Client for the Stargate instance at endpoints, authenticating with creds
+func NewClient(endpoints Endpoints, creds *credentials.Credentials) *Client {
+	rest := resty.New().
+		SetTimeout(defaultTimeout).
+		SetHeader("User-Agent", userAgent).
+		SetRetryCount(defaultRetryCount).
+		SetRetryWaitTime(defaultRetryWaitTime).
+		AddRetryCondition(func(res *resty.Response, err error) bool {
+			if res == nil || res.Request == nil || !idempotentMethods[res.Request.Method] {
+				return false
+			}
+			return err != nil || res.StatusCode() >= http.StatusInternalServerError
+		})
+
+	return &Client{
+		endpoints:   endpoints,
+		credentials: creds,
+		rest:        rest,
+	}
+}
+
+// Token returns the current auth token, authenticating first if there is none
+func (c *Client) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" {
+		return c.token, nil
+	}
+
+	token, err := c.authenticate(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	return token, nil
+}
+
+// invalidateToken forgets token unless it was already replaced by another request
+func (c *Client) invalidateToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == token {
+		c.token = ""
+	}
+}
+
+func (c *Client) authenticate(ctx context.Context) (string, error) {
+	if c.credentials == nil {
+		return "", fmt.Errorf("failed to authenticate to Stargate: no credentials")
+	}
+
+	body := map[string]string{
+		"username": c.credentials.Username,
+		"password": c.credentials.Password,
+	}
+	result := struct {
+		AuthToken string `json:"authToken"`
+	}{}
+
+	res, err := c.rest.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(body).
+		Post(c.endpoints.Auth + "/v1/auth")
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate to Stargate: %w", err)
+	}
+	if err := checkStatus(res, http.StatusOK, http.StatusCreated); err != nil {
+		return "", fmt.Errorf("failed to authenticate to Stargate: %w", err)
+	}
+	if err := json.Unmarshal(res.Body(), &result); err != nil {
+		return "", fmt.Errorf("failed to decode Stargate auth response: %w", err)
+	}
+	if result.AuthToken == "" {
+		return "", fmt.Errorf("failed to authenticate to Stargate: no token in response")
+	}
+	return result.AuthToken, nil
+}
+
+// do sends an authenticated request and decodes the JSON response into result, if not nil. When the token is
+// rejected, the client authenticates again and resends the request once.
+func (c *Client) do(ctx context.Context, method, endpoint string, params map[string]string, body, result interface{}, expected ...int) error {
+	res, err := c.send(ctx, method, endpoint, params, body)
+	if err != nil {
+		return err
+	}
+	if err := checkStatus(res, expected...); err != nil {
+		return err
+	}
+	if result != nil && len(res.Body()) > 0 {
+		if err := json.Unmarshal(res.Body(), result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) send(ctx context.Context, method, endpoint string, params map[string]string, body interface{}) (*resty.Response, error) {
+	var res *resty.Response
+	for attempt := 0; attempt < 2; attempt++ {
+		token, err := c.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		req := c.rest.R().
+			SetContext(ctx).
+			SetHeader(tokenHeader, token).
+			SetQueryParams(params)
+		if body != nil {
+			req.SetHeader("Content-Type", "application/json").SetBody(body)
+		}
+
+		res, err = req.Execute(method, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode() != http.StatusUnauthorized {
+			break
+		}
+		// The token expired or Stargate restarted and lost it
+		c.invalidateToken(token)
+	}
+	return res, nil
+}
+
+func checkStatus(res *resty.Response, expected ...int) error {
+	for _, code := range expected {
+		if res.StatusCode() == code {
+			return nil
+		}
+	}
+
+	apiErr := &APIError{StatusCode: res.StatusCode()}
+	description := struct {
+		Description string `json:"description"`
+	}{}
+	if err := json.Unmarshal(res.Body(), &description); err == nil && description.Description != "" {
+		apiErr.Description = description.Description
+	} else {
+		apiErr.Description = strings.TrimSpace(res.String())
+	}
+	return apiErr
+}
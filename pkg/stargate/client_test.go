@@ -0,0 +1,227 @@
+package stargate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/k8ssandra/k8ssandra/pkg/credentials"
+)
+
+// fakeStargate serves the auth, REST and Document APIs on a single listener. Tokens are numbered and only the
+// latest one is accepted, so that tests can expire it.
+type fakeStargate struct {
+	mu         sync.Mutex
+	tokens     int
+	namespaces []string
+	documents  map[string]json.RawMessage
+
+	// failures is the number of requests to answer with a 503 before serving again
+	failures int
+}
+
+func newFakeStargate() *fakeStargate {
+	return &fakeStargate{documents: make(map[string]json.RawMessage)}
+}
+
+func (f *fakeStargate) currentToken() string {
+	return fmt.Sprintf("token-%d", f.tokens)
+}
+
+// expireToken makes Stargate reject the token the client holds
+func (f *fakeStargate) expireToken() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokens++
+}
+
+func (f *fakeStargate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failures > 0 {
+		f.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.URL.Path == "/v1/auth" {
+		creds := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&creds)
+		if creds["username"] != "stargate" || creds["password"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"description":"Failed to create token: Invalid credentials provided","code":401}`))
+			return
+		}
+		f.tokens++
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]string{"authToken": f.currentToken()})
+		return
+	}
+
+	if r.Header.Get(tokenHeader) != f.currentToken() {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v2/schemas/namespaces":
+		namespace := Keyspace{}
+		_ = json.NewDecoder(r.Body).Decode(&namespace)
+		f.namespaces = append(f.namespaces, namespace.Name)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]string{"name": namespace.Name})
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/schemas/keyspaces":
+		data := make([]Keyspace, 0)
+		for _, name := range f.namespaces {
+			data = append(data, Keyspace{Name: name, Datacenters: []DatacenterReplicas{{Name: "dc1", Replicas: 1}}})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v2/keyspaces/"):
+		_ = json.NewEncoder(w).Encode(Rows{
+			Count: 1,
+			Data:  []map[string]interface{}{{"where": r.URL.Query().Get("where")}},
+		})
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/v2/namespaces/"):
+		document := json.RawMessage{}
+		_ = json.NewDecoder(r.Body).Decode(&document)
+		f.documents[r.URL.Path] = document
+		_ = json.NewEncoder(w).Encode(map[string]string{"documentId": r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v2/namespaces/"):
+		document, found := f.documents[r.URL.Path]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"description":"A document with the id does not exist.","code":404}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Document{DocumentId: r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:], Data: document})
+	case r.Method == http.MethodPost && r.URL.Path == "/graphql-schema":
+		request := graphQLRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		if !strings.Contains(request.Query, "keyspaces") {
+			_, _ = w.Write([]byte(`{"errors":[{"message":"Validation error of type FieldUndefined"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"keyspaces":[{"name":"system","dcs":[{"name":"dc1","replicas":3}]}]}}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestClient(t *testing.T, fake *fakeStargate, creds *credentials.Credentials) *Client {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	c := NewClient(Endpoints{Auth: server.URL, REST: server.URL, GraphQL: server.URL}, creds)
+	c.rest.SetRetryWaitTime(time.Millisecond).SetRetryMaxWaitTime(10 * time.Millisecond)
+	return c
+}
+
+func TestAuthentication(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	_, err := newTestClient(t, newFakeStargate(), &credentials.Credentials{Username: "stargate", Password: "wrong"}).Token(ctx)
+	g.Expect(err).To(MatchError(ContainSubstring("Invalid credentials provided")))
+
+	fake := newFakeStargate()
+	c := newTestClient(t, fake, &credentials.Credentials{Username: "stargate", Password: "secret"})
+
+	token, err := c.Token(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(token).To(Equal("token-1"))
+
+	// the token is cached
+	g.Expect(c.CreateNamespace(ctx, &Keyspace{Name: "ns1"})).To(Succeed())
+	g.Expect(fake.tokens).To(Equal(1))
+
+	// an expired token is replaced transparently
+	fake.expireToken()
+	keyspaces, err := c.Keyspaces(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(keyspaces).To(ConsistOf(Keyspace{Name: "ns1", Datacenters: []DatacenterReplicas{{Name: "dc1", Replicas: 1}}}))
+	g.Expect(fake.tokens).To(Equal(3))
+}
+
+func TestRetries(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	fake := newFakeStargate()
+	c := newTestClient(t, fake, &credentials.Credentials{Username: "stargate", Password: "secret"})
+
+	_, err := c.Token(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	fake.failures = defaultRetryCount - 1
+	_, err = c.Keyspaces(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	fake.failures = defaultRetryCount
+	_, err = c.Keyspaces(ctx)
+	g.Expect(err).To(MatchError(ContainSubstring("503")))
+
+	// a POST may have been applied before failing, it is not sent again
+	fake.failures = 1
+	g.Expect(c.CreateNamespace(ctx, &Keyspace{Name: "ns1"})).To(MatchError(ContainSubstring("503")))
+	g.Expect(fake.namespaces).To(BeEmpty())
+	g.Expect(c.CreateNamespace(ctx, &Keyspace{Name: "ns1"})).To(Succeed())
+	g.Expect(fake.namespaces).To(Equal([]string{"ns1"}))
+}
+
+func TestDocuments(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	c := newTestClient(t, newFakeStargate(), &credentials.Credentials{Username: "stargate", Password: "secret"})
+
+	type movie struct {
+		Director string
+		Name     string
+	}
+	watchmen := movie{Director: "Zack Snyder", Name: "Watchmen"}
+	g.Expect(c.PutDocument(ctx, "ns1", "movies", "watchmen", watchmen)).To(Succeed())
+
+	document, err := c.GetDocument(ctx, "ns1", "movies", "watchmen")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(document.DocumentId).To(Equal("watchmen"))
+
+	decoded := movie{}
+	g.Expect(document.Decode(&decoded)).To(Succeed())
+	g.Expect(decoded).To(Equal(watchmen))
+
+	_, err = c.GetDocument(ctx, "ns1", "movies", "missing")
+	g.Expect(IsNotFound(err)).To(BeTrue())
+}
+
+func TestSearchRows(t *testing.T) {
+	g := NewWithT(t)
+
+	c := newTestClient(t, newFakeStargate(), &credentials.Credentials{Username: "stargate", Password: "secret"})
+
+	rows, err := c.SearchRows(context.Background(), "ks", "users", map[string]interface{}{"name": map[string]string{"$eq": "x"}}, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(rows.Data).To(ConsistOf(HaveKeyWithValue("where", `{"name":{"$eq":"x"}}`)))
+}
+
+func TestGraphQLSchema(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	c := newTestClient(t, newFakeStargate(), &credentials.Credentials{Username: "stargate", Password: "secret"})
+
+	keyspaces, err := c.GraphQLKeyspaces(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(keyspaces).To(ConsistOf(Keyspace{Name: "system", Datacenters: []DatacenterReplicas{{Name: "dc1", Replicas: 3}}}))
+
+	err = c.GraphQLSchema(ctx, "query { tables }", nil, nil)
+	g.Expect(err).To(MatchError(ContainSubstring("FieldUndefined")))
+}
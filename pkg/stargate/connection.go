@@ -0,0 +1,73 @@
+package stargate
+
+import (
+	"context"
+	"fmt"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8ssandra/k8ssandra/pkg/credentials"
+)
+
+const (
+	instanceLabel = "app.kubernetes.io/instance"
+)
+
+// Connection holds what is needed to talk to the Stargate instance deployed by a k8ssandra release
+type Connection struct {
+	Endpoints Endpoints
+
+	// Credentials are read from the stargate user secret
+	Credentials *credentials.Credentials
+}
+
+// ServiceName returns the name of the Stargate service created by the k8ssandra chart
+func ServiceName(releaseName, datacenterName string) string {
+	return fmt.Sprintf("%s-%s-stargate-service", releaseName, datacenterName)
+}
+
+// UserSecretName returns the name of the stargate user secret created by the k8ssandra chart, unless
+// stargate.cassandraUser.secret is set
+func UserSecretName(clusterName string) string {
+	return clusterName + "-stargate"
+}
+
+// LookupConnection resolves the Stargate connection details of a release from its CassandraDatacenter, the Stargate
+// service and the stargate user secret. secretName overrides the secret created by the chart, for releases setting
+// stargate.cassandraUser.secret.
+func LookupConnection(ctx context.Context, c client.Client, namespace, releaseName, secretName string) (*Connection, error) {
+	dcs := &cassdcapi.CassandraDatacenterList{}
+	if err := c.List(ctx, dcs, client.InNamespace(namespace), client.MatchingLabels{instanceLabel: releaseName}); err != nil {
+		return nil, fmt.Errorf("failed to list CassandraDatacenters of release %s: %w", releaseName, err)
+	}
+	if len(dcs.Items) == 0 {
+		return nil, fmt.Errorf("release %s has no CassandraDatacenter in namespace %s", releaseName, namespace)
+	}
+	cassdc := &dcs.Items[0]
+
+	serviceKey := types.NamespacedName{Namespace: namespace, Name: ServiceName(releaseName, cassdc.Name)}
+	if err := c.Get(ctx, serviceKey, &corev1.Service{}); err != nil {
+		return nil, fmt.Errorf("failed to get service %s, is Stargate enabled in the release?: %w", serviceKey, err)
+	}
+
+	if secretName == "" {
+		secretName = UserSecretName(cassdc.Spec.ClusterName)
+	}
+	creds, err := credentials.FromSecret(ctx, c, types.NamespacedName{Namespace: namespace, Name: secretName})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Connection{
+		Endpoints:   EndpointsForHost("http", fmt.Sprintf("%s.%s", serviceKey.Name, namespace)),
+		Credentials: creds,
+	}, nil
+}
+
+// Connect returns a Client for the connection
+func (conn *Connection) Connect() *Client {
+	return NewClient(conn.Endpoints, conn.Credentials)
+}
@@ -0,0 +1,87 @@
+package stargate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Document is a document as returned by the Document API
+type Document struct {
+	DocumentId string          `json:"documentId"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Decode unmarshals the document data into v
+func (d *Document) Decode(v interface{}) error {
+	return json.Unmarshal(d.Data, v)
+}
+
+// Namespaces returns the document namespaces, which are keyspaces seen from the Document API
+func (c *Client) Namespaces(ctx context.Context) ([]Keyspace, error) {
+	result := struct {
+		Data []Keyspace `json:"data"`
+	}{}
+	if err := c.do(ctx, http.MethodGet, c.restURL("v2", "schemas", "namespaces"), nil, nil, &result, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	return result.Data, nil
+}
+
+// CreateNamespace creates a document namespace. Namespaces created without datacenters use SimpleStrategy with a
+// replication factor of 1.
+func (c *Client) CreateNamespace(ctx context.Context, namespace *Keyspace) error {
+	if err := c.do(ctx, http.MethodPost, c.restURL("v2", "schemas", "namespaces"), nil, namespace, nil, http.StatusCreated); err != nil {
+		return fmt.Errorf("failed to create namespace %s: %w", namespace.Name, err)
+	}
+	return nil
+}
+
+// DeleteNamespace drops a document namespace
+func (c *Client) DeleteNamespace(ctx context.Context, name string) error {
+	if err := c.do(ctx, http.MethodDelete, c.restURL("v2", "schemas", "namespaces", name), nil, nil, nil, http.StatusNoContent); err != nil {
+		return fmt.Errorf("failed to delete namespace %s: %w", name, err)
+	}
+	return nil
+}
+
+// PutDocument creates or replaces the document with the given id in the collection
+func (c *Client) PutDocument(ctx context.Context, namespace, collection, documentId string, document interface{}) error {
+	endpoint := c.restURL("v2", "namespaces", namespace, "collections", collection, documentId)
+	if err := c.do(ctx, http.MethodPut, endpoint, nil, document, nil, http.StatusOK); err != nil {
+		return fmt.Errorf("failed to write document %s/%s/%s: %w", namespace, collection, documentId, err)
+	}
+	return nil
+}
+
+// AddDocument adds a document to the collection and returns the id generated by Stargate
+func (c *Client) AddDocument(ctx context.Context, namespace, collection string, document interface{}) (string, error) {
+	result := struct {
+		DocumentId string `json:"documentId"`
+	}{}
+	endpoint := c.restURL("v2", "namespaces", namespace, "collections", collection)
+	if err := c.do(ctx, http.MethodPost, endpoint, nil, document, &result, http.StatusCreated); err != nil {
+		return "", fmt.Errorf("failed to add document to %s/%s: %w", namespace, collection, err)
+	}
+	return result.DocumentId, nil
+}
+
+// GetDocument returns a document. IsNotFound is true for the error returned if it does not exist.
+func (c *Client) GetDocument(ctx context.Context, namespace, collection, documentId string) (*Document, error) {
+	document := &Document{}
+	endpoint := c.restURL("v2", "namespaces", namespace, "collections", collection, documentId)
+	if err := c.do(ctx, http.MethodGet, endpoint, nil, nil, document, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to read document %s/%s/%s: %w", namespace, collection, documentId, err)
+	}
+	return document, nil
+}
+
+// DeleteDocument deletes a document
+func (c *Client) DeleteDocument(ctx context.Context, namespace, collection, documentId string) error {
+	endpoint := c.restURL("v2", "namespaces", namespace, "collections", collection, documentId)
+	if err := c.do(ctx, http.MethodDelete, endpoint, nil, nil, nil, http.StatusNoContent); err != nil {
+		return fmt.Errorf("failed to delete document %s/%s/%s: %w", namespace, collection, documentId, err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package stargate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// GraphQLError is an error reported in the errors field of a GraphQL response
+type GraphQLError struct {
+	Message string `json:"message"`
+}
+
+// GraphQLErrors is returned when a GraphQL query completes with errors
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	messages := make([]string, len(e))
+	for i := range e {
+		messages[i] = e[i].Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+}
+
+// GraphQLSchema runs a query or mutation against the GraphQL schema API, which manages keyspaces and tables, and
+// decodes the data field of the response into result, if not nil
+func (c *Client) GraphQLSchema(ctx context.Context, query string, variables map[string]interface{}, result interface{}) error {
+	response := &graphQLResponse{}
+	body := &graphQLRequest{Query: query, Variables: variables}
+	if err := c.do(ctx, http.MethodPost, c.endpoints.GraphQL+"/graphql-schema", nil, body, response, http.StatusOK); err != nil {
+		return fmt.Errorf("GraphQL schema query failed: %w", err)
+	}
+	if len(response.Errors) > 0 {
+		return fmt.Errorf("GraphQL schema query failed: %w", response.Errors)
+	}
+	if result != nil && len(response.Data) > 0 {
+		if err := json.Unmarshal(response.Data, result); err != nil {
+			return fmt.Errorf("failed to decode GraphQL response: %w", err)
+		}
+	}
+	return nil
+}
+
+// GraphQLKeyspaces returns the keyspaces of the cluster through the GraphQL schema API
+func (c *Client) GraphQLKeyspaces(ctx context.Context) ([]Keyspace, error) {
+	result := struct {
+		Keyspaces []struct {
+			Name string               `json:"name"`
+			Dcs  []DatacenterReplicas `json:"dcs"`
+		} `json:"keyspaces"`
+	}{}
+	if err := c.GraphQLSchema(ctx, "query { keyspaces { name dcs { name replicas } } }", nil, &result); err != nil {
+		return nil, err
+	}
+
+	keyspaces := make([]Keyspace, len(result.Keyspaces))
+	for i, keyspace := range result.Keyspaces {
+		keyspaces[i] = Keyspace{Name: keyspace.Name, Datacenters: keyspace.Dcs}
+	}
+	return keyspaces, nil
+}
@@ -0,0 +1,190 @@
+package stargate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DatacenterReplicas is the replication factor of a keyspace in a datacenter
+type DatacenterReplicas struct {
+	Name     string `json:"name"`
+	Replicas int    `json:"replicas"`
+}
+
+// Keyspace is a keyspace as described by the REST v2 schema API
+type Keyspace struct {
+	Name        string               `json:"name"`
+	Datacenters []DatacenterReplicas `json:"datacenters,omitempty"`
+}
+
+// ColumnDefinition is a column of a table
+type ColumnDefinition struct {
+	Name           string `json:"name"`
+	TypeDefinition string `json:"typeDefinition"`
+	Static         bool   `json:"static,omitempty"`
+}
+
+// PrimaryKey lists the partition and clustering columns of a table
+type PrimaryKey struct {
+	PartitionKey  []string `json:"partitionKey"`
+	ClusteringKey []string `json:"clusteringKey,omitempty"`
+}
+
+// TableOptions are the optional settings of a table
+type TableOptions struct {
+	DefaultTimeToLive    int                    `json:"defaultTimeToLive,omitempty"`
+	ClusteringExpression []ClusteringExpression `json:"clusteringExpression,omitempty"`
+}
+
+// ClusteringExpression sets the order of a clustering column, ASC or DESC
+type ClusteringExpression struct {
+	Column string `json:"column"`
+	Order  string `json:"order"`
+}
+
+// Table is a table as described by the REST v2 schema API
+type Table struct {
+	Name              string             `json:"name"`
+	Keyspace          string             `json:"keyspace,omitempty"`
+	ColumnDefinitions []ColumnDefinition `json:"columnDefinitions"`
+	PrimaryKey        PrimaryKey         `json:"primaryKey"`
+	TableOptions      *TableOptions      `json:"tableOptions,omitempty"`
+}
+
+// Rows is a page of rows returned by the REST v2 API. PageState is empty on the last page.
+type Rows struct {
+	Count     int                      `json:"count"`
+	PageState string                   `json:"pageState,omitempty"`
+	Data      []map[string]interface{} `json:"data"`
+}
+
+// Keyspaces returns the keyspaces of the cluster
+func (c *Client) Keyspaces(ctx context.Context) ([]Keyspace, error) {
+	result := struct {
+		Data []Keyspace `json:"data"`
+	}{}
+	if err := c.do(ctx, http.MethodGet, c.restURL("v2", "schemas", "keyspaces"), nil, nil, &result, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to list keyspaces: %w", err)
+	}
+	return result.Data, nil
+}
+
+// Keyspace returns the named keyspace. IsNotFound is true for the error returned if it does not exist.
+func (c *Client) Keyspace(ctx context.Context, name string) (*Keyspace, error) {
+	result := struct {
+		Data Keyspace `json:"data"`
+	}{}
+	if err := c.do(ctx, http.MethodGet, c.restURL("v2", "schemas", "keyspaces", name), nil, nil, &result, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to get keyspace %s: %w", name, err)
+	}
+	return &result.Data, nil
+}
+
+// CreateKeyspace creates a keyspace replicated in the given datacenters. Keyspaces created without datacenters use
+// SimpleStrategy with a replication factor of 1.
+func (c *Client) CreateKeyspace(ctx context.Context, keyspace *Keyspace) error {
+	if err := c.do(ctx, http.MethodPost, c.restURL("v2", "schemas", "keyspaces"), nil, keyspace, nil, http.StatusCreated); err != nil {
+		return fmt.Errorf("failed to create keyspace %s: %w", keyspace.Name, err)
+	}
+	return nil
+}
+
+// DeleteKeyspace drops a keyspace
+func (c *Client) DeleteKeyspace(ctx context.Context, name string) error {
+	if err := c.do(ctx, http.MethodDelete, c.restURL("v2", "schemas", "keyspaces", name), nil, nil, nil, http.StatusNoContent); err != nil {
+		return fmt.Errorf("failed to delete keyspace %s: %w", name, err)
+	}
+	return nil
+}
+
+// Tables returns the tables of a keyspace
+func (c *Client) Tables(ctx context.Context, keyspace string) ([]Table, error) {
+	result := struct {
+		Data []Table `json:"data"`
+	}{}
+	if err := c.do(ctx, http.MethodGet, c.restURL("v2", "schemas", "keyspaces", keyspace, "tables"), nil, nil, &result, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to list tables of %s: %w", keyspace, err)
+	}
+	return result.Data, nil
+}
+
+// CreateTable creates a table in the keyspace, doing nothing if it exists already
+func (c *Client) CreateTable(ctx context.Context, keyspace string, table *Table) error {
+	body := struct {
+		*Table
+		IfNotExists bool `json:"ifNotExists"`
+	}{Table: table, IfNotExists: true}
+
+	if err := c.do(ctx, http.MethodPost, c.restURL("v2", "schemas", "keyspaces", keyspace, "tables"), nil, body, nil, http.StatusCreated); err != nil {
+		return fmt.Errorf("failed to create table %s.%s: %w", keyspace, table.Name, err)
+	}
+	return nil
+}
+
+// DeleteTable drops a table
+func (c *Client) DeleteTable(ctx context.Context, keyspace, table string) error {
+	if err := c.do(ctx, http.MethodDelete, c.restURL("v2", "schemas", "keyspaces", keyspace, "tables", table), nil, nil, nil, http.StatusNoContent); err != nil {
+		return fmt.Errorf("failed to delete table %s.%s: %w", keyspace, table, err)
+	}
+	return nil
+}
+
+// AddRow inserts a row, given as column name to value
+func (c *Client) AddRow(ctx context.Context, keyspace, table string, row map[string]interface{}) error {
+	if err := c.do(ctx, http.MethodPost, c.restURL("v2", "keyspaces", keyspace, table), nil, row, nil, http.StatusCreated); err != nil {
+		return fmt.Errorf("failed to add row to %s.%s: %w", keyspace, table, err)
+	}
+	return nil
+}
+
+// GetRows returns the rows matching the primary key values, given in primary key order. A partial primary key returns
+// all the rows of a partition.
+func (c *Client) GetRows(ctx context.Context, keyspace, table string, primaryKey ...string) (*Rows, error) {
+	rows := &Rows{}
+	segments := append([]string{"v2", "keyspaces", keyspace, table}, primaryKey...)
+	if err := c.do(ctx, http.MethodGet, c.restURL(segments...), nil, nil, rows, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to get rows of %s.%s: %w", keyspace, table, err)
+	}
+	return rows, nil
+}
+
+// SearchRows returns the rows matching where, a REST v2 where clause such as
+// {"name": {"$eq": "x"}}. pageState is the PageState of the previous page, or empty for the first page.
+func (c *Client) SearchRows(ctx context.Context, keyspace, table string, where map[string]interface{}, pageState string) (*Rows, error) {
+	clause, err := json.Marshal(where)
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]string{"where": string(clause)}
+	if pageState != "" {
+		params["page-state"] = pageState
+	}
+
+	rows := &Rows{}
+	if err := c.do(ctx, http.MethodGet, c.restURL("v2", "keyspaces", keyspace, table), params, nil, rows, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to search rows of %s.%s: %w", keyspace, table, err)
+	}
+	return rows, nil
+}
+
+// DeleteRows deletes the rows matching the primary key values, given in primary key order
+func (c *Client) DeleteRows(ctx context.Context, keyspace, table string, primaryKey ...string) error {
+	segments := append([]string{"v2", "keyspaces", keyspace, table}, primaryKey...)
+	if err := c.do(ctx, http.MethodDelete, c.restURL(segments...), nil, nil, nil, http.StatusNoContent); err != nil {
+		return fmt.Errorf("failed to delete rows of %s.%s: %w", keyspace, table, err)
+	}
+	return nil
+}
+
+// restURL returns the URL of the REST API path made of the escaped segments
+func (c *Client) restURL(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+	return c.endpoints.REST + "/" + strings.Join(escaped, "/")
+}
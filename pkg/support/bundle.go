@@ -0,0 +1,534 @@
+// Package support collects the support bundle of a k8ssandra release: the resources of the release with their status,
+// the logs of its containers and the state of its Cassandra nodes, in a gzipped tarball with the secret values
+// redacted.
+package support
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/k8ssandra/k8ssandra/pkg/mgmtapi"
+)
+
+const (
+	instanceLabel = "app.kubernetes.io/instance"
+
+	// Redacted replaces the secret values in the bundle
+	Redacted = "REDACTED"
+
+	lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+	// nodeStatusTimeout bounds the Management API calls getting the status of each node
+	nodeStatusTimeout = time.Minute
+)
+
+// CassandraContainers are the containers of the Cassandra pods whose logs are collected, the logs of all the
+// containers of the other pods are
+var CassandraContainers = []string{"cassandra", "server-system-logger", "medusa"}
+
+// crdGroups are the API groups of the CRDs installed by the chart
+var crdGroups = []string{"cassandra.datastax.com", "cassandra.k8ssandra.io", "reaper.cassandra-reaper.io"}
+
+// sensitiveEnv matches the names of the environment variables whose values are redacted
+var sensitiveEnv = regexp.MustCompile(`(?i)password|secret|token|key|credential`)
+
+// sensitiveConfig matches the config options of the CassandraDatacenters whose values are redacted, like the
+// keystore_password of the encryption options
+var sensitiveConfig = regexp.MustCompile(`(?i)password`)
+
+var crdListGVK = schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinitionList"}
+
+// LogsFunc returns the logs of a container of a pod, of its previous instance if previous is true, limited to its last
+// tailLines lines if not 0
+type LogsFunc func(ctx context.Context, namespace, pod, container string, previous bool, tailLines int64) ([]byte, error)
+
+// ClientsetLogs returns a LogsFunc reading the logs through the API server
+func ClientsetLogs(clientset kubernetes.Interface) LogsFunc {
+	return func(ctx context.Context, namespace, pod, container string, previous bool, tailLines int64) ([]byte, error) {
+		options := &corev1.PodLogOptions{Container: container, Previous: previous, Timestamps: true}
+		if tailLines > 0 {
+			options.TailLines = &tailLines
+		}
+		return clientset.CoreV1().Pods(namespace).GetLogs(pod, options).DoRaw(ctx)
+	}
+}
+
+// Options are the parameters of a support bundle
+type Options struct {
+	Namespace   string
+	ReleaseName string
+	// TailLines limits the logs of each container to their last lines, all the lines if 0
+	TailLines int64
+}
+
+// Collector collects support bundles
+type Collector struct {
+	Client client.Client
+	Logs   LogsFunc
+	// Mgmt gets the state of the Cassandra nodes, which is not collected if nil
+	Mgmt *mgmtapi.Client
+}
+
+// Name returns the name of the bundle of a release collected at the given time, which is also the directory of the
+// files in the tarball
+func Name(releaseName string, now time.Time) string {
+	return fmt.Sprintf("k8ssandra-support-%s-%s", releaseName, now.UTC().Format("20060102150405"))
+}
+
+// bundle writes the files of a support bundle to a tarball, recording the failures to collect some of them
+type bundle struct {
+	tw       *tar.Writer
+	root     string
+	now      time.Time
+	failures []string
+}
+
+func (b *bundle) add(name string, data []byte) error {
+	header := &tar.Header{
+		Name:    path.Join(b.root, name),
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: b.now,
+	}
+	if err := b.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := b.tw.Write(data)
+	return err
+}
+
+func (b *bundle) addYAML(name string, obj interface{}) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	return b.add(name, data)
+}
+
+func (b *bundle) fail(format string, args ...interface{}) {
+	b.failures = append(b.failures, fmt.Sprintf(format, args...))
+}
+
+// Collect writes the support bundle of the release to w as a gzipped tarball. The resources, logs or node states which
+// cannot be collected are listed in the errors.txt file of the bundle rather than failing the collection, which only
+// fails if the bundle cannot be written.
+func (c *Collector) Collect(ctx context.Context, opts Options, now time.Time, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	b := &bundle{tw: tar.NewWriter(gz), root: Name(opts.ReleaseName, now), now: now}
+
+	resources, err := c.collectResources(ctx, b, opts)
+	if err != nil {
+		return err
+	}
+	if err := c.collectLogs(ctx, b, opts, resources); err != nil {
+		return err
+	}
+	if err := c.collectNodes(ctx, b, resources); err != nil {
+		return err
+	}
+
+	if len(b.failures) > 0 {
+		if err := b.add("errors.txt", []byte(strings.Join(b.failures, "\n")+"\n")); err != nil {
+			return err
+		}
+	}
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// resources are the resources of the release whose pods are collected
+type resources struct {
+	datacenters []cassdcapi.CassandraDatacenter
+	// cassandraPods are the pods of the CassandraDatacenters, pods are the other pods of the release
+	cassandraPods []corev1.Pod
+	pods          []corev1.Pod
+}
+
+// collectResources writes the resources of the release, the events of its namespace and the CRDs of the chart
+func (c *Collector) collectResources(ctx context.Context, b *bundle, opts Options) (*resources, error) {
+	inNamespace := client.InNamespace(opts.Namespace)
+	ofRelease := client.MatchingLabels{instanceLabel: opts.ReleaseName}
+	r := &resources{}
+
+	dcs := &cassdcapi.CassandraDatacenterList{}
+	if err := c.Client.List(ctx, dcs, inNamespace, ofRelease); err != nil {
+		b.fail("failed to list CassandraDatacenters: %v", err)
+	}
+	r.datacenters = dcs.Items
+	for i := range dcs.Items {
+		cleanMetadata(&dcs.Items[i].ObjectMeta)
+		if config, err := redactConfig(dcs.Items[i].Spec.Config); err != nil {
+			b.fail("failed to redact the config of CassandraDatacenter %s, it is not collected: %v", dcs.Items[i].Name, err)
+			dcs.Items[i].Spec.Config = nil
+		} else {
+			dcs.Items[i].Spec.Config = config
+		}
+	}
+	if err := b.addYAML("resources/cassandradatacenters.yaml", dcs.Items); err != nil {
+		return nil, err
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	for _, dc := range r.datacenters {
+		list := &appsv1.StatefulSetList{}
+		if err := c.Client.List(ctx, list, inNamespace, client.MatchingLabels(dc.GetDatacenterLabels())); err != nil {
+			b.fail("failed to list StatefulSets of CassandraDatacenter %s: %v", dc.Name, err)
+		}
+		statefulSets.Items = append(statefulSets.Items, list.Items...)
+	}
+	for i := range statefulSets.Items {
+		cleanMetadata(&statefulSets.Items[i].ObjectMeta)
+		redactPodSpec(&statefulSets.Items[i].Spec.Template.Spec)
+	}
+	if err := b.addYAML("resources/statefulsets.yaml", statefulSets.Items); err != nil {
+		return nil, err
+	}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := c.Client.List(ctx, deployments, inNamespace, ofRelease); err != nil {
+		b.fail("failed to list Deployments: %v", err)
+	}
+	jobs := &batchv1.JobList{}
+	if err := c.Client.List(ctx, jobs, inNamespace, ofRelease); err != nil {
+		b.fail("failed to list Jobs: %v", err)
+	}
+	// the pods of the Deployments and Jobs, like the operators and the helm hooks, are selected by their selectors
+	selectors := make([]labels.Selector, 0)
+	for i := range deployments.Items {
+		if selector, err := metav1.LabelSelectorAsSelector(deployments.Items[i].Spec.Selector); err == nil {
+			selectors = append(selectors, selector)
+		}
+		cleanMetadata(&deployments.Items[i].ObjectMeta)
+		redactPodSpec(&deployments.Items[i].Spec.Template.Spec)
+	}
+	for i := range jobs.Items {
+		if selector, err := metav1.LabelSelectorAsSelector(jobs.Items[i].Spec.Selector); err == nil {
+			selectors = append(selectors, selector)
+		}
+		cleanMetadata(&jobs.Items[i].ObjectMeta)
+		redactPodSpec(&jobs.Items[i].Spec.Template.Spec)
+	}
+	if err := b.addYAML("resources/deployments.yaml", deployments.Items); err != nil {
+		return nil, err
+	}
+	if err := b.addYAML("resources/jobs.yaml", jobs.Items); err != nil {
+		return nil, err
+	}
+
+	pods := &corev1.PodList{}
+	if err := c.Client.List(ctx, pods, inNamespace); err != nil {
+		b.fail("failed to list pods: %v", err)
+	}
+	sort.Slice(pods.Items, func(i, j int) bool { return pods.Items[i].Name < pods.Items[j].Name })
+	for _, pod := range pods.Items {
+		switch {
+		case isDatacenterPod(&pod, r.datacenters):
+			r.cassandraPods = append(r.cassandraPods, pod)
+		case pod.Labels[instanceLabel] == opts.ReleaseName || matchesAny(&pod, selectors):
+			r.pods = append(r.pods, pod)
+		}
+	}
+	collected := append(append([]corev1.Pod{}, r.cassandraPods...), r.pods...)
+	for i := range collected {
+		cleanMetadata(&collected[i].ObjectMeta)
+		redactPodSpec(&collected[i].Spec)
+	}
+	if err := b.addYAML("resources/pods.yaml", collected); err != nil {
+		return nil, err
+	}
+
+	services := &corev1.ServiceList{}
+	if err := c.Client.List(ctx, services, inNamespace, ofRelease); err != nil {
+		b.fail("failed to list Services: %v", err)
+	}
+	for i := range services.Items {
+		cleanMetadata(&services.Items[i].ObjectMeta)
+	}
+	if err := b.addYAML("resources/services.yaml", services.Items); err != nil {
+		return nil, err
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := c.Client.List(ctx, secrets, inNamespace); err != nil {
+		b.fail("failed to list Secrets: %v", err)
+	}
+	for i := range secrets.Items {
+		redactSecret(&secrets.Items[i])
+	}
+	if err := b.addYAML("resources/secrets.yaml", secrets.Items); err != nil {
+		return nil, err
+	}
+
+	events := &corev1.EventList{}
+	if err := c.Client.List(ctx, events, inNamespace); err != nil {
+		b.fail("failed to list events: %v", err)
+	}
+	sort.SliceStable(events.Items, func(i, j int) bool {
+		return events.Items[i].LastTimestamp.Before(&events.Items[j].LastTimestamp)
+	})
+	if err := b.addYAML("resources/events.yaml", events.Items); err != nil {
+		return nil, err
+	}
+
+	crds := &unstructured.UnstructuredList{}
+	crds.SetGroupVersionKind(crdListGVK)
+	if err := c.Client.List(ctx, crds); err != nil {
+		b.fail("failed to list CRDs: %v", err)
+	}
+	chartCRDs := make([]map[string]interface{}, 0)
+	for _, crd := range crds.Items {
+		for _, group := range crdGroups {
+			if strings.HasSuffix(crd.GetName(), "."+group) {
+				crd.SetManagedFields(nil)
+				chartCRDs = append(chartCRDs, crd.Object)
+			}
+		}
+	}
+	if err := b.addYAML("resources/crds.yaml", chartCRDs); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// collectLogs writes the logs of the containers of the pods of the release, and of their previous instance if they
+// restarted
+func (c *Collector) collectLogs(ctx context.Context, b *bundle, opts Options, r *resources) error {
+	addLogs := func(pod *corev1.Pod, containers []string) error {
+		for _, container := range containers {
+			restarted := false
+			found := false
+			for _, status := range pod.Status.ContainerStatuses {
+				if status.Name == container {
+					found = true
+					restarted = status.RestartCount > 0
+				}
+			}
+			if !found {
+				continue
+			}
+			for _, previous := range []bool{false, true} {
+				if previous && !restarted {
+					continue
+				}
+				logs, err := c.Logs(ctx, pod.Namespace, pod.Name, container, previous, opts.TailLines)
+				if err != nil {
+					b.fail("failed to get the logs of container %s of pod %s: %v", container, pod.Name, err)
+					continue
+				}
+				name := fmt.Sprintf("logs/%s/%s.log", pod.Name, container)
+				if previous {
+					name = fmt.Sprintf("logs/%s/%s.previous.log", pod.Name, container)
+				}
+				if err := b.add(name, logs); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for i := range r.cassandraPods {
+		if err := addLogs(&r.cassandraPods[i], CassandraContainers); err != nil {
+			return err
+		}
+	}
+	for i := range r.pods {
+		pod := &r.pods[i]
+		containers := make([]string, 0)
+		for _, container := range pod.Spec.InitContainers {
+			containers = append(containers, container.Name)
+		}
+		for _, container := range pod.Spec.Containers {
+			containers = append(containers, container.Name)
+		}
+		if err := addLogs(pod, containers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectNodes writes the status of the Cassandra nodes and their schema versions as seen by each node, like nodetool
+// status and describecluster
+func (c *Collector) collectNodes(ctx context.Context, b *bundle, r *resources) error {
+	if c.Mgmt == nil {
+		return nil
+	}
+	for i := range r.cassandraPods {
+		pod := &r.cassandraPods[i]
+		if pod.Status.PodIP == "" {
+			continue
+		}
+		statusCtx, cancel := context.WithTimeout(ctx, nodeStatusTimeout)
+		status, err := c.Mgmt.Status(statusCtx, pod)
+		cancel()
+		if err != nil {
+			b.fail("failed to get the status of pod %s: %v", pod.Name, err)
+			continue
+		}
+		if !status.Ready {
+			b.fail("the Management API of pod %s reports that Cassandra is not ready", pod.Name)
+			continue
+		}
+		if err := b.add(fmt.Sprintf("nodes/%s/status.txt", pod.Name), []byte(formatStatus(status))); err != nil {
+			return err
+		}
+		if err := b.add(fmt.Sprintf("nodes/%s/describecluster.txt", pod.Name), []byte(formatSchemaVersions(status))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatStatus formats the endpoints seen by a node like nodetool status
+func formatStatus(status *mgmtapi.NodeStatus) string {
+	endpoints := append([]mgmtapi.Endpoint{}, status.Endpoints...)
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Datacenter != endpoints[j].Datacenter {
+			return endpoints[i].Datacenter < endpoints[j].Datacenter
+		}
+		return endpoints[i].Address < endpoints[j].Address
+	})
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Cassandra %s\n", status.Version)
+	for i, endpoint := range endpoints {
+		if i == 0 || endpoint.Datacenter != endpoints[i-1].Datacenter {
+			fmt.Fprintf(&sb, "Datacenter: %s\n", endpoint.Datacenter)
+			fmt.Fprintf(&sb, "%-6s %-16s %-12s %-38s %s\n", "Alive", "Address", "Status", "Host ID", "Rack")
+		}
+		alive := "Up"
+		if !endpoint.Alive {
+			alive = "Down"
+		}
+		fmt.Fprintf(&sb, "%-6s %-16s %-12s %-38s %s\n", alive, endpoint.Address, endpoint.Status, endpoint.HostID, endpoint.Rack)
+	}
+	return sb.String()
+}
+
+// formatSchemaVersions formats the schema versions seen by a node like nodetool describecluster
+func formatSchemaVersions(status *mgmtapi.NodeStatus) string {
+	versions := status.SchemaVersions()
+	names := make([]string, 0, len(versions))
+	for version := range versions {
+		names = append(names, version)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("Schema versions:\n")
+	for _, version := range names {
+		addresses := versions[version]
+		sort.Strings(addresses)
+		fmt.Fprintf(&sb, "\t%s: [%s]\n", version, strings.Join(addresses, ", "))
+	}
+	return sb.String()
+}
+
+func isDatacenterPod(pod *corev1.Pod, datacenters []cassdcapi.CassandraDatacenter) bool {
+	for _, dc := range datacenters {
+		if labels.SelectorFromSet(dc.GetDatacenterLabels()).Matches(labels.Set(pod.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(pod *corev1.Pod, selectors []labels.Selector) bool {
+	for _, selector := range selectors {
+		if !selector.Empty() && selector.Matches(labels.Set(pod.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanMetadata drops the managed fields, which are noise, and the last applied configuration, which has the secret
+// values of Secrets applied with kubectl
+func cleanMetadata(meta *metav1.ObjectMeta) {
+	meta.ManagedFields = nil
+	delete(meta.Annotations, lastAppliedAnnotation)
+}
+
+// redactSecret replaces the values of the Secret, keeping its keys and the size of the values
+func redactSecret(secret *corev1.Secret) {
+	cleanMetadata(&secret.ObjectMeta)
+	redacted := make(map[string]string, len(secret.Data)+len(secret.StringData))
+	for key, value := range secret.Data {
+		redacted[key] = fmt.Sprintf("%s (%d bytes)", Redacted, len(value))
+	}
+	for key, value := range secret.StringData {
+		redacted[key] = fmt.Sprintf("%s (%d bytes)", Redacted, len(value))
+	}
+	secret.Data = nil
+	secret.StringData = redacted
+}
+
+// redactConfig replaces the values of the sensitive options of a CassandraDatacenter config, at any depth
+func redactConfig(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+	var config interface{}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return nil, err
+	}
+	var redact func(value interface{})
+	redact = func(value interface{}) {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			for key, option := range value {
+				if _, nested := option.(map[string]interface{}); !nested && sensitiveConfig.MatchString(key) {
+					value[key] = Redacted
+				} else {
+					redact(option)
+				}
+			}
+		case []interface{}:
+			for _, item := range value {
+				redact(item)
+			}
+		}
+	}
+	redact(config)
+	return json.Marshal(config)
+}
+
+// redactPodSpec replaces the values of the environment variables whose names look sensitive
+func redactPodSpec(spec *corev1.PodSpec) {
+	redact := func(containers []corev1.Container) {
+		for i := range containers {
+			for j := range containers[i].Env {
+				env := &containers[i].Env[j]
+				if env.Value != "" && sensitiveEnv.MatchString(env.Name) {
+					env.Value = Redacted
+				}
+			}
+		}
+	}
+	redact(spec.InitContainers)
+	redact(spec.Containers)
+}
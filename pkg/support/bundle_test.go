@@ -0,0 +1,210 @@
+package support
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k8ssandra/k8ssandra/pkg/mgmtapi"
+)
+
+const (
+	testNamespace = "k8ssandra"
+
+	endpointsOutput = `{"entity": [
+	{"DC": "dc1", "ENDPOINT_IP": "10.0.0.1", "HOST_ID": "host-1", "IS_ALIVE": "true", "RACK": "default", "SCHEMA": "2207c2a9", "STATUS": "NORMAL,-1234"},
+	{"DC": "dc1", "ENDPOINT_IP": "10.0.0.2", "HOST_ID": "host-2", "IS_ALIVE": "false", "RACK": "default", "SCHEMA": "2207c2a9", "STATUS": "shutdown,true"}
+]}`
+)
+
+func testObjects() []runtime.Object {
+	releaseLabels := map[string]string{instanceLabel: "k8ssandra"}
+	cassdc := &cassdcapi.CassandraDatacenter{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "dc1", Labels: releaseLabels},
+		Spec: cassdcapi.CassandraDatacenterSpec{
+			ClusterName: "test",
+			Config:      json.RawMessage(`{"cassandra-yaml": {"num_tokens": 256, "server_encryption_options": {"keystore": "/etc/keystore", "keystore_password": "changeit"}}}`),
+		},
+	}
+	operatorLabels := map[string]string{instanceLabel: "k8ssandra", "app.kubernetes.io/name": "cass-operator"}
+	return []runtime.Object{
+		cassdc,
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "test-dc1-default-sts", Labels: cassdc.GetDatacenterLabels()}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "test-dc1-default-sts-0", Labels: cassdc.GetDatacenterLabels()},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "cassandra", Env: []corev1.EnvVar{{Name: "MGMT_API_PASSWORD", Value: "secret"}, {Name: "DS_LICENSE", Value: "accept"}}},
+				{Name: "server-system-logger"},
+			}},
+			Status: corev1.PodStatus{
+				PodIP: "10.0.0.1",
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "cassandra", RestartCount: 1},
+					{Name: "server-system-logger"},
+				},
+			},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "k8ssandra-cass-operator", Labels: operatorLabels},
+			Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": "cass-operator"}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "k8ssandra-cass-operator-5d8f7", Labels: map[string]string{"name": "cass-operator"}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "operator"}}},
+			Status:     corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "operator"}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "other-release-pod", Labels: map[string]string{instanceLabel: "other"}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "other"}}},
+			Status:     corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "other"}}},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   testNamespace,
+				Name:        "test-superuser",
+				Annotations: map[string]string{lastAppliedAnnotation: `{"data": {"password": "c2VjcmV0"}}`},
+			},
+			Data: map[string][]byte{"username": []byte("test-superuser"), "password": []byte("secret")},
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Namespace: testNamespace, Name: "dc1.1"},
+			InvolvedObject: corev1.ObjectReference{Kind: "CassandraDatacenter", Name: "dc1"},
+			Reason:         "ScalingUpRack",
+		},
+		&apiextv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "cassandradatacenters.cassandra.datastax.com"}},
+		&apiextv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "prometheuses.monitoring.coreos.com"}},
+	}
+}
+
+// readBundle returns the files of a bundle by their path
+func readBundle(t *testing.T, data []byte) map[string]string {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(content)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(cassdcapi.AddToScheme(scheme)).To(Succeed())
+	g.Expect(apiextv1.AddToScheme(scheme)).To(Succeed())
+	c := fake.NewFakeClientWithScheme(scheme, testObjects()...)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v0/probes/readiness":
+		case "/api/v0/metadata/versions/release":
+			_, _ = w.Write([]byte("3.11.10"))
+		case "/api/v0/metadata/endpoints":
+			_, _ = w.Write([]byte(endpointsOutput))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	collector := &Collector{
+		Client: c,
+		Logs: func(ctx context.Context, namespace, pod, container string, previous bool, tailLines int64) ([]byte, error) {
+			if container == "server-system-logger" {
+				return nil, fmt.Errorf("container is waiting to start")
+			}
+			return []byte(fmt.Sprintf("logs of %s/%s previous=%t tail=%d\n", pod, container, previous, tailLines)), nil
+		},
+		Mgmt: mgmtapi.NewClient(server.Client(), func(pod *corev1.Pod) *url.URL {
+			u := *serverURL
+			return &u
+		}),
+	}
+
+	now := time.Date(2021, 6, 1, 12, 30, 0, 0, time.UTC)
+	var out bytes.Buffer
+	g.Expect(collector.Collect(context.Background(), Options{Namespace: testNamespace, ReleaseName: "k8ssandra", TailLines: 100}, now, &out)).To(Succeed())
+
+	files := readBundle(t, out.Bytes())
+	root := "k8ssandra-support-k8ssandra-20210601123000/"
+	g.Expect(files).To(HaveKey(root + "resources/cassandradatacenters.yaml"))
+	g.Expect(files).To(HaveKey(root + "resources/statefulsets.yaml"))
+	g.Expect(files).To(HaveKey(root + "resources/deployments.yaml"))
+	g.Expect(files).To(HaveKey(root + "resources/jobs.yaml"))
+	g.Expect(files).To(HaveKey(root + "resources/services.yaml"))
+	g.Expect(files[root+"resources/events.yaml"]).To(ContainSubstring("ScalingUpRack"))
+
+	g.Expect(files[root+"resources/crds.yaml"]).To(ContainSubstring("cassandradatacenters.cassandra.datastax.com"))
+	g.Expect(files[root+"resources/crds.yaml"]).ToNot(ContainSubstring("prometheuses"))
+
+	pods := files[root+"resources/pods.yaml"]
+	g.Expect(pods).To(ContainSubstring("test-dc1-default-sts-0"))
+	g.Expect(pods).To(ContainSubstring("k8ssandra-cass-operator-5d8f7"))
+	g.Expect(pods).ToNot(ContainSubstring("other-release-pod"))
+
+	// the secret values are redacted
+	g.Expect(files[root+"resources/cassandradatacenters.yaml"]).To(ContainSubstring("keystore_password: REDACTED"))
+	g.Expect(files[root+"resources/cassandradatacenters.yaml"]).To(ContainSubstring("keystore: /etc/keystore"))
+	g.Expect(pods).To(ContainSubstring("value: REDACTED"))
+	g.Expect(pods).To(ContainSubstring("value: accept"))
+	secrets := files[root+"resources/secrets.yaml"]
+	g.Expect(secrets).To(ContainSubstring("password: REDACTED (6 bytes)"))
+	g.Expect(secrets).To(ContainSubstring("username: REDACTED (14 bytes)"))
+	g.Expect(secrets).ToNot(ContainSubstring("c2VjcmV0"))
+	for name, content := range files {
+		g.Expect(content).ToNot(ContainSubstring("changeit"), name)
+		g.Expect(content).ToNot(ContainSubstring("value: secret"), name)
+	}
+
+	g.Expect(files[root+"logs/test-dc1-default-sts-0/cassandra.log"]).To(Equal("logs of test-dc1-default-sts-0/cassandra previous=false tail=100\n"))
+	g.Expect(files[root+"logs/test-dc1-default-sts-0/cassandra.previous.log"]).To(Equal("logs of test-dc1-default-sts-0/cassandra previous=true tail=100\n"))
+	g.Expect(files).ToNot(HaveKey(root + "logs/test-dc1-default-sts-0/medusa.log"))
+	g.Expect(files).To(HaveKey(root + "logs/k8ssandra-cass-operator-5d8f7/operator.log"))
+	g.Expect(files).ToNot(HaveKey(root + "logs/other-release-pod/other.log"))
+
+	g.Expect(files[root+"nodes/test-dc1-default-sts-0/status.txt"]).To(Equal(`Cassandra 3.11.10
+Datacenter: dc1
+Alive  Address          Status       Host ID                                Rack
+Up     10.0.0.1         NORMAL       host-1                                 default
+Down   10.0.0.2         shutdown     host-2                                 default
+`))
+	g.Expect(files[root+"nodes/test-dc1-default-sts-0/describecluster.txt"]).To(Equal("Schema versions:\n\t2207c2a9: [10.0.0.1]\n\tUNREACHABLE: [10.0.0.2]\n"))
+
+	g.Expect(files[root+"errors.txt"]).To(Equal(
+		"failed to get the logs of container server-system-logger of pod test-dc1-default-sts-0: container is waiting to start\n"))
+}
@@ -0,0 +1,89 @@
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/k8ssandra/k8ssandra/pkg/rollout"
+)
+
+// Backup is how the data is saved before the upgrade
+type Backup string
+
+const (
+	// BackupSnapshot takes a nodetool snapshot on each node, kept on the data volumes of the nodes
+	BackupSnapshot = Backup("snapshot")
+	// BackupMedusa creates a CassandraBackup, Medusa uploads it to its storage bucket
+	BackupMedusa = Backup("medusa")
+	// BackupNone saves nothing
+	BackupNone = Backup("none")
+)
+
+// CassandraBackupGVK is the kind of the backups of medusa-operator
+var CassandraBackupGVK = schema.GroupVersionKind{Group: "cassandra.k8ssandra.io", Version: "v1alpha1", Kind: "CassandraBackup"}
+
+// ParseBackup returns the Backup of a flag value
+func ParseBackup(value string) (Backup, error) {
+	switch backup := Backup(value); backup {
+	case BackupSnapshot, BackupMedusa, BackupNone:
+		return backup, nil
+	}
+	return "", fmt.Errorf("invalid backup %s, expected one of %s, %s or %s", value, BackupSnapshot, BackupMedusa, BackupNone)
+}
+
+// backupName returns the name of the snapshot or CassandraBackup taken before upgrading to version
+func backupName(version string, now time.Time) string {
+	return fmt.Sprintf("pre-upgrade-%s-%s", strings.ReplaceAll(version, ".", "-"), now.UTC().Format("20060102150405"))
+}
+
+// snapshot takes a snapshot of all the keyspaces on each pod
+func (u *Upgrader) snapshot(ctx context.Context, pods []string, name string) error {
+	for i, pod := range pods {
+		log.Printf("Taking snapshot %s of pod %s (%d/%d)", name, pod, i+1, len(pods))
+		if _, err := u.Nodetool(ctx, pod, "snapshot", "-t", name); err != nil {
+			return fmt.Errorf("snapshot of pod %s failed: %w", pod, err)
+		}
+	}
+	return nil
+}
+
+// medusaBackup creates a CassandraBackup of the datacenter and waits for Medusa to complete it on all the pods
+func (u *Upgrader) medusaBackup(ctx context.Context, datacenter types.NamespacedName, name string) error {
+	backup := &unstructured.Unstructured{}
+	backup.SetGroupVersionKind(CassandraBackupGVK)
+	backup.SetNamespace(datacenter.Namespace)
+	backup.SetName(name)
+	_ = unstructured.SetNestedField(backup.Object, name, "spec", "name")
+	_ = unstructured.SetNestedField(backup.Object, datacenter.Name, "spec", "cassandraDatacenter")
+	log.Printf("Creating CassandraBackup %s of CassandraDatacenter %s", name, datacenter)
+	if err := u.Client.Create(ctx, backup); err != nil {
+		return fmt.Errorf("failed to create CassandraBackup %s: %w", name, err)
+	}
+
+	key := types.NamespacedName{Namespace: datacenter.Namespace, Name: name}
+	err := wait.PollImmediate(rollout.PollInterval, u.Timeout, func() (bool, error) {
+		backup := &unstructured.Unstructured{}
+		backup.SetGroupVersionKind(CassandraBackupGVK)
+		if err := u.Client.Get(ctx, key, backup); err != nil {
+			return false, err
+		}
+		if failed, _, _ := unstructured.NestedStringSlice(backup.Object, "status", "failed"); len(failed) > 0 {
+			return false, fmt.Errorf("backup failed on pods %s", strings.Join(failed, ", "))
+		}
+		finishTime, _, _ := unstructured.NestedString(backup.Object, "status", "finishTime")
+		return finishTime != "", nil
+	})
+	if err != nil {
+		return fmt.Errorf("CassandraBackup %s did not complete: %w", name, err)
+	}
+	log.Printf("CassandraBackup %s completed", name)
+	return nil
+}
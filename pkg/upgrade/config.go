@@ -0,0 +1,75 @@
+package upgrade
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+const (
+	cassandraYamlSection    = "cassandra-yaml"
+	jvmOptionsSection       = "jvm-options"
+	jvmServerOptionsSection = "jvm-server-options"
+)
+
+// removedIn40 are the cassandra.yaml options Cassandra 4.0 no longer has, with what replaces them. Cassandra fails to
+// start on unknown options.
+var removedIn40 = map[string]string{
+	"start_rpc":                                "Thrift was removed, use the native transport",
+	"rpc_port":                                 "Thrift was removed, use native_transport_port",
+	"rpc_server_type":                          "Thrift was removed",
+	"rpc_min_threads":                          "Thrift was removed",
+	"rpc_max_threads":                          "Thrift was removed, use native_transport_max_threads",
+	"rpc_send_buff_size_in_bytes":              "Thrift was removed",
+	"rpc_recv_buff_size_in_bytes":              "Thrift was removed",
+	"thrift_framed_transport_size_in_mb":       "Thrift was removed, use native_transport_max_frame_size_in_mb",
+	"thrift_max_message_length_in_mb":          "Thrift was removed",
+	"thrift_prepared_statements_cache_size_mb": "Thrift was removed",
+	"request_scheduler":                        "the request scheduler was removed",
+	"request_scheduler_id":                     "the request scheduler was removed",
+	"request_scheduler_options":                "the request scheduler was removed",
+	"streaming_socket_timeout_in_ms":           "use streaming_keep_alive_period_in_secs",
+}
+
+// CheckConfig40 returns the problems of a CassandraDatacenter config, as set in cassandra.configOverride, which
+// Cassandra 4.0 would not start with
+func CheckConfig40(config map[string]interface{}) []string {
+	problems := make([]string, 0)
+	if _, found := config[jvmOptionsSection]; found {
+		problems = append(problems, fmt.Sprintf("%s is read from %s by Cassandra 4.0", jvmOptionsSection, jvmServerOptionsSection))
+	}
+	if cassandraYaml, ok := config[cassandraYamlSection].(map[string]interface{}); ok {
+		keys := make([]string, 0)
+		for key := range cassandraYaml {
+			if _, removed := removedIn40[key]; removed {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			problems = append(problems, fmt.Sprintf("%s.%s is not supported by Cassandra 4.0, %s", cassandraYamlSection, key, removedIn40[key]))
+		}
+	}
+	return problems
+}
+
+// convertConfig40 moves the jvm-options of a CassandraDatacenter config to jvm-server-options, as the chart renders
+// them for Cassandra 4.0. It returns nil if the config has nothing to convert.
+func convertConfig40(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	config := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode the config: %w", err)
+	}
+	options, found := config[jvmOptionsSection]
+	if !found {
+		return nil, nil
+	}
+	if _, found := config[jvmServerOptionsSection]; !found {
+		config[jvmServerOptionsSection] = options
+	}
+	delete(config, jvmOptionsSection)
+	return json.Marshal(config)
+}
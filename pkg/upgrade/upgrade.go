@@ -0,0 +1,403 @@
+// Package upgrade upgrades the Cassandra version of a CassandraDatacenter deployed by k8ssandra. It checks that the
+// nodes are up with schema agreement and that the config is supported by the target version, saves the data, lets
+// cass-operator roll the new image out and rewrites the SSTables in the format of the new version.
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8ssandra/k8ssandra/pkg/rollout"
+	"github.com/k8ssandra/k8ssandra/pkg/scale"
+	"github.com/k8ssandra/k8ssandra/pkg/values"
+)
+
+const cassandraContainer = "cassandra"
+
+// Options are the parameters of an upgrade
+type Options struct {
+	// Version is the Cassandra version to upgrade to
+	Version string
+	// Image is the image of Version, looked up in cassandra.versionImageMap of the release when empty
+	Image string
+	// Backup is how the data is saved before the upgrade
+	Backup Backup
+	// Values are the values the release was rendered with
+	Values *values.Values
+}
+
+// Plan is an upgrade of a CassandraDatacenter, with the problems found by the pre-upgrade checks
+type Plan struct {
+	Datacenter  types.NamespacedName
+	FromVersion string
+	ToVersion   string
+	Image       string
+	Pods        []string
+	Backup      Backup
+	BackupName  string
+	// Major is true when the major version changes, the SSTables are then rewritten in the format of the new version
+	Major bool
+	// Values are the values to set in the release once the datacenter is upgraded, or the next helm upgrade rolls the
+	// previous version back
+	Values []string
+	// Problems are the failed pre-upgrade checks, the upgrade is refused if there are any
+	Problems []string
+}
+
+// Steps returns the steps of the upgrade, in order
+func (p *Plan) Steps() []string {
+	steps := make([]string, 0)
+	switch p.Backup {
+	case BackupSnapshot:
+		steps = append(steps, fmt.Sprintf("take snapshot %s on %d pods", p.BackupName, len(p.Pods)))
+	case BackupMedusa:
+		steps = append(steps, fmt.Sprintf("create CassandraBackup %s and wait for Medusa to complete it", p.BackupName))
+	}
+	steps = append(steps,
+		fmt.Sprintf("set the version of CassandraDatacenter %s to %s with image %s", p.Datacenter.Name, p.ToVersion, p.Image),
+		fmt.Sprintf("wait for cass-operator to restart the %d pods one at a time, checking that all nodes are up with schema agreement", len(p.Pods)))
+	if p.Major {
+		steps = append(steps, fmt.Sprintf("run nodetool upgradesstables on the %d pods one at a time", len(p.Pods)))
+	}
+	return steps
+}
+
+// Upgrader upgrades the Cassandra version of CassandraDatacenters
+type Upgrader struct {
+	Client client.Client
+	// Nodetool runs the health checks, snapshots and upgradesstables
+	Nodetool scale.NodetoolFunc
+
+	// Timeout bounds the wait for the backup and for cass-operator to roll the new version out
+	Timeout time.Duration
+}
+
+// Plan returns the plan of the upgrade of the CassandraDatacenter with the problems found by the pre-upgrade checks:
+// the target image is known, the versions can be upgraded from one to the other, the datacenter is ready, its nodes
+// are up and normal with schema agreement, and the config is supported by the target version.
+func (u *Upgrader) Plan(ctx context.Context, key types.NamespacedName, opts Options) (*Plan, error) {
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	if err := u.Client.Get(ctx, key, cassdc); err != nil {
+		return nil, fmt.Errorf("failed to get CassandraDatacenter %s: %w", key, err)
+	}
+	pods, err := u.pods(ctx, cassdc)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Datacenter:  key,
+		FromVersion: cassdc.Spec.ServerVersion,
+		ToVersion:   opts.Version,
+		Pods:        pods,
+		Backup:      opts.Backup,
+		Values:      []string{fmt.Sprintf("cassandra.version=%s", opts.Version)},
+		Problems:    make([]string, 0),
+	}
+	if plan.Backup != BackupNone {
+		plan.BackupName = backupName(opts.Version, time.Now())
+	}
+
+	plan.checkVersions()
+	plan.checkImage(opts)
+	if plan.Major && strings.HasPrefix(opts.Version, "4.") {
+		plan.checkConfig40(cassdc, opts.Values)
+	}
+
+	if ready, err := rollout.DatacenterReady(ctx, u.Client, cassdc); err != nil {
+		return nil, err
+	} else if !ready {
+		plan.Problems = append(plan.Problems, fmt.Sprintf("CassandraDatacenter %s is not ready, wait for cass-operator to be done with it", key))
+	}
+	if err := u.checkNodes(ctx, cassdc, pods); err != nil {
+		plan.Problems = append(plan.Problems, err.Error())
+	}
+	return plan, nil
+}
+
+// checkVersions refuses downgrades and upgrades skipping a major version
+func (p *Plan) checkVersions() {
+	from, err := semver.NewVersion(p.FromVersion)
+	if err != nil {
+		p.Problems = append(p.Problems, fmt.Sprintf("invalid current version %s: %v", p.FromVersion, err))
+		return
+	}
+	to, err := semver.NewVersion(p.ToVersion)
+	if err != nil {
+		p.Problems = append(p.Problems, fmt.Sprintf("invalid version %s: %v", p.ToVersion, err))
+		return
+	}
+
+	switch {
+	case to.Equal(from):
+		p.Problems = append(p.Problems, fmt.Sprintf("CassandraDatacenter %s already runs Cassandra %s", p.Datacenter.Name, p.FromVersion))
+	case to.LessThan(from):
+		p.Problems = append(p.Problems, fmt.Sprintf("Cassandra cannot be downgraded from %s to %s", p.FromVersion, p.ToVersion))
+	case to.Major() > from.Major()+1:
+		p.Problems = append(p.Problems, fmt.Sprintf("Cassandra cannot be upgraded from %s to %s, upgrade to %d.x first", p.FromVersion, p.ToVersion, from.Major()+1))
+	}
+	p.Major = to.Major() > from.Major()
+}
+
+// checkImage looks the image of the target version up in the values of the release
+func (p *Plan) checkImage(opts Options) {
+	if opts.Image != "" {
+		p.Image = opts.Image
+		p.Values = append(p.Values, fmt.Sprintf("cassandra.image=%s", opts.Image))
+		return
+	}
+	if opts.Values == nil {
+		p.Problems = append(p.Problems, fmt.Sprintf("the image of Cassandra %s is unknown, set it", p.ToVersion))
+		return
+	}
+	if image := opts.Values.Cassandra.Image; image != nil && *image != "" {
+		p.Problems = append(p.Problems, fmt.Sprintf("the release sets cassandra.image to %s, set the image of Cassandra %s", *image, p.ToVersion))
+		return
+	}
+	image, found := opts.Values.Cassandra.VersionImageMap[p.ToVersion]
+	if !found {
+		p.Problems = append(p.Problems, fmt.Sprintf("Cassandra %s is not in cassandra.versionImageMap of the release, add it or set the image", p.ToVersion))
+		return
+	}
+	p.Image = image
+}
+
+// checkConfig40 checks the configOverride of the release against Cassandra 4.0, and keeps the number of tokens of the
+// nodes, the chart defaults to fewer tokens with 4.0 and nodes cannot change their number of tokens
+func (p *Plan) checkConfig40(cassdc *cassdcapi.CassandraDatacenter, release *values.Values) {
+	if release == nil {
+		return
+	}
+	for _, problem := range CheckConfig40(release.Cassandra.ConfigOverride) {
+		p.Problems = append(p.Problems, "cassandra.configOverride: "+problem)
+	}
+
+	config := struct {
+		CassandraYaml struct {
+			NumTokens int `json:"num_tokens"`
+		} `json:"cassandra-yaml"`
+	}{}
+	if len(cassdc.Spec.Config) > 0 {
+		if err := json.Unmarshal(cassdc.Spec.Config, &config); err != nil {
+			p.Problems = append(p.Problems, fmt.Sprintf("failed to decode the config of CassandraDatacenter %s: %v", cassdc.Name, err))
+			return
+		}
+	}
+	if tokens := config.CassandraYaml.NumTokens; tokens > 0 && len(release.Cassandra.Datacenters) > 0 &&
+		release.Cassandra.Datacenters[0].NumTokens == 0 && release.Cassandra.ConfigOverride == nil {
+		p.Values = append(p.Values, fmt.Sprintf("cassandra.datacenters[0].num_tokens=%d", tokens))
+	}
+}
+
+// checkNodes checks on a ready pod that all the nodes of the cluster are up and normal and agree on the schema
+func (u *Upgrader) checkNodes(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter, pods []string) error {
+	pod, err := u.readyPod(ctx, cassdc)
+	if err != nil {
+		return err
+	}
+	output, err := u.Nodetool(ctx, pod, "status")
+	if err != nil {
+		return err
+	}
+	nodes := rollout.ParseNodetoolStatus(output)
+	datacenterNodes := 0
+	for _, node := range nodes {
+		if node.Datacenter == cassdc.Name {
+			datacenterNodes++
+		}
+	}
+	if err := rollout.CheckNodesUp(nodes, len(nodes)-datacenterNodes+len(pods)); err != nil {
+		return err
+	}
+
+	output, err = u.Nodetool(ctx, pod, "describecluster")
+	if err != nil {
+		return err
+	}
+	return rollout.CheckSchemaAgreement(rollout.ParseSchemaVersions(output))
+}
+
+// Upgrade runs a plan without problems: it saves the data, sets the new version and image of the CassandraDatacenter,
+// waits for cass-operator to restart all the pods with them and the nodes to be up with schema agreement, then
+// rewrites the SSTables after a major upgrade
+func (u *Upgrader) Upgrade(ctx context.Context, plan *Plan) error {
+	if len(plan.Problems) > 0 {
+		return fmt.Errorf("the pre-upgrade checks failed: %s", strings.Join(plan.Problems, "; "))
+	}
+	start := time.Now()
+
+	switch plan.Backup {
+	case BackupSnapshot:
+		if err := u.snapshot(ctx, plan.Pods, plan.BackupName); err != nil {
+			return err
+		}
+	case BackupMedusa:
+		if err := u.medusaBackup(ctx, plan.Datacenter, plan.BackupName); err != nil {
+			return err
+		}
+	}
+
+	if err := u.setVersion(ctx, plan); err != nil {
+		return err
+	}
+	if err := u.waitForPods(ctx, plan); err != nil {
+		return err
+	}
+
+	if plan.Major {
+		if err := u.upgradeSSTables(ctx, plan.Pods); err != nil {
+			return err
+		}
+	}
+	log.Printf("Upgraded CassandraDatacenter %s to Cassandra %s in %s", plan.Datacenter, plan.ToVersion, time.Since(start).Round(time.Second))
+	return nil
+}
+
+// setVersion sets the version and image of the CassandraDatacenter, converting its config to the target version
+func (u *Upgrader) setVersion(ctx context.Context, plan *Plan) error {
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	if err := u.Client.Get(ctx, plan.Datacenter, cassdc); err != nil {
+		return fmt.Errorf("failed to get CassandraDatacenter %s: %w", plan.Datacenter, err)
+	}
+
+	patch := client.MergeFrom(cassdc.DeepCopy())
+	cassdc.Spec.ServerVersion = plan.ToVersion
+	cassdc.Spec.ServerImage = plan.Image
+	if plan.Major && strings.HasPrefix(plan.ToVersion, "4.") {
+		config, err := convertConfig40(cassdc.Spec.Config)
+		if err != nil {
+			return fmt.Errorf("failed to convert the config of CassandraDatacenter %s: %w", plan.Datacenter, err)
+		}
+		if config != nil {
+			cassdc.Spec.Config = config
+		}
+	}
+
+	log.Printf("Setting the version of CassandraDatacenter %s to %s with image %s", plan.Datacenter, plan.ToVersion, plan.Image)
+	if err := u.Client.Patch(ctx, cassdc, patch); err != nil {
+		return fmt.Errorf("failed to set the version of CassandraDatacenter %s: %w", plan.Datacenter, err)
+	}
+	return nil
+}
+
+// waitForPods waits for all the pods to run the target image and be ready, logging each upgraded pod, then for the
+// nodes to be up with schema agreement
+func (u *Upgrader) waitForPods(ctx context.Context, plan *Plan) error {
+	upgraded := make(map[string]bool)
+	err := wait.PollImmediate(rollout.PollInterval, u.Timeout, func() (bool, error) {
+		cassdc := &cassdcapi.CassandraDatacenter{}
+		if err := u.Client.Get(ctx, plan.Datacenter, cassdc); err != nil {
+			return false, err
+		}
+		pods := &corev1.PodList{}
+		if err := u.Client.List(ctx, pods, client.InNamespace(cassdc.Namespace), client.MatchingLabels(cassdc.GetDatacenterLabels())); err != nil {
+			return false, err
+		}
+
+		names := make([]string, 0)
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if podRuns(pod, plan.Image) && !upgraded[pod.Name] {
+				names = append(names, pod.Name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			upgraded[name] = true
+			log.Printf("Pod %s runs Cassandra %s (%d/%d)", name, plan.ToVersion, len(upgraded), len(plan.Pods))
+		}
+
+		if cassdc.Status.ObservedGeneration < cassdc.Generation || len(upgraded) < len(plan.Pods) {
+			return false, nil
+		}
+		if ready, err := rollout.DatacenterReady(ctx, u.Client, cassdc); err != nil || !ready {
+			return false, err
+		}
+		if err := u.checkNodes(ctx, cassdc, plan.Pods); err != nil {
+			log.Printf("Waiting for the nodes: %v", err)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("CassandraDatacenter %s did not roll Cassandra %s out: %w", plan.Datacenter, plan.ToVersion, err)
+	}
+	return nil
+}
+
+// upgradeSSTables rewrites the SSTables of each pod in the format of the new version, one pod at a time
+func (u *Upgrader) upgradeSSTables(ctx context.Context, pods []string) error {
+	start := time.Now()
+	for i, pod := range pods {
+		log.Printf("Upgrading the SSTables of pod %s (%d/%d)", pod, i+1, len(pods))
+		podStart := time.Now()
+		if _, err := u.Nodetool(ctx, pod, "upgradesstables"); err != nil {
+			return fmt.Errorf("upgradesstables of pod %s failed, run it again once the cause is fixed: %w", pod, err)
+		}
+		log.Printf("Upgraded the SSTables of pod %s in %s", pod, time.Since(podStart).Round(time.Second))
+	}
+	log.Printf("Upgraded the SSTables of %d pods in %s", len(pods), time.Since(start).Round(time.Second))
+	return nil
+}
+
+func (u *Upgrader) pods(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter) ([]string, error) {
+	pods := &corev1.PodList{}
+	if err := u.Client.List(ctx, pods, client.InNamespace(cassdc.Namespace), client.MatchingLabels(cassdc.GetDatacenterLabels())); err != nil {
+		return nil, fmt.Errorf("failed to list pods of CassandraDatacenter %s: %w", cassdc.Name, err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("CassandraDatacenter %s has no pod", cassdc.Name)
+	}
+	names := make([]string, len(pods.Items))
+	for i, pod := range pods.Items {
+		names[i] = pod.Name
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// readyPod returns the name of a pod of the CassandraDatacenter whose Cassandra container is ready
+func (u *Upgrader) readyPod(ctx context.Context, cassdc *cassdcapi.CassandraDatacenter) (string, error) {
+	pods := &corev1.PodList{}
+	if err := u.Client.List(ctx, pods, client.InNamespace(cassdc.Namespace), client.MatchingLabels(cassdc.GetDatacenterLabels())); err != nil {
+		return "", fmt.Errorf("failed to list pods of CassandraDatacenter %s: %w", cassdc.Name, err)
+	}
+	sort.Slice(pods.Items, func(i, j int) bool { return pods.Items[i].Name < pods.Items[j].Name })
+	for i := range pods.Items {
+		if podRuns(&pods.Items[i], "") {
+			return pods.Items[i].Name, nil
+		}
+	}
+	return "", fmt.Errorf("CassandraDatacenter %s has no ready pod", cassdc.Name)
+}
+
+// podRuns returns true if the Cassandra container of the pod is ready, with the given image if not empty
+func podRuns(pod *corev1.Pod, image string) bool {
+	if image != "" {
+		found := false
+		for _, container := range pod.Spec.Containers {
+			found = found || (container.Name == cassandraContainer && container.Image == image)
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == cassandraContainer {
+			return status.Ready
+		}
+	}
+	return false
+}
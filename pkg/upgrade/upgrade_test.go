@@ -0,0 +1,284 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k8ssandra/k8ssandra/pkg/rollout"
+	"github.com/k8ssandra/k8ssandra/pkg/values"
+)
+
+const (
+	testNamespace = "k8ssandra"
+	image311      = "k8ssandra/cass-management-api:3.11.10-v0.1.25"
+	image40       = "k8ssandra/cass-management-api:4.0.0-v0.1.25"
+)
+
+var dcKey = types.NamespacedName{Namespace: testNamespace, Name: "dc1"}
+
+const describeClusterOutput = `Cluster Information:
+	Name: test
+	Schema versions:
+		2207c2a9-f598-3971-986b-2926e09e239d: [10.0.0.0, 10.0.0.1, 10.0.0.2]
+`
+
+// fakeNodetool answers nodetool status and describecluster for healthy nodes and records the other commands
+type fakeNodetool struct {
+	sync.Mutex
+	down     bool
+	commands []string
+}
+
+func (n *fakeNodetool) run(ctx context.Context, pod string, args ...string) (string, error) {
+	n.Lock()
+	defer n.Unlock()
+	switch args[0] {
+	case "status":
+		state := "UN"
+		if n.down {
+			state = "DN"
+		}
+		output := "Datacenter: dc1\n--  Address  Load  Tokens  Owns  Host ID  Rack\n"
+		for i := 0; i < 3; i++ {
+			output += fmt.Sprintf("%s  10.0.0.%d  100 KiB  256  100.0%%  host-%d  default\n", state, i, i)
+		}
+		return output, nil
+	case "describecluster":
+		return describeClusterOutput, nil
+	}
+	n.commands = append(n.commands, pod+": "+strings.Join(args, " "))
+	return "", nil
+}
+
+func cassandraPod(index int, image string) *corev1.Pod {
+	cassdc := &cassdcapi.CassandraDatacenter{ObjectMeta: metav1.ObjectMeta{Name: "dc1"}, Spec: cassdcapi.CassandraDatacenterSpec{ClusterName: "test"}}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: fmt.Sprintf("test-dc1-default-sts-%d", index), Labels: cassdc.GetDatacenterLabels()},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "cassandra", Image: image}}},
+		Status:     corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "cassandra", Ready: true}}},
+	}
+}
+
+func newTestUpgrader(t *testing.T, config string) (*Upgrader, *fakeNodetool, client.Client) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = cassdcapi.AddToScheme(scheme)
+
+	cassdc := &cassdcapi.CassandraDatacenter{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "dc1"},
+		Spec: cassdcapi.CassandraDatacenterSpec{
+			ClusterName:   "test",
+			Size:          3,
+			ServerVersion: "3.11.10",
+			ServerImage:   image311,
+			Config:        json.RawMessage(config),
+		},
+		Status: cassdcapi.CassandraDatacenterStatus{
+			CassandraOperatorProgress: cassdcapi.ProgressReady,
+			Conditions:                []cassdcapi.DatacenterCondition{{Type: cassdcapi.DatacenterReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	replicas := int32(3)
+	objects := []runtime.Object{
+		cassdc,
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "test-dc1-default-sts", Labels: cassdc.GetDatacenterLabels()},
+			Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+			Status:     appsv1.StatefulSetStatus{Replicas: replicas, ReadyReplicas: replicas, UpdatedReplicas: replicas},
+		},
+	}
+	for i := 0; i < 3; i++ {
+		objects = append(objects, cassandraPod(i, image311))
+	}
+	c := fake.NewFakeClientWithScheme(scheme, objects...)
+
+	rollout.PollInterval = 10 * time.Millisecond
+	nodetool := &fakeNodetool{}
+	return &Upgrader{Client: c, Nodetool: nodetool.run, Timeout: 5 * time.Second}, nodetool, c
+}
+
+func releaseValues(configOverride map[string]interface{}) *values.Values {
+	v := &values.Values{}
+	v.Cassandra.Version = "3.11.10"
+	v.Cassandra.VersionImageMap = values.DefaultVersionImageMap()
+	v.Cassandra.ConfigOverride = configOverride
+	v.Cassandra.Datacenters = []values.Datacenter{{Name: "dc1", Size: 3}}
+	return v
+}
+
+const chartConfig = `{"cassandra-yaml": {"num_tokens": 256}, "jvm-options": {"initial_heap_size": "1G"}}`
+
+func TestPlan(t *testing.T) {
+	g := NewWithT(t)
+
+	upgrader, _, _ := newTestUpgrader(t, chartConfig)
+	plan, err := upgrader.Plan(context.Background(), dcKey, Options{Version: "4.0.0", Backup: BackupSnapshot, Values: releaseValues(nil)})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(plan.Problems).To(BeEmpty())
+	g.Expect(plan.Major).To(BeTrue())
+	g.Expect(plan.Image).To(Equal(image40))
+	g.Expect(plan.Pods).To(Equal([]string{"test-dc1-default-sts-0", "test-dc1-default-sts-1", "test-dc1-default-sts-2"}))
+	g.Expect(plan.BackupName).To(HavePrefix("pre-upgrade-4-0-0-"))
+	// the chart defaults to 16 tokens with 4.0
+	g.Expect(plan.Values).To(Equal([]string{"cassandra.version=4.0.0", "cassandra.datacenters[0].num_tokens=256"}))
+	g.Expect(plan.Steps()).To(HaveLen(4))
+}
+
+func TestPlanProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		down     bool
+		problems []string
+	}{
+		{
+			name:     "same version",
+			opts:     Options{Version: "3.11.10", Values: releaseValues(nil)},
+			problems: []string{"CassandraDatacenter dc1 already runs Cassandra 3.11.10"},
+		},
+		{
+			name:     "downgrade",
+			opts:     Options{Version: "3.11.9", Values: releaseValues(nil)},
+			problems: []string{"Cassandra cannot be downgraded from 3.11.10 to 3.11.9"},
+		},
+		{
+			name:     "skipped major version",
+			opts:     Options{Version: "5.0.0", Image: "cassandra:5.0.0", Values: releaseValues(nil)},
+			problems: []string{"Cassandra cannot be upgraded from 3.11.10 to 5.0.0, upgrade to 4.x first"},
+		},
+		{
+			name:     "unknown image",
+			opts:     Options{Version: "4.0.1", Values: releaseValues(nil)},
+			problems: []string{"Cassandra 4.0.1 is not in cassandra.versionImageMap of the release, add it or set the image"},
+		},
+		{
+			name: "invalid configOverride",
+			opts: Options{Version: "4.0.0", Values: releaseValues(map[string]interface{}{
+				"cassandra-yaml": map[string]interface{}{"num_tokens": 256, "start_rpc": false, "rpc_port": 9160},
+				"jvm-options":    map[string]interface{}{"initial_heap_size": "1G"},
+			})},
+			problems: []string{
+				"cassandra.configOverride: jvm-options is read from jvm-server-options by Cassandra 4.0",
+				"cassandra.configOverride: cassandra-yaml.rpc_port is not supported by Cassandra 4.0, Thrift was removed, use native_transport_port",
+				"cassandra.configOverride: cassandra-yaml.start_rpc is not supported by Cassandra 4.0, Thrift was removed, use the native transport",
+			},
+		},
+		{
+			name:     "nodes down",
+			opts:     Options{Version: "4.0.0", Values: releaseValues(nil)},
+			down:     true,
+			problems: []string{"not all nodes are up and normal: 10.0.0.0 in dc1 is DN, 10.0.0.1 in dc1 is DN, 10.0.0.2 in dc1 is DN"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			upgrader, nodetool, _ := newTestUpgrader(t, chartConfig)
+			nodetool.down = tt.down
+			plan, err := upgrader.Plan(context.Background(), dcKey, tt.opts)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(plan.Problems).To(Equal(tt.problems))
+			g.Expect(upgrader.Upgrade(context.Background(), plan)).To(MatchError(ContainSubstring("the pre-upgrade checks failed")))
+		})
+	}
+}
+
+// operate plays cass-operator's part once the image of the CassandraDatacenter changed: it restarts the pods with the
+// new image one at a time
+func operate(t *testing.T, c client.Client, image string) {
+	ctx := context.Background()
+	for {
+		cassdc := &cassdcapi.CassandraDatacenter{}
+		if err := c.Get(ctx, dcKey, cassdc); err != nil {
+			t.Error(err)
+			return
+		}
+		if cassdc.Spec.ServerImage == image {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	for i := 2; i >= 0; i-- {
+		if err := c.Update(ctx, cassandraPod(i, image)); err != nil {
+			t.Error(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	upgrader, nodetool, c := newTestUpgrader(t, chartConfig)
+	plan, err := upgrader.Plan(ctx, dcKey, Options{Version: "4.0.0", Backup: BackupSnapshot, Values: releaseValues(nil)})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	go operate(t, c, image40)
+	g.Expect(upgrader.Upgrade(ctx, plan)).To(Succeed())
+
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	g.Expect(c.Get(ctx, dcKey, cassdc)).To(Succeed())
+	g.Expect(cassdc.Spec.ServerVersion).To(Equal("4.0.0"))
+	g.Expect(cassdc.Spec.ServerImage).To(Equal(image40))
+	g.Expect(cassdc.Spec.Config).To(MatchJSON(`{"cassandra-yaml": {"num_tokens": 256}, "jvm-server-options": {"initial_heap_size": "1G"}}`))
+
+	snapshot := "snapshot -t " + plan.BackupName
+	g.Expect(nodetool.commands).To(Equal([]string{
+		"test-dc1-default-sts-0: " + snapshot,
+		"test-dc1-default-sts-1: " + snapshot,
+		"test-dc1-default-sts-2: " + snapshot,
+		"test-dc1-default-sts-0: upgradesstables",
+		"test-dc1-default-sts-1: upgradesstables",
+		"test-dc1-default-sts-2: upgradesstables",
+	}))
+}
+
+func TestUpgradeMinor(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	upgrader, nodetool, c := newTestUpgrader(t, chartConfig)
+	v := releaseValues(nil)
+	v.Cassandra.VersionImageMap["3.11.11"] = "k8ssandra/cass-management-api:3.11.11-v0.1.27"
+	plan, err := upgrader.Plan(ctx, dcKey, Options{Version: "3.11.11", Backup: BackupNone, Values: v})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(plan.Major).To(BeFalse())
+	g.Expect(plan.Values).To(Equal([]string{"cassandra.version=3.11.11"}))
+
+	go operate(t, c, "k8ssandra/cass-management-api:3.11.11-v0.1.27")
+	g.Expect(upgrader.Upgrade(ctx, plan)).To(Succeed())
+
+	cassdc := &cassdcapi.CassandraDatacenter{}
+	g.Expect(c.Get(ctx, dcKey, cassdc)).To(Succeed())
+	g.Expect(cassdc.Spec.Config).To(MatchJSON(chartConfig))
+	g.Expect(nodetool.commands).To(BeEmpty())
+}
+
+func TestParseBackup(t *testing.T) {
+	g := NewWithT(t)
+
+	backup, err := ParseBackup("medusa")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(backup).To(Equal(BackupMedusa))
+	_, err = ParseBackup("velero")
+	g.Expect(err).To(MatchError("invalid backup velero, expected one of snapshot, medusa or none"))
+}
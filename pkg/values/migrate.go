@@ -0,0 +1,233 @@
+package values
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Migration is a change of the values of the chart made by a release, which values files written for the previous
+// releases have to follow
+type Migration struct {
+	// Version is the chart version which made the change
+	Version string
+	// Description is the reason of the change, with the issue number of the changelog when there is one
+	Description string
+
+	apply func(values map[string]interface{}) []string
+}
+
+// Change is a modification of values made by a migration
+type Change struct {
+	Version     string
+	Description string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s", c.Version, c.Description)
+}
+
+// mgmtAPIImage is the name of the Management API images before they moved to the k8ssandra organization, e.g.
+// datastax/cassandra-mgmtapi-3_11_10:v0.1.22
+var mgmtAPIImage = regexp.MustCompile(`^datastax/cassandra-mgmtapi-([0-9]+)_([0-9]+)_([0-9]+):(v[0-9.]+)$`)
+
+// Migrations are the changes of the values of the chart since 1.0.0, in the order of the releases. The values of the
+// charts before 1.0.0 are migrated by the 1.0.0 migrations.
+var Migrations = []Migration{
+	{
+		Version:     "1.0.0",
+		Description: "cassandra.clusterVersion is renamed cassandra.version",
+		apply:       renameValue("cassandra.clusterVersion", "cassandra.version"),
+	},
+	{
+		Version:     "1.0.0",
+		Description: "cassandra.auth.superuser.name is renamed cassandra.auth.superuser.username",
+		apply:       renameValue("cassandra.auth.superuser.name", "cassandra.auth.superuser.username"),
+	},
+	{
+		Version:     "1.0.0",
+		Description: "#361 the ingresses are configured in the ingress section of each component",
+		apply:       removeValue("ingress"),
+	},
+	{
+		Version:     "1.1.0",
+		Description: "#637 Management API images moved to k8ssandra/cass-management-api",
+		apply:       migrateMgmtAPIImages,
+	},
+	{
+		Version:     "1.1.0",
+		Description: "#530 Upgrade Reaper to 2.2.2",
+		apply:       changeDefault("reaper.image.tag", "2.2.1", "2.2.2"),
+	},
+	{
+		Version:     "1.1.0",
+		Description: "#616 Upgrade Medusa to 0.10.0",
+		apply:       changeDefault("medusa.image.tag", "0.9.0", "0.10.0"),
+	},
+	{
+		Version:     "1.2.0",
+		Description: "#678 Upgrade Medusa to 0.10.1",
+		apply:       changeDefault("medusa.image.tag", "0.10.0", "0.10.1"),
+	},
+	{
+		Version:     "1.3.0",
+		Description: "Upgrade Reaper to 2.2.5",
+		apply:       changeDefault("reaper.image.tag", "2.2.2", "2.2.5"),
+	},
+}
+
+// Migrate applies to values the migrations of the releases after from up to to included, and returns the changes
+// made. values is modified in place.
+func Migrate(values map[string]interface{}, from, to string) ([]Change, error) {
+	fromVersion, err := semver.NewVersion(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %s: %w", from, err)
+	}
+	toVersion, err := semver.NewVersion(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %s: %w", to, err)
+	}
+	if toVersion.LessThan(fromVersion) {
+		return nil, fmt.Errorf("cannot migrate values from %s to the older version %s", from, to)
+	}
+
+	changes := make([]Change, 0)
+	for _, migration := range Migrations {
+		version := semver.MustParse(migration.Version)
+		if !version.GreaterThan(fromVersion) || version.GreaterThan(toVersion) {
+			continue
+		}
+		for _, description := range migration.apply(values) {
+			changes = append(changes, Change{Version: migration.Version, Description: description})
+		}
+	}
+	return changes, nil
+}
+
+// renameValue moves the value at path from to path to. If both are set, the value at from is dropped.
+func renameValue(from, to string) func(values map[string]interface{}) []string {
+	return func(values map[string]interface{}) []string {
+		value, found := lookupValue(values, from)
+		if !found {
+			return nil
+		}
+		deleteValue(values, from)
+		if _, found := lookupValue(values, to); found {
+			return []string{fmt.Sprintf("removed %s, %s is already set", from, to)}
+		}
+		setValue(values, to, value)
+		return []string{fmt.Sprintf("renamed %s to %s", from, to)}
+	}
+}
+
+// removeValue drops the value at path, which the chart no longer has
+func removeValue(path string) func(values map[string]interface{}) []string {
+	return func(values map[string]interface{}) []string {
+		if _, found := lookupValue(values, path); !found {
+			return nil
+		}
+		deleteValue(values, path)
+		return []string{fmt.Sprintf("removed %s, the chart no longer has it", path)}
+	}
+}
+
+// changeDefault replaces the value at path when it is the previous default of the chart, values files copied from the
+// values of the chart would otherwise keep it. Other values are kept, they were chosen.
+func changeDefault(path string, previous, current interface{}) func(values map[string]interface{}) []string {
+	return func(values map[string]interface{}) []string {
+		value, found := lookupValue(values, path)
+		if !found || fmt.Sprint(value) != fmt.Sprint(previous) {
+			return nil
+		}
+		setValue(values, path, current)
+		return []string{fmt.Sprintf("changed %s from the previous default %v to %v", path, previous, current)}
+	}
+}
+
+// migrateMgmtAPIImages replaces the datastax Management API images of cassandra.image and cassandra.versionImageMap
+// with the same images in the k8ssandra organization
+func migrateMgmtAPIImages(values map[string]interface{}) []string {
+	changes := make([]string, 0)
+	migrate := func(path string, image interface{}) (string, bool) {
+		name, ok := image.(string)
+		if !ok {
+			return "", false
+		}
+		match := mgmtAPIImage.FindStringSubmatch(name)
+		if match == nil {
+			return "", false
+		}
+		migrated := fmt.Sprintf("k8ssandra/cass-management-api:%s.%s.%s-%s", match[1], match[2], match[3], match[4])
+		changes = append(changes, fmt.Sprintf("changed %s from %s to %s", path, name, migrated))
+		return migrated, true
+	}
+
+	if image, found := lookupValue(values, "cassandra.image"); found {
+		if migrated, ok := migrate("cassandra.image", image); ok {
+			setValue(values, "cassandra.image", migrated)
+		}
+	}
+	versionImageMap, _ := lookupValue(values, "cassandra.versionImageMap")
+	if images, ok := versionImageMap.(map[string]interface{}); ok {
+		versions := make([]string, 0, len(images))
+		for version := range images {
+			versions = append(versions, version)
+		}
+		sort.Strings(versions)
+		for _, version := range versions {
+			if migrated, ok := migrate(fmt.Sprintf("cassandra.versionImageMap.%s", version), images[version]); ok {
+				images[version] = migrated
+			}
+		}
+	}
+	return changes
+}
+
+// lookupValue returns the value at a path of keys separated by dots
+func lookupValue(values map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	current := values
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	value, found := current[keys[len(keys)-1]]
+	return value, found
+}
+
+// setValue sets the value at a path of keys separated by dots, creating the missing tables
+func setValue(values map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	current := values
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[key] = next
+		}
+		current = next
+	}
+	current[keys[len(keys)-1]] = value
+}
+
+// deleteValue deletes the value at a path of keys separated by dots, and the tables it leaves empty
+func deleteValue(values map[string]interface{}, path string) {
+	keys := strings.Split(path, ".")
+	if len(keys) > 1 {
+		parent, ok := values[keys[0]].(map[string]interface{})
+		if !ok {
+			return
+		}
+		deleteValue(parent, strings.Join(keys[1:], "."))
+		if len(parent) > 0 {
+			return
+		}
+	}
+	delete(values, keys[0])
+}
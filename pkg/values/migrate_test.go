@@ -0,0 +1,190 @@
+package values
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		values   string
+		migrated string
+		changes  []string
+	}{
+		{
+			name: "1.0.0 to 1.3.0",
+			from: "1.0.0",
+			to:   "1.3.0",
+			values: `
+cassandra:
+  version: 3.11.9
+  versionImageMap:
+    3.11.9: datastax/cassandra-mgmtapi-3_11_9:v0.1.22
+    4.0.0: k8ssandra/cass-management-api:4.0.0-v0.1.25
+reaper:
+  image:
+    tag: 2.2.1
+medusa:
+  enabled: true
+  image:
+    tag: 0.9.0
+`,
+			migrated: `
+cassandra:
+  version: 3.11.9
+  versionImageMap:
+    3.11.9: k8ssandra/cass-management-api:3.11.9-v0.1.22
+    4.0.0: k8ssandra/cass-management-api:4.0.0-v0.1.25
+reaper:
+  image:
+    tag: 2.2.5
+medusa:
+  enabled: true
+  image:
+    tag: 0.10.1
+`,
+			changes: []string{
+				"1.1.0: changed cassandra.versionImageMap.3.11.9 from datastax/cassandra-mgmtapi-3_11_9:v0.1.22 to k8ssandra/cass-management-api:3.11.9-v0.1.22",
+				"1.1.0: changed reaper.image.tag from the previous default 2.2.1 to 2.2.2",
+				"1.1.0: changed medusa.image.tag from the previous default 0.9.0 to 0.10.0",
+				"1.2.0: changed medusa.image.tag from the previous default 0.10.0 to 0.10.1",
+				"1.3.0: changed reaper.image.tag from the previous default 2.2.2 to 2.2.5",
+			},
+		},
+		{
+			name: "only the releases in between",
+			from: "1.1.0",
+			to:   "1.2.0",
+			values: `
+cassandra:
+  image: datastax/cassandra-mgmtapi-3_11_9:v0.1.22
+reaper:
+  image:
+    tag: 2.2.2
+medusa:
+  image:
+    tag: 0.10.0
+`,
+			migrated: `
+cassandra:
+  image: datastax/cassandra-mgmtapi-3_11_9:v0.1.22
+reaper:
+  image:
+    tag: 2.2.2
+medusa:
+  image:
+    tag: 0.10.1
+`,
+			changes: []string{
+				"1.2.0: changed medusa.image.tag from the previous default 0.10.0 to 0.10.1",
+			},
+		},
+		{
+			name: "chosen values are kept",
+			from: "1.1.0",
+			to:   "1.3.0",
+			values: `
+reaper:
+  image:
+    tag: 2.2.3
+`,
+			migrated: `
+reaper:
+  image:
+    tag: 2.2.3
+`,
+		},
+		{
+			name: "values of the charts before 1.0.0",
+			from: "0.58.0",
+			to:   "1.0.0",
+			values: `
+cassandra:
+  clusterVersion: 3.11.9
+  auth:
+    superuser:
+      name: admin
+      secret: admin-secret
+ingress:
+  traefik:
+    enabled: true
+`,
+			migrated: `
+cassandra:
+  version: 3.11.9
+  auth:
+    superuser:
+      username: admin
+      secret: admin-secret
+`,
+			changes: []string{
+				"1.0.0: renamed cassandra.clusterVersion to cassandra.version",
+				"1.0.0: renamed cassandra.auth.superuser.name to cassandra.auth.superuser.username",
+				"1.0.0: removed ingress, the chart no longer has it",
+			},
+		},
+		{
+			name: "renamed value already set",
+			from: "0.58.0",
+			to:   "1.0.0",
+			values: `
+cassandra:
+  clusterVersion: 3.11.9
+  version: 3.11.10
+`,
+			migrated: `
+cassandra:
+  version: 3.11.10
+`,
+			changes: []string{
+				"1.0.0: removed cassandra.clusterVersion, cassandra.version is already set",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			values, err := chartutil.ReadValues([]byte(tt.values))
+			g.Expect(err).ToNot(HaveOccurred())
+			migrated, err := chartutil.ReadValues([]byte(tt.migrated))
+			g.Expect(err).ToNot(HaveOccurred())
+
+			changes, err := Migrate(values, tt.from, tt.to)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(values).To(Equal(migrated))
+			descriptions := make([]string, 0)
+			for _, change := range changes {
+				descriptions = append(descriptions, change.String())
+			}
+			if tt.changes == nil {
+				g.Expect(descriptions).To(BeEmpty())
+			} else {
+				g.Expect(descriptions).To(Equal(tt.changes))
+			}
+			g.Expect(Validate(values)).To(BeEmpty())
+		})
+	}
+}
+
+func TestMigrateVersions(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := Migrate(map[string]interface{}{}, "1.3.0", "1.1.0")
+	g.Expect(err).To(MatchError("cannot migrate values from 1.3.0 to the older version 1.1.0"))
+	_, err = Migrate(map[string]interface{}{}, "latest", "1.3.0")
+	g.Expect(err).To(HaveOccurred())
+
+	// the migrations are applied in order, a release is applied after the previous ones
+	for i := 1; i < len(Migrations); i++ {
+		previous := semver.MustParse(Migrations[i-1].Version)
+		g.Expect(semver.MustParse(Migrations[i].Version).LessThan(previous)).To(BeFalse(), Migrations[i].Description)
+	}
+}
@@ -0,0 +1,121 @@
+package values
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+const (
+	// SchemaFile is the path of the JSON schema of the values in the chart
+	SchemaFile = "values.schema.json"
+
+	schemaDraft = "http://json-schema.org/draft-07/schema#"
+	// schemaTag overrides the JSON types accepted by a field, as a comma separated list
+	schemaTag = "schema"
+)
+
+// openSchema is implemented by the types whose values are not all modeled, unknown properties are accepted
+type openSchema interface {
+	openSchema()
+}
+
+// typedSchema is implemented by the types which accept other JSON types than their Go kind
+type typedSchema interface {
+	schemaTypes() []string
+}
+
+func (Subchart) openSchema() {}
+
+func (Quantity) schemaTypes() []string {
+	return []string{"string", "integer", "number"}
+}
+
+// Schema returns the JSON schema of the values of the chart. Objects do not accept unknown properties, so that helm
+// fails on misspelled values instead of ignoring them.
+func Schema() ([]byte, error) {
+	schema := schemaOf(reflect.TypeOf(Values{}))
+	schema["$schema"] = schemaDraft
+	schema["title"] = "Values of the k8ssandra chart"
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func schemaOf(t reflect.Type) map[string]interface{} {
+	if typed, ok := reflect.Zero(t).Interface().(typedSchema); ok {
+		return map[string]interface{}{"type": typed.schemaTypes()}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := schemaOf(t.Elem())
+		if types, ok := schema["type"]; ok {
+			schema["type"] = append(typeList(types), "null")
+		}
+		return schema
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		schema := map[string]interface{}{"type": "object"}
+		if t.Elem().Kind() != reflect.Interface {
+			schema["additionalProperties"] = schemaOf(t.Elem())
+		}
+		return schema
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		addProperties(t, properties)
+		_, open := reflect.Zero(t).Interface().(openSchema)
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": open,
+		}
+	default:
+		// interface{} accepts anything
+		return map[string]interface{}{}
+	}
+}
+
+// addProperties adds the schema of the fields of a struct to properties, the fields of embedded structs are inlined
+// as encoding/json does
+func addProperties(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addProperties(field.Type, properties)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := schemaOf(field.Type)
+		if types := field.Tag.Get(schemaTag); types != "" {
+			schema = map[string]interface{}{"type": strings.Split(types, ",")}
+		}
+		properties[name] = schema
+	}
+}
+
+func typeList(types interface{}) []string {
+	if list, ok := types.([]string); ok {
+		return list
+	}
+	return []string{types.(string)}
+}
@@ -0,0 +1,206 @@
+package values
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// DefaultCassandraVersion is the default cassandra.version of the chart, used when the values are not merged with
+// the values of the chart
+const DefaultCassandraVersion = "3.11.10"
+
+// DefaultVersionImageMap returns the default cassandra.versionImageMap of the chart, the images of the values are
+// added to it
+func DefaultVersionImageMap() map[string]string {
+	return map[string]string{
+		"3.11.7":  "k8ssandra/cass-management-api:3.11.7-v0.1.25",
+		"3.11.8":  "k8ssandra/cass-management-api:3.11.8-v0.1.25",
+		"3.11.9":  "k8ssandra/cass-management-api:3.11.9-v0.1.25",
+		"3.11.10": "k8ssandra/cass-management-api:3.11.10-v0.1.25",
+		"4.0.0":   "k8ssandra/cass-management-api:4.0.0-v0.1.25",
+	}
+}
+
+// jvmSize is a size in the format of the -Xmx JVM option
+var jvmSize = regexp.MustCompile(`^([0-9]+)([kKmMgGtT]?)$`)
+
+// Load reads values files and merges them, later files taking precedence as with helm -f. When chartDir is set the
+// files are merged over the values.yaml of the chart.
+func Load(chartDir string, files ...string) (map[string]interface{}, error) {
+	merged := make(map[string]interface{})
+	for _, file := range files {
+		values, err := chartutil.ReadValuesFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		merged = chartutil.CoalesceTables(values, merged)
+	}
+	if chartDir != "" {
+		defaults, err := chartutil.ReadValuesFile(filepath.Join(chartDir, chartutil.ValuesfileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the values of chart %s: %w", chartDir, err)
+		}
+		merged = chartutil.CoalesceTables(merged, defaults)
+	}
+	return merged, nil
+}
+
+// Validate checks values against the schema of the chart, then checks the constraints between values which would
+// make the chart fail to render or Cassandra fail to start. It returns all the problems found.
+func Validate(values map[string]interface{}) []error {
+	schema, err := Schema()
+	if err != nil {
+		return []error{err}
+	}
+	if err := chartutil.ValidateAgainstSingleSchema(values, schema); err != nil {
+		errs := make([]error, 0)
+		for _, line := range strings.Split(strings.TrimSpace(err.Error()), "\n") {
+			errs = append(errs, errors.New(strings.TrimPrefix(line, "- ")))
+		}
+		return errs
+	}
+
+	v, err := Decode(values)
+	if err != nil {
+		return []error{err}
+	}
+	return v.Validate()
+}
+
+// Decode returns the typed values of values read by helm
+func Decode(values map[string]interface{}) (*Values, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	v := &Values{}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Validate checks the constraints between values the schema cannot express
+func (v *Values) Validate() []error {
+	errs := append(v.validateVersion(), v.validateHeap(v.Cassandra.Heap, "cassandra.heap")...)
+	for i := range v.Cassandra.Datacenters {
+		errs = append(errs, v.validateDatacenter(i)...)
+	}
+	for i := range v.Reaper.RepairSchedules.Schedules {
+		if err := v.Reaper.RepairSchedules.Schedules[i].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("reaper.repairSchedules.schedules[%d]: %v", i, err))
+		}
+	}
+	return errs
+}
+
+// validateVersion checks that the Cassandra version has an image, unless the image is set
+func (v *Values) validateVersion() []error {
+	if v.Cassandra.Image != nil && *v.Cassandra.Image != "" {
+		return nil
+	}
+	version := v.Cassandra.Version
+	if version == "" {
+		version = DefaultCassandraVersion
+	}
+	images := DefaultVersionImageMap()
+	for imageVersion, image := range v.Cassandra.VersionImageMap {
+		images[imageVersion] = image
+	}
+	if _, found := images[version]; found {
+		return nil
+	}
+
+	versions := make([]string, 0, len(images))
+	for imageVersion := range images {
+		versions = append(versions, imageVersion)
+	}
+	sort.Strings(versions)
+	return []error{fmt.Errorf("cassandra.version: %s is not a supported Cassandra version, expected one of %s or set cassandra.image",
+		version, strings.Join(versions, ", "))}
+}
+
+// validateDatacenter checks the racks of a datacenter against its size, and its heap against the memory limit of
+// the Cassandra containers
+func (v *Values) validateDatacenter(index int) []error {
+	dc := v.Cassandra.Datacenters[index]
+	path := fmt.Sprintf("cassandra.datacenters[%d]", index)
+	errs := make([]error, 0)
+
+	if dc.Size < 1 {
+		errs = append(errs, fmt.Errorf("%s.size: datacenter %s must have at least one node", path, dc.Name))
+	} else if dc.Size < len(dc.Racks) {
+		errs = append(errs, fmt.Errorf("%s.size: datacenter %s has %d nodes for %d racks, each rack needs at least one node",
+			path, dc.Name, dc.Size, len(dc.Racks)))
+	}
+	racks := make(map[string]bool)
+	for i, rack := range dc.Racks {
+		if racks[rack.Name] {
+			errs = append(errs, fmt.Errorf("%s.racks[%d].name: rack %s is defined more than once", path, i, rack.Name))
+		}
+		racks[rack.Name] = true
+	}
+
+	// the heap of the datacenter replaces the heap of the cluster, as in the chart
+	if dc.Heap != (Heap{}) {
+		errs = append(errs, v.validateHeap(dc.Heap, path+".heap")...)
+	}
+	return errs
+}
+
+// validateHeap checks that the heap fits in the memory limit, which also has to hold the off heap memory of
+// Cassandra, and that the young generation fits in the heap
+func (v *Values) validateHeap(heap Heap, path string) []error {
+	errs := make([]error, 0)
+	size, err := parseJVMSize(heap.Size)
+	if err != nil {
+		return append(errs, fmt.Errorf("%s.size: %v", path, err))
+	}
+	newGenSize, err := parseJVMSize(heap.NewGenSize)
+	if err != nil {
+		return append(errs, fmt.Errorf("%s.newGenSize: %v", path, err))
+	}
+
+	if size > 0 && newGenSize >= size {
+		errs = append(errs, fmt.Errorf("%s.newGenSize: %s is not smaller than the heap size %s", path, heap.NewGenSize, heap.Size))
+	}
+	if limit, found := v.Cassandra.Resources.Limits["memory"]; found && size > 0 {
+		memory, err := resource.ParseQuantity(string(limit))
+		if err != nil {
+			return append(errs, fmt.Errorf("cassandra.resources.limits.memory: %v", err))
+		}
+		if size >= memory.Value() {
+			errs = append(errs, fmt.Errorf("%s.size: %s is not smaller than cassandra.resources.limits.memory %s, Cassandra also needs off heap memory",
+				path, heap.Size, limit))
+		}
+	}
+	return errs
+}
+
+// parseJVMSize returns the number of bytes of a size in the JVM format, e.g. 512M, or 0 if size is empty
+func parseJVMSize(size Quantity) (int64, error) {
+	if size == "" {
+		return 0, nil
+	}
+	match := jvmSize.FindStringSubmatch(string(size))
+	if match == nil {
+		return 0, fmt.Errorf("invalid size %s, expected a number of bytes optionally followed by k, m, g or t", size)
+	}
+	bytes, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if match[2] != "" {
+		bytes <<= 10 * uint(strings.Index("kmgt", strings.ToLower(match[2]))+1)
+	}
+	return bytes, nil
+}
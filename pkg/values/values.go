@@ -0,0 +1,339 @@
+// Package values models the values of the k8ssandra chart. The JSON schema of the chart, values.schema.json, is
+// generated from the types of this package so that helm rejects unknown values, and Validate checks the constraints
+// between values the schema cannot express.
+package values
+
+import (
+	"encoding/json"
+	"fmt"
+
+	api "github.com/k8ssandra/k8ssandra/pkg/apis/k8ssandra/v1alpha1"
+	"github.com/k8ssandra/k8ssandra/pkg/reaper"
+)
+
+// Values are the values of the k8ssandra chart
+type Values struct {
+	Cassandra  Cassandra  `json:"cassandra"`
+	Stargate   Stargate   `json:"stargate"`
+	Reaper     Reaper     `json:"reaper"`
+	Medusa     Medusa     `json:"medusa"`
+	Monitoring Monitoring `json:"monitoring"`
+	Cleaner    Cleaner    `json:"cleaner"`
+	Client     Client     `json:"client"`
+	Vault      Vault      `json:"vault"`
+
+	NameOverride     string         `json:"nameOverride"`
+	FullnameOverride string         `json:"fullnameOverride"`
+	ClusterName      string         `json:"clusterName"`
+	ClusterDomain    string         `json:"clusterDomain"`
+	ServiceAccount   ServiceAccount `json:"serviceAccount"`
+
+	// The values of the subcharts are checked by their own schema
+	CassOperator        Subchart               `json:"cass-operator"`
+	ReaperOperator      Subchart               `json:"reaper-operator"`
+	MedusaOperator      Subchart               `json:"medusa-operator"`
+	K8ssandraCommon     Subchart               `json:"k8ssandra-common"`
+	KubePrometheusStack Subchart               `json:"kube-prometheus-stack"`
+	Global              map[string]interface{} `json:"global"`
+}
+
+// Cassandra are the values of the Cassandra datacenter
+type Cassandra struct {
+	Enabled                     bool                   `json:"enabled"`
+	Version                     string                 `json:"version"`
+	VersionImageMap             map[string]string      `json:"versionImageMap"`
+	Image                       *string                `json:"image"`
+	ClusterName                 string                 `json:"clusterName"`
+	Auth                        Auth                   `json:"auth"`
+	CassandraLibDirVolume       Volume                 `json:"cassandraLibDirVolume"`
+	AllowMultipleNodesPerWorker bool                   `json:"allowMultipleNodesPerWorker"`
+	AdditionalSeeds             []string               `json:"additionalSeeds"`
+	AdditionalSeedsConfigMap    string                 `json:"additionalSeedsConfigMap"`
+	KeyspaceReplication         KeyspaceReplication    `json:"keyspaceReplication"`
+	LoggingSidecar              Toggle                 `json:"loggingSidecar"`
+	Heap                        Heap                   `json:"heap"`
+	GC                          GC                     `json:"gc"`
+	Resources                   Resources              `json:"resources"`
+	Tolerations                 []interface{}          `json:"tolerations"`
+	Datacenters                 []Datacenter           `json:"datacenters"`
+	Ingress                     Ingress                `json:"ingress"`
+	ConfigOverride              map[string]interface{} `json:"configOverride"`
+}
+
+// Auth are the authentication settings of Cassandra
+type Auth struct {
+	Enabled                   bool        `json:"enabled"`
+	Superuser                 Credentials `json:"superuser"`
+	CacheValidityPeriodMillis int64       `json:"cacheValidityPeriodMillis"`
+	CacheUpdateIntervalMillis int64       `json:"cacheUpdateIntervalMillis"`
+}
+
+// Credentials name the secret of a Cassandra user, or the user whose secret the chart generates
+type Credentials struct {
+	Secret   string `json:"secret"`
+	Username string `json:"username"`
+}
+
+// Volume is a persistent volume claimed by each pod
+type Volume struct {
+	StorageClass string   `json:"storageClass"`
+	Size         Quantity `json:"size"`
+}
+
+// KeyspaceReplication are the settings of the keyspace replication hook
+type KeyspaceReplication struct {
+	Enabled       bool     `json:"enabled"`
+	Timeout       string   `json:"timeout"`
+	Keyspaces     []string `json:"keyspaces"`
+	Repair        bool     `json:"repair"`
+	AllowDecrease bool     `json:"allowDecrease"`
+}
+
+// Toggle enables a component
+type Toggle struct {
+	Enabled bool `json:"enabled"`
+}
+
+// Heap is the heap of the Cassandra JVM, sizes are in the JVM format, e.g. 512M or 8G
+type Heap struct {
+	Size       Quantity `json:"size"`
+	NewGenSize Quantity `json:"newGenSize"`
+}
+
+// GC are the settings of the garbage collector of the Cassandra JVM
+type GC struct {
+	CMS CMS `json:"cms"`
+	G1  G1  `json:"g1"`
+}
+
+// CMS are the settings of the CMS garbage collector
+type CMS struct {
+	Enabled                     bool `json:"enabled"`
+	SurvivorRatio               int  `json:"survivorRatio"`
+	MaxTenuringThreshold        int  `json:"maxTenuringThreshold"`
+	InitiatingOccupancyFraction int  `json:"initiatingOccupancyFraction"`
+	WaitDuration                int  `json:"waitDuration"`
+}
+
+// G1 are the settings of the G1 garbage collector
+type G1 struct {
+	Enabled                        bool `json:"enabled"`
+	SetUpdatingPauseTimePercent    int  `json:"setUpdatingPauseTimePercent"`
+	MaxGcPauseMillis               int  `json:"maxGcPauseMillis"`
+	InitiatingHeapOccupancyPercent int  `json:"initiatingHeapOccupancyPercent"`
+	ParallelGcThreads              int  `json:"parallelGcThreads"`
+	ConcurrentGcThreads            int  `json:"concurrentGcThreads"`
+}
+
+// Resources are the resource requests and limits of a container
+type Resources struct {
+	Limits   map[string]Quantity `json:"limits"`
+	Requests map[string]Quantity `json:"requests"`
+}
+
+// Datacenter are the values of a Cassandra datacenter
+type Datacenter struct {
+	Name                     string `json:"name"`
+	Size                     int    `json:"size"`
+	NumTokens                int    `json:"num_tokens"`
+	AllocateTokensForLocalRF int    `json:"allocateTokensForLocalRF"`
+	Racks                    []Rack `json:"racks"`
+	Heap                     Heap   `json:"heap"`
+	GC                       GC     `json:"gc"`
+}
+
+// Rack is a rack of a datacenter
+type Rack struct {
+	Name           string            `json:"name"`
+	AffinityLabels map[string]string `json:"affinityLabels"`
+}
+
+// Ingress are the settings of a Traefik ingress
+type Ingress struct {
+	Enabled bool    `json:"enabled"`
+	Method  string  `json:"method"`
+	Host    *string `json:"host"`
+	Traefik Traefik `json:"traefik"`
+}
+
+// Traefik are the settings of a Traefik entrypoint
+type Traefik struct {
+	Entrypoint string                 `json:"entrypoint"`
+	TLS        map[string]interface{} `json:"tls"`
+}
+
+// Stargate are the values of the Stargate deployment
+type Stargate struct {
+	Enabled                      bool            `json:"enabled"`
+	Version                      string          `json:"version"`
+	ClusterVersion               string          `json:"clusterVersion"`
+	Replicas                     int             `json:"replicas"`
+	Image                        *string         `json:"image"`
+	ImagePullPolicy              string          `json:"imagePullPolicy"`
+	HeapMB                       int             `json:"heapMB"`
+	CPUReqMillicores             int             `json:"cpuReqMillicores"`
+	CPULimMillicores             int             `json:"cpuLimMillicores"`
+	LivenessInitialDelaySeconds  int             `json:"livenessInitialDelaySeconds"`
+	ReadinessInitialDelaySeconds int             `json:"readinessInitialDelaySeconds"`
+	CassandraUser                Credentials     `json:"cassandraUser"`
+	Ingress                      StargateIngress `json:"ingress"`
+	Affinity                     interface{}     `json:"affinity"`
+	Tolerations                  []interface{}   `json:"tolerations"`
+}
+
+// StargateIngress are the settings of the ingresses of the Stargate APIs
+type StargateIngress struct {
+	Enabled   bool           `json:"enabled"`
+	Host      *string        `json:"host"`
+	Auth      APIIngress     `json:"auth"`
+	Rest      APIIngress     `json:"rest"`
+	Graphql   GraphqlIngress `json:"graphql"`
+	Cassandra Ingress        `json:"cassandra"`
+}
+
+// APIIngress are the settings of the ingress of a Stargate HTTP API
+type APIIngress struct {
+	Enabled bool                   `json:"enabled"`
+	Host    *string                `json:"host"`
+	TLS     map[string]interface{} `json:"tls"`
+}
+
+// GraphqlIngress are the settings of the ingress of the Stargate GraphQL API
+type GraphqlIngress struct {
+	APIIngress
+	Playground Toggle `json:"playground"`
+}
+
+// Reaper are the values of the Reaper deployment
+type Reaper struct {
+	Enabled                bool                   `json:"enabled"`
+	Autoschedule           bool                   `json:"autoschedule"`
+	AutoscheduleProperties map[string]interface{} `json:"autoschedule_properties"`
+	Image                  Image                  `json:"image"`
+	CassandraUser          Credentials            `json:"cassandraUser"`
+	JMX                    Credentials            `json:"jmx"`
+	RepairSchedules        RepairSchedules        `json:"repairSchedules"`
+	Ingress                Ingress                `json:"ingress"`
+	Tolerations            []interface{}          `json:"tolerations"`
+}
+
+// Image is a container image
+type Image struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	PullPolicy string `json:"pullPolicy"`
+}
+
+// RepairSchedules are the settings of the repair schedules hook
+type RepairSchedules struct {
+	Enabled   bool                  `json:"enabled"`
+	Timeout   string                `json:"timeout"`
+	Schedules []reaper.ScheduleSpec `json:"schedules"`
+}
+
+// Medusa are the values of the Medusa backups
+type Medusa struct {
+	Enabled           bool                   `json:"enabled"`
+	Image             Image                  `json:"image"`
+	CassandraUser     Credentials            `json:"cassandraUser"`
+	MultiTenant       bool                   `json:"multiTenant"`
+	Storage           string                 `json:"storage"`
+	StorageProperties map[string]interface{} `json:"storage_properties"`
+	BucketName        string                 `json:"bucketName"`
+	StorageSecret     string                 `json:"storageSecret"`
+	PodStorage        PodStorage             `json:"podStorage"`
+}
+
+// PodStorage is the volume the backups are written to with the local storage
+type PodStorage struct {
+	StorageClass string   `json:"storageClass"`
+	Size         Quantity `json:"size"`
+	AccessModes  []string `json:"accessModes"`
+}
+
+// Monitoring are the values of the ServiceMonitors, alerting rules and dashboards
+type Monitoring struct {
+	Grafana struct {
+		ProvisionDashboards bool `json:"provision_dashboards"`
+	} `json:"grafana"`
+	Prometheus struct {
+		ProvisionServiceMonitors bool   `json:"provision_service_monitors"`
+		Alerts                   Alerts `json:"alerts"`
+	} `json:"prometheus"`
+}
+
+// Alerts are the settings of the alerting rules, shared with the MonitoringConfiguration resource
+type Alerts struct {
+	Enabled bool `json:"enabled"`
+	api.AlertsConfig
+}
+
+// Client are the settings of the k8ssandra-tools jobs
+type Client struct {
+	Image             string `json:"image"`
+	SkipUpgradeChecks bool   `json:"skipUpgradeChecks"`
+}
+
+// Cleaner are the settings of the pre-delete hook removing the resources of the release
+type Cleaner struct {
+	Image         string   `json:"image"`
+	Namespaces    []string `json:"namespaces"`
+	AllNamespaces bool     `json:"allNamespaces"`
+	ClusterScoped bool     `json:"clusterScoped"`
+}
+
+// Vault are the settings of the synchronization of the credentials from Vault
+type Vault struct {
+	Enabled bool   `json:"enabled"`
+	Address string `json:"address"`
+	Auth    struct {
+		Method      string `json:"method"`
+		MountPath   string `json:"mountPath"`
+		Role        string `json:"role"`
+		TokenSecret string `json:"tokenSecret"`
+	} `json:"auth"`
+	KV struct {
+		Mount   string `json:"mount"`
+		Version int    `json:"version"`
+	} `json:"kv"`
+	Paths struct {
+		Superuser string `json:"superuser"`
+		Reaper    string `json:"reaper"`
+		Medusa    string `json:"medusa"`
+		Stargate  string `json:"stargate"`
+	} `json:"paths"`
+}
+
+// ServiceAccount is the service account of the chart
+type ServiceAccount struct {
+	Create bool   `json:"create"`
+	Name   string `json:"name"`
+}
+
+// Subchart are the values of a subchart, only the enabled condition is modeled
+type Subchart struct {
+	Enabled bool `json:"enabled"`
+}
+
+// Quantity is a size or an amount of resources. helm --set passes plain numbers as integers, quantities accept them
+// as well as strings.
+type Quantity string
+
+// UnmarshalJSON accepts numbers as well as strings
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case nil:
+		*q = ""
+	case string:
+		*q = Quantity(v)
+	case float64:
+		*q = Quantity(json.Number(data).String())
+	default:
+		return fmt.Errorf("invalid quantity %s", data)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package values
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+var update = flag.Bool("update", false, "update the values schema of the chart generated from this package")
+
+const chartDir = "../../charts/k8ssandra"
+
+// TestChartTemplates checks that the values schema of the chart is up to date, it is regenerated with -update
+func TestChartTemplates(t *testing.T) {
+	g := NewWithT(t)
+
+	schema, err := Schema()
+	g.Expect(err).ToNot(HaveOccurred())
+	path := filepath.Join(chartDir, SchemaFile)
+	if *update {
+		g.Expect(ioutil.WriteFile(path, schema, 0644)).To(Succeed())
+	}
+	committed, err := ioutil.ReadFile(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(committed)).To(Equal(string(schema)), "%s is out of date, run make chart-templates", path)
+}
+
+// TestDefaultsMatchValues checks that the defaults used without the values of the chart are the ones of the chart
+func TestDefaultsMatchValues(t *testing.T) {
+	g := NewWithT(t)
+
+	values, err := Load(chartDir)
+	g.Expect(err).ToNot(HaveOccurred())
+	cassandra := values["cassandra"].(map[string]interface{})
+	g.Expect(cassandra["version"]).To(Equal(DefaultCassandraVersion))
+	images := make(map[string]string)
+	for version, image := range cassandra["versionImageMap"].(map[string]interface{}) {
+		images[version] = image.(string)
+	}
+	g.Expect(images).To(Equal(DefaultVersionImageMap()))
+}
+
+// TestValidateChartValues checks that the values of the chart and the values files of the tests are valid
+func TestValidateChartValues(t *testing.T) {
+	g := NewWithT(t)
+
+	files, err := filepath.Glob("../../tests/*/testdata/*.yaml")
+	g.Expect(err).ToNot(HaveOccurred())
+	charts, err := filepath.Glob("../../tests/integration/charts/*.yaml")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(files).ToNot(BeEmpty())
+
+	values, err := Load(chartDir)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(Validate(values)).To(BeEmpty())
+	for _, file := range append(files, charts...) {
+		values, err := Load(chartDir, file)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(Validate(values)).To(BeEmpty(), file)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		errors []string
+	}{
+		{
+			name: "valid",
+			values: `
+cassandra:
+  version: 4.0.0
+  heap:
+    size: 2G
+    newGenSize: 512M
+  resources:
+    limits:
+      memory: 4Gi
+  datacenters:
+  - name: dc1
+    size: 3
+    racks:
+    - name: r1
+    - name: r2
+    - name: r3
+reaper:
+  repairSchedules:
+    schedules:
+    - keyspace: ks1
+      intensity: "0.5"
+`,
+		},
+		{
+			name: "unknown values",
+			values: `
+cassandra:
+  datacenter:
+  - name: dc1
+stargate:
+  heapMb: 512
+`,
+			errors: []string{
+				"cassandra: Additional property datacenter is not allowed",
+				"stargate: Additional property heapMb is not allowed",
+			},
+		},
+		{
+			name: "invalid types",
+			values: `
+cassandra:
+  datacenters:
+  - name: dc1
+    size: three
+  heap:
+    size: [1G]
+`,
+			errors: []string{
+				"cassandra.datacenters.0.size: Invalid type. Expected: integer, given: string",
+				"cassandra.heap.size: Invalid type. Expected: [string,integer,number], given: array",
+			},
+		},
+		{
+			name: "unsupported version",
+			values: `
+cassandra:
+  version: 3.11.5
+`,
+			errors: []string{
+				"cassandra.version: 3.11.5 is not a supported Cassandra version, expected one of 3.11.10, 3.11.7, 3.11.8, 3.11.9, 4.0.0 or set cassandra.image",
+			},
+		},
+		{
+			name: "version of an image of the values",
+			values: `
+cassandra:
+  version: 4.0.1
+  versionImageMap:
+    4.0.1: k8ssandra/cass-management-api:4.0.1-v0.1.27
+`,
+		},
+		{
+			name: "version of an image",
+			values: `
+cassandra:
+  version: 3.11.5
+  image: my-cassandra:3.11.5
+`,
+		},
+		{
+			name: "heap larger than the memory limit",
+			values: `
+cassandra:
+  heap:
+    size: 8G
+  resources:
+    limits:
+      memory: 8Gi
+  datacenters:
+  - name: dc1
+    size: 1
+  - name: dc2
+    size: 1
+    heap:
+      size: 4096m
+      newGenSize: 4G
+`,
+			errors: []string{
+				"cassandra.heap.size: 8G is not smaller than cassandra.resources.limits.memory 8Gi, Cassandra also needs off heap memory",
+				"cassandra.datacenters[1].heap.newGenSize: 4G is not smaller than the heap size 4096m",
+			},
+		},
+		{
+			name: "invalid heap size",
+			values: `
+cassandra:
+  heap:
+    size: 8GB
+`,
+			errors: []string{
+				"cassandra.heap.size: invalid size 8GB, expected a number of bytes optionally followed by k, m, g or t",
+			},
+		},
+		{
+			name: "more racks than nodes",
+			values: `
+cassandra:
+  datacenters:
+  - name: dc1
+    size: 2
+    racks:
+    - name: r1
+    - name: r2
+    - name: r2
+  - name: dc2
+    size: 0
+`,
+			errors: []string{
+				"cassandra.datacenters[0].size: datacenter dc1 has 2 nodes for 3 racks, each rack needs at least one node",
+				"cassandra.datacenters[0].racks[2].name: rack r2 is defined more than once",
+				"cassandra.datacenters[1].size: datacenter dc2 must have at least one node",
+			},
+		},
+		{
+			name: "invalid repair schedule",
+			values: `
+reaper:
+  repairSchedules:
+    schedules:
+    - keyspace: ks1
+      intensity: 2
+`,
+			errors: []string{
+				"reaper.repairSchedules.schedules[0]: repair schedule ks1: intensity must be in (0.0, 1.0]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			values, err := chartutil.ReadValues([]byte(tt.values))
+			g.Expect(err).ToNot(HaveOccurred())
+			errs := make([]string, 0)
+			for _, err := range Validate(values) {
+				errs = append(errs, err.Error())
+			}
+			if tt.errors == nil {
+				g.Expect(errs).To(BeEmpty())
+			} else {
+				g.Expect(errs).To(ConsistOf(tt.errors))
+			}
+		})
+	}
+}
+
+func TestParseJVMSize(t *testing.T) {
+	g := NewWithT(t)
+
+	for size, bytes := range map[Quantity]int64{"": 0, "1024": 1024, "512k": 512 << 10, "500M": 500 << 20, "8g": 8 << 30, "1T": 1 << 40} {
+		parsed, err := parseJVMSize(size)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(parsed).To(Equal(bytes), string(size))
+	}
+	_, err := parseJVMSize("1.5G")
+	g.Expect(err).To(HaveOccurred())
+}
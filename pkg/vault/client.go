@@ -0,0 +1,136 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	tokenHeader = "X-Vault-Token"
+
+	// ServiceAccountTokenPath is where the token used for the Kubernetes auth method is mounted in pods
+	ServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// Client is a minimal client of the Vault HTTP API, covering authentication and reads from the KV secrets engine
+type Client struct {
+	address    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient returns a Client for the Vault server at address, such as https://vault.vault:8200
+func NewClient(address string) *Client {
+	return &Client{
+		address:    strings.TrimSuffix(address, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetToken sets the token sent with the requests
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// LoginKubernetes authenticates with the Kubernetes auth method mounted at mountPath, exchanging the service account
+// token jwt for a Vault token bound to role
+func (c *Client) LoginKubernetes(ctx context.Context, mountPath, role, jwt string) error {
+	body := map[string]string{"role": role, "jwt": jwt}
+	result := struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}{}
+
+	path := fmt.Sprintf("auth/%s/login", strings.Trim(mountPath, "/"))
+	if err := c.do(ctx, http.MethodPost, path, body, &result); err != nil {
+		return fmt.Errorf("failed to log in to Vault with role %s: %w", role, err)
+	}
+	if result.Auth.ClientToken == "" {
+		return fmt.Errorf("failed to log in to Vault with role %s: no token in response", role)
+	}
+	c.token = result.Auth.ClientToken
+	return nil
+}
+
+// ReadKV reads the secret at path in the KV secrets engine mounted at mount. version is the version of the engine,
+// 1 or 2.
+func (c *Client) ReadKV(ctx context.Context, mount, path string, version int) (map[string]interface{}, error) {
+	mount = strings.Trim(mount, "/")
+	path = strings.Trim(path, "/")
+
+	switch version {
+	case 1:
+		result := struct {
+			Data map[string]interface{} `json:"data"`
+		}{}
+		if err := c.do(ctx, http.MethodGet, mount+"/"+path, nil, &result); err != nil {
+			return nil, fmt.Errorf("failed to read %s/%s from Vault: %w", mount, path, err)
+		}
+		return result.Data, nil
+	case 2:
+		result := struct {
+			Data struct {
+				Data map[string]interface{} `json:"data"`
+			} `json:"data"`
+		}{}
+		if err := c.do(ctx, http.MethodGet, mount+"/data/"+path, nil, &result); err != nil {
+			return nil, fmt.Errorf("failed to read %s/%s from Vault: %w", mount, path, err)
+		}
+		return result.Data.Data, nil
+	default:
+		return nil, fmt.Errorf("unsupported KV engine version %d", version)
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.address+"/v1/"+path, reader)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set(tokenHeader, c.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return responseError(res)
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+// responseError turns the errors list returned by Vault into an error
+func responseError(res *http.Response) error {
+	body, _ := ioutil.ReadAll(res.Body)
+	vaultErr := struct {
+		Errors []string `json:"errors"`
+	}{}
+	if err := json.Unmarshal(body, &vaultErr); err == nil && len(vaultErr.Errors) > 0 {
+		return fmt.Errorf("unexpected status code %d: %s", res.StatusCode, strings.Join(vaultErr.Errors, "; "))
+	}
+	return fmt.Errorf("unexpected status code %d", res.StatusCode)
+}
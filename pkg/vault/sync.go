@@ -0,0 +1,160 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8ssandra/k8ssandra/pkg/credentials"
+	"github.com/k8ssandra/k8ssandra/pkg/helmutil"
+)
+
+const (
+	managedByLabel      = "app.kubernetes.io/managed-by"
+	managedByLabelValue = "k8ssandra-client"
+	instanceLabel       = "app.kubernetes.io/instance"
+	partOfLabel         = "app.kubernetes.io/part-of"
+
+	// PathAnnotation records the KV path a Secret was synced from
+	PathAnnotation = "k8ssandra.io/vault-path"
+)
+
+// Syncer materializes Kubernetes Secrets from credentials stored in Vault's KV secrets engine
+type Syncer struct {
+	Client      client.Client
+	Vault       *Client
+	Namespace   string
+	ReleaseName string
+
+	// KVMount is the mount path of the KV secrets engine
+	KVMount string
+	// KVVersion is the version of the KV secrets engine, 1 or 2
+	KVVersion int
+}
+
+// Sync reads each KV path of secrets, which maps Secret names to KV paths, and creates or updates the Secret with
+// the username and password keys the chart templates expect. Nothing is written unless all the paths can be read.
+func (s *Syncer) Sync(ctx context.Context, secrets map[string]string) error {
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	data := make(map[string]map[string][]byte, len(secrets))
+	for _, name := range names {
+		d, err := s.read(ctx, secrets[name])
+		if err != nil {
+			return err
+		}
+		data[name] = d
+	}
+
+	for _, name := range names {
+		key := types.NamespacedName{Namespace: s.Namespace, Name: name}
+		changed, err := s.apply(ctx, key, secrets[name], data[name])
+		if err != nil {
+			return fmt.Errorf("failed to write secret %s: %w", key, err)
+		}
+		if changed {
+			log.Printf("Synced secret %s from %s/%s", key, s.KVMount, secrets[name])
+		} else {
+			log.Printf("Secret %s is up to date", key)
+		}
+	}
+	return nil
+}
+
+// read returns the Secret data of the credentials stored at path
+func (s *Syncer) read(ctx context.Context, path string) (map[string][]byte, error) {
+	kv, err := s.Vault.ReadKV(ctx, s.KVMount, path, s.KVVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]byte, 2)
+	for _, key := range []string{credentials.UsernameKey, credentials.PasswordKey} {
+		value, ok := kv[key].(string)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("Vault secret %s/%s has no %s", s.KVMount, path, key)
+		}
+		data[key] = []byte(value)
+	}
+	return data, nil
+}
+
+// apply creates or updates the Secret and returns true if it was changed. The Secrets the chart generated for the
+// release before Vault was enabled are adopted, see adopt.
+func (s *Syncer) apply(ctx context.Context, key types.NamespacedName, path string, data map[string][]byte) (bool, error) {
+	secret := &corev1.Secret{}
+	err := s.Client.Get(ctx, key, secret)
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        key.Name,
+				Namespace:   key.Namespace,
+				Labels:      s.labels(),
+				Annotations: map[string]string{PathAnnotation: path},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: data,
+		}
+		return true, s.Client.Create(ctx, secret)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	adopted := s.adopt(secret)
+	if secret.Labels[managedByLabel] != managedByLabelValue && !adopted {
+		return false, fmt.Errorf("the secret exists and is not managed by %s nor by helm for release %s", managedByLabelValue, s.ReleaseName)
+	}
+	if !adopted && string(secret.Data[credentials.UsernameKey]) == string(data[credentials.UsernameKey]) &&
+		string(secret.Data[credentials.PasswordKey]) == string(data[credentials.PasswordKey]) &&
+		secret.Annotations[PathAnnotation] == path {
+		return false, nil
+	}
+
+	patch := client.MergeFrom(secret.DeepCopy())
+	if adopted {
+		log.Printf("Adopting secret %s generated by the chart for release %s", key, s.ReleaseName)
+		secret.Labels = s.labels()
+		delete(secret.Annotations, helmutil.ReleaseAnnotation)
+		delete(secret.Annotations, helmutil.ReleaseNamespaceAnnotation)
+		secret.Annotations[helmutil.ResourcePolicyAnnotation] = helmutil.KeepPolicy
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations[PathAnnotation] = path
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	for k, v := range data {
+		secret.Data[k] = v
+	}
+	return true, s.Client.Patch(ctx, secret, patch)
+}
+
+// adopt tells whether the Secret is managed by helm for the release: the chart generates it unless Vault is enabled,
+// helm deletes it after the upgrade enabling Vault unless the hook takes it over, keeping helm from deleting it
+func (s *Syncer) adopt(secret *corev1.Secret) bool {
+	return secret.Labels[helmutil.ManagedLabel] == helmutil.ManagedLabelValue &&
+		secret.Annotations[helmutil.ReleaseAnnotation] == s.ReleaseName &&
+		secret.Annotations[helmutil.ReleaseNamespaceAnnotation] == s.Namespace
+}
+
+func (s *Syncer) labels() map[string]string {
+	return map[string]string{
+		managedByLabel: managedByLabelValue,
+		instanceLabel:  s.ReleaseName,
+		partOfLabel:    fmt.Sprintf("k8ssandra-%s-%s", s.ReleaseName, s.Namespace),
+	}
+}
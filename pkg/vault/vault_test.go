@@ -0,0 +1,209 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	testNamespace = "k8ssandra"
+	rootToken     = "root"
+	loginToken    = "s.kubernetes"
+)
+
+// fakeVault stands in for a Vault server in dev mode: a KV v2 engine mounted at secret/, a KV v1 engine mounted at
+// kv/ and the Kubernetes auth method mounted at kubernetes/
+type fakeVault struct {
+	kv map[string]map[string]interface{}
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost && r.URL.Path == "/v1/auth/kubernetes/login" {
+		login := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&login)
+		if login["role"] != "k8ssandra" || login["jwt"] != "sa-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"auth": map[string]string{"client_token": loginToken}})
+		return
+	}
+
+	if token := r.Header.Get(tokenHeader); token != rootToken && token != loginToken {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/secret/data/"):
+		data, found := f.kv[strings.TrimPrefix(r.URL.Path, "/v1/secret/data/")]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": data, "metadata": map[string]interface{}{"version": 1}}})
+	case strings.HasPrefix(r.URL.Path, "/v1/kv/"):
+		data, found := f.kv[strings.TrimPrefix(r.URL.Path, "/v1/kv/")]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestVault(t *testing.T) *Client {
+	fake := &fakeVault{kv: map[string]map[string]interface{}{
+		"k8ssandra/superuser": {"username": "admin", "password": "admin-secret"},
+		"k8ssandra/reaper":    {"username": "reaper", "password": "reaper-secret"},
+		"k8ssandra/broken":    {"username": "broken"},
+	}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return NewClient(server.URL)
+}
+
+func newTestSyncer(t *testing.T, objects ...runtime.Object) (*Syncer, client.Client) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	c := fake.NewFakeClientWithScheme(scheme, objects...)
+
+	vault := newTestVault(t)
+	vault.SetToken(rootToken)
+	return &Syncer{
+		Client:      c,
+		Vault:       vault,
+		Namespace:   testNamespace,
+		ReleaseName: "test",
+		KVMount:     "secret",
+		KVVersion:   2,
+	}, c
+}
+
+func getSecret(g *WithT, c client.Client, name string) *corev1.Secret {
+	secret := &corev1.Secret{}
+	g.Expect(c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: name}, secret)).To(Succeed())
+	return secret
+}
+
+func TestLoginKubernetes(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	vault := newTestVault(t)
+	g.Expect(vault.LoginKubernetes(ctx, "kubernetes", "other", "sa-token")).To(MatchError(ContainSubstring("permission denied")))
+
+	g.Expect(vault.LoginKubernetes(ctx, "/kubernetes/", "k8ssandra", "sa-token")).To(Succeed())
+	data, err := vault.ReadKV(ctx, "secret", "k8ssandra/reaper", 2)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(data).To(HaveKeyWithValue("password", "reaper-secret"))
+}
+
+func TestReadKVv1(t *testing.T) {
+	g := NewWithT(t)
+
+	vault := newTestVault(t)
+	vault.SetToken(rootToken)
+	data, err := vault.ReadKV(context.Background(), "kv", "k8ssandra/superuser", 1)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(data).To(HaveKeyWithValue("username", "admin"))
+}
+
+func TestSync(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	syncer, c := newTestSyncer(t)
+	secrets := map[string]string{
+		"test-superuser": "k8ssandra/superuser",
+		"test-reaper":    "k8ssandra/reaper",
+	}
+	g.Expect(syncer.Sync(ctx, secrets)).To(Succeed())
+
+	superuser := getSecret(g, c, "test-superuser")
+	g.Expect(superuser.Data).To(HaveKeyWithValue("username", []byte("admin")))
+	g.Expect(superuser.Data).To(HaveKeyWithValue("password", []byte("admin-secret")))
+	g.Expect(superuser.Labels).To(HaveKeyWithValue(managedByLabel, managedByLabelValue))
+	g.Expect(superuser.Annotations).To(HaveKeyWithValue(PathAnnotation, "k8ssandra/superuser"))
+
+	// a re-sync on upgrade picks the changes made in Vault
+	syncer.Vault.SetToken(rootToken)
+	secrets["test-superuser"] = "k8ssandra/reaper"
+	g.Expect(syncer.Sync(ctx, secrets)).To(Succeed())
+	g.Expect(getSecret(g, c, "test-superuser").Data).To(HaveKeyWithValue("password", []byte("reaper-secret")))
+}
+
+func TestSyncAdoptsChartSecrets(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	chartSecret := func(name, release string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   testNamespace,
+				Name:        name,
+				Labels:      map[string]string{managedByLabel: "Helm", instanceLabel: release},
+				Annotations: map[string]string{"meta.helm.sh/release-name": release, "meta.helm.sh/release-namespace": testNamespace},
+			},
+			Data: map[string][]byte{"username": []byte("test-superuser"), "password": []byte("generated")},
+		}
+	}
+	// the secrets generated by the chart before Vault was enabled
+	syncer, c := newTestSyncer(t, chartSecret("test-superuser", "test"), chartSecret("other-superuser", "other"))
+
+	g.Expect(syncer.Sync(ctx, map[string]string{"test-superuser": "k8ssandra/superuser"})).To(Succeed())
+	superuser := getSecret(g, c, "test-superuser")
+	g.Expect(superuser.Data).To(HaveKeyWithValue("password", []byte("admin-secret")))
+	g.Expect(superuser.Labels).To(HaveKeyWithValue(managedByLabel, managedByLabelValue))
+	// helm keeps the secret once the chart stops generating it
+	g.Expect(superuser.Annotations).To(HaveKeyWithValue("helm.sh/resource-policy", "keep"))
+	g.Expect(superuser.Annotations).ToNot(HaveKey("meta.helm.sh/release-name"))
+
+	// the secrets of other releases are not
+	err := syncer.Sync(ctx, map[string]string{"other-superuser": "k8ssandra/superuser"})
+	g.Expect(err).To(MatchError(ContainSubstring("not managed by k8ssandra-client nor by helm for release test")))
+	g.Expect(getSecret(g, c, "other-superuser").Data).To(HaveKeyWithValue("password", []byte("generated")))
+}
+
+func TestSyncFailures(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	unmanaged := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "test-stargate"},
+		Data:       map[string][]byte{"username": []byte("stargate"), "password": []byte("generated")},
+	}
+	syncer, c := newTestSyncer(t, unmanaged)
+
+	// nothing is written unless all the paths can be read
+	err := syncer.Sync(ctx, map[string]string{"test-reaper": "k8ssandra/reaper", "test-medusa": "k8ssandra/broken"})
+	g.Expect(err).To(MatchError(ContainSubstring("has no password")))
+	g.Expect(c.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "test-reaper"}, &corev1.Secret{})).ToNot(Succeed())
+
+	err = syncer.Sync(ctx, map[string]string{"test-reaper": "k8ssandra/missing"})
+	g.Expect(err).To(MatchError(ContainSubstring("unexpected status code 404")))
+
+	// secrets not created by the sync are not overwritten
+	err = syncer.Sync(ctx, map[string]string{"test-stargate": "k8ssandra/reaper"})
+	g.Expect(err).To(MatchError(ContainSubstring("not managed by k8ssandra-client")))
+	g.Expect(getSecret(g, c, "test-stargate").Data).To(HaveKeyWithValue("password", []byte("generated")))
+}
@@ -1,6 +1,10 @@
 #!/bin/bash
 # This script deletes all CRDS that are installed by k8ssandra. Note that helm
 # uninstall does not remove CRDs.
+#
+# The CRDs are deleted along with their custom resources in every namespace.
+# `k8ssandra-client crds delete` refuses to delete them while custom resources
+# exist.
 
 kubectl delete crd alertmanagerconfigs.monitoring.coreos.com
 kubectl delete crd alertmanagers.monitoring.coreos.com
@@ -0,0 +1,20 @@
+#!/bin/bash
+
+VERSION=2.27.1
+if [[ -n $1 ]]; then
+  VERSION=$1
+  shift
+fi
+
+if [ ! -d "bin" ]
+then
+  mkdir bin
+fi
+
+if [ ! -f "bin/promtool" ]
+then
+  curl -L https://github.com/prometheus/prometheus/releases/download/v$VERSION/prometheus-$VERSION.linux-amd64.tar.gz | \
+    tar -xz -C bin --strip-components=1 prometheus-$VERSION.linux-amd64/promtool
+fi
+
+bin/promtool --version
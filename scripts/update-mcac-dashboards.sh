@@ -1,5 +1,6 @@
 #!/usr/bin/env bash
-# This is a utility script for k8ssandra developers. We need to downloads JSON versions of the MCAC Grafana dashboards into our chart's dashboards directory.
+# This is a utility script for k8ssandra developers. It downloads JSON versions of the MCAC Grafana dashboards into
+# pkg/dashboards/testdata/mcac, where TestMCACParity checks that the dashboards generated for our chart keep up with them.
 # This script:
 #  1. Grabs the latest copies of each dashboard from MCAC (optionally from a specified tag or branch)
 #
@@ -27,8 +28,11 @@ echo -e "${BOLDCYAN}Using dashboard definitions from MCAC ${BOLDWHITE}${MCAC_VER
 cd "$(dirname "$0")"
 
 echo -e "${BOLDCYAN}Retrieving latest copies of the dashboards...${NOCOLOR}"
-wget -q --show-progress https://raw.githubusercontent.com/datastax/metric-collector-for-apache-cassandra/${MCAC_VERSION}/dashboards/grafana/generated-dashboards/system-metrics.json -O ../charts/k8ssandra/dashboards/system-metrics.json
-wget -q --show-progress https://raw.githubusercontent.com/datastax/metric-collector-for-apache-cassandra/${MCAC_VERSION}/dashboards/grafana/generated-dashboards/cassandra-condensed.json -O ../charts/k8ssandra/dashboards/cassandra-condensed.json
-wget -q --show-progress https://raw.githubusercontent.com/datastax/metric-collector-for-apache-cassandra/${MCAC_VERSION}/dashboards/grafana/generated-dashboards/overview.json -O ../charts/k8ssandra/dashboards/overview.json
+wget -q --show-progress https://raw.githubusercontent.com/datastax/metric-collector-for-apache-cassandra/${MCAC_VERSION}/dashboards/grafana/generated-dashboards/system-metrics.json -O ../pkg/dashboards/testdata/mcac/system-metrics.json
+wget -q --show-progress https://raw.githubusercontent.com/datastax/metric-collector-for-apache-cassandra/${MCAC_VERSION}/dashboards/grafana/generated-dashboards/cassandra-condensed.json -O ../pkg/dashboards/testdata/mcac/cassandra-condensed.json
+wget -q --show-progress https://raw.githubusercontent.com/datastax/metric-collector-for-apache-cassandra/${MCAC_VERSION}/dashboards/grafana/generated-dashboards/overview.json -O ../pkg/dashboards/testdata/mcac/overview.json
+
+echo -e "${BOLDCYAN}Checking the generated dashboards against them...${NOCOLOR}"
+(cd .. && go test ./pkg/dashboards -run TestMCACParity)
 
 echo -e "${BOLDGREEN}Done!${NOCOLOR}"
@@ -341,12 +341,12 @@ func deployClusterForStargate(t *testing.T, namespace string) {
 func testStargate(t *testing.T, namespace string) {
 	WaitForAuthEndpoint(t) // Wait for the auth endpoint to be reachable, this takes a little time after the Stargate rollout is complete
 	log.Println(Step("Writing data to the Stargate document API"))
-	token := GenerateStargateAuthToken(t, namespace)
-	docNamespace := CreateStargateDocumentNamespace(t, token)
+	client := NewStargateClient(t, namespace)
+	docNamespace := CreateStargateDocumentNamespace(t, client)
 	log.Println(fmt.Sprintf("Created Stargate document namespace: %s", docNamespace))
-	documentId := WriteStargateDocument(t, token, docNamespace)
+	documentId := WriteStargateDocument(t, client, docNamespace)
 	log.Println(fmt.Sprintf("Created document with id: %s", documentId))
-	CheckStargateDocumentExists(t, token, docNamespace, documentId)
+	CheckStargateDocumentExists(t, client, docNamespace, documentId)
 }
 
 func TestUpgradeScenario(t *testing.T) {
@@ -3,19 +3,21 @@ package steps
 import (
 	"context"
 	"fmt"
-	"github.com/google/uuid"
-	"github.com/k8ssandra/reaper-client-go/reaper"
 	"log"
 	"net/url"
 	"testing"
 
+	"github.com/google/uuid"
+	reapergo "github.com/k8ssandra/reaper-client-go/reaper"
 	. "github.com/onsi/gomega"
+
+	"github.com/k8ssandra/k8ssandra/pkg/reaper"
 )
 
 // Reaper related steps
 
 var reaperURL, _ = url.Parse("http://repair.127.0.0.1.nip.io:8080")
-var reaperClient = reaper.NewClient(reaperURL)
+var reaperClient, _ = reaper.NewClient(context.Background(), reaperURL, nil)
 
 func CheckClusterIsRegisteredInReaper(t *testing.T, clusterName string) {
 	g(t).Eventually(func() bool {
@@ -38,7 +40,7 @@ func CancelRepair(t *testing.T, repairId uuid.UUID) {
 
 // TriggerRepair starts a repair on keyspace and return the repair id
 func TriggerRepair(t *testing.T, clusterName, keyspace, owner string) uuid.UUID {
-	options := &reaper.RepairRunCreateOptions{SegmentCountPerNode: 5}
+	options := &reapergo.RepairRunCreateOptions{SegmentCountPerNode: 5}
 	repairId, err := reaperClient.CreateRepairRun(context.Background(), clusterName, keyspace, owner, options)
 	g(t).Expect(err).To(BeNil(), "Failed to create repair run: %s", err)
 	// Start the previously created repair run
@@ -57,7 +59,7 @@ func oneSegmentIsDone(t *testing.T, repairId uuid.UUID) bool {
 	segments, err := reaperClient.RepairRunSegments(context.Background(), repairId)
 	g(t).Expect(err).To(BeNil(), "Failed to get segments of repair run %s: %s", repairId, err)
 	for _, segment := range segments {
-		if segment.State == reaper.RepairSegmentStateDone {
+		if segment.State == reapergo.RepairSegmentStateDone {
 			return true
 		}
 	}
@@ -2,7 +2,6 @@ package steps
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
@@ -14,8 +13,14 @@ import (
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	pkgcredentials "github.com/k8ssandra/k8ssandra/pkg/credentials"
+	"github.com/k8ssandra/k8ssandra/pkg/stargate"
 )
 
+// stargateHost is the host of the Stargate ingress in the test cluster
+const stargateHost = "stargate.127.0.0.1.nip.io"
+
 // Stargate related functions
 func StargateService(t *testing.T, namespace string) (v1.Service, error) {
 	clientset, err := k8s.GetKubernetesClientFromOptionsE(t, getKubectlOptions(namespace))
@@ -56,7 +61,7 @@ func WaitForAuthEndpoint(t *testing.T) {
 
 func authEndpointIsReachable(t *testing.T) bool {
 	restClient := resty.New()
-	response, err := restClient.R().Get("http://stargate.127.0.0.1.nip.io:8081/v1/auth")
+	response, err := restClient.R().Get(stargate.EndpointsForHost("http", stargateHost).Auth + "/v1/auth")
 	if err != nil {
 		log.Printf("Failed connecting to Stargate auth endpoint: %s", err.Error())
 		return false
@@ -64,34 +69,17 @@ func authEndpointIsReachable(t *testing.T) bool {
 	return response.StatusCode() == 405 // This endpoint should be invoked with a Post, we expect a 405 with a Get
 }
 
-func GenerateStargateAuthToken(t *testing.T, namespace string) string {
-	credentials := ExtractUsernamePassword(t, "k8ssandra-superuser", namespace)
-	creds := fmt.Sprintf("{\"username\":\"%s\", \"password\":\"%s\"}", credentials.username, credentials.password)
-	restClient := resty.New()
-	response, err := restClient.R().
-		SetHeader("Content-Type", "application/json").
-		SetBody(creds).
-		Post("http://stargate.127.0.0.1.nip.io:8081/v1/auth")
-	g(t).Expect(err).To(BeNil(), "Failed connecting to Stargate")
-	stargateResponse := response.Body()
-	var genericJson map[string]interface{}
-	json.Unmarshal(stargateResponse, &genericJson)
-	g(t).Expect(genericJson["authToken"]).ToNot(BeNil(), fmt.Sprintf("Expected response to have authToken property: %#v", genericJson))
-	return genericJson["authToken"].(string)
+// NewStargateClient returns a Stargate client authenticating as the superuser of the release
+func NewStargateClient(t *testing.T, namespace string) *stargate.Client {
+	superuser := ExtractUsernamePassword(t, "k8ssandra-superuser", namespace)
+	creds := &pkgcredentials.Credentials{Username: superuser.username, Password: superuser.password}
+	return stargate.NewClient(stargate.EndpointsForHost("http", stargateHost), creds)
 }
 
-func CreateStargateDocumentNamespace(t *testing.T, token string) string {
+func CreateStargateDocumentNamespace(t *testing.T, client *stargate.Client) string {
 	docNamespace := fmt.Sprintf("stargate%s", time.Now().Format("2006010215040507"))
-	docNamespaceJson := fmt.Sprintf("{\"name\":\"%s\"}", docNamespace)
-	restClient := resty.New()
-	response, err := restClient.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("X-Cassandra-Token", token).
-		SetBody(docNamespaceJson).
-		Post("http://stargate.127.0.0.1.nip.io:8082/v2/schemas/namespaces")
+	err := client.CreateNamespace(context.Background(), &stargate.Keyspace{Name: docNamespace})
 	g(t).Expect(err).To(BeNil(), "Failed creating Stargate document namespace")
-	stargateResponse := string(response.Body())
-	g(t).Expect(stargateResponse).To(Equal(docNamespaceJson), fmt.Sprintf("Unexpected response from Stargate: %s", stargateResponse))
 	return docNamespace
 }
 
@@ -100,37 +88,26 @@ const (
 	awesomeMovieName     = "Watchmen"
 )
 
-func WriteStargateDocument(t *testing.T, token, docNamespace string) string {
-	awesomeMovieDocument := fmt.Sprintf("{\"Director\":\"%s\",\"Name\":\"%s\"}", awesomeMovieDirector, awesomeMovieName)
+type movie struct {
+	Director string
+	Name     string
+}
+
+func WriteStargateDocument(t *testing.T, client *stargate.Client, docNamespace string) string {
+	awesomeMovieDocument := movie{Director: awesomeMovieDirector, Name: awesomeMovieName}
 	documentId := fmt.Sprintf("watchmen%s", time.Now().Format("2006010215040507"))
-	restClient := resty.New()
-	response, err := restClient.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("X-Cassandra-Token", token).
-		SetBody(awesomeMovieDocument).
-		Put(fmt.Sprintf("http://stargate.127.0.0.1.nip.io:8082/v2/namespaces/%s/collections/movies/%s", docNamespace, documentId))
+	err := client.PutDocument(context.Background(), docNamespace, "movies", documentId, awesomeMovieDocument)
 	g(t).Expect(err).To(BeNil(), "Failed creating Stargate document")
-	stargateResponse := string(response.Body())
-	expectedResponse := fmt.Sprintf("{\"documentId\":\"%s\"}", documentId)
-	g(t).Expect(stargateResponse).To(Equal(expectedResponse))
 	return documentId
 }
 
-func CheckStargateDocumentExists(t *testing.T, token, docNamespace, documentId string) {
-	document := readStargateDocument(t, token, docNamespace, documentId)
-	var genericJson map[string]interface{}
-	json.Unmarshal(document, &genericJson)
-	g(t).Expect(genericJson["documentId"].(string)).To(Equal(documentId))
-	g(t).Expect(genericJson["data"].(map[string]interface{})["Director"].(string)).To(Equal(awesomeMovieDirector))
-	g(t).Expect(genericJson["data"].(map[string]interface{})["Name"].(string)).To(Equal(awesomeMovieName))
-}
-
-func readStargateDocument(t *testing.T, token, docNamespace, documentId string) []byte {
-	restClient := resty.New()
-	response, err := restClient.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("X-Cassandra-Token", token).
-		Get(fmt.Sprintf("http://stargate.127.0.0.1.nip.io:8082/v2/namespaces/%s/collections/movies/%s", docNamespace, documentId))
+func CheckStargateDocumentExists(t *testing.T, client *stargate.Client, docNamespace, documentId string) {
+	document, err := client.GetDocument(context.Background(), docNamespace, "movies", documentId)
 	g(t).Expect(err).To(BeNil(), "Failed reading Stargate document")
-	return response.Body()
+	g(t).Expect(document.DocumentId).To(Equal(documentId))
+
+	awesomeMovie := movie{}
+	g(t).Expect(document.Decode(&awesomeMovie)).To(Succeed())
+	g(t).Expect(awesomeMovie.Director).To(Equal(awesomeMovieDirector))
+	g(t).Expect(awesomeMovie.Name).To(Equal(awesomeMovieName))
 }
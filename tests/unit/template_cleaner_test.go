@@ -3,11 +3,14 @@ package unit_test
 import (
 	helmUtils "github.com/k8ssandra/k8ssandra/tests/unit/utils/helm"
 	"path/filepath"
+	"strings"
 
 	"github.com/gruntwork-io/terratest/modules/helm"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	v1batch "k8s.io/api/batch/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var _ = Describe("Verify Cleaner job template", func() {
@@ -46,10 +49,95 @@ var _ = Describe("Verify Cleaner job template", func() {
 			By("checking that correct hook annotations are present")
 			Expect(cleanerJob.Annotations).Should(HaveKeyWithValue(HelmHookAnnotation, "pre-delete"))
 			Expect(cleanerJob.Annotations).Should(HaveKeyWithValue(HelmHookPreDeleteAnnotation, "hook-succeeded,before-hook-creation"))
+			Expect(cleanerJob.Annotations).Should(HaveKeyWithValue("helm.sh/hook-weight", "10"))
 
 			Expect(len(cleanerJob.Spec.Template.Spec.Containers)).To(Equal(1))
 			Expect(len(cleanerJob.Spec.Template.Spec.Containers[0].Env)).To(Equal(1))
 			Expect(cleanerJob.Spec.Template.Spec.Containers[0].Env[0].Name).To(Equal("POD_NAMESPACE"))
+			Expect(cleanerJob.Spec.Template.Spec.Containers[0].Args).To(Equal([]string{"-clean", "--release", HelmReleaseName}))
+		})
+
+		It("cleaning other namespaces and cluster-scoped resources", func() {
+			options := &helm.Options{
+				KubectlOptions: defaultKubeCtlOptions,
+				SetValues: map[string]string{
+					"cleaner.namespaces":    "{dc2,dc3}",
+					"cleaner.clusterScoped": "true",
+				},
+			}
+
+			Expect(renderTemplate(options)).To(Succeed())
+			Expect(cleanerJob.Spec.Template.Spec.Containers[0].Args).To(Equal([]string{
+				"-clean", "--release", HelmReleaseName, "-namespaces", "dc2,dc3", "-clusterScoped",
+			}))
+		})
+
+		It("granting the permissions of the cleaner for the duration of the hook only", func() {
+			options := &helm.Options{
+				KubectlOptions: defaultKubeCtlOptions,
+				SetValues: map[string]string{
+					"cleaner.namespaces":    "{dc2}",
+					"cleaner.allNamespaces": "true",
+					"cleaner.clusterScoped": "true",
+				},
+			}
+
+			// the RBAC resources are created before the job, and deleted by helm once it succeeded
+			for template, weight := range map[string]string{
+				"templates/cleaner/service_account.yaml": "0",
+				"templates/cleaner/role.yaml":            "1",
+				"templates/cleaner/role_binding.yaml":    "2",
+			} {
+				documents := 0
+				Expect(helmUtils.RenderAndUnmarshall(template, options, helmChartPath, HelmReleaseName,
+					func(renderedYaml string) error {
+						for _, document := range strings.Split(renderedYaml, "\n---") {
+							if !strings.Contains(document, "apiVersion:") {
+								continue
+							}
+							documents++
+							meta := &metav1.PartialObjectMetadata{}
+							if err := helm.UnmarshalK8SYamlE(GinkgoT(), document, meta); err != nil {
+								return err
+							}
+							Expect(meta.Annotations).To(HaveKeyWithValue(HelmHookAnnotation, "pre-delete"), meta.Name)
+							Expect(meta.Annotations).To(HaveKeyWithValue(HelmHookPreDeleteAnnotation, "before-hook-creation,hook-succeeded"), meta.Name)
+							Expect(meta.Annotations).To(HaveKeyWithValue("helm.sh/hook-weight", weight), meta.Name)
+						}
+						return nil
+					})).To(Succeed())
+				Expect(documents).ToNot(BeZero(), template)
+			}
+		})
+
+		It("granting the namespaced types of the release only in every namespace", func() {
+			options := &helm.Options{
+				KubectlOptions: defaultKubeCtlOptions,
+				SetValues:      map[string]string{"cleaner.allNamespaces": "true"},
+			}
+
+			clusterRole := &rbacv1.ClusterRole{}
+			Expect(helmUtils.RenderAndUnmarshall("templates/cleaner/role.yaml", options, helmChartPath, HelmReleaseName,
+				func(renderedYaml string) error {
+					for _, document := range strings.Split(renderedYaml, "\n---") {
+						if strings.Contains(document, "kind: ClusterRole") {
+							return helm.UnmarshalK8SYamlE(GinkgoT(), document, clusterRole)
+						}
+					}
+					return nil
+				})).To(Succeed())
+
+			Expect(clusterRole.Rules).ToNot(BeEmpty())
+			for _, rule := range clusterRole.Rules {
+				Expect(rule.APIGroups).ToNot(ContainElement("*"))
+				Expect(rule.Resources).ToNot(ContainElement("*"))
+				Expect(rule.Resources).ToNot(ContainElement("namespaces"))
+			}
+			Expect(clusterRole.Rules).To(ContainElement(rbacv1.PolicyRule{
+				APIGroups: []string{"monitoring.coreos.com"},
+				Resources: []string{"podmonitors", "prometheusrules", "servicemonitors"},
+				Verbs:     []string{"list", "delete"},
+			}))
 		})
 	})
 })
@@ -0,0 +1,79 @@
+package unit_test
+
+import (
+	"path/filepath"
+
+	helmUtils "github.com/k8ssandra/k8ssandra/tests/unit/utils/helm"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1batch "k8s.io/api/batch/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+var _ = Describe("Verify CRD upgrader templates", func() {
+	var (
+		helmChartPath string
+		err           error
+		options       *helm.Options
+	)
+
+	BeforeEach(func() {
+		helmChartPath, err = filepath.Abs(ChartsPath)
+		Expect(err).To(BeNil())
+		options = &helm.Options{
+			KubectlOptions: defaultKubeCtlOptions,
+		}
+	})
+
+	It("checks the values of the deployed release", func() {
+		job := &v1batch.Job{}
+		Expect(helmUtils.RenderAndUnmarshall("templates/crd/batch_job.yaml", options, helmChartPath, HelmReleaseName,
+			func(renderedYaml string) error {
+				return helm.UnmarshalK8SYamlE(GinkgoT(), renderedYaml, job)
+			})).To(Succeed())
+
+		Expect(job.Annotations).Should(HaveKeyWithValue(HelmHookAnnotation, "pre-upgrade"))
+		Expect(job.Spec.Template.Spec.Containers).To(HaveLen(1))
+		args := job.Spec.Template.Spec.Containers[0].Args
+		Expect(args).To(ContainElement("-checkupgrade"))
+		Expect(args).To(ContainElement("-upgradecrds"))
+		Expect(args).To(ContainElement("-checkvalues"))
+		Expect(args).To(ContainElement("--cassandraVersion"))
+		Expect(args).To(ContainElement(HelmReleaseName))
+	})
+
+	It("skips the upgrade checks when asked to", func() {
+		options.SetValues = map[string]string{"client.skipUpgradeChecks": "true"}
+		job := &v1batch.Job{}
+		Expect(helmUtils.RenderAndUnmarshall("templates/crd/batch_job.yaml", options, helmChartPath, HelmReleaseName,
+			func(renderedYaml string) error {
+				return helm.UnmarshalK8SYamlE(GinkgoT(), renderedYaml, job)
+			})).To(Succeed())
+
+		args := job.Spec.Template.Spec.Containers[0].Args
+		Expect(args).NotTo(ContainElement("-checkupgrade"))
+		Expect(args).NotTo(ContainElement("--cassandraVersion"))
+		Expect(args).To(ContainElement("-upgradecrds"))
+		Expect(args).To(ContainElement("-checkvalues"))
+	})
+
+	It("only lets the upgrader list the resources of its namespace it checks", func() {
+		role := &rbacv1.Role{}
+		Expect(helmUtils.RenderAndUnmarshall("templates/crd/role.yaml", options, helmChartPath, HelmReleaseName,
+			func(renderedYaml string) error {
+				return helm.UnmarshalK8SYamlE(GinkgoT(), renderedYaml, role)
+			})).To(Succeed())
+
+		Expect(role.Rules).To(HaveLen(3))
+		Expect(role.Rules[0].Resources).To(ConsistOf("secrets"))
+		Expect(role.Rules[1].APIGroups).To(ConsistOf("cassandra.datastax.com"))
+		Expect(role.Rules[1].Resources).To(ConsistOf("cassandradatacenters"))
+		Expect(role.Rules[2].APIGroups).To(ConsistOf("apps"))
+		Expect(role.Rules[2].Resources).To(ConsistOf("deployments", "statefulsets"))
+		for _, rule := range role.Rules {
+			Expect(rule.Verbs).To(ConsistOf("list"))
+		}
+	})
+})
@@ -33,22 +33,26 @@ var _ = Describe("Verify k8ssandra and dependent template labels", func() {
 			options := &helm.Options{
 				KubectlOptions: defaultKubeCtlOptions,
 				SetValues: map[string]string{
-					"stargate.enabled":                              "true",
-					"reaper.enabled":                                "true",
-					"medusa.enabled":                                "true",
-					"reaper.ingress.enabled":                        "true",
-					"reaper.ingress.host":                           "reaper.host",
-					"cassandra.ingress.enabled":                     "false",
-					"ingress.traefik.monitoring.grafana.enabled":    "true",
-					"ingress.traefik.monitoring.prometheus.enabled": "true",
-					"stargate.ingress.enabled":                      "true",
-					"stargate.ingress.auth.enabled":                 "true",
-					"stargate.ingress.rest.enabled":                 "true",
-					"stargate.ingress.graphql.enabled":              "true",
-					"stargate.ingress.graphql.playground.enabled":   "true",
-					"cassandra.auth.enabled":                        "true",
-					"cassandra.auth.superuser.username":             "admin",
-					"cassandra.clusterName":                         "test-cluster",
+					"stargate.enabled":                            "true",
+					"reaper.enabled":                              "true",
+					"reaper.repairSchedules.enabled":              "true",
+					"medusa.enabled":                              "true",
+					"reaper.ingress.enabled":                      "true",
+					"reaper.ingress.host":                         "reaper.host",
+					"cassandra.ingress.enabled":                   "false",
+					"stargate.ingress.enabled":                    "true",
+					"stargate.ingress.auth.enabled":               "true",
+					"stargate.ingress.rest.enabled":               "true",
+					"stargate.ingress.graphql.enabled":            "true",
+					"stargate.ingress.graphql.playground.enabled": "true",
+					"cassandra.auth.enabled":                      "true",
+					"cassandra.auth.superuser.username":           "admin",
+					"cassandra.clusterName":                       "test-cluster",
+					"cassandra.keyspaceReplication.enabled":       "true",
+					"vault.enabled":                               "true",
+					"vault.address":                               "https://vault:8200",
+					"vault.auth.role":                             "k8ssandra",
+					"vault.paths.superuser":                       "k8ssandra/superuser",
 				},
 			}
 
@@ -1,9 +1,11 @@
 package unit_test
 
 import (
-	helmUtils "github.com/k8ssandra/k8ssandra/tests/unit/utils/helm"
 	"path/filepath"
 
+	"github.com/k8ssandra/k8ssandra/pkg/monitoring"
+	helmUtils "github.com/k8ssandra/k8ssandra/tests/unit/utils/helm"
+
 	"github.com/gruntwork-io/terratest/modules/helm"
 	"github.com/gruntwork-io/terratest/modules/k8s"
 	. "github.com/onsi/ginkgo"
@@ -72,3 +74,151 @@ var _ = Describe("Verify Service Monitor template", func() {
 		})
 	})
 })
+
+var _ = Describe("Verify PrometheusRule template", func() {
+
+	var (
+		HelmReleaseName       = "k8ssandra-test"
+		DefaultTestNamespace  = "k8ssandra"
+		defaultKubeCtlOptions = k8s.NewKubectlOptions("", "", DefaultTestNamespace)
+
+		helmChartPath string
+		err           error
+		rule          monitoring.PrometheusRuleSpec
+	)
+
+	BeforeEach(func() {
+		helmChartPath, err = filepath.Abs(ChartsPath)
+		Expect(err).To(BeNil())
+		rule = monitoring.PrometheusRuleSpec{}
+	})
+
+	AfterEach(func() {
+		err = nil
+	})
+
+	renderTemplate := func(options *helm.Options) error {
+		return helmUtils.RenderAndUnmarshall("templates/prometheus/prometheus_rules.yaml",
+			options, helmChartPath, HelmReleaseName,
+			func(renderedYaml string) error {
+				object := struct {
+					Spec *monitoring.PrometheusRuleSpec `json:"spec"`
+				}{Spec: &rule}
+				return helm.UnmarshalK8SYamlE(GinkgoT(), renderedYaml, &object)
+			})
+	}
+
+	alerts := func() map[string]monitoring.Rule {
+		rules := make(map[string]monitoring.Rule)
+		for _, r := range rule.Groups[0].Rules {
+			rules[r.Alert] = r
+		}
+		return rules
+	}
+
+	Context("by rendering it with options", func() {
+
+		It("using alerts disabled", func() {
+			options := &helm.Options{
+				KubectlOptions: defaultKubeCtlOptions,
+				SetValues:      map[string]string{"monitoring.prometheus.alerts.enabled": "false"},
+			}
+
+			err = renderTemplate(options)
+
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("could not find template"))
+		})
+
+		It("using the default alerts", func() {
+			options := &helm.Options{
+				KubectlOptions: defaultKubeCtlOptions,
+			}
+
+			Expect(renderTemplate(options)).To(Succeed())
+
+			// the chart renders the rules the operator generates
+			expected, err := monitoring.DatacenterRules(nil, HelmReleaseName, "dc1", DefaultTestNamespace)
+			Expect(err).To(BeNil())
+			Expect(rule).To(Equal(expected))
+		})
+
+		It("using custom thresholds", func() {
+			options := &helm.Options{
+				KubectlOptions: defaultKubeCtlOptions,
+				SetValues: map[string]string{
+					"monitoring.prometheus.alerts.nodeDown.enabled":      "false",
+					"monitoring.prometheus.alerts.readLatency.threshold": "2000000",
+					"monitoring.prometheus.alerts.readLatency.quantile":  "0.95",
+					"monitoring.prometheus.alerts.writeLatency.severity": "critical",
+					"monitoring.prometheus.alerts.backupAge.enabled":     "true",
+					"monitoring.prometheus.alerts.backupAge.metric":      "backup_timestamp",
+				},
+			}
+
+			Expect(renderTemplate(options)).To(Succeed())
+
+			rules := alerts()
+			Expect(rules).ToNot(HaveKey("CassandraNodeDown"))
+			Expect(rules["CassandraReadLatencyHigh"].Expr).To(HavePrefix("histogram_quantile(0.95, "))
+			Expect(rules["CassandraReadLatencyHigh"].Expr).To(HaveSuffix(" > 2000000"))
+			Expect(rules["CassandraWriteLatencyHigh"].Labels).To(HaveKeyWithValue("severity", "critical"))
+			Expect(rules["CassandraBackupTooOld"].Expr).To(ContainSubstring(`backup_timestamp{namespace="k8ssandra"}`))
+		})
+	})
+})
+
+var _ = Describe("Verify Reaper PodMonitor template", func() {
+
+	var (
+		HelmReleaseName       = "k8ssandra-test"
+		DefaultTestNamespace  = "k8ssandra"
+		defaultKubeCtlOptions = k8s.NewKubectlOptions("", "", DefaultTestNamespace)
+
+		helmChartPath string
+		err           error
+		pm            map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		helmChartPath, err = filepath.Abs(ChartsPath)
+		Expect(err).To(BeNil())
+		pm = map[string]interface{}{}
+	})
+
+	renderTemplate := func(options *helm.Options) error {
+		return helmUtils.RenderAndUnmarshall("templates/reaper/pod_monitor.yaml",
+			options, helmChartPath, HelmReleaseName,
+			func(renderedYaml string) error {
+				return helm.UnmarshalK8SYamlE(GinkgoT(), renderedYaml, &pm)
+			})
+	}
+
+	It("using reaper disabled", func() {
+		options := &helm.Options{
+			KubectlOptions: defaultKubeCtlOptions,
+			SetValues:      map[string]string{"reaper.enabled": "false"},
+		}
+
+		err = renderTemplate(options)
+
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("could not find template"))
+	})
+
+	It("using reaper enabled", func() {
+		options := &helm.Options{
+			KubectlOptions: defaultKubeCtlOptions,
+			SetValues:      map[string]string{"reaper.enabled": "true"},
+		}
+
+		Expect(renderTemplate(options)).To(Succeed())
+
+		spec := pm["spec"].(map[string]interface{})
+		selector := spec["selector"].(map[string]interface{})["matchLabels"].(map[string]interface{})
+		Expect(selector["reaper.cassandra-reaper.io/reaper"]).To(Equal(HelmReleaseName + "-reaper"))
+		endpoint := spec["podMetricsEndpoints"].([]interface{})[0].(map[string]interface{})
+		Expect(endpoint["port"]).To(Equal("admin"))
+		Expect(endpoint["path"]).To(Equal("/prometheusMetrics"))
+	})
+})
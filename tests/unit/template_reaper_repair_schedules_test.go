@@ -0,0 +1,94 @@
+package unit_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1batch "k8s.io/api/batch/v1"
+
+	"github.com/k8ssandra/k8ssandra/pkg/reaper"
+	helmUtils "github.com/k8ssandra/k8ssandra/tests/unit/utils/helm"
+)
+
+var _ = Describe("Verify Reaper repair schedules job template", func() {
+	var (
+		helmChartPath string
+		err           error
+		schedulesJob  *v1batch.Job
+	)
+
+	BeforeEach(func() {
+		helmChartPath, err = filepath.Abs(ChartsPath)
+		Expect(err).To(BeNil())
+		schedulesJob = &v1batch.Job{}
+	})
+
+	AfterEach(func() {
+		err = nil
+	})
+
+	renderTemplate := func(options *helm.Options) error {
+		return helmUtils.RenderAndUnmarshall("templates/reaper/repair_schedules_job.yaml",
+			options, helmChartPath, HelmReleaseName,
+			func(renderedYaml string) error {
+				return helm.UnmarshalK8SYamlE(GinkgoT(), renderedYaml, schedulesJob)
+			})
+	}
+
+	Context("by rendering it with options", func() {
+		It("using only default options", func() {
+			options := &helm.Options{
+				KubectlOptions: defaultKubeCtlOptions,
+			}
+
+			Expect(renderTemplate(options)).ShouldNot(Succeed())
+		})
+
+		It("with repair schedules enabled", func() {
+			options := &helm.Options{
+				KubectlOptions: defaultKubeCtlOptions,
+				SetValues: map[string]string{
+					"reaper.repairSchedules.enabled":                          "true",
+					"reaper.repairSchedules.schedules[0].keyspace":            "ks1",
+					"reaper.repairSchedules.schedules[0].intensity":           "0.5",
+					"reaper.repairSchedules.schedules[0].daysBetween":         "3",
+					"reaper.repairSchedules.schedules[1].keyspace":            "ks2",
+					"reaper.repairSchedules.schedules[1].segmentCountPerNode": "16",
+				},
+			}
+
+			Expect(renderTemplate(options)).To(Succeed())
+
+			By("checking that correct hook annotations are present")
+			Expect(schedulesJob.Annotations).Should(HaveKeyWithValue(HelmHookAnnotation, "post-install,post-upgrade"))
+
+			Expect(len(schedulesJob.Spec.Template.Spec.Containers)).To(Equal(1))
+			args := schedulesJob.Spec.Template.Spec.Containers[0].Args
+			Expect(args[:3]).To(Equal([]string{"repair", "schedule", "reconcile"}))
+			Expect(args).To(ContainElement(HelmReleaseName))
+
+			By("checking that the schedules are passed to the reconciler")
+			specs := make([]reaper.ScheduleSpec, 0)
+			Expect(json.Unmarshal([]byte(args[len(args)-1]), &specs)).To(Succeed())
+			Expect(specs).To(ConsistOf(
+				reaper.ScheduleSpec{Keyspace: "ks1", Intensity: 0.5, DaysBetween: 3},
+				reaper.ScheduleSpec{Keyspace: "ks2", SegmentCountPerNode: 16},
+			))
+		})
+
+		It("with Reaper disabled", func() {
+			options := &helm.Options{
+				KubectlOptions: defaultKubeCtlOptions,
+				SetValues: map[string]string{
+					"reaper.enabled":                 "false",
+					"reaper.repairSchedules.enabled": "true",
+				},
+			}
+
+			Expect(renderTemplate(options)).ShouldNot(Succeed())
+		})
+	})
+})
@@ -52,9 +52,11 @@ var _ = Describe("Verify Reaper template", func() {
 		It("changing datacenter name", func() {
 			targetDcName := "reaper-dc"
 			options := &helm.Options{
+				SetValues: map[string]string{
+					"cassandra.datacenters[0].size": "1",
+				},
 				SetStrValues: map[string]string{
 					"cassandra.datacenters[0].name": targetDcName,
-					"cassandra.datacenters[0].size": "1",
 				},
 				KubectlOptions: defaultKubeCtlOptions,
 			}
@@ -65,7 +67,7 @@ var _ = Describe("Verify Reaper template", func() {
 
 		It("modifying autoscheduling option", func() {
 			options := &helm.Options{
-				SetStrValues:   map[string]string{"reaper.autoschedule": "true"},
+				SetValues:      map[string]string{"reaper.autoschedule": "true"},
 				KubectlOptions: defaultKubeCtlOptions,
 			}
 
@@ -75,8 +77,10 @@ var _ = Describe("Verify Reaper template", func() {
 
 		It("modifying autoscheduling additional properties", func() {
 			options := &helm.Options{
+				SetValues: map[string]string{
+					"reaper.autoschedule": "true",
+				},
 				SetStrValues: map[string]string{
-					"reaper.autoschedule":                               "true",
 					"reaper.autoschedule_properties.initialDelayPeriod": "PT10S",
 				},
 				KubectlOptions: defaultKubeCtlOptions,
@@ -247,11 +247,11 @@ var _ = Describe("Verify Stargate template", func() {
 			options := &helm.Options{
 				KubectlOptions: defaultKubeCtlOptions,
 				SetValues: map[string]string{
-					"stargate.enabled": "true",
+					"stargate.enabled":              "true",
+					"cassandra.datacenters[0].size": "1",
 				},
 				SetStrValues: map[string]string{
 					"cassandra.datacenters[0].name": targetDcName,
-					"cassandra.datacenters[0].size": "1",
 				},
 			}
 
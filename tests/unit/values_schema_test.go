@@ -0,0 +1,131 @@
+package unit_test
+
+import (
+	"path/filepath"
+
+	cassdcv1beta1 "github.com/k8ssandra/cass-operator/operator/pkg/apis/cassandra/v1beta1"
+	"github.com/k8ssandra/k8ssandra/pkg/values"
+	helmUtils "github.com/k8ssandra/k8ssandra/tests/unit/utils/helm"
+
+	"github.com/gruntwork-io/terratest/modules/helm"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Verify values schema", func() {
+	var (
+		helmChartPath string
+		err           error
+		cassdc        *cassdcv1beta1.CassandraDatacenter
+	)
+
+	BeforeEach(func() {
+		helmChartPath, err = filepath.Abs(ChartsPath)
+		Expect(err).To(BeNil())
+		cassdc = &cassdcv1beta1.CassandraDatacenter{}
+	})
+
+	renderTemplate := func(options *helm.Options) error {
+		return helmUtils.RenderAndUnmarshall("templates/cassandra/cassdc.yaml",
+			options, helmChartPath, HelmReleaseName,
+			func(renderedYaml string) error {
+				return helm.UnmarshalK8SYamlE(GinkgoT(), renderedYaml, cassdc)
+			})
+	}
+
+	// validate returns the problems values.Validate finds in a values file merged over the values of the chart
+	validate := func(file string) []string {
+		merged, err := values.Load(helmChartPath, file)
+		Expect(err).ToNot(HaveOccurred())
+		problems := make([]string, 0)
+		for _, err := range values.Validate(merged) {
+			problems = append(problems, err.Error())
+		}
+		return problems
+	}
+
+	Context("by rendering the chart", func() {
+		It("with a misspelled value", func() {
+			options := &helm.Options{
+				KubectlOptions: defaultKubeCtlOptions,
+				SetValues: map[string]string{
+					"cassandra.datacenter[0].name": "dc1",
+				},
+			}
+
+			err = renderTemplate(options)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Additional property datacenter is not allowed"))
+		})
+
+		It("with a value of the wrong type", func() {
+			options := &helm.Options{
+				KubectlOptions: defaultKubeCtlOptions,
+				SetValues: map[string]string{
+					"cassandra.datacenters[0].name": "dc1",
+					"cassandra.datacenters[0].size": "three",
+				},
+			}
+
+			err = renderTemplate(options)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cassandra.datacenters.0.size: Invalid type. Expected: integer, given: string"))
+		})
+
+		It("with an unknown value in a values file", func() {
+			options := &helm.Options{
+				KubectlOptions: defaultKubeCtlOptions,
+				ValuesFiles:    []string{"./testdata/invalid/unknown-values.yaml"},
+			}
+
+			err = renderTemplate(options)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Additional property datacenter is not allowed"))
+			Expect(err.Error()).To(ContainSubstring("Additional property heapMb is not allowed"))
+		})
+
+		It("with values of subcharts", func() {
+			options := &helm.Options{
+				KubectlOptions: defaultKubeCtlOptions,
+				SetValues: map[string]string{
+					"kube-prometheus-stack.grafana.adminPassword": "admin123",
+					"cass-operator.image":                         "k8ssandra/cass-operator:v1.7.0",
+				},
+			}
+
+			Expect(renderTemplate(options)).To(Succeed())
+		})
+	})
+
+	Context("by validating values files", func() {
+		It("with valid values", func() {
+			Expect(validate("./testdata/racks-affinity-values.yaml")).To(BeEmpty())
+		})
+
+		It("with an unknown value", func() {
+			Expect(validate("./testdata/invalid/unknown-values.yaml")).To(ConsistOf(
+				"cassandra: Additional property datacenter is not allowed",
+				"stargate: Additional property heapMb is not allowed",
+			))
+		})
+
+		It("with a heap larger than the memory limit", func() {
+			Expect(validate("./testdata/invalid/heap-values.yaml")).To(ConsistOf(
+				"cassandra.heap.size: 8G is not smaller than cassandra.resources.limits.memory 8Gi, Cassandra also needs off heap memory",
+				"cassandra.datacenters[0].heap.newGenSize: 2G is not smaller than the heap size 2G",
+			))
+		})
+
+		It("with more racks than nodes", func() {
+			Expect(validate("./testdata/invalid/racks-values.yaml")).To(ConsistOf(
+				"cassandra.datacenters[0].size: datacenter dc1 has 2 nodes for 3 racks, each rack needs at least one node",
+			))
+		})
+
+		It("with a version missing from versionImageMap", func() {
+			Expect(validate("./testdata/invalid/version-values.yaml")).To(ConsistOf(
+				ContainSubstring("cassandra.version: 3.11.6 is not a supported Cassandra version"),
+			))
+		})
+	})
+})